@@ -13,6 +13,7 @@ import (
 	"github.com/ksankeerth/open-image-registry/log"
 	"github.com/ksankeerth/open-image-registry/middleware"
 	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
 	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
 	"github.com/ksankeerth/open-image-registry/user"
 )
@@ -153,11 +154,52 @@ func (h *AuthAPIHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// RegistryToken handles GET /api/v1/auth/token
+// Docker clients are redirected here by registry listeners to obtain bearer tokens.
+// See: https://distribution.github.io/distribution/spec/auth/token/
+func (h *AuthAPIHandler) RegistryToken(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	scopes := r.URL.Query()["scope"]
+
+	username, password, ok := r.BasicAuth()
+	if r.Header.Get("Authorization") != "" && (!ok || username == "") {
+		httperrors.Unauthorized(w, 401, "Invalid authorization header")
+		return
+	}
+
+	result, err := h.svc.issueRegistryToken(r.Context(), username, password, service, scopes)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Issuing registry token failed due to errors")
+		httperrors.InternalError(w, 500, "Issuing token failed due to errors")
+		return
+	}
+
+	if !result.success {
+		httperrors.SendError(w, result.statusCode, result.errorMessage)
+		return
+	}
+
+	res := dockerv2.DockerRegistryLoginResponse{
+		Token:       result.token,
+		AccessToken: result.token,
+		ExpiresIn:   int64(result.expiresIn),
+		IssuedAt:    result.issuedAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Error occurred when writing registry token response to client")
+	}
+}
+
 func (h *AuthAPIHandler) Routes() chi.Router {
 
 	router := chi.NewRouter()
 	router.Route("/", func(r chi.Router) {
 		r.Post("/login", h.Login)
+		r.Get("/token", h.RegistryToken)
 		r.With(h.authenticator.Authenticate).Post("/logout", h.Logout)
 	})
 
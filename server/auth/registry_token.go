@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/security"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
+)
+
+type registryTokenResult struct {
+	statusCode   int
+	success      bool
+	errorMessage string
+	token        string
+	issuedAt     time.Time
+	expiresIn    int
+}
+
+// parseRegistryScope parses scope in the form of `repository:ns/repo:pull,push`
+// See: https://distribution.github.io/distribution/spec/auth/scope/
+func parseRegistryScope(scope string) (*dockerv2.RegistryTokenAccess, bool) {
+	first := strings.Index(scope, ":")
+	last := strings.LastIndex(scope, ":")
+	if first <= 0 || first == last || last == len(scope)-1 {
+		return nil, false
+	}
+
+	resourceType := scope[:first]
+	name := scope[first+1 : last]
+	if resourceType != constants.RegistryScopeTypeRepository && resourceType != constants.RegistryScopeTypeRegistry {
+		return nil, false
+	}
+	if name == "" {
+		return nil, false
+	}
+
+	var actions []string
+	for _, action := range strings.Split(scope[last+1:], ",") {
+		action = strings.TrimSpace(action)
+		if action != "" {
+			actions = append(actions, action)
+		}
+	}
+
+	return &dockerv2.RegistryTokenAccess{
+		Type:    resourceType,
+		Name:    name,
+		Actions: actions,
+	}, true
+}
+
+// issueRegistryToken issues bearer tokens for docker clients. If username is empty, an anonymous token is issued.
+// Requested scopes are validated but not granted in the token; registry requests are authorized against the access
+// grants of the user when they are served.
+func (svc *authService) issueRegistryToken(reqCtx context.Context, username, password, service string,
+	scopes []string) (*registryTokenResult, error) {
+	res := &registryTokenResult{}
+
+	if service != config.GetImageRegistryConfig().TokenService {
+		log.Logger().Warn().Msgf("Registry token requested for unknown service: %s", service)
+		res.statusCode = http.StatusBadRequest
+		res.errorMessage = "Unknown service"
+		return res, nil
+	}
+
+	for _, scope := range scopes {
+		for _, s := range strings.Split(scope, " ") {
+			if s == "" {
+				continue
+			}
+			if _, ok := parseRegistryScope(s); !ok {
+				log.Logger().Warn().Msgf("Registry token requested with invalid scope: %s", s)
+				res.statusCode = http.StatusBadRequest
+				res.errorMessage = "Invalid scope"
+				return res, nil
+			}
+		}
+	}
+
+	claims := map[string]any{
+		constants.ClaimSubject:  constants.AnonymousUsername,
+		constants.ClaimAudience: service,
+	}
+
+	if username != "" {
+		tx, err := svc.store.Begin(reqCtx)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("error occurred when starting transaction")
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+			} else {
+				tx.Commit()
+			}
+		}()
+
+		ctx := store.WithTxContext(reqCtx, tx)
+
+		userAccount, err := svc.store.Users().GetByUsername(ctx, username)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to issue registry token due to user retrieval errors: %s", username)
+			return nil, err
+		}
+
+		if userAccount == nil {
+			log.Logger().Warn().Msgf("Registry token requested for non-existing user: %s", username)
+			res.statusCode = http.StatusUnauthorized
+			res.errorMessage = "Invalid username or password!"
+			return res, nil
+		}
+
+		if userAccount.Locked {
+			res.statusCode = http.StatusForbidden
+			res.errorMessage = "User account has been locked! Contact system administrator."
+			return res, nil
+		}
+
+		currentPw, currentSalt, err := svc.store.Users().GetPasswordAndSalt(ctx, userAccount.Id)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to issue registry token due to user retrieval errors: %s", username)
+			return nil, err
+		}
+
+		if !security.ComparePasswordAndHash(password, currentSalt, currentPw) {
+			err = svc.store.Users().RecordFailedAttempt(ctx, username)
+			if err != nil {
+				log.Logger().Error().Err(err).Msgf("Unable to record failed attempt of user: %s", username)
+				return nil, err
+			}
+			if (userAccount.FailedAttempts + 1) > constants.MaxFailedLoginAttempts {
+				err = svc.store.Users().LockAccount(ctx, username, constants.ReasonLockedFailedLoginAttempts)
+				if err != nil {
+					log.Logger().Error().Err(err).Msgf("Unable to lock user account: %s", username)
+					return nil, err
+				}
+			}
+			res.statusCode = http.StatusUnauthorized
+			res.errorMessage = "Invalid username or password!"
+			return res, nil
+		}
+
+		role, err := svc.store.Users().GetRole(ctx, userAccount.Id)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to issue registry token due to role retrieval errors: %s", username)
+			return nil, err
+		}
+
+		claims[constants.ClaimSubject] = userAccount.Username
+		claims[constants.ClaimRole] = role
+	}
+
+	token, err := svc.jwtAuthenticator.Sign(claims)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Registry token generation failed for user: %s", claims[constants.ClaimSubject])
+		return nil, err
+	}
+
+	res.success = true
+	res.statusCode = http.StatusOK
+	res.token = token
+	res.issuedAt = time.Now()
+	res.expiresIn = config.GetAuthTokenConfig().Expiry
+
+	return res, nil
+}
@@ -1,5 +1,40 @@
 package upstream
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ErrNotFound is returned when the requested resource does not exist in the upstream registry
+var ErrNotFound = errors.New("resource not found in upstream registry")
+
+// StatusError is returned when the upstream registry responds with an unexpected status
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// IsUnavailable reports whether err is caused by the upstream registry being unreachable, rate limiting requests
+// (429) or failing with a server error (5xx), as opposed to rejecting the request
+func IsUnavailable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// UpstreamClient fetches images from an upstream registry. Repository names may have multiple path components
+// (eg: bitnami/charts/redis); they are forwarded to the upstream registry as they are.
 type UpstreamClient interface {
 	GetManifest(namespace, repository, identifier string) (content []byte, mediaType string, err error)
 
@@ -7,6 +42,13 @@ type UpstreamClient interface {
 
 	GetBlob(namespace, repository, digest string) (content []byte, err error)
 
+	// OpenBlob streams the blob from the upstream registry. size is -1 if the upstream registry doesn't report it.
+	// The caller must close the returned reader.
+	OpenBlob(namespace, repository, digest string) (content io.ReadCloser, size int64, err error)
+
 	HeadBlob(namespace, repository, digest string) (exists bool, err error)
-}
 
+	// ListTags lists tags of the repository using `n` and `last` pagination parameters. n less than 1 omits the
+	// limit. hasMore is true when the upstream registry advertises a next page via `Link` header.
+	ListTags(namespace, repository string, n int, last string) (tags []string, hasMore bool, err error)
+}
@@ -7,6 +7,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ksankeerth/open-image-registry/client/upstream"
@@ -99,14 +102,15 @@ func NewClient(cfg *Config) upstream.UpstreamClient {
 		config:     cfg,
 		tokenCache: lib.NewCache(1 * time.Minute),
 		httpClient: &http.Client{
-			Timeout: cfg.RequestTimeout,
 			Transport: &http.Transport{
 				DialContext: (&net.Dialer{
 					Timeout:   cfg.ConnectionTimeout,
 					KeepAlive: 30 * time.Second,
 				}).DialContext,
-				MaxIdleConnsPerHost: cfg.MaxConnections,
-				MaxIdleConns:        cfg.MaxIdleConnections,
+				// only waiting for the response is limited; bodies of large blobs are streamed for longer
+				ResponseHeaderTimeout: cfg.RequestTimeout,
+				MaxIdleConnsPerHost:   cfg.MaxConnections,
+				MaxIdleConns:          cfg.MaxIdleConnections,
 			},
 		},
 	}
@@ -165,7 +169,7 @@ func (d *dockerClient) GetManifest(namespace, repository, identifier string) (co
 			Str("url", url).
 			Str("response_body", string(body)).
 			Msg("Unexpected status code while fetching manifest")
-		return nil, "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, "", &upstream.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	content, err = io.ReadAll(resp.Body)
@@ -233,6 +237,11 @@ func (d *dockerClient) HeadManifest(namespace, repository, identifier string) (e
 	}
 	defer resp.Body.Close()
 
+	// failures of the registry aren't reported as missing content
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return false, &upstream.StatusError{StatusCode: resp.StatusCode}
+	}
+
 	exists = resp.StatusCode == http.StatusOK
 
 	log.Logger().Debug().
@@ -247,6 +256,35 @@ func (d *dockerClient) HeadManifest(namespace, repository, identifier string) (e
 }
 
 func (d *dockerClient) GetBlob(namespace, repository, digest string) (content []byte, err error) {
+	body, _, err := d.OpenBlob(namespace, repository, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	content, err = io.ReadAll(body)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to read blob response from upstream: %s/%s@%s", namespace,
+			repository, digest)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if d.config.LogBody {
+		log.Logger().Debug().Int("blob_size", len(content)).Msg("Blob fetched")
+	}
+
+	log.Logger().Debug().
+		Str("namespace", namespace).
+		Str("repository", repository).
+		Str("digest", digest).
+		Int("size", len(content)).
+		Msg("Blob fetched successfully")
+
+	return content, nil
+}
+
+func (d *dockerClient) OpenBlob(namespace, repository, digest string) (content io.ReadCloser, size int64,
+	err error) {
 	log.Logger().Debug().
 		Str("namespace", namespace).
 		Str("repository", repository).
@@ -260,7 +298,7 @@ func (d *dockerClient) GetBlob(namespace, repository, digest string) (content []
 			Str("repository", repository).
 			Str("digest", digest).
 			Msg("Failed to get token for blob fetch")
-		return nil, fmt.Errorf("failed to get token: %w", err)
+		return nil, 0, fmt.Errorf("failed to get token: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/v2/%s/%s/blobs/%s",
@@ -269,7 +307,7 @@ func (d *dockerClient) GetBlob(namespace, repository, digest string) (content []
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Failed to create blob request to %s", url)
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -279,38 +317,26 @@ func (d *dockerClient) GetBlob(namespace, repository, digest string) (content []
 		log.Logger().Error().Err(err).
 			Str("url", url).
 			Msg("Failed to fetch blob from upstream")
-		return nil, fmt.Errorf("failed to fetch blob: %w", err)
+		return nil, 0, fmt.Errorf("failed to fetch blob: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, 0, upstream.ErrNotFound
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		log.Logger().Error().
 			Int("status_code", resp.StatusCode).
 			Str("url", url).
 			Str("response_body", string(body)).
 			Msg("Unexpected status code while fetching blob")
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-	}
-
-	content, err = io.ReadAll(resp.Body)
-	if err != nil {
-		log.Logger().Error().Err(err).Msgf("Failed to read blob response from upstream: %s", url)
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if d.config.LogBody {
-		log.Logger().Debug().Int("blob_size", len(content)).Msg("Blob fetched")
+		return nil, 0, &upstream.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	log.Logger().Debug().
-		Str("namespace", namespace).
-		Str("repository", repository).
-		Str("digest", digest).
-		Int("size", len(content)).
-		Msg("Blob fetched successfully")
-
-	return content, nil
+	return resp.Body, resp.ContentLength, nil
 }
 
 func (d *dockerClient) HeadBlob(namespace, repository, digest string) (exists bool, err error) {
@@ -350,6 +376,11 @@ func (d *dockerClient) HeadBlob(namespace, repository, digest string) (exists bo
 	}
 	defer resp.Body.Close()
 
+	// failures of the registry aren't reported as missing content
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return false, &upstream.StatusError{StatusCode: resp.StatusCode}
+	}
+
 	exists = resp.StatusCode == http.StatusOK
 
 	log.Logger().Debug().
@@ -363,6 +394,89 @@ func (d *dockerClient) HeadBlob(namespace, repository, digest string) (exists bo
 	return exists, nil
 }
 
+func (d *dockerClient) ListTags(namespace, repository string, n int, last string) (tags []string, hasMore bool,
+	err error) {
+	log.Logger().Debug().
+		Str("namespace", namespace).
+		Str("repository", repository).
+		Int("n", n).
+		Str("last", last).
+		Msg("Listing tags")
+
+	token, err := d.getToken(namespace, repository, "pull")
+	if err != nil {
+		log.Logger().Error().Err(err).
+			Str("namespace", namespace).
+			Str("repository", repository).
+			Msg("Failed to get token for tag listing")
+		return nil, false, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	query := url.Values{}
+	if n > 0 {
+		query.Set("n", strconv.Itoa(n))
+	}
+	if last != "" {
+		query.Set("last", last)
+	}
+
+	reqURL := fmt.Sprintf("%s/v2/%s/%s/tags/list", d.config.RegistryURL, namespace, repository)
+	if len(query) > 0 {
+		reqURL = reqURL + "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to create tag list request to %s", reqURL)
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := d.doWithRetry(req)
+	if err != nil {
+		log.Logger().Error().Err(err).
+			Str("url", reqURL).
+			Msg("Failed to list tags from upstream")
+		return nil, false, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, upstream.ErrNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Logger().Error().
+			Int("status_code", resp.StatusCode).
+			Str("url", reqURL).
+			Str("response_body", string(body)).
+			Msg("Unexpected status code while listing tags")
+		return nil, false, &upstream.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var tagList struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tagList); err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to decode tag list response from %s", reqURL)
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	hasMore = strings.Contains(resp.Header.Get("Link"), `rel="next"`)
+
+	log.Logger().Debug().
+		Str("namespace", namespace).
+		Str("repository", repository).
+		Int("count", len(tagList.Tags)).
+		Bool("has_more", hasMore).
+		Msg("Tags listed successfully")
+
+	return tagList.Tags, hasMore, nil
+}
+
 func (d *dockerClient) getToken(namespace, repository, scope string) (string, error) {
 	cacheKey := fmt.Sprintf("token:%s:%s:%s", namespace, repository, scope)
 
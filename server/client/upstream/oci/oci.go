@@ -0,0 +1,540 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/client/upstream"
+	"github.com/ksankeerth/open-image-registry/lib"
+	"github.com/ksankeerth/open-image-registry/log"
+)
+
+// defaultTokenTTL is used when the token server doesn't report `expires_in`. The distribution spec defines 60
+// seconds as the minimum lifetime of a token.
+const defaultTokenTTL = 60 * time.Second
+
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+type Config struct {
+	RegistryURL string
+	Username    string
+	Password    string
+
+	ConnectionTimeout time.Duration
+	RequestTimeout    time.Duration
+
+	MaxConnections     int
+	MaxIdleConnections int
+
+	MaxRetries             int
+	RetryDelay             time.Duration
+	RetryBackOffMultiplier float32
+}
+
+// AuthConfig is the auth config of upstream registries served by this client. The auth scheme is discovered from
+// the registry; credentials are used for Basic auth and for requesting Bearer tokens.
+type AuthConfig struct {
+	Username   string `json:"username"`
+	Credential string `json:"credential"` // this could be password or PAT
+}
+
+// challenge is an auth challenge of the `WWW-Authenticate` header
+type challenge struct {
+	scheme string // lower case; basic or bearer
+	params map[string]string
+}
+
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// ociClient fetches images from registries implementing the OCI distribution spec. Requests are sent anonymously
+// until the registry challenges them; the challenge is remembered and used to authorize later requests.
+type ociClient struct {
+	config     *Config
+	tokenCache *lib.Cache
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	challenge *challenge
+}
+
+func NewClient(cfg *Config) upstream.UpstreamClient {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	if cfg.ConnectionTimeout == 0 {
+		cfg.ConnectionTimeout = 10 * time.Second
+	}
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = 30 * time.Second
+	}
+	if cfg.MaxConnections == 0 {
+		cfg.MaxConnections = 100
+	}
+	if cfg.MaxIdleConnections == 0 {
+		cfg.MaxIdleConnections = 10
+	}
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.RetryBackOffMultiplier == 0 {
+		cfg.RetryBackOffMultiplier = 2.0
+	}
+	cfg.RegistryURL = strings.TrimSuffix(cfg.RegistryURL, "/")
+
+	client := &ociClient{
+		config:     cfg,
+		tokenCache: lib.NewCache(1 * time.Minute),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   cfg.ConnectionTimeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				// only waiting for the response is limited; bodies of large blobs are streamed for longer
+				ResponseHeaderTimeout: cfg.RequestTimeout,
+				MaxIdleConnsPerHost:   cfg.MaxConnections,
+				MaxIdleConns:          cfg.MaxIdleConnections,
+			},
+		},
+	}
+
+	log.Logger().Info().Str("registry_url", cfg.RegistryURL).Msg("OCI distribution client initialized")
+
+	return client
+}
+
+func (c *ociClient) GetManifest(namespace, repository, identifier string) (content []byte, mediaType string,
+	err error) {
+	name := repositoryName(namespace, repository)
+
+	resp, err := c.do(http.MethodGet, fmt.Sprintf("/v2/%s/manifests/%s", name, identifier), name,
+		manifestMediaTypes)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err = checkStatus(resp); err != nil {
+		return nil, "", err
+	}
+
+	content, err = io.ReadAll(resp.Body)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to read manifest of %s:%s from upstream", name, identifier)
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return content, resp.Header.Get("Content-Type"), nil
+}
+
+func (c *ociClient) HeadManifest(namespace, repository, identifier string) (exists bool, err error) {
+	name := repositoryName(namespace, repository)
+
+	resp, err := c.do(http.MethodHead, fmt.Sprintf("/v2/%s/manifests/%s", name, identifier), name,
+		manifestMediaTypes)
+	if err != nil {
+		return false, fmt.Errorf("failed to check manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return headExists(resp)
+}
+
+func (c *ociClient) GetBlob(namespace, repository, digest string) (content []byte, err error) {
+	body, _, err := c.OpenBlob(namespace, repository, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	content, err = io.ReadAll(body)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to read blob %s of %s from upstream", digest,
+			repositoryName(namespace, repository))
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return content, nil
+}
+
+func (c *ociClient) OpenBlob(namespace, repository, digest string) (content io.ReadCloser, size int64, err error) {
+	name := repositoryName(namespace, repository)
+
+	resp, err := c.do(http.MethodGet, fmt.Sprintf("/v2/%s/blobs/%s", name, digest), name, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch blob: %w", err)
+	}
+
+	if err = checkStatus(resp); err != nil {
+		resp.Body.Close()
+		return nil, 0, err
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+func (c *ociClient) HeadBlob(namespace, repository, digest string) (exists bool, err error) {
+	name := repositoryName(namespace, repository)
+
+	resp, err := c.do(http.MethodHead, fmt.Sprintf("/v2/%s/blobs/%s", name, digest), name, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to check blob: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return headExists(resp)
+}
+
+func (c *ociClient) ListTags(namespace, repository string, n int, last string) (tags []string, hasMore bool,
+	err error) {
+	name := repositoryName(namespace, repository)
+
+	query := url.Values{}
+	if n > 0 {
+		query.Set("n", strconv.Itoa(n))
+	}
+	if last != "" {
+		query.Set("last", last)
+	}
+
+	path := fmt.Sprintf("/v2/%s/tags/list", name)
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+
+	resp, err := c.do(http.MethodGet, path, name, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err = checkStatus(resp); err != nil {
+		return nil, false, err
+	}
+
+	var tagList struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tagList); err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to decode tag list of %s from upstream", name)
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	hasMore = strings.Contains(resp.Header.Get("Link"), `rel="next"`)
+	return tagList.Tags, hasMore, nil
+}
+
+// do sends the request authorized with the known challenge of the registry. If the registry challenges the
+// request, the request is authorized according to the challenge and sent once more.
+func (c *ociClient) do(method, path, name string, accept []string) (*http.Response, error) {
+	reqURL := c.config.RegistryURL + path
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, reqURL, nil)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to create request to %s", reqURL)
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for _, mediaType := range accept {
+			req.Header.Add("Accept", mediaType)
+		}
+
+		c.mu.RLock()
+		ch := c.challenge
+		c.mu.RUnlock()
+
+		if err = c.authorize(req, ch, name); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.doWithRetry(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		next := parseChallenge(resp.Header.Values("WWW-Authenticate"))
+		resp.Body.Close()
+		if next == nil {
+			return nil, fmt.Errorf("registry rejected the request without a supported auth challenge")
+		}
+		// token of the previous challenge may have been revoked or lacks the scope of the request
+		if ch != nil && ch.scheme == "bearer" {
+			c.tokenCache.Delete(tokenCacheKey(ch, name))
+		}
+
+		c.mu.Lock()
+		c.challenge = next
+		c.mu.Unlock()
+	}
+}
+
+func (c *ociClient) authorize(req *http.Request, ch *challenge, name string) error {
+	if ch == nil {
+		return nil
+	}
+
+	switch ch.scheme {
+	case "basic":
+		if c.config.Username != "" {
+			req.SetBasicAuth(c.config.Username, c.config.Password)
+		}
+	case "bearer":
+		token, err := c.getToken(ch, name)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// getToken requests a token granting pull access to the repository from the realm of the Bearer challenge
+func (c *ociClient) getToken(ch *challenge, name string) (string, error) {
+	cacheKey := tokenCacheKey(ch, name)
+	if token := c.tokenCache.Get(cacheKey); token != "" {
+		return token, nil
+	}
+
+	realm, err := url.Parse(ch.params["realm"])
+	if err != nil || realm.Host == "" {
+		log.Logger().Error().Msgf("Invalid realm in auth challenge: %s", ch.params["realm"])
+		return "", fmt.Errorf("invalid realm in auth challenge: %s", ch.params["realm"])
+	}
+
+	query := realm.Query()
+	if service := ch.params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", name))
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to create token request to %s", realm.Host)
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	resp, err := c.doWithRetry(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		log.Logger().Error().Int("status_code", resp.StatusCode).Str("response_body", string(body)).
+			Msgf("Token request to %s failed", realm.Host)
+		return "", fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+
+	var tokenResp tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to decode token response from %s", realm.Host)
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	token := tokenResp.Token
+	if token == "" {
+		token = tokenResp.AccessToken
+	}
+	if token == "" {
+		log.Logger().Error().Msgf("Received empty token from %s", realm.Host)
+		return "", fmt.Errorf("received empty token from auth server")
+	}
+
+	ttl := defaultTokenTTL
+	if tokenResp.ExpiresIn > 0 {
+		ttl = time.Duration(tokenResp.ExpiresIn) * time.Second
+	}
+	// token must not be used after it expires in the registry
+	c.tokenCache.Set(cacheKey, token, ttl-ttl/10)
+
+	return token, nil
+}
+
+// doWithRetry sends the request, retrying on network errors and 5xx responses
+func (c *ociClient) doWithRetry(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	delay := c.config.RetryDelay
+
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay = time.Duration(float32(delay) * c.config.RetryBackOffMultiplier)
+		}
+
+		resp, err = c.httpClient.Do(req.Clone(context.Background()))
+		if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+
+		if err != nil {
+			log.Logger().Warn().Err(err).Int("attempt", attempt).Str("url", req.URL.String()).
+				Msg("Request to upstream registry failed")
+		} else {
+			log.Logger().Warn().Int("status_code", resp.StatusCode).Int("attempt", attempt).
+				Str("url", req.URL.String()).Msg("Upstream registry responded with server error")
+			if attempt < c.config.MaxRetries {
+				resp.Body.Close()
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("max retries exceeded: %w", err)
+	}
+	return resp, nil
+}
+
+// parseChallenge returns the Bearer challenge of the `WWW-Authenticate` headers, or else the Basic challenge. nil is
+// returned if neither is offered.
+func parseChallenge(headers []string) *challenge {
+	var basic *challenge
+	for _, header := range headers {
+		for _, ch := range parseChallenges(header) {
+			switch ch.scheme {
+			case "bearer":
+				if ch.params["realm"] != "" {
+					return ch
+				}
+			case "basic":
+				basic = ch
+			}
+		}
+	}
+	return basic
+}
+
+// parseChallenges parses challenges of a `WWW-Authenticate` header as defined by RFC 7235; eg:
+// `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull"`
+func parseChallenges(header string) []*challenge {
+	challenges := []*challenge{}
+	var current *challenge
+
+	s := strings.TrimSpace(header)
+	for s != "" {
+		token := readToken(s)
+		if token == "" {
+			// skip the malformed part up to the next separator
+			i := strings.IndexByte(s, ',')
+			if i < 0 {
+				break
+			}
+			s = strings.TrimSpace(s[i+1:])
+			continue
+		}
+		rest := strings.TrimLeft(s[len(token):], " \t")
+
+		if strings.HasPrefix(rest, "=") && current != nil {
+			value, remaining := readValue(strings.TrimLeft(rest[1:], " \t"))
+			current.params[strings.ToLower(token)] = value
+			s = remaining
+		} else {
+			current = &challenge{scheme: strings.ToLower(token), params: map[string]string{}}
+			challenges = append(challenges, current)
+			s = rest
+		}
+
+		s = strings.TrimLeft(s, " \t,")
+	}
+	return challenges
+}
+
+func readToken(s string) string {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '=' || r == '"'
+	})
+	if i < 0 {
+		return s
+	}
+	return s[:i]
+}
+
+// readValue reads a token or a quoted string and returns it along with the rest of s
+func readValue(s string) (value, rest string) {
+	if !strings.HasPrefix(s, `"`) {
+		token := readToken(s)
+		return token, s[len(token):]
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+func tokenCacheKey(ch *challenge, name string) string {
+	return fmt.Sprintf("token:%s:%s:%s", ch.params["realm"], ch.params["service"], name)
+}
+
+func repositoryName(namespace, repository string) string {
+	if namespace == "" {
+		return repository
+	}
+	return namespace + "/" + repository
+}
+
+// checkStatus returns upstream.ErrNotFound for 404 and an error for other responses except 200
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return upstream.ErrNotFound
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	log.Logger().Error().Int("status_code", resp.StatusCode).Str("url", resp.Request.URL.String()).
+		Str("response_body", string(body)).Msg("Unexpected status code from upstream registry")
+	return &upstream.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// headExists reports existence from the response of a HEAD request
+func headExists(resp *http.Response) (bool, error) {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, &upstream.StatusError{StatusCode: resp.StatusCode}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,287 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/client/upstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testManifest  = `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`
+	testBlob      = "blob content"
+	testBlobRef   = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	testMediaType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// fakeRegistry serves `library/alpine` and rejects requests for which authorized returns false along with the
+// challenge
+func fakeRegistry(t *testing.T, challenge string, authorized func(r *http.Request) bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.Header().Set("WWW-Authenticate", challenge)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/library/alpine/manifests/latest":
+			assert.Contains(t, r.Header.Values("Accept"), testMediaType)
+			w.Header().Set("Content-Type", testMediaType)
+			w.Write([]byte(testManifest))
+		case "/v2/library/alpine/blobs/" + testBlobRef:
+			w.Write([]byte(testBlob))
+		case "/v2/library/alpine/tags/list":
+			if r.URL.Query().Get("n") == "2" {
+				w.Header().Set("Link", `</v2/library/alpine/tags/list?n=2&last=3.19>; rel="next"`)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"name": "library/alpine", "tags": []string{"3.18", "3.19"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func assertPulls(t *testing.T, client upstream.UpstreamClient) {
+	t.Helper()
+
+	content, mediaType, err := client.GetManifest("library", "alpine", "latest")
+	require.NoError(t, err)
+	assert.Equal(t, testManifest, string(content))
+	assert.Equal(t, testMediaType, mediaType)
+
+	exists, err := client.HeadManifest("library", "alpine", "latest")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	blob, err := client.GetBlob("library", "alpine", testBlobRef)
+	require.NoError(t, err)
+	assert.Equal(t, testBlob, string(blob))
+
+	body, size, err := client.OpenBlob("library", "alpine", testBlobRef)
+	require.NoError(t, err)
+	streamed, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, testBlob, string(streamed))
+	assert.Equal(t, int64(len(testBlob)), size)
+
+	exists, err = client.HeadBlob("library", "alpine", testBlobRef)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	tags, hasMore, err := client.ListTags("library", "alpine", 2, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3.18", "3.19"}, tags)
+	assert.True(t, hasMore)
+}
+
+func TestAnonymous(t *testing.T) {
+	server := fakeRegistry(t, "", func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == ""
+	})
+	defer server.Close()
+
+	client := NewClient(&Config{RegistryURL: server.URL})
+	assertPulls(t, client)
+
+	_, _, err := client.GetManifest("library", "alpine", "unknown")
+	assert.ErrorIs(t, err, upstream.ErrNotFound)
+	_, err = client.GetBlob("library", "unknown", testBlobRef)
+	assert.ErrorIs(t, err, upstream.ErrNotFound)
+	_, _, err = client.ListTags("library", "unknown", 0, "")
+	assert.ErrorIs(t, err, upstream.ErrNotFound)
+
+	exists, err := client.HeadManifest("library", "alpine", "unknown")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestBasic(t *testing.T) {
+	server := fakeRegistry(t, `Basic realm="registry"`, func(r *http.Request) bool {
+		username, password, ok := r.BasicAuth()
+		return ok && username == "user" && password == "secret"
+	})
+	defer server.Close()
+
+	assertPulls(t, NewClient(&Config{RegistryURL: server.URL, Username: "user", Password: "secret"}))
+
+	_, _, err := NewClient(&Config{RegistryURL: server.URL, Username: "user", Password: "wrong"}).
+		GetManifest("library", "alpine", "latest")
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestBearer(t *testing.T) {
+	var tokenRequests atomic.Int32
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+
+		username, password, ok := r.BasicAuth()
+		if ok && (username != "user" || password != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "registry.test", r.URL.Query().Get("service"))
+		assert.Equal(t, "repository:library/alpine:pull", r.URL.Query().Get("scope"))
+
+		token := "anonymous-token"
+		if ok {
+			token = "user-token"
+		}
+		json.NewEncoder(w).Encode(map[string]any{"token": token, "expires_in": 300})
+	}))
+	defer tokenServer.Close()
+
+	challenge := fmt.Sprintf(`Bearer realm="%s/token",service="registry.test",scope="repository:library/alpine:pull"`,
+		tokenServer.URL)
+
+	t.Run("Anonymous token", func(t *testing.T) {
+		tokenRequests.Store(0)
+		server := fakeRegistry(t, challenge, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer anonymous-token"
+		})
+		defer server.Close()
+
+		assertPulls(t, NewClient(&Config{RegistryURL: server.URL}))
+		// token is reused for the following requests
+		assert.Equal(t, int32(1), tokenRequests.Load())
+	})
+
+	t.Run("User token", func(t *testing.T) {
+		server := fakeRegistry(t, challenge, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer user-token"
+		})
+		defer server.Close()
+
+		assertPulls(t, NewClient(&Config{RegistryURL: server.URL, Username: "user", Password: "secret"}))
+	})
+
+	t.Run("Invalid credentials", func(t *testing.T) {
+		server := fakeRegistry(t, challenge, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer user-token"
+		})
+		defer server.Close()
+
+		_, _, err := NewClient(&Config{RegistryURL: server.URL, Username: "user", Password: "wrong"}).
+			GetManifest("library", "alpine", "latest")
+		assert.ErrorContains(t, err, "token request failed with status 401")
+	})
+}
+
+func TestUnavailable(t *testing.T) {
+	var status atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{RegistryURL: server.URL})
+
+	for code, unavailable := range map[int]bool{
+		http.StatusTooManyRequests:     true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusInternalServerError: true,
+		http.StatusForbidden:           false,
+	} {
+		status.Store(int32(code))
+
+		_, _, err := client.GetManifest("library", "alpine", "latest")
+		require.Error(t, err)
+		assert.Equal(t, unavailable, upstream.IsUnavailable(err), "manifest status %d", code)
+
+		_, err = client.HeadBlob("library", "alpine", testBlobRef)
+		require.Error(t, err)
+		assert.Equal(t, unavailable, upstream.IsUnavailable(err), "blob status %d", code)
+	}
+
+	status.Store(http.StatusNotFound)
+	_, _, err := client.GetManifest("library", "alpine", "latest")
+	assert.False(t, upstream.IsUnavailable(err))
+
+	// unreachable registry
+	server.Close()
+	_, _, err = client.GetManifest("library", "alpine", "latest")
+	require.Error(t, err)
+	assert.True(t, upstream.IsUnavailable(err))
+}
+
+func TestParseChallenges(t *testing.T) {
+	tests := []struct {
+		header     string
+		challenges []*challenge
+	}{
+		{
+			`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:a/b:pull,push"`,
+			[]*challenge{{scheme: "bearer", params: map[string]string{"realm": "https://auth.docker.io/token",
+				"service": "registry.docker.io", "scope": "repository:a/b:pull,push"}}},
+		},
+		{
+			`Basic realm="Registry Realm"`,
+			[]*challenge{{scheme: "basic", params: map[string]string{"realm": "Registry Realm"}}},
+		},
+		{
+			`Basic realm=registry, Bearer realm="https://ghcr.io/token" , service = "ghcr.io"`,
+			[]*challenge{
+				{scheme: "basic", params: map[string]string{"realm": "registry"}},
+				{scheme: "bearer", params: map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io"}},
+			},
+		},
+		{
+			`Bearer realm="quoted \"realm\""`,
+			[]*challenge{{scheme: "bearer", params: map[string]string{"realm": `quoted "realm"`}}},
+		},
+		{"", []*challenge{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.challenges, parseChallenges(tt.header), tt.header)
+	}
+
+	// Bearer is preferred over Basic
+	ch := parseChallenge([]string{`Basic realm="registry"`, `Bearer realm="https://ghcr.io/token"`})
+	require.NotNil(t, ch)
+	assert.Equal(t, "bearer", ch.scheme)
+	assert.Nil(t, parseChallenge([]string{`Negotiate`}))
+}
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/library/alpine/blobs/" + testBlobRef:
+			// headers arrive in time; the body takes longer than the timeout
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(testBlob[:4]))
+			w.(http.Flusher).Flush()
+			time.Sleep(300 * time.Millisecond)
+			w.Write([]byte(testBlob[4:]))
+		case "/v2/library/alpine/manifests/latest":
+			time.Sleep(300 * time.Millisecond)
+			w.Header().Set("Content-Type", testMediaType)
+			w.Write([]byte(testManifest))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{RegistryURL: server.URL, RequestTimeout: 100 * time.Millisecond})
+
+	blob, _, err := client.OpenBlob("library", "alpine", testBlobRef)
+	require.NoError(t, err)
+	content, err := io.ReadAll(blob)
+	blob.Close()
+	require.NoError(t, err)
+	assert.Equal(t, testBlob, string(content))
+
+	_, _, err = client.GetManifest("library", "alpine", "latest")
+	require.Error(t, err)
+	assert.True(t, upstream.IsUnavailable(err))
+}
\ No newline at end of file
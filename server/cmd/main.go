@@ -33,6 +33,9 @@ func main() {
 
 	// ------------- parse flags and options ---------------
 	appHomeDir := flag.String("app_home", "", "Path to app home directory")
+	runGC := flag.Bool("gc", false, "Run garbage collection of the image registry and exit. It can't run while the "+
+		"server is running")
+	dryRun := flag.Bool("dry_run", false, "Report what garbage collection would remove without removing it")
 	flag.Parse()
 
 	if *appHomeDir == "" {
@@ -69,6 +72,30 @@ func main() {
 		log.Logger().Info().Msg("Development mode is enabled.")
 	}
 
+	// ------------ lock the database ---------------
+	// Removal of content is coordinated with pushes only within a process. Garbage collection from the command line
+	// can't be coordinated with a running server, hence only one process can use the database at a time.
+	dbLock, err := lib.LockFile(appConfig.Database.Path + ".lock")
+	switch {
+	case errors.Is(err, lib.ErrFileLocked) && *runGC:
+		log.Logger().Fatal().Msg("Garbage collection can't run while the server is running. Use POST /api/v1/gc instead.")
+		return
+	case errors.Is(err, lib.ErrFileLocked):
+		log.Logger().Fatal().Msg("Server startup failed since the database is used by another process.")
+		return
+	case errors.Is(err, lib.ErrFileLockUnsupported) && *runGC:
+		log.Logger().Fatal().Msg("Garbage collection from the command line isn't supported on this platform. " +
+			"Use POST /api/v1/gc instead.")
+		return
+	case errors.Is(err, lib.ErrFileLockUnsupported):
+		log.Logger().Warn().Msg("Database can't be locked on this platform.")
+	case err != nil:
+		log.Logger().Fatal().Err(err).Msg("Server startup failed due to database lock errors.")
+		return
+	default:
+		defer dbLock.Unlock()
+	}
+
 	// ------------ initialize database and daos ---------------
 
 	store, err := sqlite.New(appConfig.Database)
@@ -85,6 +112,27 @@ func main() {
 		return
 	}
 
+	// blobs stored per repository by earlier versions are moved to content addressed locations
+	err = registry.MigrateBlobStorage(context.Background(), store)
+	if err != nil {
+		log.Logger().Fatal().Err(err).Msg("Server startup failed due to blob storage migration errors")
+		return
+	}
+
+	garbageCollector := registry.NewGarbageCollector(store, appConfig.ImageRegistry.GC)
+	eventDispatcher := registry.NewEventDispatcher(store, appConfig.Notification.Webhook)
+	retentionManager := registry.NewRetentionManager(store, appConfig.ImageRegistry.Retention, eventDispatcher)
+	cacheEvictor := registry.NewCacheEvictor(store, appConfig.UpstreamRegistry.CacheEviction)
+
+	// ------------- run garbage collection if requested ---------------
+	if *runGC {
+		_, err = garbageCollector.Collect(context.Background(), *dryRun)
+		if err != nil {
+			log.Logger().Fatal().Err(err).Msg("Garbage collection failed")
+		}
+		return
+	}
+
 	// --------------------- Initialize admin user account ---------------
 	err = initializeAdminUserAccount(store, &appConfig.Admin)
 	if err != nil {
@@ -123,7 +171,8 @@ func main() {
 		time.Duration(authConfig.Expiry)*time.Second)
 
 	// ------------ start serving ManagementAPIs and UI -----------------------
-	appRouter := rest.AppRouter(&appConfig.WebApp, store, jwtAuth, accessManager, emailClient)
+	appRouter := rest.AppRouter(&appConfig.WebApp, store, jwtAuth, accessManager, emailClient, garbageCollector,
+		retentionManager, eventDispatcher, cacheEvictor)
 
 	address := fmt.Sprintf("%s:%d", appConfig.Server.Hostname, appConfig.Server.Port)
 
@@ -147,11 +196,34 @@ func main() {
 		log.Logger().Info().Msgf("Serving UI from: %s", appConfig.WebApp.DistPath)
 	}
 
-	go startRegistryListeners(appConfig.ImageRegistry.Enabled, appConfig.ImageRegistry.Port, store)
+	// registry events are delivered to webhooks in background
+	eventDispatcher.Start()
+
+	go startRegistryListeners(appConfig.ImageRegistry.Enabled, appConfig.ImageRegistry.Port, store, jwtAuth,
+		eventDispatcher)
+
+	// ------------- remove abandoned blob uploads ----------------------------
+	uploadSessionReaper := registry.NewUploadSessionReaper(store, appConfig.ImageRegistry.UploadSession)
+	if appConfig.ImageRegistry.Enabled {
+		uploadSessionReaper.Start()
+		garbageCollector.Start()
+		retentionManager.Start()
+	}
+
+	// ------------- enforce storage limits of upstream registries ------------
+	if appConfig.UpstreamRegistry.Enabled {
+		cacheEvictor.Start()
+	}
 
 	<-shutdown
 
 	log.Logger().Info().Msg("Server is about to shutdown.")
+	uploadSessionReaper.Stop()
+	garbageCollector.Stop()
+	retentionManager.Stop()
+	cacheEvictor.Stop()
+	eventDispatcher.Stop()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	err = server.Shutdown(ctx)
@@ -160,12 +232,14 @@ func main() {
 	}
 }
 
-func startRegistryListeners(localRegistryEnabled bool, localRegistryPort uint, store store.Store) {
+func startRegistryListeners(localRegistryEnabled bool, localRegistryPort uint, store store.Store, jwtProvider lib.JWTProvider,
+	events *registry.EventDispatcher) {
 	lm := listeners.GetListenerManager()
 
 	if localRegistryEnabled {
 		err := lm.RegisterListener(constants.HostedRegistryID, constants.HostedRegistryName, localRegistryPort,
-			registry.NewRegistryHandler(constants.HostedRegistryID, constants.HostedRegistryName, store).Routes(),
+			registry.NewRegistryHandler(constants.HostedRegistryID, constants.HostedRegistryName, store, jwtProvider,
+				events).Routes(),
 			time.Second*10)
 		if err != nil {
 			log.Logger().Error().Err(err).Msgf("Unable to start listener for LocalRegistry")
@@ -181,7 +255,8 @@ func startRegistryListeners(localRegistryEnabled bool, localRegistryPort uint, s
 
 	for _, upstreamAddr := range upstreamAddrs {
 		err = lm.RegisterListener(upstreamAddr.ID, upstreamAddr.Name, uint(upstreamAddr.Port),
-			registry.NewRegistryHandler(upstreamAddr.ID, upstreamAddr.Name, store).Routes(), time.Second*10)
+			registry.NewRegistryHandler(upstreamAddr.ID, upstreamAddr.Name, store, jwtProvider, events).Routes(),
+			time.Second*10)
 		if err != nil {
 			log.Logger().Error().Err(err).Msgf("Unable to start listener for %s", upstreamAddr.Name)
 			continue
@@ -45,10 +45,65 @@ type ImageRegistryConfig struct {
 	CreateNamespaceOnPush bool `yaml:"create_namespace_on_push"`
 	// if this is true, it allows developers to create repository on docker push
 	CreateRepositoryOnPush bool `yaml:"create_repository_on_push"`
+	// TokenRealm is the url of the token endpoint advertised to docker clients in `WWW-Authenticate` challenge.
+	// If it is not set, token endpoint of management server will be used.
+	TokenRealm string `yaml:"token_realm"`
+	// TokenService is the `service` advertised in the challenge and verified against `aud` claim of registry tokens.
+	TokenService string `yaml:"token_service"`
+	// Delete controls whether images can be deleted through the registry API
+	Delete RegistryDeleteConfig `yaml:"delete"`
+	// UploadSession controls how long incomplete blob uploads are kept
+	UploadSession UploadSessionConfig `yaml:"upload_session"`
+	// GC controls garbage collection of manifests and blobs which are no longer referenced
+	GC GCConfig `yaml:"gc"`
+	// Retention controls how often retention rules of namespaces and repositories are applied
+	Retention RetentionConfig `yaml:"retention"`
+}
+
+type UploadSessionConfig struct {
+	// MaxIdleAge is the time an upload session is kept without receiving content. Zero disables the reaper.
+	MaxIdleAge time.Duration `yaml:"max_idle_age"`
+	// ReapInterval is how often idle upload sessions are looked up
+	ReapInterval time.Duration `yaml:"reap_interval"`
+}
+
+type GCConfig struct {
+	// GracePeriod protects manifests and blobs pushed recently from being collected so that in-flight pushes, which
+	// upload blobs before the manifest referring them, are not affected.
+	GracePeriod time.Duration `yaml:"grace_period"`
+	// Interval is how often garbage collection runs in background. Zero disables scheduled runs.
+	Interval time.Duration `yaml:"interval"`
+}
+
+type RetentionConfig struct {
+	// Interval is how often retention rules are applied in background. Zero disables scheduled runs.
+	Interval time.Duration `yaml:"interval"`
+}
+
+type RegistryDeleteConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Namespaces overrides `enabled` for the given namespaces. eg: {"releases": false}
+	Namespaces map[string]bool `yaml:"namespaces"`
+}
+
+// IsAllowed reports whether images of the given namespace can be deleted
+func (d *RegistryDeleteConfig) IsAllowed(namespace string) bool {
+	if enabled, ok := d.Namespaces[namespace]; ok {
+		return enabled
+	}
+	return d.Enabled
 }
 
 type UpstreamRegistryConfig struct {
 	Enabled bool `yaml:"enabled"`
+	// CacheEviction controls how often storage limits of upstream registries are enforced
+	CacheEviction CacheEvictionConfig `yaml:"cache_eviction"`
+}
+
+type CacheEvictionConfig struct {
+	// Interval is how often the cache of each upstream registry is checked against its storage limit in background.
+	// Zero disables scheduled runs.
+	Interval time.Duration `yaml:"interval"`
 }
 
 type AdminUserAccountConfig struct {
@@ -73,6 +128,20 @@ type StorageConfig struct {
 
 type NotificationConfig struct {
 	Email EmailSenderConfig `yaml:"email"`
+	// Webhook controls delivery of registry events to webhooks
+	Webhook WebhookConfig `yaml:"webhook"`
+}
+
+type WebhookConfig struct {
+	// MaxAttempts is the number of times an event is sent to a webhook before the delivery is marked as failed
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the delay before the first retry. It doubles on each retry.
+	Backoff time.Duration `yaml:"backoff"`
+	// Timeout is the time a webhook is given to respond
+	Timeout time.Duration `yaml:"timeout"`
+	// QueueSize is the number of events waiting to be delivered. Events are dropped when the queue is full so
+	// that registry requests are never blocked by webhooks.
+	QueueSize int `yaml:"queue_size"`
 }
 
 type EmailSenderConfig struct {
@@ -145,6 +214,14 @@ func GetTestingConfig() TestingConfig {
 	return appConfiguration.Testing
 }
 
+func GetMgmtServerConfig() MgmtServerConfig {
+	if appConfiguration == nil {
+		return MgmtServerConfig{}
+	}
+
+	return appConfiguration.Server
+}
+
 func GetImageRegistryConfig() ImageRegistryConfig {
 	if appConfiguration == nil {
 		return ImageRegistryConfig{}
@@ -207,6 +284,14 @@ func resolveAppHomeVars(cfg *AppConfig, appHome string) {
 	cfg.WebApp.DistPath = replace(cfg.WebApp.DistPath)
 	cfg.Security.AuthToken.PrivateKeyPath = replace(cfg.Security.AuthToken.PrivateKeyPath)
 	cfg.Security.AuthToken.PublicKeyPath = replace(cfg.Security.AuthToken.PublicKeyPath)
+
+	if cfg.ImageRegistry.TokenRealm == "" {
+		cfg.ImageRegistry.TokenRealm = fmt.Sprintf("http://%s:%d%s", cfg.Server.Hostname, cfg.Server.Port,
+			constants.RegistryTokenEndpoint)
+	}
+	if cfg.ImageRegistry.TokenService == "" {
+		cfg.ImageRegistry.TokenService = constants.DefaultRegistryTokenService
+	}
 }
 
 func validateConfig(cfg *AppConfig) (bool, string) {
@@ -226,6 +311,25 @@ func validateConfig(cfg *AppConfig) (bool, string) {
 		if cfg.ImageRegistry.Port == 0 {
 			return false, "image_registry.port must be greater than 0 when image_registry.enabled = true"
 		}
+		if cfg.ImageRegistry.UploadSession.MaxIdleAge < 0 {
+			return false, "image_registry.upload_session.max_idle_age cannot be negative"
+		}
+		if cfg.ImageRegistry.UploadSession.MaxIdleAge > 0 && cfg.ImageRegistry.UploadSession.ReapInterval <= 0 {
+			return false, "image_registry.upload_session.reap_interval must be greater than 0"
+		}
+		if cfg.ImageRegistry.GC.GracePeriod < 0 {
+			return false, "image_registry.gc.grace_period cannot be negative"
+		}
+		if cfg.ImageRegistry.GC.Interval < 0 {
+			return false, "image_registry.gc.interval cannot be negative"
+		}
+		if cfg.ImageRegistry.Retention.Interval < 0 {
+			return false, "image_registry.retention.interval cannot be negative"
+		}
+	}
+
+	if cfg.UpstreamRegistry.CacheEviction.Interval < 0 {
+		return false, "upstream_registry.cache_eviction.interval cannot be negative"
 	}
 
 	// --- Admin Account ---
@@ -304,6 +408,21 @@ func validateConfig(cfg *AppConfig) (bool, string) {
 		}
 	}
 
+	// --- Notification / Webhook ---
+	webhookCfg := cfg.Notification.Webhook
+	if webhookCfg.MaxAttempts < 1 {
+		return false, "notification.webhook.max_attempts must be greater than 0"
+	}
+	if webhookCfg.Backoff < 0 {
+		return false, "notification.webhook.backoff cannot be negative"
+	}
+	if webhookCfg.Timeout <= 0 {
+		return false, "notification.webhook.timeout must be greater than 0"
+	}
+	if webhookCfg.QueueSize < 1 {
+		return false, "notification.webhook.queue_size must be greater than 0"
+	}
+
 	// Security - Auth Token
 	if cfg.Security.AuthToken.Issuer == "" {
 		return false, "Auth token issuer must be set for security.auth_token.issuer"
@@ -342,9 +461,22 @@ func defaultConfig(severHome string) *AppConfig {
 			Enabled:  true,
 			Hostname: "localhost",
 			Port:     5000,
+			UploadSession: UploadSessionConfig{
+				MaxIdleAge:   24 * time.Hour,
+				ReapInterval: time.Hour,
+			},
+			GC: GCConfig{
+				GracePeriod: time.Hour,
+			},
+			Retention: RetentionConfig{
+				Interval: 24 * time.Hour,
+			},
 		},
 		UpstreamRegistry: UpstreamRegistryConfig{
 			Enabled: true,
+			CacheEviction: CacheEvictionConfig{
+				Interval: 10 * time.Minute,
+			},
 		},
 		Admin: AdminUserAccountConfig{
 			Username:      "admin",
@@ -364,6 +496,12 @@ func defaultConfig(severHome string) *AppConfig {
 			Email: EmailSenderConfig{
 				Enabled: false,
 			},
+			Webhook: WebhookConfig{
+				MaxAttempts: 5,
+				Backoff:     time.Second,
+				Timeout:     10 * time.Second,
+				QueueSize:   1000,
+			},
 		},
 		Audit: AuditEventsConfig{
 			Enable:               true,
@@ -6,8 +6,9 @@ const (
 )
 
 const (
-	ClaimSubject = "sub"
-	ClaimRole    = "role"
+	ClaimSubject  = "sub"
+	ClaimRole     = "role"
+	ClaimAudience = "aud"
 )
 
 const (
@@ -16,4 +17,18 @@ const (
 	ContextSignatureHash = "sig_hash"
 	ContextExpAt         = "exp"
 	ContextIssuedAt      = "iat"
+	ContextEventRequest  = "event_request"
+)
+
+// Registry tokens are issued to anonymous clients (eg: `docker pull` without login) using this subject.
+const AnonymousUsername = "anonymous"
+
+const (
+	RegistryScopeTypeRepository = "repository"
+	RegistryScopeTypeRegistry   = "registry"
+
+	RegistryActionPull   = "pull"
+	RegistryActionPush   = "push"
+	RegistryActionDelete = "delete"
+	RegistryActionAll    = "*"
 )
@@ -13,4 +13,10 @@ const (
 // security
 const (
 	TokenSigningAlgoES256 = "ES256"
+)
+
+// registry token authentication
+const (
+	DefaultRegistryTokenService = "open-image-registry"
+	RegistryTokenEndpoint       = "/api/v1/auth/token"
 )
\ No newline at end of file
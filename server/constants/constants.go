@@ -39,4 +39,26 @@ const (
 	RegistryVendorArtifactory = "artifactory"
 	RegistryVendorNexus       = "nexus"
 	RegistryVendorCustom      = "custom"
+)
+
+// Auth types of upstream registries which are supported by upstream clients
+const (
+	UpstreamAuthTypeAnonymous = "anonymous"
+	UpstreamAuthTypeBasic     = "basic"
+	UpstreamAuthTypeBearer    = "bearer"
+)
+
+// Actions of registry events delivered to webhooks. push, pull and delete are defined by Docker distribution
+// notifications; create is emitted when a repository is created.
+const (
+	WebhookActionPush   = "push"
+	WebhookActionPull   = "pull"
+	WebhookActionDelete = "delete"
+	WebhookActionCreate = "create"
+)
+
+const (
+	WebhookDeliveryPending   = "Pending"
+	WebhookDeliveryDelivered = "Delivered"
+	WebhookDeliveryFailed    = "Failed"
 )
\ No newline at end of file
@@ -24,6 +24,11 @@ var (
 	AllowedRepositorySortFields   = []string{"name", "tags", "created_at"}
 )
 
+var (
+	AllowedUpstreamFilterFields = []string{"vendor", "state"}
+	AllowedUpstreamSortFields   = []string{"name", "port", "created_at"}
+)
+
 var (
 	AllowedResourceAccessFilterFields = []string{"access_level", "user_id", "resource_type", "resource_id"}
 	AllowedResourceAccessSortFields   = []string{"user", "granted_user", "granted_at"}
@@ -40,6 +40,10 @@ CREATE TABLE IF NOT EXISTS UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG (
   CLEANUP_THRESHOLD_PERCENTAGE REAL NOT NULL DEFAULT 80.0 CHECK(
     CLEANUP_THRESHOLD_PERCENTAGE BETWEEN 50.0 AND 95.0
   ),
+  -- expired cache entries are served when the upstream registry is unreachable, rate limiting or failing
+  STALE_IF_ERROR INTEGER NOT NULL DEFAULT 0 CHECK(STALE_IF_ERROR IN (0, 1)),
+  -- the upstream registry is never contacted; only cached content is served
+  OFFLINE_MODE INTEGER NOT NULL DEFAULT 0 CHECK(OFFLINE_MODE IN (0, 1)),
   CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   FOREIGN KEY (REGISTRY_ID) REFERENCES UPSTREAM_REGISTRY(ID) ON DELETE CASCADE,
@@ -104,19 +108,85 @@ CREATE TABLE IF NOT EXISTS REGISTRY_REPOSITORY (
   FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE
 );
 
+-- Tags matching any of the patterns can't be moved to another manifest once pushed.
+-- PATTERN is either a glob (eg: v*, release-*) or `semver`
+CREATE TABLE IF NOT EXISTS REGISTRY_REPOSITORY_IMMUTABLE_TAG (
+  REPOSITORY_ID TEXT NOT NULL,
+  PATTERN TEXT NOT NULL,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (REPOSITORY_ID, PATTERN),
+  FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE
+);
+
+-- Storage limits of namespaces and repositories. NULL means unlimited. Usage is the size of the blobs and the
+-- manifests of the repositories; images are counted as the manifests stored.
+CREATE TABLE IF NOT EXISTS REGISTRY_NAMESPACE_QUOTA (
+  NAMESPACE_ID TEXT PRIMARY KEY,
+  MAX_BYTES INTEGER,
+  MAX_IMAGES INTEGER,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS REGISTRY_REPOSITORY_QUOTA (
+  REPOSITORY_ID TEXT PRIMARY KEY,
+  MAX_BYTES INTEGER,
+  MAX_IMAGES INTEGER,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE
+);
+
+-- Retention rules of namespaces and repositories. Tags matching TAG_PATTERN are deleted unless they are among the
+-- KEEP_LAST most recently pushed matching tags or pushed within MAX_AGE_DAYS. NULL disables the condition.
+-- Stable tags are never deleted. Rules of a namespace apply to all its repositories.
+CREATE TABLE IF NOT EXISTS REGISTRY_NAMESPACE_RETENTION_RULE (
+  NAMESPACE_ID TEXT NOT NULL,
+  TAG_PATTERN TEXT NOT NULL,
+  KEEP_LAST INTEGER,
+  MAX_AGE_DAYS INTEGER,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (NAMESPACE_ID, TAG_PATTERN),
+  FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS REGISTRY_REPOSITORY_RETENTION_RULE (
+  REPOSITORY_ID TEXT NOT NULL,
+  TAG_PATTERN TEXT NOT NULL,
+  KEEP_LAST INTEGER,
+  MAX_AGE_DAYS INTEGER,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (REPOSITORY_ID, TAG_PATTERN),
+  FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE
+);
+
 --------------- End of Namespace and Repository ----------------------------------------------------------
 
 --------------- Image blob, manifest, tag and mapping -----------------------------------------------
 
+-- Content of a blob is stored once at a content addressed location (eg: blobs/sha256/ab/abcd...). REF_COUNT is the
+-- number of IMAGE_BLOB_META records linking the blob to repositories; it is maintained by trg_unlink_image_blob on
+-- removal. Content is removed when it drops to 0.
+CREATE TABLE IF NOT EXISTS IMAGE_BLOB (
+  DIGEST TEXT PRIMARY KEY,
+  SIZE INTEGER NOT NULL,
+  LOCATION TEXT NOT NULL UNIQUE,
+  REF_COUNT INTEGER NOT NULL DEFAULT 0,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+-- Links a blob to a repository. Repositories sharing a blob refer the same LOCATION.
 CREATE TABLE IF NOT EXISTS IMAGE_BLOB_META (
   NAMESPACE_ID TEXT NOT NULL,
   REGISTRY_ID TEXT NOT NULL,
   REPOSITORY_ID TEXT NOT NULL,
   BLOB_DIGEST TEXT NOT NULL,
   SIZE INTEGER NOT NULL,
-  LOCATION TEXT NOT NULL UNIQUE,
+  LOCATION TEXT NOT NULL,
   CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  LAST_ACCESSED_AT TIMESTAMP, -- last pull of blobs cached from upstream registries; NULL until pulled again
   UNIQUE (REGISTRY_ID, NAMESPACE_ID, REPOSITORY_ID, BLOB_DIGEST),
   FOREIGN KEY (REGISTRY_ID) REFERENCES UPSTREAM_REGISTRY(ID) ON DELETE CASCADE,
   FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE,
@@ -128,6 +198,8 @@ CREATE TABLE IF NOT EXISTS IMAGE_BLOB_UPLOAD_SESSION(
   NAMESPACE_ID TEXT NOT NULL,
   REPOSITORY_ID TEXT NOT NULL,
   BYTES_RECEIVED INT DEFAULT 0,
+  SHA256_STATE BLOB,
+  SHA512_STATE BLOB,
   CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID),
@@ -143,19 +215,23 @@ CREATE TABLE IF NOT EXISTS IMAGE_TAG (
   TAG TEXT NOT NULL,
   CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PUSHED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   UNIQUE (REGISTRY_ID, NAMESPACE_ID, REPOSITORY_ID, TAG),
   FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE,
   FOREIGN KEY (REGISTRY_ID) REFERENCES UPSTREAM_REGISTRY(ID) ON DELETE CASCADE,
   FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE
 );
 
+-- A tag points to exactly one manifest, while a manifest can be referred by many tags (eg: 1.4.2 and latest)
 CREATE TABLE IF NOT EXISTS IMAGE_MANIFEST_TAG_MAPPING (
-  MANIFEST_ID  TEXT NOT NULL UNIQUE,
+  MANIFEST_ID  TEXT NOT NULL,
   TAG_ID TEXT NOT NULL UNIQUE,
   FOREIGN KEY (MANIFEST_ID) REFERENCES IMAGE_MANIFEST(ID),
   FOREIGN KEY (TAG_ID) REFERENCES IMAGE_TAG(ID)
 );
 
+CREATE INDEX IF NOT EXISTS IDX_IMAGE_MANIFEST_TAG_MAPPING_MANIFEST ON IMAGE_MANIFEST_TAG_MAPPING(MANIFEST_ID);
+
 -- For the cached manifest, DIGEST = UNIQUE_DIGEST
 -- For the hosted manifest, DIGEST != UNIQUE_DIGEST
 CREATE TABLE IF NOT EXISTS IMAGE_MANIFEST (
@@ -170,12 +246,27 @@ CREATE TABLE IF NOT EXISTS IMAGE_MANIFEST (
   UNIQUE_DIGEST TEXT NOT NULL, 
   CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  LAST_ACCESSED_AT TIMESTAMP, -- last pull of manifests cached from upstream registries; NULL until pulled again
   UNIQUE (REGISTRY_ID, NAMESPACE_ID, REPOSITORY_ID, UNIQUE_DIGEST),
   FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE,
   FOREIGN KEY (REGISTRY_ID) REFERENCES UPSTREAM_REGISTRY(ID) ON DELETE CASCADE,
   FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE
 );
 
+-- Manifests which refer another manifest via `subject` field (eg: signatures, SBOMs). SUBJECT_DIGEST is not
+-- a reference to IMAGE_MANIFEST since referrers can be pushed before their subject.
+CREATE TABLE IF NOT EXISTS IMAGE_MANIFEST_REFERRER (
+  MANIFEST_ID TEXT PRIMARY KEY,
+  REPOSITORY_ID TEXT NOT NULL,
+  SUBJECT_DIGEST TEXT NOT NULL,
+  ARTIFACT_TYPE TEXT NOT NULL DEFAULT '',
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (MANIFEST_ID) REFERENCES IMAGE_MANIFEST(ID) ON DELETE CASCADE,
+  FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS IDX_IMAGE_MANIFEST_REFERRER_SUBJECT ON IMAGE_MANIFEST_REFERRER(REPOSITORY_ID, SUBJECT_DIGEST);
+
 CREATE TABLE IF NOT EXISTS IMAGE_REGISTRY_CACHE (
   NAMESPACE_ID TEXT NOT NULL,
   REGISTRY_ID TEXT NOT NULL,
@@ -187,6 +278,18 @@ CREATE TABLE IF NOT EXISTS IMAGE_REGISTRY_CACHE (
   UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Cached images of upstream registries which are never evicted. REFERENCE is a tag or a digest of the image; the
+-- manifests, child manifests and blobs of the image are kept once it is cached.
+CREATE TABLE IF NOT EXISTS IMAGE_REGISTRY_CACHE_PIN (
+  REGISTRY_ID TEXT NOT NULL,
+  REPOSITORY_ID TEXT NOT NULL,
+  REFERENCE TEXT NOT NULL,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (REPOSITORY_ID, REFERENCE),
+  FOREIGN KEY (REGISTRY_ID) REFERENCES UPSTREAM_REGISTRY(ID) ON DELETE CASCADE,
+  FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE
+);
+
 ------------------------------------------------------------------------------------------------------------
 -- Triggers to auto-update UPDATED_AT on changes
 ------------------------------------------------------------------------------------------------------------
@@ -275,6 +378,43 @@ BEGIN
     WHERE rowid = NEW.rowid;
 END;
 
+-- Unlinks the blob content when a link is removed. Content left without links is removed by garbage collection.
+DROP TRIGGER IF EXISTS trg_unlink_image_blob;
+CREATE TRIGGER trg_unlink_image_blob
+AFTER DELETE ON IMAGE_BLOB_META
+FOR EACH ROW
+BEGIN
+    UPDATE IMAGE_BLOB
+    SET REF_COUNT = REF_COUNT - 1, UPDATED_AT = CURRENT_TIMESTAMP
+    WHERE DIGEST = OLD.BLOB_DIGEST;
+END;
+
+-- Links of blobs are removed along with their repositories, namespaces and upstream registries. Foreign keys aren't
+-- enforced by the connection; therefore, these don't rely on ON DELETE CASCADE.
+DROP TRIGGER IF EXISTS trg_unlink_repository_blobs;
+CREATE TRIGGER trg_unlink_repository_blobs
+AFTER DELETE ON REGISTRY_REPOSITORY
+FOR EACH ROW
+BEGIN
+    DELETE FROM IMAGE_BLOB_META WHERE REPOSITORY_ID = OLD.ID;
+END;
+
+DROP TRIGGER IF EXISTS trg_unlink_namespace_blobs;
+CREATE TRIGGER trg_unlink_namespace_blobs
+AFTER DELETE ON REGISTRY_NAMESPACE
+FOR EACH ROW
+BEGIN
+    DELETE FROM IMAGE_BLOB_META WHERE NAMESPACE_ID = OLD.ID;
+END;
+
+DROP TRIGGER IF EXISTS trg_unlink_upstream_blobs;
+CREATE TRIGGER trg_unlink_upstream_blobs
+AFTER DELETE ON UPSTREAM_REGISTRY
+FOR EACH ROW
+BEGIN
+    DELETE FROM IMAGE_BLOB_META WHERE REGISTRY_ID = OLD.ID;
+END;
+
 -- Trigger for IMAGE_TAG table
 DROP TRIGGER IF EXISTS trg_update_image_tag;
 CREATE TRIGGER trg_update_image_tag
@@ -481,4 +621,41 @@ CREATE TABLE IF NOT EXISTS REVOKED_TOKENS(
   ISSUED_AT BIGINT NOT NULL,
   USER_ID TEXT NOT NULL,
   FOREIGN KEY (USER_ID) REFERENCES USER_ACCOUNT(ID) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+-------------------------------- webhooks ----------------------------------
+
+-- Webhooks receive registry events in the Docker distribution notification format. Webhooks without NAMESPACE_ID
+-- receive events of all namespaces. EVENTS is a comma separated list of actions (push, pull, delete, create);
+-- empty means all actions. Requests are signed with SECRET if it is set.
+CREATE TABLE IF NOT EXISTS WEBHOOK (
+  ID TEXT PRIMARY KEY DEFAULT (HEX(RANDOMBLOB(16))),
+  NAME TEXT NOT NULL UNIQUE,
+  URL TEXT NOT NULL,
+  SECRET TEXT NOT NULL DEFAULT '',
+  NAMESPACE_ID TEXT,
+  EVENTS TEXT NOT NULL DEFAULT '',
+  ENABLED INT NOT NULL DEFAULT 1,
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE
+);
+
+-- Delivery history of webhooks. A delivery is `Pending` until it is accepted by the endpoint or attempts are
+-- exhausted. RESPONSE_CODE and ERROR describe the last attempt.
+CREATE TABLE IF NOT EXISTS WEBHOOK_DELIVERY (
+  ID TEXT PRIMARY KEY DEFAULT (HEX(RANDOMBLOB(16))),
+  WEBHOOK_ID TEXT NOT NULL,
+  EVENT_ID TEXT NOT NULL,
+  ACTION TEXT NOT NULL,
+  PAYLOAD TEXT NOT NULL,
+  STATUS TEXT NOT NULL DEFAULT 'Pending' CHECK(STATUS IN ('Pending', 'Delivered', 'Failed')),
+  ATTEMPTS INTEGER NOT NULL DEFAULT 0,
+  RESPONSE_CODE INTEGER,
+  ERROR TEXT NOT NULL DEFAULT '',
+  CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (WEBHOOK_ID) REFERENCES WEBHOOK(ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS IDX_WEBHOOK_DELIVERY_WEBHOOK ON WEBHOOK_DELIVERY(WEBHOOK_ID, CREATED_AT);
\ No newline at end of file
@@ -61,7 +61,7 @@ var StatusCodes = map[string]int{
 	ErrCodeBlobUnknown:             404,
 	ErrCodeBlobUploadInvalid:       400,
 	ErrCodeBlobUploadUnknown:       404,
-	ErrCodeManifestBlobUnknown:     404,
+	ErrCodeManifestBlobUnknown:     400,
 	ErrCodeDigestInvalid:           400,
 	ErrCodeSizeInvalid:             400,
 	ErrCodeRangeInvalid:            416,
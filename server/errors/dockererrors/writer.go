@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // WriteError writes a single Docker Registry error response
@@ -82,12 +83,13 @@ func WriteInvalidDigest(w http.ResponseWriter, digest string) {
 	WriteError(w, ErrCodeDigestInvalid, detail)
 }
 
+// WriteBlobUnknownErrors reports blobs referred by a manifest, but not pushed to the repository
 func WriteBlobUnknownErrors(w http.ResponseWriter, unknownBlobs []string) {
 	var dockerErrors []DockerError
 
 	for _, digest := range unknownBlobs {
 		detail := map[string]string{"digest": digest}
-		dockerErrors = append(dockerErrors, NewDockerError(ErrCodeBlobUnknown, detail))
+		dockerErrors = append(dockerErrors, NewDockerError(ErrCodeManifestBlobUnknown, detail))
 	}
 
 	WriteErrors(w, dockerErrors)
@@ -109,6 +111,34 @@ func WriteInvalidTag(w http.ResponseWriter) {
 	WriteError(w, ErrCodeTagInvalid, nil)
 }
 
+// WriteImmutableTag writes DENIED error for pushes which try to move an immutable tag to another manifest
+func WriteImmutableTag(w http.ResponseWriter, tag string) {
+	dockerError := NewDockerError(ErrCodeDenied, map[string]string{"tag": tag})
+	dockerError.Message = fmt.Sprintf("tag %s is immutable and can't be moved to another manifest", tag)
+	WriteErrors(w, []DockerError{dockerError})
+}
+
+// WriteImmutableTagDeletion writes DENIED error for deletions of an immutable tag or of a manifest it points to
+func WriteImmutableTagDeletion(w http.ResponseWriter, tag string) {
+	dockerError := NewDockerError(ErrCodeDenied, map[string]string{"tag": tag})
+	dockerError.Message = fmt.Sprintf("tag %s is immutable and can't be deleted", tag)
+	WriteErrors(w, []DockerError{dockerError})
+}
+
+// WriteQuotaExceeded writes DENIED error for pushes which exceed the quota of a namespace or a repository
+func WriteQuotaExceeded(w http.ResponseWriter, resourceType, name, unit string, usage, limit int64) {
+	dockerError := NewDockerError(ErrCodeDenied, map[string]interface{}{
+		"resource_type": resourceType,
+		"name":          name,
+		"unit":          unit,
+		"usage":         usage,
+		"limit":         limit,
+	})
+	dockerError.Message = fmt.Sprintf("quota exceeded: %s %s uses %d of %d %s", strings.ToLower(resourceType), name,
+		usage, limit, unit)
+	WriteErrors(w, []DockerError{dockerError})
+}
+
 func WriteManifestInvalid(w http.ResponseWriter, detail interface{}) {
 	WriteError(w, ErrCodeManifestInvalid, detail)
 }
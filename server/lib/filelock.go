@@ -0,0 +1,15 @@
+package lib
+
+import "errors"
+
+// ErrFileLocked is returned by LockFile if another process holds the lock
+var ErrFileLocked = errors.New("file is locked by another process")
+
+// ErrFileLockUnsupported is returned by LockFile on platforms without advisory file locks
+var ErrFileLockUnsupported = errors.New("file locks are not supported on this platform")
+
+// FileLock is an exclusive advisory lock of a file held by this process. The lock is released by Unlock or when the
+// process exits.
+type FileLock interface {
+	Unlock() error
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package lib
+
+// LockFile always returns ErrFileLockUnsupported since advisory file locks are only supported on unix platforms
+func LockFile(path string) (FileLock, error) {
+	return nil, ErrFileLockUnsupported
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.db.lock")
+
+	lock, err := LockFile(path)
+	require.NoError(t, err)
+
+	_, err = LockFile(path)
+	assert.ErrorIs(t, err, ErrFileLocked)
+
+	require.NoError(t, lock.Unlock())
+
+	lock, err = LockFile(path)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
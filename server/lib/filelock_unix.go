@@ -0,0 +1,41 @@
+//go:build unix
+
+package lib
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+type flock struct {
+	file *os.File
+}
+
+// LockFile takes the exclusive lock of the file without waiting. The file is created if it doesn't exist.
+// ErrFileLocked is returned if another process holds the lock.
+func LockFile(path string) (FileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrFileLocked
+		}
+		return nil, err
+	}
+	return &flock{file: file}, nil
+}
+
+func (l *flock) Unlock() error {
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
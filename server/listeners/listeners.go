@@ -79,7 +79,8 @@ func (lm *ListenerManager) RegisterListener(regId, regName string, port uint, h
 	if ln, ok := lm.listeners[regId]; ok {
 		log.Logger().Error().Msgf("Existing HTTP listener with address: %s found for registry: %s", ln.Server.Addr, regId)
 		lm.mu.Unlock()
-		lm.cleanup(regId, port)
+		lm.portsLock.Unlock(strconv.Itoa(int(port)))
+		lm.regListenersLock.Unlock(regId)
 		return fmt.Errorf("Registry(%s) has a HTTP listener already.", regId)
 	}
 	lm.mu.Unlock()
@@ -112,16 +113,24 @@ func (lm *ListenerManager) RegisterListener(regId, regName string, port uint, h
 	lm.listeners[regId] = regLn
 	lm.mu.Unlock()
 
-	go func() {
-		defer close(regLn.Done)
-		time.Sleep(listenDelayInSeconds * time.Second)
+	go func(done chan struct{}) {
+		defer close(done)
+		select {
+		case <-time.After(listenDelayInSeconds * time.Second):
+		case <-ctx.Done():
+			lm.cleanup(regLn)
+			return
+		}
 		log.Logger().Info().Msgf("Listener is about to start on port %d for registry %s", port, regName)
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Logger().Error().Err(err).Msgf("Registry Listener went shutdown due to errors: %s", regId)
-
-			lm.cleanup(regId, port)
+			cancel()
 		}
-	}()
+
+		// the port is released as soon as the server stops listening, so that the listener can be registered
+		// again without waiting for active connections to be drained
+		lm.cleanup(regLn)
+	}(regLn.Done)
 
 	go func() {
 		<-ctx.Done()
@@ -134,16 +143,16 @@ func (lm *ListenerManager) RegisterListener(regId, regName string, port uint, h
 			log.Logger().Error().Err(err).Msgf("Error occurred while shutting down HTTP listener for registry: %s", regId)
 			server.Close()
 		}
-
-		lm.cleanup(regId, port)
-
 	}()
 
 	return nil
 }
 
+// UnregisterListener stops the listener of the registry and waits until the port is released
 func (lm *ListenerManager) UnregisterListener(regId string, waitTimeout time.Duration) error {
+	lm.mu.Lock()
 	regLn, ok := lm.listeners[regId]
+	lm.mu.Unlock()
 	if !ok {
 		return nil
 	}
@@ -155,15 +164,33 @@ func (lm *ListenerManager) UnregisterListener(regId string, waitTimeout time.Dur
 	case <-regLn.Done:
 		return nil
 	case <-time.After(waitTimeout * time.Second):
-		lm.cleanup(regId, regLn.Port)
+		lm.cleanup(regLn)
 		return fmt.Errorf("Timeout occurred while shutting down listener for registry: %s", regId)
 	}
 }
 
-func (lm *ListenerManager) cleanup(regId string, port uint) {
+// PortInUse reports whether a registered listener uses the port
+func (lm *ListenerManager) PortInUse(port uint) bool {
 	lm.mu.Lock()
-	delete(lm.listeners, regId)
-	lm.mu.Unlock()
-	lm.portsLock.Unlock(strconv.Itoa(int(port)))
-	lm.regListenersLock.Unlock(regId)
+	defer lm.mu.Unlock()
+
+	for _, ln := range lm.listeners {
+		if ln.Port == port {
+			return true
+		}
+	}
+	return false
 }
+
+// cleanup releases the port and the registry of the listener unless they were released already
+func (lm *ListenerManager) cleanup(regLn *RegistryListener) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	if lm.listeners[regLn.RegId] != regLn {
+		return
+	}
+	delete(lm.listeners, regLn.RegId)
+	lm.portsLock.Unlock(strconv.Itoa(int(regLn.Port)))
+	lm.regListenersLock.Unlock(regLn.RegId)
+}
\ No newline at end of file
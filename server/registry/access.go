@@ -0,0 +1,181 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/dockererrors"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+)
+
+type accessCheckResult struct {
+	allowed   bool
+	anonymous bool
+}
+
+// accessLevelRank returns a comparable rank for access levels. Higher rank includes the privileges of lower ranks.
+func accessLevelRank(accessLevel string) int {
+	switch accessLevel {
+	case constants.AccessLevelMaintainer:
+		return 3
+	case constants.AccessLevelDeveloper:
+		return 2
+	case constants.AccessLevelGuest:
+		return 1
+	}
+	return 0
+}
+
+// requiredAccessLevel returns the minimum access level required to perform registry action
+func requiredAccessLevel(action string) string {
+	switch action {
+	case constants.RegistryActionPush:
+		return constants.AccessLevelDeveloper
+	case constants.RegistryActionDelete:
+		return constants.AccessLevelMaintainer
+	default:
+		return constants.AccessLevelGuest
+	}
+}
+
+// checkAccess verifies whether the user in request context can perform the action on given namespace and repository.
+// Pull requires `Guest` or higher access unless the namespace or the repository is public. Push requires `Developer`
+// or higher access. Admins are allowed to perform any action.
+func (svc *RegistryService) checkAccess(reqCtx context.Context, namespace, repository,
+	action string) (result *accessCheckResult, err error) {
+	result = &accessCheckResult{}
+
+	username, _ := reqCtx.Value(constants.ContextUsername).(string)
+	role, _ := reqCtx.Value(constants.ContextRole).(string)
+
+	if username == "" || username == constants.AnonymousUsername {
+		result.anonymous = true
+	}
+
+	if !result.anonymous && role == constants.RoleAdmin {
+		result.allowed = true
+		return result, nil
+	}
+
+	// Images of upstream registries are cached copies of upstream images. Therefore, any user is allowed to pull
+	// them, including anonymous users.
+	if svc.registryId != constants.HostedRegistryID {
+		result.allowed = action == constants.RegistryActionPull
+		return result, nil
+	}
+
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to check access due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ns, err := svc.store.Namespaces().GetByName(ctx, svc.registryId, namespace)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to check access to namespace: %s", namespace)
+		return nil, err
+	}
+
+	if ns == nil {
+		// Namespaces are created on push only by maintainers
+		result.allowed = !result.anonymous && action == constants.RegistryActionPush &&
+			config.GetImageRegistryConfig().CreateNamespaceOnPush && role == constants.RoleMaintainer
+		return result, nil
+	}
+
+	if ns.State == constants.ResourceStateDisabled ||
+		(ns.State == constants.ResourceStateDeprecated && action != constants.RegistryActionPull) {
+		return result, nil
+	}
+
+	repo, err := svc.store.Repositories().GetByIdentifier(ctx, ns.Id, repository)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to check access to repository: %s/%s", namespace, repository)
+		return nil, err
+	}
+
+	if repo != nil && (repo.State == constants.ResourceStateDisabled ||
+		(repo.State == constants.ResourceStateDeprecated && action != constants.RegistryActionPull)) {
+		return result, nil
+	}
+
+	// all repositories of public namespaces are public, whether they exist yet or not
+	if action == constants.RegistryActionPull && (ns.IsPublic || (repo != nil && repo.IsPublic)) {
+		result.allowed = true
+		return result, nil
+	}
+
+	if result.anonymous {
+		return result, nil
+	}
+
+	user, err := svc.store.Users().GetByUsername(ctx, username)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to check access of user: %s", username)
+		return nil, err
+	}
+	if user == nil {
+		return result, nil
+	}
+
+	grantedRank := 0
+
+	nsAccess, err := svc.store.Access().GetUserAccess(ctx, ns.Id, constants.ResourceTypeNamespace, user.Id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve namespace access of user: %s", username)
+		return nil, err
+	}
+	if nsAccess != nil {
+		grantedRank = accessLevelRank(nsAccess.AccessLevel)
+	}
+
+	if repo != nil {
+		repoAccess, err := svc.store.Access().GetUserAccess(ctx, repo.ID, constants.ResourceTypeRepository, user.Id)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to retrieve repository access of user: %s", username)
+			return nil, err
+		}
+		if repoAccess != nil {
+			grantedRank = max(grantedRank, accessLevelRank(repoAccess.AccessLevel))
+		}
+	}
+
+	result.allowed = grantedRank >= accessLevelRank(requiredAccessLevel(action))
+	return result, nil
+}
+
+// authorize checks access of the user to perform action and writes error response if access is not allowed.
+// Anonymous users are challenged so that docker clients can retry with credentials.
+func (rh *RegistryHandler) authorize(w http.ResponseWriter, r *http.Request, namespace, repository,
+	action string) bool {
+	result, err := rh.svc.checkAccess(r.Context(), namespace, repository, action)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to access check errors: %s", r.RequestURI)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	if result.allowed {
+		return true
+	}
+
+	if result.anonymous {
+		registryConfig := config.GetImageRegistryConfig()
+		dockererrors.WriteUnauthorized(w, registryConfig.TokenRealm, registryConfig.TokenService)
+		return false
+	}
+
+	dockererrors.WriteAccessDenied(w)
+	return false
+}
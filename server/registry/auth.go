@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/dockererrors"
+	"github.com/ksankeerth/open-image-registry/log"
+)
+
+// authenticate verifies bearer tokens issued by token endpoint of management server.
+// Requests without a valid token are challenged with `WWW-Authenticate` header so docker clients can obtain a token.
+func (rh *RegistryHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryConfig := config.GetImageRegistryConfig()
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+
+		authHeader := r.Header.Get("Authorization")
+		token, found := strings.CutPrefix(authHeader, "Bearer ")
+		if !found || token == "" {
+			dockererrors.WriteUnauthorized(w, registryConfig.TokenRealm, registryConfig.TokenService)
+			return
+		}
+
+		claims, err := rh.jwtProvider.Verify(token)
+		if err != nil {
+			log.Logger().Warn().Err(err).Msg("Registry token verification failed")
+			dockererrors.WriteUnauthorized(w, registryConfig.TokenRealm, registryConfig.TokenService)
+			return
+		}
+
+		audience, _ := claims[constants.ClaimAudience].(string)
+		if audience != registryConfig.TokenService {
+			log.Logger().Warn().Msgf("Registry token was issued for another service: %s", audience)
+			dockererrors.WriteUnauthorized(w, registryConfig.TokenRealm, registryConfig.TokenService)
+			return
+		}
+
+		username, _ := claims[constants.ClaimSubject].(string)
+		role, _ := claims[constants.ClaimRole].(string)
+		if username == "" {
+			dockererrors.WriteUnauthorized(w, registryConfig.TokenRealm, registryConfig.TokenService)
+			return
+		}
+
+		// anonymous tokens don't carry role claim
+		if role != "" {
+			user, err := rh.store.Users().GetByUsername(r.Context(), username)
+			if err != nil {
+				log.Logger().Error().Err(err).Msgf("Unable to verify registry token due to user retrieval errors: %s", username)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if user == nil || user.Locked {
+				log.Logger().Warn().Msgf("Registry token of non-existing or locked user: %s", username)
+				dockererrors.WriteUnauthorized(w, registryConfig.TokenRealm, registryConfig.TokenService)
+				return
+			}
+		} else if username != constants.AnonymousUsername {
+			dockererrors.WriteUnauthorized(w, registryConfig.TokenRealm, registryConfig.TokenService)
+			return
+		}
+
+		ctx := r.Context()
+		ctx = context.WithValue(ctx, constants.ContextUsername, username)
+		ctx = context.WithValue(ctx, constants.ContextRole, role)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
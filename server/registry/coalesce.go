@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"sync"
+)
+
+// fetchGroup coalesces concurrent fetches of the same artifact from the upstream registry. The first request of an
+// artifact missing from the cache fetches it; requests arriving meanwhile wait for the fetch and are served from the
+// cache filled by it.
+type fetchGroup struct {
+	mu      sync.Mutex
+	fetches map[string]*upstreamFetch
+}
+
+// upstreamFetch is a fetch of an artifact from the upstream registry in flight
+type upstreamFetch struct {
+	group *fetchGroup
+	key   string
+	done  chan struct{}
+	once  sync.Once
+
+	// found and err are the outcome of the fetch. They are set before done is closed.
+	found bool
+	err   error
+}
+
+func newFetchGroup() *fetchGroup {
+	return &fetchGroup{
+		fetches: make(map[string]*upstreamFetch),
+	}
+}
+
+// join returns the fetch of the key in flight. If there is none, a new fetch is returned with leader set to true;
+// the caller must fetch the artifact and finish the fetch.
+func (g *fetchGroup) join(key string) (fetch *upstreamFetch, leader bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if fetch, ok := g.fetches[key]; ok {
+		return fetch, false
+	}
+
+	fetch = &upstreamFetch{
+		group: g,
+		key:   key,
+		done:  make(chan struct{}),
+	}
+	g.fetches[key] = fetch
+	return fetch, true
+}
+
+// finish releases the requests waiting for the fetch. found reports whether the artifact exists in the upstream
+// registry and err is the error which failed the fetch. Only the first call has an effect; nil fetch is ignored.
+func (f *upstreamFetch) finish(found bool, err error) {
+	if f == nil {
+		return
+	}
+
+	f.once.Do(func() {
+		f.group.mu.Lock()
+		delete(f.group.fetches, f.key)
+		f.group.mu.Unlock()
+
+		f.found = found
+		f.err = err
+		close(f.done)
+	})
+}
+
+// wait blocks until the fetch is finished or ctx is done
+func (f *upstreamFetch) wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
\ No newline at end of file
@@ -1,8 +1,14 @@
 package registry
 
 import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
+	"strings"
 
 	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
 	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/oci"
@@ -44,6 +50,10 @@ func UniqueDigest(mediaType string, content []byte) (digest string, err error) {
 		for _, layer := range manifest.Layers {
 			inputs = append(inputs, layer.Digest)
 		}
+		// artifacts with same content can be attached to different subjects
+		if manifest.Subject != nil {
+			inputs = append(inputs, manifest.Subject.Digest)
+		}
 		uniqueDigest = utils.CombineAndCalculateSHA256Digest(inputs...)
 
 	case "application/vnd.oci.image.index.v1+json":
@@ -58,6 +68,9 @@ func UniqueDigest(mediaType string, content []byte) (digest string, err error) {
 		for _, m := range manifest.Manifests {
 			manifestDigests = append(manifestDigests, m.Digest)
 		}
+		if manifest.Subject != nil {
+			manifestDigests = append(manifestDigests, manifest.Subject.Digest)
+		}
 		uniqueDigest = utils.CombineAndCalculateSHA256Digest(manifestDigests...)
 
 	default:
@@ -65,4 +78,152 @@ func UniqueDigest(mediaType string, content []byte) (digest string, err error) {
 	}
 
 	return uniqueDigest, nil
+}
+
+// manifestSubject returns the subject and the artifact type of OCI manifests. Nil subject is returned if the
+// manifest doesn't refer another manifest.
+func manifestSubject(mediaType string, content []byte) (subject *oci.OCIDescriptor, artifactType string, err error) {
+	switch mediaType {
+	case oci.MediaTypeImageManifest:
+		var manifest oci.OCIImageManifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, "", err
+		}
+		// config media type is used when artifact type is not set
+		artifactType = manifest.ArtifactType
+		if artifactType == "" {
+			artifactType = manifest.Config.MediaType
+		}
+		return manifest.Subject, artifactType, nil
+
+	case oci.MediaTypeImageIndex:
+		var index oci.OCIImageIndex
+		if err := json.Unmarshal(content, &index); err != nil {
+			return nil, "", err
+		}
+		return index.Subject, index.ArtifactType, nil
+
+	default:
+		return nil, "", nil
+	}
+}
+
+// manifestReferences returns digests of the blobs and the child manifests referred by the manifest. Subject is
+// not included since referrers can be pushed before their subject. Layers hosted elsewhere (`urls`) are skipped.
+func manifestReferences(mediaType string, content []byte) (blobs, manifests []string, err error) {
+	switch mediaType {
+	case "application/vnd.docker.distribution.manifest.v2+json":
+		var manifest dockerv2.ManifestV2
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, nil, err
+		}
+		blobs = append(blobs, manifest.Config.Digest)
+		for _, layer := range manifest.Layers {
+			if len(layer.URLs) == 0 {
+				blobs = append(blobs, layer.Digest)
+			}
+		}
+
+	case "application/vnd.docker.distribution.manifest.list.v2+json":
+		var manifest dockerv2.ManifestListV2
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range manifest.Manifests {
+			manifests = append(manifests, entry.Digest)
+		}
+
+	case oci.MediaTypeImageManifest:
+		var manifest oci.OCIImageManifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, nil, err
+		}
+		blobs = append(blobs, manifest.Config.Digest)
+		for _, layer := range manifest.Layers {
+			if len(layer.URLs) == 0 {
+				blobs = append(blobs, layer.Digest)
+			}
+		}
+
+	case oci.MediaTypeImageIndex:
+		var index oci.OCIImageIndex
+		if err := json.Unmarshal(content, &index); err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range index.Manifests {
+			manifests = append(manifests, entry.Digest)
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported mediaType: %s", mediaType)
+	}
+
+	return blobs, manifests, nil
+}
+
+// uploadHasher computes sha256 and sha512 digests of a blob upload incrementally. The state of the hashes
+// is persisted with the upload session, so the next chunk can resume from where the previous chunk ended.
+type uploadHasher struct {
+	sha256 hash.Hash
+	sha512 hash.Hash
+}
+
+// newUploadHasher restores the hashes from the persisted states. Empty states start fresh hashes.
+func newUploadHasher(sha256State, sha512State []byte) (*uploadHasher, error) {
+	h := &uploadHasher{
+		sha256: sha256.New(),
+		sha512: sha512.New(),
+	}
+
+	if len(sha256State) > 0 {
+		if err := h.sha256.(encoding.BinaryUnmarshaler).UnmarshalBinary(sha256State); err != nil {
+			return nil, fmt.Errorf("invalid sha256 hash state: %w", err)
+		}
+	}
+
+	if len(sha512State) > 0 {
+		if err := h.sha512.(encoding.BinaryUnmarshaler).UnmarshalBinary(sha512State); err != nil {
+			return nil, fmt.Errorf("invalid sha512 hash state: %w", err)
+		}
+	}
+
+	return h, nil
+}
+
+func (h *uploadHasher) Write(p []byte) (int, error) {
+	h.sha256.Write(p)
+	h.sha512.Write(p)
+	return len(p), nil
+}
+
+// state returns the marshalled states of hashes to be persisted
+func (h *uploadHasher) state() (sha256State, sha512State []byte, err error) {
+	sha256State, err = h.sha256.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sha512State, err = h.sha512.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	return sha256State, sha512State, nil
+}
+
+// verify returns true if the digest matches the content written so far. Only sha256 and sha512
+// algorithms are supported.
+func (h *uploadHasher) verify(digest string) bool {
+	algorithm, encoded, found := strings.Cut(digest, ":")
+	if !found {
+		return false
+	}
+
+	switch algorithm {
+	case "sha256":
+		return hex.EncodeToString(h.sha256.Sum(nil)) == encoded
+	case "sha512":
+		return hex.EncodeToString(h.sha512.Sum(nil)) == encoded
+	default:
+		return false
+	}
 }
\ No newline at end of file
@@ -0,0 +1,276 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+// Headers of webhook requests. The signature is the hex encoded HMAC-SHA256 of the body keyed with the secret of
+// the webhook, prefixed with `sha256=`. It is sent only if the webhook has a secret.
+const (
+	WebhookEventHeader     = "X-OIR-Event"
+	WebhookDeliveryHeader  = "X-OIR-Delivery"
+	WebhookSignatureHeader = "X-OIR-Signature"
+)
+
+// eventRequest is the request stored in the context by CaptureEventRequest
+type eventRequest struct {
+	dockerv2.EventRequest
+	scheme string
+}
+
+type queuedEvent struct {
+	namespaceID string
+	event       *dockerv2.Event
+}
+
+// EventDispatcher delivers registry events to webhooks. Events are queued and delivered in background so that
+// registry requests are never delayed by webhooks. Each delivery is recorded in the delivery history and retried
+// with exponential backoff until the webhook accepts it or attempts are exhausted.
+type EventDispatcher struct {
+	store       store.Store
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	source      dockerv2.EventSource
+	queue       chan *queuedEvent
+	stop        chan struct{}
+	stopOnce    sync.Once
+	wg          sync.WaitGroup
+}
+
+func NewEventDispatcher(store store.Store, cfg config.WebhookConfig) *EventDispatcher {
+	registryConfig := config.GetImageRegistryConfig()
+
+	return &EventDispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		source: dockerv2.EventSource{
+			Addr:       fmt.Sprintf("%s:%d", registryConfig.Hostname, registryConfig.Port),
+			InstanceID: uuid.New().String(),
+		},
+		queue: make(chan *queuedEvent, cfg.QueueSize),
+		stop:  make(chan struct{}),
+	}
+}
+
+// Start delivers queued events in background
+func (d *EventDispatcher) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case e := <-d.queue:
+				d.dispatch(e)
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops delivering events. Deliveries waiting for a retry are left pending in the delivery history.
+func (d *EventDispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+	d.wg.Wait()
+}
+
+// Notify queues the event for webhooks of the namespace and global webhooks. The event is dropped if the queue is
+// full.
+func (d *EventDispatcher) Notify(namespaceID string, event *dockerv2.Event) {
+	if d == nil {
+		return
+	}
+
+	event.Source = d.source
+	select {
+	case d.queue <- &queuedEvent{namespaceID: namespaceID, event: event}:
+	default:
+		log.Logger().Warn().Msgf("Webhook event queue is full. Event is dropped: %s %s", event.Action,
+			event.Target.Repository)
+	}
+}
+
+func (d *EventDispatcher) dispatch(e *queuedEvent) {
+	ctx := context.Background()
+
+	webhooks, err := d.store.Webhooks().ListSubscribers(ctx, e.namespaceID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Unable to load webhooks to deliver registry event")
+		return
+	}
+
+	payload, err := json.Marshal(dockerv2.EventEnvelope{Events: []dockerv2.Event{*e.event}})
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Unable to encode registry event")
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if len(webhook.Events) > 0 && !slices.Contains(webhook.Events, e.event.Action) {
+			continue
+		}
+
+		delivery := &models.WebhookDeliveryModel{
+			WebhookID: webhook.ID,
+			EventID:   e.event.ID,
+			Action:    e.event.Action,
+			Payload:   string(payload),
+			Status:    constants.WebhookDeliveryPending,
+		}
+		delivery.ID, err = d.store.Webhooks().CreateDelivery(ctx, delivery)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to record delivery of webhook: %s", webhook.Name)
+			continue
+		}
+
+		d.wg.Add(1)
+		go func(webhook *models.WebhookModel, delivery *models.WebhookDeliveryModel) {
+			defer d.wg.Done()
+			d.deliver(webhook, delivery)
+		}(webhook, delivery)
+	}
+}
+
+// deliver sends the delivery to the webhook until it is accepted or attempts are exhausted. Outcome of each attempt
+// is recorded in the delivery history.
+func (d *EventDispatcher) deliver(webhook *models.WebhookModel, delivery *models.WebhookDeliveryModel) {
+	backoff := d.backoff
+
+	for attempt := 1; ; attempt++ {
+		code, err := d.send(webhook, delivery)
+
+		delivery.Attempts = attempt
+		delivery.ResponseCode = code
+		delivery.Error = ""
+		switch {
+		case err == nil:
+			delivery.Status = constants.WebhookDeliveryDelivered
+		case attempt >= d.maxAttempts:
+			delivery.Status = constants.WebhookDeliveryFailed
+			delivery.Error = err.Error()
+		default:
+			delivery.Error = err.Error()
+		}
+
+		if err := d.store.Webhooks().UpdateDelivery(context.Background(), delivery); err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to record delivery of webhook: %s", webhook.Name)
+		}
+
+		if delivery.Status == constants.WebhookDeliveryFailed {
+			log.Logger().Warn().Msgf("Delivery of %s event to webhook: %s failed after %d attempts: %s",
+				delivery.Action, webhook.Name, attempt, delivery.Error)
+		}
+		if delivery.Status != constants.WebhookDeliveryPending {
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-d.stop:
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// send posts the payload of the delivery to the webhook. An error is returned unless the webhook responds with a
+// 2xx status.
+func (d *EventDispatcher) send(webhook *models.WebhookModel, delivery *models.WebhookDeliveryModel) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", dockerv2.EventsMediaType)
+	req.Header.Set(WebhookEventHeader, delivery.Action)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, []byte(delivery.Payload)))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the value of the signature header of a webhook request
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CaptureEventRequest stores the details of the request in the context so that events emitted while serving it
+// describe the request.
+func CaptureEventRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+
+		request := &eventRequest{
+			EventRequest: dockerv2.EventRequest{
+				ID:        uuid.New().String(),
+				Addr:      r.RemoteAddr,
+				Host:      r.Host,
+				Method:    r.Method,
+				UserAgent: r.UserAgent(),
+			},
+			scheme: scheme,
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), constants.ContextEventRequest, request)))
+	})
+}
+
+// NewEvent creates an event of the action performed while serving the request of ctx. The user and the request are
+// read from ctx.
+func NewEvent(ctx context.Context, action string, target dockerv2.EventTarget) *dockerv2.Event {
+	event := &dockerv2.Event{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Target:    target,
+	}
+	event.Actor.Name, _ = ctx.Value(constants.ContextUsername).(string)
+
+	if request, ok := ctx.Value(constants.ContextEventRequest).(*eventRequest); ok {
+		event.Request = request.EventRequest
+		if target.Digest != "" {
+			event.Target.URL = fmt.Sprintf("%s://%s/v2/%s/manifests/%s", request.scheme, request.Host,
+				target.Repository, target.Digest)
+		}
+	}
+	return event
+}
\ No newline at end of file
@@ -0,0 +1,419 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+type EvictionItem struct {
+	Repository string // `namespace/repository`
+	Digest     string
+	Size       int64
+}
+
+// EvictionReport lists the manifests and the blobs evicted from the cache of an upstream registry. In dry run,
+// nothing is evicted and the report lists what would have been evicted.
+type EvictionReport struct {
+	DryRun bool
+	// UsedBytes is the usage of the cache before eviction
+	UsedBytes int64
+	// ThresholdBytes is the usage at which eviction starts; the cleanup threshold of the storage limit
+	ThresholdBytes int64
+	Manifests      []*EvictionItem
+	Blobs          []*EvictionItem
+	// FreedBytes counts evicted manifests and blobs whose content is removed; content still linked by other
+	// repositories isn't freed
+	FreedBytes int64
+}
+
+// CacheEvictor enforces storage limits of upstream registries. Once the content cached from an upstream registry
+// exceeds the cleanup threshold of its storage limit, the least recently pulled manifests and blobs are evicted until
+// the usage drops below the threshold. Pinned images, along with their child manifests, referrers and blobs, are never
+// evicted. Evicted content is fetched from the upstream registry again on the next pull.
+type CacheEvictor struct {
+	store    store.Store
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewCacheEvictor(store store.Store, cfg config.CacheEvictionConfig) *CacheEvictor {
+	return &CacheEvictor{
+		store:    store,
+		interval: cfg.Interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start enforces storage limits of all upstream registries periodically in background. It does nothing if the
+// interval is not configured.
+func (e *CacheEvictor) Start() {
+	if e.interval <= 0 {
+		log.Logger().Info().Msg("Scheduled cache eviction is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				e.evictAll(context.Background())
+			case <-e.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (e *CacheEvictor) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+	})
+}
+
+// evictAll enforces the storage limit of each upstream registry. Failure of one registry doesn't stop the others.
+func (e *CacheEvictor) evictAll(ctx context.Context) {
+	cacheConfigs, err := e.store.Upstreams().ListRegistryCacheConfigs(ctx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Scheduled cache eviction failed")
+		return
+	}
+
+	for _, cacheConfig := range cacheConfigs {
+		_, err = e.evict(ctx, cacheConfig, false)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Scheduled cache eviction of upstream registry failed: %s",
+				cacheConfig.RegistryID)
+		}
+	}
+}
+
+// Evict enforces the storage limit of the upstream registry. nil report is returned if the upstream registry doesn't
+// exist. If dryRun is true, nothing is evicted.
+func (e *CacheEvictor) Evict(ctx context.Context, registryID string, dryRun bool) (*EvictionReport, error) {
+	cacheConfig, err := e.store.Upstreams().GetRegistryCacheConfig(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+	if cacheConfig == nil {
+		return nil, nil
+	}
+	cacheConfig.RegistryID = registryID
+
+	return e.evict(ctx, cacheConfig, dryRun)
+}
+
+func (e *CacheEvictor) evict(ctx context.Context, cacheConfig *models.UpstreamRegistryCacheStoreConfig,
+	dryRun bool) (*EvictionReport, error) {
+	report := &EvictionReport{
+		DryRun:         dryRun,
+		ThresholdBytes: thresholdBytes(cacheConfig),
+		Manifests:      []*EvictionItem{},
+		Blobs:          []*EvictionItem{},
+	}
+
+	// usage is checked without the content lock so that pulls and pushes aren't blocked while the cache is in limits
+	used, err := e.store.ImageQueries().GetCacheUsage(ctx, cacheConfig.RegistryID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Unable to compute cache usage of upstream registry: %s",
+			cacheConfig.RegistryID)
+		return nil, err
+	}
+	report.UsedBytes = used
+	if report.ThresholdBytes <= 0 || used <= report.ThresholdBytes {
+		return report, nil
+	}
+
+	contentLock.Lock()
+	defer contentLock.Unlock()
+
+	locations, err := e.evictLRU(ctx, cacheConfig.RegistryID, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are removed after metadata is committed, the same way as garbage collection does
+	deleteBlobFiles(locations)
+
+	log.Logger().Info().Bool("dryRun", dryRun).Str("registry", cacheConfig.RegistryID).
+		Msgf("Cache eviction completed. Used bytes: %d, Manifests: %d, Blobs: %d, Freed bytes: %d",
+			report.UsedBytes, len(report.Manifests), len(report.Blobs), report.FreedBytes)
+
+	return report, nil
+}
+
+// DeleteRegistry deletes the upstream registry along with the content cached from it. Blobs are unlinked from the
+// cached repositories and files no longer referenced by any repository are removed once the deletion is committed.
+// found is false if the upstream registry doesn't exist.
+func (e *CacheEvictor) DeleteRegistry(ctx context.Context, registryID string) (found bool, err error) {
+	contentLock.Lock()
+	defer contentLock.Unlock()
+
+	found, locations, err := e.deleteRegistry(ctx, registryID)
+	if err != nil {
+		return false, err
+	}
+
+	deleteBlobFiles(locations)
+	return found, nil
+}
+
+func (e *CacheEvictor) deleteRegistry(reqCtx context.Context, registryID string) (found bool, locations []string,
+	err error) {
+	tx, err := e.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to delete upstream registry due to database transaction errors")
+		return false, nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to commit deletion of upstream registry: %s", registryID)
+			found, locations = false, nil
+		}
+	}()
+
+	m, err := e.store.Upstreams().GetRegistry(ctx, registryID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to delete upstream registry due to database errors: %s", registryID)
+		return false, nil, err
+	}
+	if m == nil {
+		return false, nil, nil
+	}
+
+	blobs, err := e.store.ImageQueries().ListCachedBlobs(ctx, registryID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, b := range blobs {
+		unreferenced, err := e.store.Blobs().Delete(ctx, b.RepositoryID, b.Digest)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to unlink cached blob: %s@%s", b.Repository, b.Digest)
+			return false, nil, err
+		}
+		if unreferenced {
+			locations = append(locations, b.Location)
+		}
+	}
+
+	err = e.store.Upstreams().DeleteRegistry(ctx, registryID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to delete upstream registry: %s", m.Name)
+		return false, nil, err
+	}
+
+	log.Logger().Info().Msgf("Upstream registry %s is deleted along with %d cached blobs", m.Name, len(blobs))
+	return true, locations, nil
+}
+
+// thresholdBytes returns the usage at which content is evicted. Zero means the storage isn't limited.
+func thresholdBytes(cacheConfig *models.UpstreamRegistryCacheStoreConfig) int64 {
+	limit := float64(cacheConfig.StorageLimit) * 1024 * 1024
+	return int64(limit * float64(cacheConfig.CleanupThreshold) / 100)
+}
+
+// evictionCandidate is a manifest of a repository or a blob digest with all its links, which are evicted together
+type evictionCandidate struct {
+	manifest     *models.CachedManifestModel
+	blobs        []*models.CachedBlobModel
+	size         int64
+	lastAccessed time.Time
+}
+
+// evictLRU removes metadata of the least recently pulled content until the usage drops below the threshold. Storage
+// locations of the blobs no longer referenced are returned so that files can be removed once the transaction is
+// committed.
+func (e *CacheEvictor) evictLRU(reqCtx context.Context, registryID string, report *EvictionReport) (
+	locations []string, err error) {
+	tx, err := e.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to evict cache due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to commit cache eviction of upstream registry: %s", registryID)
+			locations = nil
+		}
+	}()
+
+	manifests, err := e.store.ImageQueries().ListCachedManifests(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := e.store.ImageQueries().ListCachedBlobs(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	pinnedManifests, pinnedBlobs, err := e.pinned(ctx, manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	// usage is computed again within the transaction since content may have been cached meanwhile
+	used := int64(0)
+	candidates := []*evictionCandidate{}
+	for _, m := range manifests {
+		used += m.Size
+		if pinnedManifests[gcRef{m.RepositoryID, m.Digest}] {
+			continue
+		}
+		candidates = append(candidates, &evictionCandidate{manifest: m, size: m.Size, lastAccessed: m.LastAccessed})
+	}
+
+	// content of a blob is freed only when the blob is evicted from all repositories
+	blobCandidates := map[string]*evictionCandidate{}
+	pinnedDigests := map[string]bool{}
+	for _, b := range blobs {
+		if pinnedBlobs[gcRef{b.RepositoryID, b.Digest}] {
+			pinnedDigests[b.Digest] = true
+		}
+
+		candidate, ok := blobCandidates[b.Digest]
+		if !ok {
+			candidate = &evictionCandidate{}
+			blobCandidates[b.Digest] = candidate
+		}
+		candidate.blobs = append(candidate.blobs, b)
+		if b.Size > candidate.size {
+			candidate.size = b.Size
+		}
+		if b.LastAccessed.After(candidate.lastAccessed) {
+			candidate.lastAccessed = b.LastAccessed
+		}
+	}
+	for digest, candidate := range blobCandidates {
+		used += candidate.size
+		if !pinnedDigests[digest] {
+			candidates = append(candidates, candidate)
+		}
+	}
+	report.UsedBytes = used
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].lastAccessed.Equal(candidates[j].lastAccessed) {
+			return candidates[i].lastAccessed.Before(candidates[j].lastAccessed)
+		}
+		return candidates[i].digest() < candidates[j].digest()
+	})
+
+	for _, candidate := range candidates {
+		if used <= report.ThresholdBytes {
+			break
+		}
+		used -= candidate.size
+
+		if candidate.manifest != nil {
+			m := candidate.manifest
+			report.Manifests = append(report.Manifests, &EvictionItem{Repository: m.Repository, Digest: m.Digest,
+				Size: m.Size})
+			report.FreedBytes += m.Size
+			if report.DryRun {
+				continue
+			}
+
+			err = e.evictManifest(ctx, m)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if report.DryRun {
+			report.FreedBytes += candidate.size
+		}
+		for _, b := range candidate.blobs {
+			report.Blobs = append(report.Blobs, &EvictionItem{Repository: b.Repository, Digest: b.Digest,
+				Size: b.Size})
+			if report.DryRun {
+				continue
+			}
+
+			// content shared with the hosted registry or other upstream registries isn't freed
+			unreferenced, err := e.store.Blobs().Delete(ctx, b.RepositoryID, b.Digest)
+			if err != nil {
+				log.Logger().Error().Err(err).Msgf("Failed to evict blob: %s@%s", b.Repository, b.Digest)
+				return nil, err
+			}
+			if unreferenced {
+				locations = append(locations, b.Location)
+				report.FreedBytes += b.Size
+			}
+			log.Logger().Debug().Msgf("Cache eviction removed blob: %s@%s", b.Repository, b.Digest)
+		}
+	}
+
+	return locations, nil
+}
+
+func (c *evictionCandidate) digest() string {
+	if c.manifest != nil {
+		return c.manifest.Digest
+	}
+	return c.blobs[0].Digest
+}
+
+// evictManifest removes the manifest along with the cache entries referring it so that the next pull fetches it from
+// the upstream registry
+func (e *CacheEvictor) evictManifest(ctx context.Context, m *models.CachedManifestModel) error {
+	err := e.store.Cache().DeleteByDigest(ctx, m.RepositoryID, m.Digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to delete cache entries of manifest: %s@%s", m.Repository, m.Digest)
+		return err
+	}
+
+	err = e.store.Manifests().DeleteByDigest(ctx, m.RepositoryID, m.Digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to evict manifest: %s@%s", m.Repository, m.Digest)
+		return err
+	}
+
+	log.Logger().Debug().Msgf("Cache eviction removed manifest: %s@%s", m.Repository, m.Digest)
+	return nil
+}
+
+// pinned returns the manifests and the blobs protected by pins. Child manifests of pinned indexes and referrers of
+// pinned manifests are protected as well.
+func (e *CacheEvictor) pinned(ctx context.Context, manifests []*models.CachedManifestModel) (
+	pinnedManifests, pinnedBlobs map[gcRef]bool, err error) {
+	referrers := map[gcRef][]gcRef{}
+	roots := []gcRef{}
+
+	for _, m := range manifests {
+		ref := gcRef{m.RepositoryID, m.Digest}
+		if m.SubjectDigest != "" {
+			subject := gcRef{m.RepositoryID, m.SubjectDigest}
+			referrers[subject] = append(referrers[subject], ref)
+		}
+		if m.Pinned {
+			roots = append(roots, ref)
+		}
+	}
+
+	// eviction is aborted rather than evicting blobs which may be referred by a pinned manifest
+	return reachable(ctx, e.store, roots, referrers)
+}
@@ -0,0 +1,413 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	storage_errors "github.com/ksankeerth/open-image-registry/errors/storage"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/storage"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+// contentLock serializes removal of content (garbage collection and blob deletion) with the operations which make
+// blobs and manifests referenced. Those operations hold the read lock until their transaction is committed.
+// Therefore, content referenced by a push completed during a removal is never removed.
+var contentLock sync.RWMutex
+
+// gcRef identifies a manifest or a blob within a repository
+type gcRef struct {
+	repositoryID string
+	digest       string
+}
+
+type GCItem struct {
+	Repository string // `namespace/repository`; empty if the repository no longer exists
+	Digest     string
+	Size       int64
+}
+
+// GCReport lists the manifests and the blobs removed by garbage collection. In dry run, nothing is removed and
+// the report lists what would have been removed.
+type GCReport struct {
+	DryRun     bool
+	Manifests  []*GCItem
+	Blobs      []*GCItem
+	// FreedBytes counts blobs whose content is removed; content still linked by other repositories isn't freed
+	FreedBytes int64
+}
+
+// GarbageCollector removes manifests and blobs of the hosted registry which are not reachable from tags.
+// Manifests and blobs pushed within the grace period are kept since a push uploads blobs and child manifests
+// before the manifest referring them.
+type GarbageCollector struct {
+	store       store.Store
+	gracePeriod time.Duration
+	interval    time.Duration
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+func NewGarbageCollector(store store.Store, cfg config.GCConfig) *GarbageCollector {
+	return &GarbageCollector{
+		store:       store,
+		gracePeriod: cfg.GracePeriod,
+		interval:    cfg.Interval,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start runs garbage collection periodically in background. It does nothing if the interval is not configured.
+func (gc *GarbageCollector) Start() {
+	if gc.interval <= 0 {
+		log.Logger().Info().Msg("Scheduled garbage collection is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(gc.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				_, err := gc.Collect(context.Background(), false)
+				if err != nil {
+					log.Logger().Error().Err(err).Msg("Scheduled garbage collection failed")
+				}
+			case <-gc.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (gc *GarbageCollector) Stop() {
+	gc.stopOnce.Do(func() {
+		close(gc.stop)
+	})
+}
+
+// Collect removes unreferenced manifests and blobs. If dryRun is true, nothing is removed.
+func (gc *GarbageCollector) Collect(ctx context.Context, dryRun bool) (*GCReport, error) {
+	contentLock.Lock()
+	defer contentLock.Unlock()
+
+	report, locations, err := gc.collect(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are removed after metadata is committed. A file left behind wastes space, but metadata of a
+	// removed file would break pulls.
+	deleteBlobFiles(locations)
+
+	log.Logger().Info().Bool("dryRun", dryRun).Msgf("Garbage collection completed. Manifests: %d, Blobs: %d, "+
+		"Freed bytes: %d", len(report.Manifests), len(report.Blobs), report.FreedBytes)
+
+	return report, nil
+}
+
+// collect marks reachable content and removes metadata of the rest. Storage locations of the removed blobs are
+// returned so that files can be removed once the transaction is committed.
+func (gc *GarbageCollector) collect(reqCtx context.Context, dryRun bool) (report *GCReport, locations []string,
+	err error) {
+	tx, err := gc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to collect garbage due to database transaction errors")
+		return nil, nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	// files must not be removed unless the metadata is committed
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to commit garbage collection")
+			report, locations = nil, nil
+		}
+	}()
+
+	graceSeconds := int64(gc.gracePeriod.Seconds())
+
+	manifests, err := gc.store.ImageQueries().ListGCManifests(ctx, constants.HostedRegistryID, graceSeconds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reachableManifests, reachableBlobs, err := gc.mark(ctx, manifests)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report = &GCReport{
+		DryRun:    dryRun,
+		Manifests: []*GCItem{},
+		Blobs:     []*GCItem{},
+	}
+
+	removed := map[gcRef]bool{}
+	for _, m := range manifests {
+		ref := gcRef{m.RepositoryID, m.Digest}
+		if reachableManifests[ref] || removed[ref] {
+			continue
+		}
+		removed[ref] = true
+
+		report.Manifests = append(report.Manifests, &GCItem{Repository: m.Repository, Digest: m.Digest})
+		if dryRun {
+			continue
+		}
+
+		err = gc.store.Manifests().DeleteByDigest(ctx, m.RepositoryID, m.Digest)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to delete manifest: %s@%s", m.Repository, m.Digest)
+			return nil, nil, err
+		}
+		log.Logger().Debug().Msgf("Garbage collection removed manifest: %s@%s", m.Repository, m.Digest)
+	}
+
+	blobs, err := gc.store.ImageQueries().ListGCBlobs(ctx, constants.HostedRegistryID, graceSeconds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// number of links per blob removed in dry run. Content is freed once all the links of the blob are removed.
+	unlinked := map[string]int{}
+	for _, b := range blobs {
+		if reachableBlobs[gcRef{b.RepositoryID, b.Digest}] || b.Recent {
+			continue
+		}
+
+		report.Blobs = append(report.Blobs, &GCItem{Repository: b.Repository, Digest: b.Digest, Size: b.Size})
+		if dryRun {
+			unlinked[b.Digest]++
+			// blobs stored before content addressing have no reference count
+			if unlinked[b.Digest] >= b.RefCount {
+				report.FreedBytes += b.Size
+			}
+			continue
+		}
+
+		// content shared with other repositories isn't freed
+		unreferenced, err := gc.store.Blobs().Delete(ctx, b.RepositoryID, b.Digest)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to delete blob: %s@%s", b.Repository, b.Digest)
+			return nil, nil, err
+		}
+		if unreferenced {
+			locations = append(locations, b.Location)
+			report.FreedBytes += b.Size
+		}
+		log.Logger().Debug().Msgf("Garbage collection removed blob: %s@%s", b.Repository, b.Digest)
+	}
+
+	swept, err := gc.sweep(ctx, report, dryRun, locations)
+	if err != nil {
+		return nil, nil, err
+	}
+	locations = append(locations, swept...)
+
+	return report, locations, nil
+}
+
+// mark returns the manifests and the blobs reachable from tagged and recently pushed manifests. Child manifests of
+// reachable indexes and referrers of reachable manifests are reachable as well. Content of removed repositories is
+// never reachable.
+func (gc *GarbageCollector) mark(ctx context.Context, manifests []*models.GCManifestModel) (
+	reachableManifests, reachableBlobs map[gcRef]bool, err error) {
+	referrers := map[gcRef][]gcRef{}
+	roots := []gcRef{}
+
+	for _, m := range manifests {
+		if !m.RepositoryExists {
+			continue
+		}
+		ref := gcRef{m.RepositoryID, m.Digest}
+		if m.SubjectDigest != "" {
+			subject := gcRef{m.RepositoryID, m.SubjectDigest}
+			referrers[subject] = append(referrers[subject], ref)
+		}
+		if m.Tagged || m.Recent {
+			roots = append(roots, ref)
+		}
+	}
+
+	return reachable(ctx, gc.store, roots, referrers)
+}
+
+// reachable returns the manifests and the blobs reachable from the roots. Child manifests of reachable indexes and
+// referrers of reachable manifests, given by referrers, are reachable as well. Manifests which don't exist in the
+// repository are skipped. The walk fails if a reachable manifest can't be parsed, rather than letting callers remove
+// blobs which may be referred by it.
+func reachable(ctx context.Context, s store.Store, roots []gcRef, referrers map[gcRef][]gcRef) (
+	manifests, blobs map[gcRef]bool, err error) {
+	manifests = map[gcRef]bool{}
+	blobs = map[gcRef]bool{}
+
+	queue := roots
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+
+		if manifests[ref] {
+			continue
+		}
+
+		manifest, err := s.Manifests().GetByDigest(ctx, true, ref.repositoryID, ref.digest)
+		if err != nil {
+			return nil, nil, err
+		}
+		// child manifests may not exist in the repository
+		if manifest == nil {
+			continue
+		}
+		manifests[ref] = true
+
+		blobDigests, children, err := manifestReferences(manifest.MediaType, []byte(manifest.Content))
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to parse references of manifest: %s", ref.digest)
+			return nil, nil, err
+		}
+
+		for _, digest := range blobDigests {
+			blobs[gcRef{ref.repositoryID, digest}] = true
+		}
+		for _, digest := range children {
+			queue = append(queue, gcRef{ref.repositoryID, digest})
+		}
+		queue = append(queue, referrers[ref]...)
+	}
+
+	return manifests, blobs, nil
+}
+
+// sweep removes content which isn't linked to any repository. Links are removed without their content when
+// repositories, namespaces or upstream registries are deleted. Files of content addressed locations without stored
+// content are left behind if storing a blob fails after its file is moved. Removed content is reported without
+// repository. Storage locations of it are returned, except the ones already removed by the caller.
+func (gc *GarbageCollector) sweep(ctx context.Context, report *GCReport, dryRun bool,
+	removed []string) (locations []string, err error) {
+	contents, err := gc.store.ImageQueries().ListUnlinkedBlobContents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range contents {
+		if !dryRun {
+			deleted, err := gc.store.Blobs().DeleteContent(ctx, c.Digest)
+			if err != nil {
+				log.Logger().Error().Err(err).Msgf("Failed to delete blob content: %s", c.Digest)
+				return nil, err
+			}
+			if !deleted {
+				continue
+			}
+			locations = append(locations, c.Location)
+		}
+		report.Blobs = append(report.Blobs, &GCItem{Digest: c.Digest, Size: c.Size})
+		report.FreedBytes += c.Size
+		log.Logger().Debug().Msgf("Garbage collection removed unlinked blob content: %s", c.Digest)
+	}
+
+	files, err := gc.orphanedFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// content removed in this collection has no stored content either
+	skip := map[string]bool{}
+	for _, location := range append(removed, locations...) {
+		skip[location] = true
+	}
+
+	for _, f := range files {
+		if skip[f.Location] {
+			continue
+		}
+		report.Blobs = append(report.Blobs, &GCItem{Digest: f.Digest, Size: f.Size})
+		report.FreedBytes += f.Size
+		if !dryRun {
+			locations = append(locations, f.Location)
+		}
+		log.Logger().Debug().Msgf("Garbage collection removed orphaned blob file: %s", f.Location)
+	}
+
+	return locations, nil
+}
+
+// orphanedFiles returns the content addressed files which have no stored content. Files modified within the grace
+// period are skipped since a server running alongside the command line may not have committed them yet.
+func (gc *GarbageCollector) orphanedFiles(ctx context.Context) ([]*models.ImageBlobModel, error) {
+	files := []*models.ImageBlobModel{}
+	graceStart := time.Now().Add(-gc.gracePeriod)
+
+	for _, algorithm := range []string{"sha256", "sha512"} {
+		root := utils.StorageLocation("blobs", algorithm)
+		dirs, err := storage.ListDirs(root)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range dirs {
+			names, err := storage.ListFiles(utils.StorageLocation(root, dir))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, name := range names {
+				digest := algorithm + ":" + name
+				location := utils.StorageLocation(root, dir, name)
+				// other files such as caches of upstream registries named after an algorithm are skipped
+				if !utils.IsValidDigest(digest) || utils.BlobLocation(digest) != location {
+					continue
+				}
+
+				content, err := gc.store.Blobs().GetContent(ctx, digest)
+				if err != nil {
+					return nil, err
+				}
+				if content != nil {
+					continue
+				}
+
+				modTime, err := storage.ModTime(location)
+				if err != nil {
+					return nil, err
+				}
+				if modTime.After(graceStart) {
+					continue
+				}
+
+				size, err := storage.Size(location)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, &models.ImageBlobModel{Digest: digest, Size: size, Location: location})
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// deleteBlobFiles removes files of blobs whose metadata is already removed. Failures are only logged since the
+// files left behind are removed by the next garbage collection.
+func deleteBlobFiles(locations []string) {
+	for _, location := range locations {
+		err := storage.DeleteFile(location)
+		if err != nil {
+			if ok, code := storage_errors.UnwrapStorageError(err); !ok || code != storage_errors.CodeFileNotFound {
+				log.Logger().Error().Err(err).Msgf("Failed to delete blob file: %s", location)
+			}
+		}
+	}
+}
\ No newline at end of file
@@ -1,19 +1,25 @@
 package registry
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httplog/v2"
 
+	"github.com/ksankeerth/open-image-registry/config"
 	"github.com/ksankeerth/open-image-registry/constants"
 	"github.com/ksankeerth/open-image-registry/errors/dockererrors"
+	"github.com/ksankeerth/open-image-registry/lib"
 	"github.com/ksankeerth/open-image-registry/log"
 	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/oci"
 	"github.com/ksankeerth/open-image-registry/utils"
 )
 
@@ -21,16 +27,21 @@ type RegistryHandler struct {
 	registryId   string
 	registryName string
 	svc          *RegistryService
+	store        store.Store
+	jwtProvider  lib.JWTProvider
 }
 
-func NewRegistryHandler(registryId, registryName string, s store.Store) *RegistryHandler {
+func NewRegistryHandler(registryId, registryName string, s store.Store, jwtProvider lib.JWTProvider,
+	events *EventDispatcher) *RegistryHandler {
 
-	svc := NewRegistryService(registryId, registryName, s)
+	svc := NewRegistryService(registryId, registryName, s, events)
 
 	return &RegistryHandler{
 		registryId:   registryId,
 		registryName: registryName,
 		svc:          svc,
+		store:        s,
+		jwtProvider:  jwtProvider,
 	}
 }
 
@@ -43,34 +54,17 @@ func (rh *RegistryHandler) Routes() chi.Router {
 		RequestHeaders:   true,
 		MessageFieldName: "message",
 	})))
+	r.Use(CaptureEventRequest)
 
 	r.Route("/v2", func(r chi.Router) {
-		r.Get("/", rh.dockerV2APISupport)
-
-		//blob
-		r.Post("/{namespace}/{repository}/blobs/uploads/", rh.initiateBlobUpload)
-		r.Post("/{repository}/blobs/uploads/", rh.initiateBlobUpload)
-
-		r.Head("/{namespace}/{repository}/blobs/{digest}", rh.blobExists)
-		r.Head("/{repository}/blobs/{digest}", rh.blobExists)
-
-		r.Put("/{namespace}/{repository}/blobs/uploads/{session_id}", rh.handleBlobUpload)
-		r.Put("/{repository}/blobs/uploads/{session_id}", rh.handleBlobUpload)
-
-		r.Patch("/{namespace}/{repository}/blobs/uploads/{session_id}", rh.handleBlobUpload)
-		r.Patch("/{repository}/blobs/uploads/{session_id}", rh.handleBlobUpload)
+		r.Use(rh.authenticate)
 
-		r.Get("/{namespace}/{repository}/blobs/{digest}", rh.getImageBlob)
-		r.Get("/{repository}/blobs/{digest}", rh.getImageBlob)
-
-		r.Head("/{namespace}/{repository}/manifests/{tag_or_digest}", rh.manifestExists)
-		r.Head("/{repository}/manifests/{tag_or_digest}", rh.manifestExists)
+		r.Get("/", rh.dockerV2APISupport)
 
-		r.Put("/{namespace}/{repository}/manifests/{tag}", rh.updateManifest)
-		r.Put("/{repository}/manifests/{tag}", rh.updateManifest)
+		r.Get("/_catalog", rh.listCatalog)
 
-		r.Get("/{namespace}/{repository}/manifests/{tag_or_digest}", rh.getManifest)
-		r.Get("/{repository}/manifests/{tag_or_digest}", rh.getManifest)
+		// blobs, uploads, manifests, tags and referrers of repositories
+		r.HandleFunc("/*", rh.routeRepository(rh.repositoryRoutes()))
 	})
 
 	return r
@@ -92,14 +86,17 @@ func (rh *RegistryHandler) initiateBlobUpload(w http.ResponseWriter, r *http.Req
 
 	namespace, repository := extractNamespaceAndRepository(r)
 
-	// allowing namespace and repository with invalid characters may cause issues when storing
-	// blob in file-system. Therefore, We will not accept this request.
-	if !utils.IsValidNamespace(namespace) || !utils.IsValidRepository(repository) {
-		w.WriteHeader(http.StatusBadRequest)
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPush) {
+		return
+	}
+
+	mountDigest := r.URL.Query().Get("mount")
+	from := r.URL.Query().Get("from")
+	if mountDigest != "" && from != "" && rh.mountBlob(w, r, namespace, repository, mountDigest, from) {
 		return
 	}
 
-	sessionID, err := rh.svc.initiateBlobUpload(r.Context(), namespace, repository)
+	result, err := rh.svc.initiateBlobUpload(r.Context(), namespace, repository)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("Request aborted due to errors")
 		dockererrors.WriteBlobUploadInvalid(w)
@@ -107,11 +104,18 @@ func (rh *RegistryHandler) initiateBlobUpload(w http.ResponseWriter, r *http.Req
 	}
 
 	// namespace or repository do not exist
-	if sessionID == "" {
+	if result.notFound {
 		dockererrors.WriteRepositoryNotFound(w)
 		return
 	}
 
+	if result.quota != nil {
+		writeQuotaExceeded(w, result.quota)
+		return
+	}
+
+	sessionID := result.sessionID
+
 	scheme := "http"
 	if r.TLS != nil {
 		scheme = "https"
@@ -126,9 +130,66 @@ func (rh *RegistryHandler) initiateBlobUpload(w http.ResponseWriter, r *http.Req
 	w.Write([]byte(`{"Location":"` + uploadUrl + `" }`))
 }
 
+// mountBlob tries to mount the blob from another repository. It returns true if the response has been written.
+// Otherwise, the caller should proceed with regular blob upload as defined by the spec.
+func (rh *RegistryHandler) mountBlob(w http.ResponseWriter, r *http.Request, namespace, repository, digest,
+	from string) bool {
+	if !utils.IsValidDigest(digest) {
+		return false
+	}
+
+	fromNamespace, fromRepository := splitRepositoryName(from)
+	if !utils.IsValidNamespace(fromNamespace) || !utils.IsValidExistingRepository(fromRepository) {
+		dockererrors.WriteInvalidRepository(w)
+		return true
+	}
+
+	// Users must not be able to mount blobs of repositories they can't read
+	access, err := rh.svc.checkAccess(r.Context(), fromNamespace, fromRepository, constants.RegistryActionPull)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to access check errors: %s", r.RequestURI)
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+	if !access.allowed {
+		return false
+	}
+
+	result, err := rh.svc.mountBlob(r.Context(), namespace, repository, digest, fromNamespace, fromRepository)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	if result.notFound {
+		dockererrors.WriteRepositoryNotFound(w)
+		return true
+	}
+
+	if result.quota != nil {
+		writeQuotaExceeded(w, result.quota)
+		return true
+	}
+
+	if !result.mounted {
+		return false
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/%s/blobs/%s", namespace, repository, digest))
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusCreated)
+	return true
+}
+
 func (rh *RegistryHandler) blobExists(w http.ResponseWriter, r *http.Request) {
 	namespace, repository, digest := extractNamespaceRepositoryAndDigest(r)
 
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPull) {
+		return
+	}
+
 	exists, err := rh.svc.blobExists(r.Context(), namespace, repository, digest)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -148,31 +209,20 @@ func (rh *RegistryHandler) handleBlobUpload(w http.ResponseWriter, r *http.Reque
 
 	namespace, repository, sessionId := extractNamespaceRepositoryAndSessionId(r)
 
-	// Prevent invalid namespace/repository names (storage safety)
-	if !utils.IsValidNamespace(namespace) || !utils.IsValidRepository(repository) {
-		w.WriteHeader(http.StatusBadRequest)
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPush) {
 		return
 	}
 
-	payload, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Logger().Error().Err(err).Msgf("Unable to read blob payload from request: %s", r.RequestURI)
-		dockererrors.WriteBlobUploadInvalid(w)
-		return
-	}
+	switch r.Method {
+	case http.MethodPatch:
+		rh.handleBlobChunk(w, r, namespace, repository, sessionId)
 
-	switch {
-	case len(payload) == 0 && r.Method == http.MethodPut:
+	case http.MethodPut:
 		rh.handleLastBlobChunk(w, r, namespace, repository, sessionId)
-
-	case r.Method == http.MethodPatch:
-		rh.handleBlobChunk(w, r, namespace, repository, sessionId, payload)
-
-	case r.Method == http.MethodPut:
-		rh.handleBlob(w, r, namespace, repository, sessionId, payload)
 	}
 }
 
+// handleLastBlobChunk completes the upload. The request body may carry the final chunk or the whole blob.
 func (rh *RegistryHandler) handleLastBlobChunk(w http.ResponseWriter, r *http.Request,
 	namespace, repository, sessionID string) {
 	blobDigest := r.URL.Query().Get("digest")
@@ -182,7 +232,12 @@ func (rh *RegistryHandler) handleLastBlobChunk(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	result, err := rh.svc.handleLastBlobChunk(r.Context(), namespace, repository, blobDigest, sessionID)
+	if !utils.IsValidDigest(blobDigest) {
+		dockererrors.WriteInvalidDigest(w, blobDigest)
+		return
+	}
+
+	result, err := rh.svc.handleLastBlobChunk(r.Context(), namespace, repository, blobDigest, sessionID, r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -193,27 +248,40 @@ func (rh *RegistryHandler) handleLastBlobChunk(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if result.digestInvalid {
+		dockererrors.WriteInvalidDigest(w, blobDigest)
+		return
+	}
+
 	if result.partialUpload {
 		dockererrors.WriteBlobUploadNotFound(w)
 		return
 	}
 
-	writeBlobUploadSuccess(w, r.URL.String(), sessionID, 0, 0)
+	if result.quota != nil {
+		log.Logger().Warn().Msgf("Blob upload rejected since %s quota of %s is exceeded", result.quota.unit,
+			result.quota.name)
+		writeQuotaExceeded(w, result.quota)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/%s/blobs/%s", namespace, repository, blobDigest))
+	w.Header().Set("Range", fmt.Sprintf("0-%d", lastByteOffset(result.size)))
+	w.Header().Set("Docker-Upload-UUID", sessionID)
+	w.Header().Set("Docker-Content-Digest", blobDigest)
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusCreated)
 }
 
 func (rh *RegistryHandler) handleBlobChunk(w http.ResponseWriter, r *http.Request,
-	namespace, repository, sessionID string, payload []byte) {
-	var start int64 = 0
-	var end int64 = 0
+	namespace, repository, sessionID string) {
+	var start int64 = -1
 	contentRange := r.Header.Get("Content-Range")
 
 	// Content-Range cannot be empty for second+ chunks
-	if contentRange == "" {
-		// correcting end
-		end = int64(len(payload))
-	} else {
+	if contentRange != "" {
 		var err error
-		start, end, err = utils.ParseImageBlobContentRangeFromRequest(contentRange)
+		start, _, err = utils.ParseImageBlobContentRangeFromRequest(contentRange)
 		if err != nil {
 			log.Logger().Warn().Msg("Unable to parse Content-Range header")
 			dockererrors.WriteInvalidRange(w)
@@ -221,7 +289,7 @@ func (rh *RegistryHandler) handleBlobChunk(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	result, err := rh.svc.uploadBlobChunk(r.Context(), namespace, repository, sessionID, start, payload)
+	result, err := rh.svc.uploadBlobChunk(r.Context(), namespace, repository, sessionID, start, r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -236,36 +304,73 @@ func (rh *RegistryHandler) handleBlobChunk(w http.ResponseWriter, r *http.Reques
 		dockererrors.WriteBlobUploadNotFound(w)
 		return
 	}
-	writeBlobUploadSuccess(w, r.URL.String(), sessionID, int(start), int(end))
+	writeBlobUploadSuccess(w, http.StatusAccepted, r.URL.String(), sessionID, 0, lastByteOffset(result.size))
 }
 
-func (rh *RegistryHandler) handleBlob(w http.ResponseWriter, r *http.Request,
-	namespace, repository, sessionID string, payload []byte) {
-	blobDigest := r.URL.Query().Get("digest")
-	if blobDigest == "" {
-		log.Logger().Warn().Msgf("Request aborted due to missing query param `digest` : %s", r.RequestURI)
-		dockererrors.WriteInvalidDigest(w, "")
+func (rh *RegistryHandler) getBlobUploadStatus(w http.ResponseWriter, r *http.Request) {
+	if rh.registryId != constants.HostedRegistryID {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, repository, sessionID := extractNamespaceRepositoryAndSessionId(r)
+
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPush) {
 		return
 	}
 
-	res, err := rh.svc.uploadBlobWhole(r.Context(), namespace, repository, sessionID, blobDigest, payload)
+	result, err := rh.svc.getBlobUploadStatus(r.Context(), namespace, repository, sessionID)
 	if err != nil {
-		log.Logger().Warn().Msgf("Request aborted due to errors")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if res.invalid {
-		dockererrors.WriteBlobUploadInvalid(w)
+	if result.invalid {
+		dockererrors.WriteBlobUploadNotFound(w)
 		return
 	}
 
-	writeBlobUploadSuccess(w, r.URL.Path, sessionID, 0, len(payload))
+	w.Header().Set("Location", r.URL.Path)
+	w.Header().Set("Range", fmt.Sprintf("0-%d", lastByteOffset(result.size)))
+	w.Header().Set("Docker-Upload-UUID", sessionID)
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusNoContent)
 }
 
+func (rh *RegistryHandler) cancelBlobUpload(w http.ResponseWriter, r *http.Request) {
+	if rh.registryId != constants.HostedRegistryID {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, repository, sessionID := extractNamespaceRepositoryAndSessionId(r)
+
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPush) {
+		return
+	}
+
+	result, err := rh.svc.cancelBlobUpload(r.Context(), namespace, repository, sessionID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if result.invalid {
+		dockererrors.WriteBlobUploadNotFound(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getImageBlob serves the blob content. Range requests are supported for blobs which are served from storage.
 func (rh *RegistryHandler) getImageBlob(w http.ResponseWriter, r *http.Request) {
 	namespace, repository, digest := extractNamespaceRepositoryAndDigest(r)
 
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPull) {
+		return
+	}
+
 	exists, content, err := rh.svc.getImageBlob(r.Context(), namespace, repository, digest)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -273,18 +378,94 @@ func (rh *RegistryHandler) getImageBlob(w http.ResponseWriter, r *http.Request)
 	}
 	if !exists {
 		dockererrors.WriteBlobNotFound(w)
-	} else {
-		w.Header().Set("Content-Type", "application/octet-stream")
-		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
-		w.Header().Set("Docker-Content-Digest", digest)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Etag", fmt.Sprintf(`"%s"`, digest))
+
+	stream, ok := content.(*blobStream)
+	if !ok {
+		http.ServeContent(w, r, "", time.Time{}, content.(io.ReadSeeker))
+		return
+	}
+
+	// blobs streamed from upstream registries are served in full
+	if stream.size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(stream.size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(&flushWriter{w: w, rc: http.NewResponseController(w)}, stream)
+	if err != nil {
+		// the response is aborted; clients detect the truncated or invalid content
+		log.Logger().Error().Err(err).Msgf("Failed to stream blob %s from upstream registry", digest)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// flushWriter flushes every write so that content streamed from upstream registries isn't held back in buffers
+type flushWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, fw.rc.Flush()
+}
+
+func (rh *RegistryHandler) deleteBlob(w http.ResponseWriter, r *http.Request) {
+	if rh.registryId != constants.HostedRegistryID {
+		dockererrors.WriteUnsupported(w)
+		return
+	}
+
+	namespace, repository, digest := extractNamespaceRepositoryAndDigest(r)
+
+	registryConfig := config.GetImageRegistryConfig()
+	if !registryConfig.Delete.IsAllowed(namespace) {
+		dockererrors.WriteUnsupported(w)
+		return
+	}
+
+	if !utils.IsValidDigest(digest) {
+		dockererrors.WriteInvalidDigest(w, digest)
+		return
+	}
+
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionDelete) {
+		return
+	}
 
-		w.Write(content)
+	result, err := rh.svc.deleteBlob(r.Context(), namespace, repository, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occured when deleting blob for request: %s", r.RequestURI)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+
+	if result.notFound {
+		dockererrors.WriteBlobNotFound(w)
+		return
+	}
+
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusAccepted)
 }
 
 func (rh *RegistryHandler) manifestExists(w http.ResponseWriter, r *http.Request) {
 	namespace, repository, tagOrDigest := extractNamespaceRepositoryAndTagOrDigest(r)
 
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPull) {
+		return
+	}
+
 	exists, mediaType, digest, err := rh.svc.manifestExists(r.Context(), namespace, repository, tagOrDigest)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -305,6 +486,10 @@ func (rh *RegistryHandler) manifestExists(w http.ResponseWriter, r *http.Request
 func (rh *RegistryHandler) getManifest(w http.ResponseWriter, r *http.Request) {
 	namespace, repository, tagOrDigest := extractNamespaceRepositoryAndTagOrDigest(r)
 
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPull) {
+		return
+	}
+
 	exists, mediaType, digest, content, err := rh.svc.getImageManifest(r.Context(), namespace, repository, tagOrDigest)
 
 	if err != nil {
@@ -331,7 +516,11 @@ func (rh *RegistryHandler) updateManifest(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	namespace, repository, tag := extractNamespaceRepositoryAndTag(r)
+	namespace, repository, tagOrDigest := extractNamespaceRepositoryAndTagOrDigest(r)
+
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPush) {
+		return
+	}
 
 	contentType := r.Header.Get("Content-Type")
 	if contentType == "" {
@@ -346,12 +535,201 @@ func (rh *RegistryHandler) updateManifest(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	digest, err := rh.svc.updateManifest(r.Context(), namespace, repository, tag, contentType, content)
+	// manifests pushed by digest are stored untagged
+	tag, digest := tagOrDigest, ""
+	if utils.IsImageDigest(tagOrDigest) {
+		if calculated, ok := utils.CalculateDigestWith(tagOrDigest, content); !ok || calculated != tagOrDigest {
+			dockererrors.WriteInvalidDigest(w, tagOrDigest)
+			return
+		}
+		tag, digest = "", tagOrDigest
+	}
+
+	result, err := rh.svc.updateManifest(r.Context(), namespace, repository, tag, digest, contentType, content)
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Error occured when updating manifest for request: %s", r.RequestURI)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if result.invalid {
+		dockererrors.WriteManifestInvalid(w, fmt.Sprintf("Manifest of media type %s can't be parsed", contentType))
+		return
+	}
+
+	if result.notFound {
+		dockererrors.WriteRepositoryNotFound(w)
+		return
+	}
+
+	if len(result.unknownBlobs) > 0 {
+		dockererrors.WriteBlobUnknownErrors(w, result.unknownBlobs)
+		return
+	}
+
+	if result.storedDigest != "" {
+		log.Logger().Warn().Msgf("Push of %s/%s@%s rejected since the manifest is stored as %s", namespace, repository,
+			digest, result.storedDigest)
+		dockererrors.WriteManifestInvalid(w, fmt.Sprintf("Manifest with the same content is stored as %s",
+			result.storedDigest))
+		return
+	}
+
+	if result.immutableTag {
+		log.Logger().Warn().Msgf("Push rejected since tag %s of %s/%s is immutable", tag, namespace, repository)
+		dockererrors.WriteImmutableTag(w, tag)
+		return
+	}
+
+	if result.quota != nil {
+		log.Logger().Warn().Msgf("Push rejected since %s quota of %s is exceeded", result.quota.unit,
+			result.quota.name)
+		writeQuotaExceeded(w, result.quota)
 		return
 	}
+
+	// clients use `OCI-Subject` to detect that the registry supports referrers API
+	if result.subject != "" {
+		w.Header().Set("OCI-Subject", result.subject)
+	}
 	w.Header().Set("Content-Length", "0")
-	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Docker-Content-Digest", result.digest)
 	w.WriteHeader(http.StatusCreated)
+}
+
+func (rh *RegistryHandler) deleteManifest(w http.ResponseWriter, r *http.Request) {
+	if rh.registryId != constants.HostedRegistryID {
+		dockererrors.WriteUnsupported(w)
+		return
+	}
+
+	namespace, repository, tagOrDigest := extractNamespaceRepositoryAndTagOrDigest(r)
+
+	registryConfig := config.GetImageRegistryConfig()
+	if !registryConfig.Delete.IsAllowed(namespace) {
+		dockererrors.WriteUnsupported(w)
+		return
+	}
+
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionDelete) {
+		return
+	}
+
+	result, err := rh.svc.deleteManifest(r.Context(), namespace, repository, tagOrDigest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occured when deleting manifest for request: %s", r.RequestURI)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if result.notFound {
+		dockererrors.WriteManifestNotFound(w)
+		return
+	}
+
+	if result.immutableTag != "" {
+		log.Logger().Warn().Msgf("Deletion of %s/%s:%s rejected since tag %s is immutable", namespace, repository,
+			tagOrDigest, result.immutableTag)
+		dockererrors.WriteImmutableTagDeletion(w, result.immutableTag)
+		return
+	}
+
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (rh *RegistryHandler) listTags(w http.ResponseWriter, r *http.Request) {
+	namespace, repository := extractNamespaceAndRepository(r)
+
+	n, last, ok := parsePaginationParams(w, r)
+	if !ok {
+		return
+	}
+
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPull) {
+		return
+	}
+
+	result, err := rh.svc.listTags(r.Context(), namespace, repository, n, last)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if result.notFound {
+		dockererrors.WriteRepositoryNotFound(w)
+		return
+	}
+
+	if result.hasMore && len(result.tags) > 0 {
+		writeNextPageLink(w, r, n, result.tags[len(result.tags)-1])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dockerv2.TagListResponse{
+		Name: fmt.Sprintf("%s/%s", namespace, repository),
+		Tags: result.tags,
+	})
+}
+
+func (rh *RegistryHandler) listCatalog(w http.ResponseWriter, r *http.Request) {
+	n, last, ok := parsePaginationParams(w, r)
+	if !ok {
+		return
+	}
+
+	// Catalog is not authorized as a whole. Instead, it only contains the repositories which the user can pull from
+	result, err := rh.svc.listCatalog(r.Context(), n, last)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if result.hasMore && len(result.repositories) > 0 {
+		writeNextPageLink(w, r, n, result.repositories[len(result.repositories)-1])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dockerv2.CatalogResponse{
+		Repositories: result.repositories,
+	})
+}
+
+func (rh *RegistryHandler) listReferrers(w http.ResponseWriter, r *http.Request) {
+	namespace, repository, digest := extractNamespaceRepositoryAndDigest(r)
+
+	if !utils.IsValidDigest(digest) {
+		dockererrors.WriteInvalidDigest(w, digest)
+		return
+	}
+
+	if !rh.authorize(w, r, namespace, repository, constants.RegistryActionPull) {
+		return
+	}
+
+	artifactType := r.URL.Query().Get("artifactType")
+
+	result, err := rh.svc.listReferrers(r.Context(), namespace, repository, digest, artifactType)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if result.notFound {
+		dockererrors.WriteRepositoryNotFound(w)
+		return
+	}
+
+	if artifactType != "" {
+		w.Header().Set("OCI-Filters-Applied", "artifactType")
+	}
+	w.Header().Set("Content-Type", oci.MediaTypeImageIndex)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(oci.OCIImageIndex{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeImageIndex,
+		Manifests:     result.referrers,
+	})
 }
\ No newline at end of file
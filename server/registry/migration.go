@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"context"
+
+	storage_errors "github.com/ksankeerth/open-image-registry/errors/storage"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/storage"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+// MigrateBlobStorage moves blobs stored per repository by earlier versions (blobs/<registry>/<namespace>/
+// <repository>/<digest>) to content addressed locations. A blob stored by several repositories is kept once and
+// the duplicates are removed. Each blob is migrated in its own transaction; therefore, an interrupted migration
+// continues from where it stopped on the next run.
+func MigrateBlobStorage(ctx context.Context, store store.Store) error {
+	blobs, err := store.ImageQueries().ListUnmigratedBlobs(ctx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Unable to load blobs to migrate")
+		return err
+	}
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	log.Logger().Info().Msgf("Migrating %d blobs to content addressed storage", len(blobs))
+
+	migrated := 0
+	for _, blob := range blobs {
+		ok, err := migrateBlob(ctx, store, blob)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to migrate blob: %s", blob.Location)
+			return err
+		}
+		if ok {
+			migrated++
+		}
+	}
+
+	log.Logger().Info().Msgf("Blob storage migration completed. Migrated: %d, Missing: %d", migrated,
+		len(blobs)-migrated)
+	return nil
+}
+
+// migrateBlob moves the blob to its content addressed location and links it to the repository. Blobs missing in
+// storage are removed. ok is false if the blob was removed.
+func migrateBlob(reqCtx context.Context, s store.Store, blob *models.ImageBlobMetaModel) (ok bool, err error) {
+	contentLock.Lock()
+	defer contentLock.Unlock()
+
+	tx, err := s.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to migrate blob due to database transaction errors")
+		return false, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	location := utils.BlobLocation(blob.Digest)
+
+	content, err := s.Blobs().GetContent(ctx, blob.Digest)
+	if err != nil {
+		return false, err
+	}
+
+	if content == nil {
+		err = storage.RenameFile(blob.Location, location)
+		if err != nil && !isFileNotFound(err) {
+			return false, err
+		}
+		// file may have been moved by an interrupted migration
+		if err != nil {
+			_, err = storage.Size(location)
+			if err != nil {
+				if !isFileNotFound(err) {
+					return false, err
+				}
+				log.Logger().Warn().Msgf("Blob file: %s is missing in storage. Blob is removed", blob.Location)
+				_, err = s.Blobs().Delete(ctx, blob.RepositoryID, blob.Digest)
+				return false, err
+			}
+		}
+	} else {
+		// content is already stored by another repository
+		err = storage.DeleteFile(blob.Location)
+		if err != nil && !isFileNotFound(err) {
+			return false, err
+		}
+		location = content.Location
+	}
+
+	err = s.Blobs().Relocate(ctx, blob.RepositoryID, blob.Digest, location, int64(blob.Size))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func isFileNotFound(err error) bool {
+	ok, code := storage_errors.UnwrapStorageError(err)
+	return ok && code == storage_errors.CodeFileNotFound
+}
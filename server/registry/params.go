@@ -2,9 +2,12 @@ package registry
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/dockererrors"
 )
 
 func extractNamespaceRepositoryAndDigest(r *http.Request) (namespace, repository, digest string) {
@@ -37,8 +40,7 @@ func extractNamespaceRepositoryAndTagOrDigest(r *http.Request) (namespace, repos
 	return
 }
 
-func extractNamespaceRepositoryAndTag(r *http.Request) (namespace, repository, tag string) {
-	tag = chi.URLParam(r, "tag")
+func extractNamespaceAndRepository(r *http.Request) (namespace, repository string) {
 	namespace = chi.URLParam(r, "namespace")
 	if namespace == "" {
 		namespace = constants.DefaultNamespace
@@ -47,11 +49,32 @@ func extractNamespaceRepositoryAndTag(r *http.Request) (namespace, repository, t
 	return
 }
 
-func extractNamespaceAndRepository(r *http.Request) (namespace, repository string) {
-	namespace = chi.URLParam(r, "namespace")
-	if namespace == "" {
-		namespace = constants.DefaultNamespace
+// splitRepositoryName splits full repository name such as `library/nginx` into namespace and repository.
+// Repositories without namespace belong to the default namespace.
+func splitRepositoryName(name string) (namespace, repository string) {
+	namespace, repository, found := strings.Cut(name, "/")
+	if !found {
+		return constants.DefaultNamespace, name
 	}
-	repository = chi.URLParam(r, "repository")
-	return
+	return namespace, repository
+}
+
+// parsePaginationParams parses `n` and `last` query params. n will be -1 if it is not present in the request.
+// If n is invalid, PAGINATION_NUMBER_INVALID error is written to the response.
+func parsePaginationParams(w http.ResponseWriter, r *http.Request) (n int, last string, ok bool) {
+	n = -1
+	last = r.URL.Query().Get("last")
+
+	nParam := r.URL.Query().Get("n")
+	if nParam == "" {
+		return n, last, true
+	}
+
+	n, err := strconv.Atoi(nParam)
+	if err != nil || n < 0 {
+		dockererrors.WriteInvalidPagination(w, nParam)
+		return 0, "", false
+	}
+
+	return n, last, true
 }
\ No newline at end of file
@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+// quotaViolation describes the quota which would be exceeded by a push
+type quotaViolation struct {
+	resourceType string // Namespace or Repository
+	name         string
+	unit         string // bytes or images
+	usage        int64
+	limit        int64
+}
+
+// checkQuota reports the quota of the repository or its namespace which would be exceeded by adding the given
+// bytes and images. nil is returned if the push is within the quotas.
+func (svc *RegistryService) checkQuota(ctx context.Context, namespace, repository, namespaceID,
+	repositoryID string, bytes, images int64) (*quotaViolation, error) {
+	quota, err := svc.store.Repositories().GetQuota(ctx, repositoryID)
+	if err != nil {
+		return nil, err
+	}
+	if v := exceedsQuota(quota, bytes, images); v != nil {
+		v.resourceType = constants.ResourceTypeRepository
+		v.name = namespace + "/" + repository
+		return v, nil
+	}
+
+	quota, err = svc.store.Namespaces().GetQuota(ctx, namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	if v := exceedsQuota(quota, bytes, images); v != nil {
+		v.resourceType = constants.ResourceTypeNamespace
+		v.name = namespace
+		return v, nil
+	}
+	return nil, nil
+}
+
+func exceedsQuota(quota *models.QuotaModel, bytes, images int64) *quotaViolation {
+	if quota.MaxBytes != nil && quota.UsedBytes+bytes > *quota.MaxBytes {
+		return &quotaViolation{unit: "bytes", usage: quota.UsedBytes, limit: *quota.MaxBytes}
+	}
+	if quota.MaxImages != nil && quota.UsedImages+images > *quota.MaxImages {
+		return &quotaViolation{unit: "images", usage: quota.UsedImages, limit: *quota.MaxImages}
+	}
+	return nil
+}
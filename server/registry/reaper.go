@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+// UploadSessionReaper periodically removes blob upload sessions which haven't received content
+// for the configured age, along with the content uploaded so far.
+type UploadSessionReaper struct {
+	store      store.Store
+	maxIdleAge time.Duration
+	interval   time.Duration
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+func NewUploadSessionReaper(store store.Store, cfg config.UploadSessionConfig) *UploadSessionReaper {
+	return &UploadSessionReaper{
+		store:      store,
+		maxIdleAge: cfg.MaxIdleAge,
+		interval:   cfg.ReapInterval,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start runs the reaper in background. It does nothing if max idle age is not configured.
+func (r *UploadSessionReaper) Start() {
+	if r.maxIdleAge <= 0 || r.interval <= 0 {
+		log.Logger().Info().Msg("Upload session reaper is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.reap(context.Background())
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *UploadSessionReaper) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+func (r *UploadSessionReaper) reap(ctx context.Context) {
+	sessions, err := r.store.ImageQueries().ListIdleUploadSessions(ctx, int64(r.maxIdleAge.Seconds()))
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Unable to load idle upload sessions")
+		return
+	}
+
+	for _, session := range sessions {
+		// Uploads are only accepted by hosted registry
+		location := ""
+		if session.Namespace != "" && session.Repository != "" {
+			location = utils.StorageLocation("blobs", constants.HostedRegistryName, session.Namespace,
+				session.Repository, session.SessionID)
+		}
+
+		if location == "" {
+			err = r.store.Blobs().DeleteUploadSession(ctx, session.SessionID)
+		} else {
+			err = removeUploadSession(ctx, r.store, session.SessionID, location)
+		}
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to remove idle upload session: %s", session.SessionID)
+			continue
+		}
+		log.Logger().Debug().Msgf("Idle upload session: %s was removed", session.SessionID)
+	}
+}
\ No newline at end of file
@@ -3,6 +3,10 @@ package registry
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ksankeerth/open-image-registry/errors/dockererrors"
 )
 
 func writeBlobExistsResponse(w http.ResponseWriter, digest string) {
@@ -11,10 +15,34 @@ func writeBlobExistsResponse(w http.ResponseWriter, digest string) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func writeBlobUploadSuccess(w http.ResponseWriter, url, sessionId string, start, end int) {
+func writeQuotaExceeded(w http.ResponseWriter, v *quotaViolation) {
+	dockererrors.WriteQuotaExceeded(w, v.resourceType, v.name, v.unit, v.usage, v.limit)
+}
+
+// writeBlobUploadSuccess writes the `202 Accepted` response of an upload request whose chunk has been stored.
+func writeBlobUploadSuccess(w http.ResponseWriter, statusCode int, url, sessionId string, start, end int) {
 	w.Header().Add("Location", url)
 	w.Header().Add("Range", fmt.Sprintf("%d-%d", start, end))
 	w.Header().Add("Docker-Upload-UUID", sessionId)
 	w.Header().Set("Content-Length", "0")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(statusCode)
+}
+
+// lastByteOffset returns the offset of the last received byte to be used in `Range` header of upload responses
+func lastByteOffset(size int64) int {
+	if size < 1 {
+		return 0
+	}
+	return int(size - 1)
+}
+
+// writeNextPageLink sets RFC5988 `Link` header pointing to the next page of the paginated response
+func writeNextPageLink(w http.ResponseWriter, r *http.Request, n int, last string) {
+	query := url.Values{}
+	if n > 0 {
+		query.Set("n", strconv.Itoa(n))
+	}
+	query.Set("last", last)
+
+	w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode()))
 }
\ No newline at end of file
@@ -0,0 +1,237 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+type RetentionItem struct {
+	Repository string // `namespace/repository`
+	Tag        string
+	Digest     string // empty if the tag doesn't point to a manifest
+}
+
+// RetentionReport lists the tags deleted by retention rules. In dry run, nothing is deleted and the report lists
+// what would have been deleted.
+type RetentionReport struct {
+	DryRun bool
+	Tags   []*RetentionItem
+}
+
+// RetentionManager applies retention rules of namespaces and repositories of the hosted registry. Tags are deleted
+// the same way as deleting them through the registry API. Therefore, namespaces in which deletion is disabled are
+// skipped and delete events are sent to webhooks. Manifests left without tags are removed by garbage collection
+// along with their blobs.
+type RetentionManager struct {
+	store    store.Store
+	svc      *RegistryService
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewRetentionManager(store store.Store, cfg config.RetentionConfig, events *EventDispatcher) *RetentionManager {
+	return &RetentionManager{
+		store:    store,
+		svc:      NewRegistryService(constants.HostedRegistryID, constants.HostedRegistryName, store, events),
+		interval: cfg.Interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start applies retention rules of all repositories periodically in background. It does nothing if the interval is
+// not configured.
+func (m *RetentionManager) Start() {
+	if m.interval <= 0 {
+		log.Logger().Info().Msg("Scheduled retention is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				_, err := m.apply(context.Background(), "", "", false)
+				if err != nil {
+					log.Logger().Error().Err(err).Msg("Scheduled retention failed")
+				}
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (m *RetentionManager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+}
+
+// ApplyToNamespace applies retention rules to the repositories of the namespace. Rules of each repository are
+// applied along with the rules of the namespace. If dryRun is true, nothing is deleted.
+func (m *RetentionManager) ApplyToNamespace(ctx context.Context, namespaceID string,
+	dryRun bool) (*RetentionReport, error) {
+	return m.apply(ctx, namespaceID, "", dryRun)
+}
+
+// ApplyToRepository applies retention rules of the repository and its namespace to the repository. If dryRun is
+// true, nothing is deleted.
+func (m *RetentionManager) ApplyToRepository(ctx context.Context, repositoryID string,
+	dryRun bool) (*RetentionReport, error) {
+	return m.apply(ctx, "", repositoryID, dryRun)
+}
+
+func (m *RetentionManager) apply(ctx context.Context, namespaceID, repositoryID string,
+	dryRun bool) (*RetentionReport, error) {
+	repositories, err := m.store.ImageQueries().ListRetentionRepositories(ctx, constants.HostedRegistryID,
+		namespaceID, repositoryID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Unable to load repositories to apply retention rules")
+		return nil, err
+	}
+
+	report := &RetentionReport{
+		DryRun: dryRun,
+		Tags:   []*RetentionItem{},
+	}
+
+	registryConfig := config.GetImageRegistryConfig()
+
+	// each repository is handled in its own transaction so that a large namespace doesn't block pushes for long
+	for _, repository := range repositories {
+		if !registryConfig.Delete.IsAllowed(repository.Namespace) {
+			log.Logger().Info().Msgf("Retention skipped repository %s/%s since deletion is disabled in the namespace",
+				repository.Namespace, repository.Name)
+			continue
+		}
+
+		items, err := m.applyToRepository(ctx, repository, dryRun)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Unable to apply retention rules to repository: %s/%s",
+				repository.Namespace, repository.Name)
+			return nil, err
+		}
+		report.Tags = append(report.Tags, items...)
+	}
+
+	log.Logger().Info().Bool("dryRun", dryRun).Msgf("Retention completed. Repositories: %d, Tags: %d",
+		len(repositories), len(report.Tags))
+
+	return report, nil
+}
+
+func (m *RetentionManager) applyToRepository(reqCtx context.Context, repository *models.RetentionRepositoryModel,
+	dryRun bool) (items []*RetentionItem, err error) {
+	var deleted []dockerv2.EventTarget
+	defer func() {
+		if err == nil {
+			for _, target := range deleted {
+				m.svc.notify(reqCtx, repository.NamespaceID, constants.WebhookActionDelete, target)
+			}
+		}
+	}()
+
+	tx, err := m.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to apply retention rules due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	rules, err := m.store.Repositories().GetRetentionRules(ctx, repository.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceRules, err := m.store.Namespaces().GetRetentionRules(ctx, repository.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+	rules = append(rules, namespaceRules...)
+
+	immutable, err := m.store.Repositories().GetImmutableTagPatterns(ctx, repository.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := m.store.ImageQueries().ListRetentionTags(ctx, repository.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := repository.Namespace + "/" + repository.Name
+	for _, tag := range expiredTags(rules, immutable, tags, time.Now().UTC()) {
+		if !dryRun {
+			ok, err := m.svc.deleteTag(ctx, repository.Namespace, repository.Name, repository.ID, tag.Tag)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				deleted = append(deleted, tagEventTarget(repository.Namespace, repository.Name, tag.Tag))
+			}
+		}
+		items = append(items, &RetentionItem{
+			Repository: name,
+			Tag:        tag.Tag,
+			Digest:     tag.Digest,
+		})
+	}
+	return items, nil
+}
+
+// expiredTags returns the tags selected by the rules, in the given order. A tag is selected by a rule if it matches
+// the pattern, isn't among the KeepLast most recent matching tags and was pushed MaxAgeDays ago. A tag is expired
+// if every rule matching it selects it. Stable tags and tags matching immutable tag patterns never expire and
+// aren't counted towards KeepLast. tags must be ordered by push time, most recent first.
+func expiredTags(rules []*models.RetentionRuleModel, immutable []string, tags []*models.RetentionTagModel,
+	now time.Time) []*models.RetentionTagModel {
+	matched := make(map[string]int)
+	selected := make(map[string]int)
+
+	for _, rule := range rules {
+		rank := int64(0)
+		for _, tag := range tags {
+			if tag.IsStable || matchesAnyTagPattern(immutable, tag.Tag) || !utils.MatchTagPattern(rule.TagPattern, tag.Tag) {
+				continue
+			}
+			matched[tag.Tag]++
+
+			beyondKeepLast := rule.KeepLast == nil || rank >= *rule.KeepLast
+			rank++
+			olderThanMaxAge := rule.MaxAgeDays == nil ||
+				!tag.PushedAt.After(now.AddDate(0, 0, -int(*rule.MaxAgeDays)))
+
+			if beyondKeepLast && olderThanMaxAge {
+				selected[tag.Tag]++
+			}
+		}
+	}
+
+	expired := []*models.RetentionTagModel{}
+	for _, tag := range tags {
+		if matched[tag.Tag] > 0 && selected[tag.Tag] == matched[tag.Tag] {
+			expired = append(expired, tag)
+		}
+	}
+	return expired
+}
\ No newline at end of file
@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ksankeerth/open-image-registry/errors/dockererrors"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+// repositoryRoute is an endpoint scoped to a repository. Repository names may have any number of path components
+// (eg: platform/team-a/services/billing-api) which can't be expressed with chi patterns. Therefore, these endpoints
+// are matched against the path following `/v2/`. The first capture group of the pattern is the repository name and
+// the rest are exposed as URL params in the order of `params`.
+type repositoryRoute struct {
+	pattern  *regexp.Regexp
+	params   []string
+	handlers map[string]http.HandlerFunc
+}
+
+// repositoryRoutes returns repository scoped endpoints. Upload endpoints are listed before blob endpoints since
+// `<name>/blobs/uploads/<session_id>` matches the blob pattern as well.
+func (rh *RegistryHandler) repositoryRoutes() []*repositoryRoute {
+	return []*repositoryRoute{
+		{
+			pattern: regexp.MustCompile(`^(.+)/blobs/uploads/$`),
+			handlers: map[string]http.HandlerFunc{
+				http.MethodPost: rh.initiateBlobUpload,
+			},
+		},
+		{
+			pattern: regexp.MustCompile(`^(.+)/blobs/uploads/([^/]+)$`),
+			params:  []string{"session_id"},
+			handlers: map[string]http.HandlerFunc{
+				http.MethodPut:    rh.handleBlobUpload,
+				http.MethodPatch:  rh.handleBlobUpload,
+				http.MethodGet:    rh.getBlobUploadStatus,
+				http.MethodDelete: rh.cancelBlobUpload,
+			},
+		},
+		{
+			pattern: regexp.MustCompile(`^(.+)/blobs/([^/]+)$`),
+			params:  []string{"digest"},
+			handlers: map[string]http.HandlerFunc{
+				http.MethodHead:   rh.blobExists,
+				http.MethodGet:    rh.getImageBlob,
+				http.MethodDelete: rh.deleteBlob,
+			},
+		},
+		{
+			pattern: regexp.MustCompile(`^(.+)/manifests/([^/]+)$`),
+			params:  []string{"tag_or_digest"},
+			handlers: map[string]http.HandlerFunc{
+				http.MethodHead:   rh.manifestExists,
+				http.MethodPut:    rh.updateManifest,
+				http.MethodGet:    rh.getManifest,
+				http.MethodDelete: rh.deleteManifest,
+			},
+		},
+		{
+			pattern: regexp.MustCompile(`^(.+)/tags/list$`),
+			handlers: map[string]http.HandlerFunc{
+				http.MethodGet: rh.listTags,
+			},
+		},
+		{
+			pattern: regexp.MustCompile(`^(.+)/referrers/([^/]+)$`),
+			params:  []string{"digest"},
+			handlers: map[string]http.HandlerFunc{
+				http.MethodGet: rh.listReferrers,
+			},
+		},
+	}
+}
+
+// routeRepository dispatches the request to the matching repository scoped endpoint. The first component of the
+// repository name is the namespace and the rest is the repository within the namespace. Names with a single
+// component belong to the default namespace. Requests with invalid names are answered with NAME_INVALID.
+func (rh *RegistryHandler) routeRepository(routes []*repositoryRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := chi.URLParam(r, "*")
+
+		for _, route := range routes {
+			matches := route.pattern.FindStringSubmatch(path)
+			if matches == nil {
+				continue
+			}
+
+			handler, ok := route.handlers[r.Method]
+			if !ok {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			namespace, repository := splitRepositoryName(matches[1])
+
+			// names are used in storage locations; requests with invalid names are rejected before they are handled
+			if !utils.IsValidNamespace(namespace) || !utils.IsValidExistingRepository(repository) {
+				dockererrors.WriteInvalidRepository(w)
+				return
+			}
+
+			rctx := chi.RouteContext(r.Context())
+			rctx.URLParams.Add("namespace", namespace)
+			rctx.URLParams.Add("repository", repository)
+			for i, param := range route.params {
+				rctx.URLParams.Add(param, matches[i+2])
+			}
+
+			handler(w, r)
+			return
+		}
+
+		http.NotFound(w, r)
+	}
+}
\ No newline at end of file
@@ -3,16 +3,23 @@ package registry
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	up "github.com/ksankeerth/open-image-registry/client/upstream"
 	"github.com/ksankeerth/open-image-registry/client/upstream/docker"
+	ociclient "github.com/ksankeerth/open-image-registry/client/upstream/oci"
 	"github.com/ksankeerth/open-image-registry/config"
 	"github.com/ksankeerth/open-image-registry/constants"
+	storage_errors "github.com/ksankeerth/open-image-registry/errors/storage"
+	"github.com/ksankeerth/open-image-registry/lib"
 	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/oci"
 	"github.com/ksankeerth/open-image-registry/types/models"
 
 	"github.com/ksankeerth/open-image-registry/log"
@@ -24,6 +31,10 @@ import (
 type upstreamInfo struct {
 	cacheEnabled bool
 	cacheTTL     int
+	// staleIfError serves expired cache entries when the upstream registry is unavailable
+	staleIfError bool
+	// offline serves cached content only; the upstream registry is never contacted
+	offline bool
 }
 
 type RegistryService struct {
@@ -34,9 +45,14 @@ type RegistryService struct {
 	repositoryIdMap sync.Map
 	upstream        *upstreamInfo
 	client          up.UpstreamClient
+	tagListCache    *lib.Cache
+	events          *EventDispatcher
+	// fetches coalesces concurrent pulls of artifacts missing from the upstream cache
+	fetches *fetchGroup
 }
 
-func NewRegistryService(registryID, registryName string, store store.Store) *RegistryService {
+func NewRegistryService(registryID, registryName string, store store.Store,
+	events *EventDispatcher) *RegistryService {
 
 	var upstream upstreamInfo
 	var client up.UpstreamClient
@@ -48,13 +64,6 @@ func NewRegistryService(registryID, registryName string, store store.Store) *Reg
 			return nil
 		}
 
-		if registryModel.Vendor != constants.RegistryVendorDockerHub {
-			// For now, we only support docker-hub
-			// TODO: add support for other upstream registeries
-			log.Logger().Warn().Msg("OpenImageRegistry currently supports  DockerHub only")
-			return nil
-		}
-
 		cacheModel, err := store.Upstreams().GetRegistryCacheConfig(context.Background(), registryID)
 		if err != nil {
 			log.Logger().Error().Err(err).Msg("Registry Service Initialization failed due to database errors")
@@ -62,6 +71,8 @@ func NewRegistryService(registryID, registryName string, store store.Store) *Reg
 		}
 		upstream.cacheEnabled = cacheModel.CacheEnabled
 		upstream.cacheTTL = cacheModel.TTLSeconds
+		upstream.staleIfError = cacheModel.StaleIfError
+		upstream.offline = cacheModel.OfflineMode
 
 		networkConfig, err := store.Upstreams().GetRegistryNetworkConfig(context.Background(), registryID)
 		if err != nil {
@@ -85,29 +96,17 @@ func NewRegistryService(registryID, registryName string, store store.Store) *Reg
 			return nil
 		}
 
-		var dockerAuthConfig docker.AuthConfig
-
-		err = json.Unmarshal(authConfig.ConfigJSON, &dockerAuthConfig)
+		client, err = newUpstreamClient(registryModel, authConfig, networkConfig)
 		if err != nil {
-			log.Logger().Warn().Str("registry", registryName).
-				Msg("Upstream Registry exists without auth config")
+			log.Logger().Warn().Err(err).Str("registry", registryName).
+				Msg("Upstream Registry exists with invalid auth config")
 			return nil
 		}
+	}
 
-		cfg := docker.Config{}
-		cfg.RegistryURL = registryModel.UpstreamURL
-		cfg.TokenURL = dockerAuthConfig.TokenEndpoint
-		cfg.Username = dockerAuthConfig.Username
-		cfg.Password = dockerAuthConfig.Credential
-
-		cfg.ConnectionTimeout = time.Duration(networkConfig.ConnectionTimeout)
-		cfg.RequestTimeout = time.Duration(networkConfig.ReadTimeout)
-		cfg.MaxConnections = networkConfig.MaxConnections
-		cfg.MaxIdleConnections = networkConfig.MaxIdleConnections
-		cfg.MaxRetries = networkConfig.MaxRetries
-		cfg.RetryBackOffMultiplier = networkConfig.RetryBackOffMultiplier
-
-		client = docker.NewClient(&cfg)
+	var tagListCache *lib.Cache
+	if upstream.cacheEnabled {
+		tagListCache = lib.NewCache(time.Minute)
 	}
 
 	return &RegistryService{
@@ -116,15 +115,87 @@ func NewRegistryService(registryID, registryName string, store store.Store) *Reg
 		store:        store,
 		upstream:     &upstream,
 		client:       client,
+		tagListCache: tagListCache,
+		fetches:      newFetchGroup(),
+		events:       events,
 	}
 }
 
-func (svc *RegistryService) initiateBlobUpload(reqCtx context.Context, namespace, repository string) (sessionID string,
-	err error) {
+// newUpstreamClient creates the client of the upstream registry. Docker Hub is served by its own client; any other
+// vendor is expected to implement the OCI distribution spec.
+func newUpstreamClient(registryModel *models.UpstreamRegistry, authConfig *models.UpstreamRegistryAuthConfig,
+	networkConfig *models.UpstreamRegistryNetworkConfig) (up.UpstreamClient, error) {
+	connectionTimeout := time.Duration(networkConfig.ConnectionTimeout) * time.Second
+	requestTimeout := time.Duration(networkConfig.ReadTimeout) * time.Second
+	retryDelay := time.Duration(networkConfig.RetryDelay) * time.Second
+
+	if registryModel.Vendor == constants.RegistryVendorDockerHub {
+		var dockerAuthConfig docker.AuthConfig
+		if err := json.Unmarshal(authConfig.ConfigJSON, &dockerAuthConfig); err != nil {
+			return nil, err
+		}
+
+		return docker.NewClient(&docker.Config{
+			RegistryURL:            registryModel.UpstreamURL,
+			TokenURL:               dockerAuthConfig.TokenEndpoint,
+			Username:               dockerAuthConfig.Username,
+			Password:               dockerAuthConfig.Credential,
+			ConnectionTimeout:      connectionTimeout,
+			RequestTimeout:         requestTimeout,
+			MaxConnections:         networkConfig.MaxConnections,
+			MaxIdleConnections:     networkConfig.MaxIdleConnections,
+			MaxRetries:             networkConfig.MaxRetries,
+			RetryDelay:             retryDelay,
+			RetryBackOffMultiplier: networkConfig.RetryBackOffMultiplier,
+		}), nil
+	}
+
+	var ociAuthConfig ociclient.AuthConfig
+	if err := json.Unmarshal(authConfig.ConfigJSON, &ociAuthConfig); err != nil {
+		return nil, err
+	}
+
+	return ociclient.NewClient(&ociclient.Config{
+		RegistryURL:            registryModel.UpstreamURL,
+		Username:               ociAuthConfig.Username,
+		Password:               ociAuthConfig.Credential,
+		ConnectionTimeout:      connectionTimeout,
+		RequestTimeout:         requestTimeout,
+		MaxConnections:         networkConfig.MaxConnections,
+		MaxIdleConnections:     networkConfig.MaxIdleConnections,
+		MaxRetries:             networkConfig.MaxRetries,
+		RetryDelay:             retryDelay,
+		RetryBackOffMultiplier: networkConfig.RetryBackOffMultiplier,
+	}), nil
+}
+
+// notify queues an event of the request for webhooks of the namespace. Events must only be emitted for committed
+// changes; therefore, callers defer it before deferring the commit of their transaction.
+func (svc *RegistryService) notify(ctx context.Context, namespaceID, action string, target dockerv2.EventTarget) {
+	svc.events.Notify(namespaceID, NewEvent(ctx, action, target))
+}
+
+type blobUploadInitResult struct {
+	sessionID string
+	notFound  bool            // true if namespace or repository doesn't exist and can't be created
+	quota     *quotaViolation // set if the quota of the repository or its namespace is used up
+}
+
+func (svc *RegistryService) initiateBlobUpload(reqCtx context.Context, namespace,
+	repository string) (result *blobUploadInitResult, err error) {
+	var namespaceID string
+	var repositoryCreated bool
+	defer func() {
+		if err == nil && repositoryCreated {
+			svc.notify(reqCtx, namespaceID, constants.WebhookActionCreate,
+				dockerv2.EventTarget{Repository: namespace + "/" + repository})
+		}
+	}()
+
 	tx, err := svc.store.Begin(reqCtx)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("Failed to initiate blob upload due to database transaction errors")
-		return "", err
+		return nil, err
 	}
 	ctx := store.WithTxContext(reqCtx, tx)
 	defer func() {
@@ -135,50 +206,103 @@ func (svc *RegistryService) initiateBlobUpload(reqCtx context.Context, namespace
 		}
 	}()
 
+	result = &blobUploadInitResult{}
+
+	var repositoryID string
+	namespaceID, repositoryID, repositoryCreated, err = svc.ensureNamespaceAndRepository(ctx, namespace, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespaceID == "" || repositoryID == "" {
+		result.notFound = true
+		return result, nil
+	}
+
+	// size of the blob is unknown until the upload completes. Therefore, uploads are refused once the quota is
+	// used up.
+	result.quota, err = svc.checkQuota(ctx, namespace, repository, namespaceID, repositoryID, 1, 0)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to check quota of repository")
+		return nil, err
+	}
+	if result.quota != nil {
+		return result, nil
+	}
+
+	result.sessionID = uuid.New().String()
+
+	err = svc.store.Blobs().CreateUploadSession(ctx, result.sessionID, namespaceID, repositoryID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to persist image blob upload session")
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ensureNamespaceAndRepository returns IDs of the namespace and repository. If they don't exist, they will be
+// created when it is allowed by configuration. Otherwise, empty IDs will be returned. created is true if the
+// repository was created.
+func (svc *RegistryService) ensureNamespaceAndRepository(ctx context.Context, namespace,
+	repository string) (namespaceID, repositoryID string, created bool, err error) {
 	cfg := config.GetImageRegistryConfig()
+	username, _ := ctx.Value(constants.ContextUsername).(string)
+	role, _ := ctx.Value(constants.ContextRole).(string)
 
 	// namespace does not exist
-	namespaceID, err := svc.getNamespaceID(ctx, namespace)
+	namespaceID, err = svc.getNamespaceID(ctx, namespace)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("Failed to retrieve namespace from database")
-		return "", err
+		return "", "", false, err
 	}
 	if namespaceID == "" && cfg.CreateNamespaceOnPush {
-		// TODO: createdBy has to be changed with correct value
-		namespaceID, err = svc.store.Namespaces().Create(ctx, svc.registryId, namespace, "", "", false, "admin")
+		namespaceID, err = svc.store.Namespaces().Create(ctx, svc.registryId, namespace, constants.NamespacePurposeProject,
+			"", false, username)
 		if err != nil {
 			log.Logger().Error().Err(err).Msg("Failed to create namespace on image push")
-			return "", err
+			return "", "", false, err
+		}
+
+		// Admins have access to all namespaces. Other users become maintainers of the namespace they created.
+		if role != constants.RoleAdmin {
+			user, err := svc.store.Users().GetByUsername(ctx, username)
+			if err != nil {
+				log.Logger().Error().Err(err).Msg("Failed to retrieve namespace creator on image push")
+				return "", "", false, err
+			}
+			if user != nil {
+				_, err = svc.store.Access().GrantAccess(ctx, namespaceID, constants.ResourceTypeNamespace, user.Id,
+					constants.AccessLevelMaintainer, user.Id)
+				if err != nil {
+					log.Logger().Error().Err(err).Msg("Failed to grant namespace access to creator on image push")
+					return "", "", false, err
+				}
+			}
 		}
 	}
 
-	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	// repository can't be created without namespace
+	if namespaceID == "" {
+		return "", "", false, nil
+	}
+
+	repositoryID, err = svc.getRepositoryID(ctx, namespace, repository)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("Failed to retrieve repository from database")
-		return "", err
+		return "", "", false, err
 	}
-	if repositoryID == "" && cfg.CreateRepositoryOnPush {
-
-		repositoryID, err = svc.store.Repositories().Create(ctx, svc.registryId, namespaceID, repository, "", false, "todo")
+	// names accepted only for compatibility with repositories of earlier versions aren't used for new repositories
+	if repositoryID == "" && cfg.CreateRepositoryOnPush && utils.IsValidRepository(repository) {
+		repositoryID, err = svc.store.Repositories().Create(ctx, svc.registryId, namespaceID, repository, "", false, username)
 		if err != nil {
 			log.Logger().Error().Err(err).Msg("Failed to create repository on image push")
-			return "", err
+			return "", "", false, err
 		}
+		created = true
 	}
 
-	if namespaceID == "" || repositoryID == "" {
-		return "", nil
-	}
-
-	sessionID = uuid.New().String()
-
-	err = svc.store.Blobs().CreateUploadSession(ctx, sessionID, namespace, repository)
-	if err != nil {
-		log.Logger().Error().Err(err).Msg("Failed to persist image blob upload session")
-		return "", err
-	}
-
-	return sessionID, nil
+	return namespaceID, repositoryID, created, nil
 }
 
 func (svc *RegistryService) blobExists(reqCtx context.Context, namespace, repository,
@@ -199,7 +323,7 @@ func (svc *RegistryService) blobExists(reqCtx context.Context, namespace, reposi
 
 	ctx := store.WithTxContext(reqCtx, tx)
 
-	exists, _, err := svc.loadImageBlob(ctx, namespace, repository, digest, false)
+	exists, _, err := svc.loadImageBlob(ctx, namespace, repository, digest, true)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("Failed to retrieve image blob due to database errors")
 	}
@@ -223,7 +347,8 @@ func (svc *RegistryService) verifyNamespaceRepositorySession(ctx context.Context
 		return false, nil, err
 	}
 
-	if session == nil {
+	// session can't be used with other repositories
+	if session == nil || session.RepositoryID != repoID {
 		return false, nil, nil
 	}
 
@@ -231,12 +356,61 @@ func (svc *RegistryService) verifyNamespaceRepositorySession(ctx context.Context
 }
 
 type blobUploadResult struct {
-	invalid       bool // if namespace or repository or session doesn't exist, it will be true
-	partialUpload bool // true if partial blob upload detected
+	invalid       bool            // if namespace or repository or session doesn't exist, it will be true
+	partialUpload bool            // true if partial blob upload detected
+	digestInvalid bool            // true if the uploaded content doesn't match the digest
+	size          int64           // number of bytes received for the session so far
+	quota         *quotaViolation // set if the completed blob exceeds the quota of the repository or its namespace
 }
 
+// handleLastBlobChunk appends the remaining content of the upload (if any), verifies the digest and moves the
+// uploaded file to its digest location. The content is streamed to storage outside the database transaction.
 func (svc *RegistryService) handleLastBlobChunk(reqCtx context.Context, namespace, repository, digest,
-	sessionID string) (result *blobUploadResult, err error) {
+	sessionID string, body io.Reader) (result *blobUploadResult, err error) {
+	result = &blobUploadResult{}
+
+	ok, session, err := svc.verifyNamespaceRepositorySession(reqCtx, namespace, repository, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		result.invalid = true
+		return result, nil
+	}
+
+	newLocation := utils.BlobLocation(digest)
+	oldLocation := utils.StorageLocation("blobs", svc.registryName, namespace, repository, sessionID)
+
+	hasher, err := newUploadHasher(session.SHA256State, session.SHA512State)
+	if err != nil {
+		log.Logger().Error().Err(err).Str("sessionID", sessionID).Msg("Unable to restore hash state of upload session")
+		return nil, err
+	}
+
+	written, err := storage.AppendFrom(oldLocation, int64(session.BytesReceived), io.TeeReader(body, hasher))
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasher.verify(digest) {
+		log.Logger().Warn().
+			Str("namespace", namespace).
+			Str("repository", repository).
+			Str("blob digest", digest).
+			Msg("Uploaded content does not match the digest")
+		result.digestInvalid = true
+
+		err = removeUploadSession(reqCtx, svc.store, sessionID, oldLocation)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// held until the transaction is committed so that the content isn't removed in between
+	contentLock.RLock()
+	defer contentLock.RUnlock()
+
 	tx, err := svc.store.Begin(reqCtx)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("Failed to upload blob due to database transaction errors")
@@ -253,26 +427,12 @@ func (svc *RegistryService) handleLastBlobChunk(reqCtx context.Context, namespac
 
 	ctx := store.WithTxContext(reqCtx, tx)
 
-	result = &blobUploadResult{}
-
-	ok, session, err := svc.verifyNamespaceRepositorySession(ctx, namespace, repository, sessionID)
-	if err != nil {
-		return nil, err
-	}
-	if !ok {
-		result.invalid = true
-		return result, nil
-	}
-
-	newLocation := utils.StorageLocation("blobs", svc.registryName, namespace, repository, digest)
-	oldLocation := utils.StorageLocation("blobs", svc.registryName, namespace, repository, sessionID)
-
 	size, err := storage.Size(oldLocation)
 	if err != nil {
 		return nil, err
 	}
 
-	if size != int64(session.BytesReceived) {
+	if size != int64(session.BytesReceived)+written {
 		log.Logger().Warn().
 			Str("namespace", namespace).
 			Str("repository", repository).
@@ -282,17 +442,45 @@ func (svc *RegistryService) handleLastBlobChunk(reqCtx context.Context, namespac
 		return result, nil
 	}
 
-	err = storage.RenameFile(oldLocation, newLocation)
+	nsId, repoId, err := svc.getNameSpaceIdAndRepositoryId(ctx, namespace, repository)
 	if err != nil {
 		return nil, err
 	}
 
-	nsId, repoId, err := svc.getNameSpaceIdAndRepositoryId(ctx, namespace, repository)
+	blobMeta, err := svc.store.Blobs().Get(ctx, digest, repoId)
+	if err != nil {
+		return nil, err
+	}
+
+	// quota is checked at the start of the upload before the size is known. Now the size is known, the blob is
+	// only accepted if it fits into the remaining quota.
+	if blobMeta == nil {
+		result.quota, err = svc.checkQuota(ctx, namespace, repository, nsId, repoId, size, 0)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to check quota of repository")
+			return nil, err
+		}
+		if result.quota != nil {
+			err = removeUploadSession(ctx, svc.store, sessionID, oldLocation)
+			if err != nil {
+				return nil, err
+			}
+			return result, nil
+		}
+	}
+
+	err = storage.RenameFile(oldLocation, newLocation)
 	if err != nil {
 		return nil, err
 	}
 
-	err = svc.store.Blobs().Create(ctx, svc.registryId, nsId, repoId, digest, newLocation, size)
+	// same blob can be uploaded to this or another repository again. content addressed file is already replaced
+	// with identical content. It is touched so that the grace period of garbage collection applies to it again.
+	if blobMeta == nil {
+		err = svc.store.Blobs().Create(ctx, svc.registryId, nsId, repoId, digest, newLocation, size)
+	} else {
+		err = svc.store.Blobs().Touch(ctx, repoId, digest)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -302,30 +490,17 @@ func (svc *RegistryService) handleLastBlobChunk(reqCtx context.Context, namespac
 		return nil, err
 	}
 
+	result.size = size
 	return result, err
 }
 
+// uploadBlobChunk streams the chunk into the upload file. offset is the position of the chunk; a negative
+// offset means the chunk follows the bytes already received.
 func (svc *RegistryService) uploadBlobChunk(reqCtx context.Context, namespace, repository,
-	sessionID string, offset int64, payload []byte) (result *blobUploadResult, err error) {
-	tx, err := svc.store.Begin(reqCtx)
-	if err != nil {
-		log.Logger().Error().Err(err).Msg("Failed to upload blob due to database transaction errors")
-		return nil, err
-	}
-
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		} else {
-			tx.Commit()
-		}
-	}()
-
-	ctx := store.WithTxContext(reqCtx, tx)
-
+	sessionID string, offset int64, body io.Reader) (result *blobUploadResult, err error) {
 	result = &blobUploadResult{}
 
-	ok, session, err := svc.verifyNamespaceRepositorySession(ctx, namespace, repository, sessionID)
+	ok, session, err := svc.verifyNamespaceRepositorySession(reqCtx, namespace, repository, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -336,64 +511,75 @@ func (svc *RegistryService) uploadBlobChunk(reqCtx context.Context, namespace, r
 
 	location := utils.StorageLocation("blobs", svc.registryName, namespace, repository, sessionID)
 
-	if session.BytesReceived != int(offset) {
-		log.Logger().Error().Err(err).
+	if offset < 0 {
+		offset = int64(session.BytesReceived)
+	}
+
+	if int64(session.BytesReceived) != offset {
+		log.Logger().Error().
 			Str("namespace", namespace).
 			Str("repository", repository).
 			Str("session", sessionID).
 			Msg("Corrupted blob upload detected.")
 		result.partialUpload = true
 
-		err = storage.DeleteFile(location)
-		if err != nil {
-			log.Logger().Error().Err(err).Str("location", location).
-				Msg("Cleaning corrupted blob failed")
-			return nil, err
-		}
-
-		err = svc.store.Blobs().DeleteUploadSession(ctx, sessionID)
+		err = removeUploadSession(reqCtx, svc.store, sessionID, location)
 		if err != nil {
-			log.Logger().Error().Err(err).Str("sessionID", sessionID).
-				Msg("Cleaning corrupted blob failed")
 			return nil, err
 		}
 		return result, nil
 	}
 
-	err = storage.PutFileChunk(location, payload, offset)
+	hasher, err := newUploadHasher(session.SHA256State, session.SHA512State)
+	if err != nil {
+		log.Logger().Error().Err(err).Str("sessionID", sessionID).Msg("Unable to restore hash state of upload session")
+		return nil, err
+	}
+
+	written, err := storage.AppendFrom(location, offset, io.TeeReader(body, hasher))
+	if err != nil {
+		return nil, err
+	}
+
+	sha256State, sha512State, err := hasher.state()
 	if err != nil {
+		log.Logger().Error().Err(err).Str("sessionID", sessionID).Msg("Unable to save hash state of upload session")
 		return nil, err
 	}
 
-	err = svc.store.Blobs().UpdateUploadSession(ctx, sessionID, int(offset)+len(payload))
+	err = svc.store.Blobs().UpdateUploadSession(reqCtx, sessionID, int(offset+written), sha256State, sha512State)
 	if err != nil {
 		return nil, err
 	}
 
+	result.size = offset + written
 	return result, nil
 }
 
-func (svc *RegistryService) uploadBlobWhole(reqCtx context.Context, namespace, repository,
-	sessionID, digest string, payload []byte) (result *blobUploadResult, err error) {
-	tx, err := svc.store.Begin(reqCtx)
+// getBlobUploadStatus returns the number of bytes received for the upload session so far
+func (svc *RegistryService) getBlobUploadStatus(reqCtx context.Context, namespace, repository,
+	sessionID string) (result *blobUploadResult, err error) {
+	result = &blobUploadResult{}
+
+	ok, session, err := svc.verifyNamespaceRepositorySession(reqCtx, namespace, repository, sessionID)
 	if err != nil {
-		log.Logger().Error().Err(err).Msg("Failed to upload blob due to database transaction errors")
 		return nil, err
 	}
+	if !ok {
+		result.invalid = true
+		return result, nil
+	}
 
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		} else {
-			tx.Commit()
-		}
-	}()
-
-	ctx := store.WithTxContext(reqCtx, tx)
+	result.size = int64(session.BytesReceived)
+	return result, nil
+}
 
+// cancelBlobUpload removes the upload session and the content uploaded so far
+func (svc *RegistryService) cancelBlobUpload(reqCtx context.Context, namespace, repository,
+	sessionID string) (result *blobUploadResult, err error) {
 	result = &blobUploadResult{}
 
-	ok, _, err := svc.verifyNamespaceRepositorySession(ctx, namespace, repository, sessionID)
+	ok, _, err := svc.verifyNamespaceRepositorySession(reqCtx, namespace, repository, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -402,169 +588,417 @@ func (svc *RegistryService) uploadBlobWhole(reqCtx context.Context, namespace, r
 		return result, nil
 	}
 
-	location := utils.StorageLocation("blobs", svc.registryName, namespace, repository, digest)
-
-	err = storage.PutFile(location, payload)
+	location := utils.StorageLocation("blobs", svc.registryName, namespace, repository, sessionID)
+	err = removeUploadSession(reqCtx, svc.store, sessionID, location)
 	if err != nil {
 		return nil, err
 	}
 
-	err = svc.store.Blobs().DeleteUploadSession(ctx, sessionID)
-	return result, err
+	return result, nil
 }
 
-func (svc *RegistryService) getImageBlob(reqCtx context.Context, namespace, repository,
-	digest string) (exists bool, content []byte, err error) {
+// removeUploadSession deletes the upload session and its file. It is not an error if the file doesn't exist since
+// no content may have been uploaded for the session.
+func removeUploadSession(ctx context.Context, s store.Store, sessionID, location string) error {
+	err := storage.DeleteFile(location)
+	if err != nil {
+		if ok, code := storage_errors.UnwrapStorageError(err); !ok || code != storage_errors.CodeFileNotFound {
+			log.Logger().Error().Err(err).Str("location", location).Msg("Unable to remove file of upload session")
+			return err
+		}
+	}
+
+	err = s.Blobs().DeleteUploadSession(ctx, sessionID)
+	if err != nil {
+		log.Logger().Error().Err(err).Str("sessionID", sessionID).Msg("Unable to remove upload session")
+		return err
+	}
+	return nil
+}
+
+type blobMountResult struct {
+	mounted  bool            // true if the blob is available in target repository
+	notFound bool            // true if target namespace or repository doesn't exist and can't be created
+	quota    *quotaViolation // set if mounting the blob exceeds the quota of target repository or its namespace
+}
+
+// mountBlob makes a blob of the source repository available in the target repository without uploading it again.
+// The caller must verify that the user can pull from the source repository.
+func (svc *RegistryService) mountBlob(reqCtx context.Context, namespace, repository, digest, fromNamespace,
+	fromRepository string) (result *blobMountResult, err error) {
+	contentLock.RLock()
+	defer contentLock.RUnlock()
+
+	var namespaceID string
+	var repositoryCreated bool
+	defer func() {
+		if err == nil && repositoryCreated {
+			svc.notify(reqCtx, namespaceID, constants.WebhookActionCreate,
+				dockerv2.EventTarget{Repository: namespace + "/" + repository})
+		}
+	}()
+
 	tx, err := svc.store.Begin(reqCtx)
 	if err != nil {
-		log.Logger().Error().Err(err).Msg("Failed to initiate blob upload due to database transaction errors")
-		return false, nil, err
+		log.Logger().Error().Err(err).Msg("Failed to mount blob due to database transaction errors")
+		return nil, err
 	}
 	ctx := store.WithTxContext(reqCtx, tx)
+	// mounted blobs share the content of the source blob; nothing is written to storage. The mount fails if it
+	// isn't committed.
 	defer func() {
 		if err != nil {
 			tx.Rollback()
-		} else {
-			tx.Commit()
+		} else if err = tx.Commit(); err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to commit mounted blob")
+			result = nil
 		}
 	}()
-	return svc.loadImageBlob(ctx, namespace, repository, digest, false)
-}
-
-func (svc *RegistryService) pullBlobFromUpstream(_ context.Context, namespace, repository, digest string) (content []byte,
-	err error) {
-	return svc.client.GetBlob(namespace, repository, digest)
-}
-
-func (svc *RegistryService) loadImageBlob(ctx context.Context, namespace, repository,
-	digest string, skipContent bool) (exists bool, content []byte, err error) {
-	if svc.registryId == constants.HostedRegistryID {
-		return svc.loadImageBlobFromRegistry(ctx, namespace, repository, digest, skipContent)
-	} else {
-		return svc.loadImageBlobFromUpstream(ctx, namespace, repository, digest, skipContent)
-	}
-}
 
-func (svc *RegistryService) loadImageBlobFromRegistry(ctx context.Context, namespace, repository,
-	digest string, skipContent bool) (exists bool, content []byte, err error) {
+	result = &blobMountResult{}
 
-	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	sourceRepositoryID, err := svc.getRepositoryID(ctx, fromNamespace, fromRepository)
 	if err != nil {
-		return false, nil, err
+		log.Logger().Error().Err(err).Msg("Failed to retrieve source repository from database")
+		return nil, err
+	}
+	if sourceRepositoryID == "" {
+		return result, nil
 	}
 
-	blobMeta, err := svc.store.Blobs().Get(ctx, digest, repositoryID)
+	sourceBlob, err := svc.store.Blobs().Get(ctx, digest, sourceRepositoryID)
 	if err != nil {
-		return false, nil, err
+		log.Logger().Error().Err(err).Msg("Failed to retrieve source blob from database")
+		return nil, err
 	}
-	if blobMeta == nil {
-		return false, nil, nil
+	if sourceBlob == nil {
+		return result, nil
 	}
 
-	if skipContent {
-		return true, nil, nil
+	var repositoryID string
+	namespaceID, repositoryID, repositoryCreated, err = svc.ensureNamespaceAndRepository(ctx, namespace, repository)
+	if err != nil {
+		return nil, err
+	}
+	if namespaceID == "" || repositoryID == "" {
+		result.notFound = true
+		return result, nil
 	}
 
-	content, err = storage.ReadFile(blobMeta.Location)
+	targetBlob, err := svc.store.Blobs().Get(ctx, digest, repositoryID)
 	if err != nil {
-		return false, nil, err
+		log.Logger().Error().Err(err).Msg("Failed to retrieve target blob from database")
+		return nil, err
 	}
-	if len(content) != blobMeta.Size {
-		log.Logger().Warn().Msgf("Size mismatch for blob %s; Size in storage: %d, Size in database: %d",
-			blobMeta.Location, len(content), blobMeta.Size)
+	if targetBlob != nil {
+		err = svc.store.Blobs().Touch(ctx, repositoryID, digest)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to touch target blob")
+			return nil, err
+		}
+		result.mounted = true
+		return result, nil
 	}
-	return true, content, nil
+
+	result.quota, err = svc.checkQuota(ctx, namespace, repository, namespaceID, repositoryID,
+		int64(sourceBlob.Size), 0)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to check quota of target repository")
+		return nil, err
+	}
+	if result.quota != nil {
+		return result, nil
+	}
+
+	// content is shared with the source repository
+	err = svc.store.Blobs().Create(ctx, svc.registryId, namespaceID, repositoryID, digest, sourceBlob.Location,
+		int64(sourceBlob.Size))
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to persist mounted blob")
+		return nil, err
+	}
+
+	log.Logger().Debug().Msgf("Blob %s mounted from %s/%s to %s/%s", digest, fromNamespace, fromRepository,
+		namespace, repository)
+
+	result.mounted = true
+	return result, nil
 }
 
-func (svc *RegistryService) loadImageBlobFromUpstream(ctx context.Context, namespace, repository,
-	digest string, skipContent bool) (exists bool, content []byte, err error) {
+type blobDeleteResult struct {
+	notFound bool // true if repository or blob doesn't exist
+}
+
+func (svc *RegistryService) deleteBlob(reqCtx context.Context, namespace, repository,
+	digest string) (result *blobDeleteResult, err error) {
+	contentLock.Lock()
+	defer contentLock.Unlock()
 
-	if svc.upstream.cacheEnabled {
-		repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to delete blob due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
 		if err != nil {
-			return false, nil, err
+			tx.Rollback()
+		} else {
+			tx.Commit()
 		}
+	}()
 
-		blobMeta, err := svc.store.Blobs().Get(ctx, digest, repositoryID)
-		if err != nil {
-			return false, nil, err
+	result = &blobDeleteResult{}
+
+	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve repository from database")
+		return nil, err
+	}
+	if repositoryID == "" {
+		result.notFound = true
+		return result, nil
+	}
+
+	blobMeta, err := svc.store.Blobs().Get(ctx, digest, repositoryID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve blob from database")
+		return nil, err
+	}
+	if blobMeta == nil {
+		result.notFound = true
+		return result, nil
+	}
+
+	unreferenced, err := svc.store.Blobs().Delete(ctx, repositoryID, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to delete blob: %s/%s@%s", namespace, repository, digest)
+		return nil, err
+	}
+
+	// content is kept while other repositories link the blob
+	if !unreferenced {
+		return result, nil
+	}
+
+	err = storage.DeleteFile(blobMeta.Location)
+	if err != nil {
+		if ok, code := storage_errors.UnwrapStorageError(err); !ok || code != storage_errors.CodeFileNotFound {
+			log.Logger().Error().Err(err).Msgf("Failed to delete blob file: %s", blobMeta.Location)
+			return nil, err
 		}
+		log.Logger().Warn().Msgf("Blob file: %s was already removed from storage", blobMeta.Location)
+	}
 
-		if blobMeta == nil {
-			// not found in cache, load from cache and store it in cache
-			content, err = svc.pullBlobFromUpstream(ctx, namespace, repository, digest)
-			if err != nil {
-				return false, nil, err
+	return result, nil
+}
+
+// getImageBlob returns a reader for the blob content. Cached blobs are returned as io.ReadSeekCloser; blobs
+// fetched from the upstream registry are streamed as *blobStream. The caller must close the returned reader.
+func (svc *RegistryService) getImageBlob(reqCtx context.Context, namespace, repository,
+	digest string) (exists bool, content io.ReadCloser, err error) {
+	if svc.registryId == constants.HostedRegistryID || !svc.upstream.cacheEnabled {
+		return svc.readImageBlob(reqCtx, namespace, repository, digest, false)
+	}
+
+	// Concurrent pulls of a blob missing from the cache are coalesced, across repositories since content is shared.
+	// The first pull streams the blob from the upstream registry and the others are served from the cache once the
+	// blob is cached.
+	key := fmt.Sprintf("blob:%s@%s", svc.registryId, digest)
+	for {
+		exists, content, err = svc.readCachedImageBlob(reqCtx, namespace, repository, digest)
+		if err != nil || exists {
+			return exists, content, err
+		}
+
+		fetch, leader := svc.fetches.join(key)
+		if leader {
+			// the previous fetch may have cached the blob after it was looked up
+			exists, content, err = svc.readCachedImageBlob(reqCtx, namespace, repository, digest)
+			if err != nil || exists {
+				fetch.finish(exists, err)
+				return exists, content, err
 			}
 
-			err = svc.cacheBlob(ctx, namespace, repository, digest, content)
-			if err != nil {
-				return false, nil, err
+			exists, content, err = svc.readImageBlob(reqCtx, namespace, repository, digest, false)
+			if stream, ok := content.(*blobStream); ok {
+				if reader, ok := stream.ReadCloser.(*cachingBlobReader); ok {
+					// waiting pulls are released once the blob is streamed to the end
+					reader.fetch = fetch
+					return exists, content, err
+				}
 			}
-			return true, content, nil
+			fetch.finish(exists, err)
+			return exists, content, err
 		}
 
-		if skipContent {
-			return true, nil, nil
+		if err = fetch.wait(reqCtx); err != nil {
+			return false, nil, err
 		}
+		if fetch.err != nil || !fetch.found {
+			return false, nil, fetch.err
+		}
+		// blob is cached unless the fetch was abandoned; in which case one of the waiting pulls fetches it again
+	}
+}
+
+// readCachedImageBlob loads the blob from the cache. Content cached for another repository is linked to the
+// repository first.
+func (svc *RegistryService) readCachedImageBlob(reqCtx context.Context, namespace, repository,
+	digest string) (exists bool, content io.ReadCloser, err error) {
+	exists, content, err = svc.readImageBlob(reqCtx, namespace, repository, digest, true)
+	if err != nil || exists || svc.upstream.offline {
+		return exists, content, err
+	}
+
+	linked, err := svc.linkCachedBlob(reqCtx, namespace, repository, digest)
+	if err != nil || !linked {
+		return false, nil, err
+	}
+	return svc.readImageBlob(reqCtx, namespace, repository, digest, true)
+}
 
-		content, err = storage.ReadFile(blobMeta.Location)
+// readImageBlob loads the blob in a transaction. If cacheOnly is set, the upstream registry isn't contacted.
+func (svc *RegistryService) readImageBlob(reqCtx context.Context, namespace, repository, digest string,
+	cacheOnly bool) (exists bool, content io.ReadCloser, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve blob due to database transaction errors")
+		return false, nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
 		if err != nil {
-			return false, nil, err
+			tx.Rollback()
+		} else {
+			tx.Commit()
 		}
+	}()
 
-		if len(content) != blobMeta.Size {
-			log.Logger().Warn().Msgf("Size mismatch for %s; Size in storage: %d, Size in database: %d",
-				blobMeta.Location, len(content), blobMeta.Size)
-		}
-		return true, content, nil
+	if cacheOnly {
+		return svc.loadCachedBlob(ctx, namespace, repository, digest, false)
+	}
+	return svc.loadImageBlob(ctx, namespace, repository, digest, false)
+}
+
+func (svc *RegistryService) loadImageBlob(ctx context.Context, namespace, repository,
+	digest string, skipContent bool) (exists bool, content io.ReadCloser, err error) {
+	if svc.registryId == constants.HostedRegistryID {
+		return svc.loadImageBlobFromRegistry(ctx, namespace, repository, digest, skipContent)
 	} else {
-		if skipContent {
-			exists, err = svc.client.HeadBlob(namespace, repository, digest)
-			if err != nil {
-				return false, nil, err
-			}
-			return true, nil, nil
-		} else {
-			content, err = svc.client.GetBlob(namespace, repository, digest)
-			if err != nil {
-				return false, nil, err
-			}
-			return true, content, nil
+		return svc.loadImageBlobFromUpstream(ctx, namespace, repository, digest, skipContent)
+	}
+}
+
+func (svc *RegistryService) loadImageBlobFromRegistry(ctx context.Context, namespace, repository,
+	digest string, skipContent bool) (exists bool, content io.ReadCloser, err error) {
+
+	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	if err != nil {
+		return false, nil, err
+	}
+
+	blobMeta, err := svc.store.Blobs().Get(ctx, digest, repositoryID)
+	if err != nil {
+		return false, nil, err
+	}
+	if blobMeta == nil {
+		return false, nil, nil
+	}
+
+	if skipContent {
+		return true, nil, nil
+	}
+
+	return svc.openBlob(blobMeta)
+}
+
+func (svc *RegistryService) loadImageBlobFromUpstream(ctx context.Context, namespace, repository,
+	digest string, skipContent bool) (exists bool, content io.ReadCloser, err error) {
+
+	if svc.upstream.cacheEnabled || svc.upstream.offline {
+		exists, content, err = svc.loadCachedBlob(ctx, namespace, repository, digest, skipContent)
+		if err != nil || exists || svc.upstream.offline {
+			return exists, content, err
 		}
 	}
+
+	if skipContent {
+		exists, err = svc.client.HeadBlob(namespace, repository, digest)
+		if err != nil {
+			return false, nil, err
+		}
+		return exists, nil, nil
+	}
+
+	body, size, err := svc.client.OpenBlob(namespace, repository, digest)
+	if errors.Is(err, up.ErrNotFound) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	if !svc.upstream.cacheEnabled {
+		return true, &blobStream{ReadCloser: body, size: size}, nil
+	}
+
+	// not found in cache; the blob is cached while it is streamed to the client
+	reader, err := svc.newCachingBlobReader(ctx, namespace, repository, digest, body)
+	if err != nil {
+		body.Close()
+		return false, nil, err
+	}
+	return true, &blobStream{ReadCloser: reader, size: size}, nil
 }
 
-// cacheBlob stores the blob fetched from upstream registry and add meta information in database
-func (svc *RegistryService) cacheBlob(ctx context.Context, namespace, repository, digest string,
-	payload []byte) error {
-	location := utils.StorageLocation("blobs", svc.registryName, namespace, repository, digest)
-	err := svc.storeImageBlob(ctx, location, false, 0, payload)
+// loadCachedBlob loads the blob from the cache of the upstream registry
+func (svc *RegistryService) loadCachedBlob(ctx context.Context, namespace, repository, digest string,
+	skipContent bool) (exists bool, content io.ReadCloser, err error) {
+	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
 	if err != nil {
-		return err
+		return false, nil, err
 	}
 
-	nsId, repoId, err := svc.getNameSpaceIdAndRepositoryId(ctx, namespace, repository)
+	blobMeta, err := svc.store.Blobs().Get(ctx, digest, repositoryID)
 	if err != nil {
-		return err
+		return false, nil, err
+	}
+	if blobMeta == nil {
+		return false, nil, nil
+	}
+
+	if skipContent {
+		return true, nil, nil
 	}
 
-	err = svc.store.Blobs().Create(ctx, svc.registryId, nsId, repoId, digest, location, int64(len(payload)))
+	// least recently pulled blobs are evicted first once the cache exceeds its storage limit. The pull isn't failed
+	// if the access time can't be recorded, e.g. while concurrent pulls are caching content.
+	err = svc.store.Blobs().MarkAccessed(ctx, repositoryID, digest)
 	if err != nil {
-		return err
+		log.Logger().Warn().Err(err).Msgf("Failed to record access of cached blob: %s/%s@%s", namespace, repository,
+			digest)
 	}
-	return nil
+	return svc.openBlob(blobMeta)
 }
 
-func (svc *RegistryService) storeImageBlob(ctx context.Context, storageLocation string, isChunked bool,
-	offset int64, payload []byte) error {
-	var err error
-	if isChunked {
-		err = storage.PutFileChunk(storageLocation, payload, offset)
-	} else {
-		err = storage.PutFile(storageLocation, payload)
+// openBlob opens the blob file referred by blobMeta for reading
+func (svc *RegistryService) openBlob(blobMeta *models.ImageBlobMetaModel) (exists bool, content io.ReadCloser,
+	err error) {
+	size, err := storage.Size(blobMeta.Location)
+	if err != nil {
+		return false, nil, err
+	}
+	if size != int64(blobMeta.Size) {
+		log.Logger().Warn().Msgf("Size mismatch for blob %s; Size in storage: %d, Size in database: %d",
+			blobMeta.Location, size, blobMeta.Size)
 	}
-	return err
+
+	content, err = storage.Open(blobMeta.Location)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, content, nil
 }
 
 func (svc *RegistryService) getNameSpaceIdAndRepositoryId(ctx context.Context, namespace,
@@ -592,7 +1026,10 @@ func (svc *RegistryService) getNamespaceID(ctx context.Context, namespace string
 		return "", err
 	}
 
-	svc.namespaceIdMap.Store(namespace, nsId)
+	// non-existing namespaces are not cached since they can be created later
+	if nsId != "" {
+		svc.namespaceIdMap.Store(namespace, nsId)
+	}
 	return nsId, nil
 }
 
@@ -613,38 +1050,106 @@ func (svc *RegistryService) getRepositoryID(ctx context.Context, namespace, repo
 	if err != nil {
 		return "", err
 	}
-	svc.repositoryIdMap.Store(key, repositoryId)
+	if repositoryId != "" {
+		svc.repositoryIdMap.Store(key, repositoryId)
+	}
 
 	return repositoryId, nil
 }
 
 func (svc *RegistryService) getImageManifest(reqCtx context.Context, namespace, repository,
 	tagOrDigest string) (exists bool, mediaType, digest string, content []byte, err error) {
-	tx, err := svc.store.Begin(reqCtx)
-	if err != nil {
-		log.Logger().Error().Err(err).Msg("Failed to retrieve manifest due to database transaction errors")
-		return false, "", "", nil, err
-	}
-	ctx := store.WithTxContext(reqCtx, tx)
+	var namespaceID string
 	defer func() {
-		if err != nil {
-			tx.Rollback()
-		} else {
-			tx.Commit()
+		if err == nil && exists {
+			svc.notify(reqCtx, namespaceID, constants.WebhookActionPull,
+				manifestEventTarget(namespace, repository, tagOrDigest, mediaType, digest, content))
 		}
 	}()
-	exists, mediaType, digest, content, err = svc.loadImageManifest(ctx, namespace, repository,
-		tagOrDigest, false)
+
+	exists, mediaType, digest, content, err = svc.pullImageManifest(reqCtx, namespace, repository, tagOrDigest,
+		false)
+	if err != nil || !exists {
+		return
+	}
+
+	namespaceID, err = svc.getNamespaceID(reqCtx, namespace)
 	return
 }
 
+// manifestEventTarget describes the manifest pulled, pushed or deleted for webhooks
+func manifestEventTarget(namespace, repository, tagOrDigest, mediaType, digest string,
+	content []byte) dockerv2.EventTarget {
+	target := dockerv2.EventTarget{
+		MediaType:  mediaType,
+		Size:       int64(len(content)),
+		Length:     int64(len(content)),
+		Digest:     digest,
+		Repository: namespace + "/" + repository,
+	}
+	if !utils.IsImageDigest(tagOrDigest) {
+		target.Tag = tagOrDigest
+	}
+	return target
+}
+
 func (svc *RegistryService) manifestExists(reqCtx context.Context, namespace, repository,
 	tagOrDigest string) (exists bool, mediaType, digest string, err error) {
+	exists, mediaType, digest, _, err = svc.pullImageManifest(reqCtx, namespace, repository, tagOrDigest, true)
+	return
+}
+
+// pullImageManifest loads the manifest. Concurrent pulls of a tag or digest missing from the cache of the upstream
+// registry are coalesced; the first pull fetches the manifest from the upstream registry and the others are served
+// from the cache.
+func (svc *RegistryService) pullImageManifest(reqCtx context.Context, namespace, repository, tagOrDigest string,
+	skipContent bool) (exists bool, mediaType, digest string, content []byte, err error) {
+	if svc.registryId == constants.HostedRegistryID || !svc.upstream.cacheEnabled {
+		return svc.readImageManifest(reqCtx, namespace, repository, tagOrDigest, skipContent, false)
+	}
+
+	// manifests are cached per repository; unlike blobs, a manifest cached for another repository can't be served
+	key := fmt.Sprintf("manifest:%s/%s/%s:%s", svc.registryId, namespace, repository, tagOrDigest)
+	for {
+		exists, mediaType, digest, content, err = svc.readImageManifest(reqCtx, namespace, repository, tagOrDigest,
+			skipContent, true)
+		if err != nil || exists {
+			return
+		}
+
+		fetch, leader := svc.fetches.join(key)
+		if leader {
+			// the previous fetch may have cached the manifest after it was looked up
+			exists, mediaType, digest, content, err = svc.readImageManifest(reqCtx, namespace, repository,
+				tagOrDigest, skipContent, true)
+			if err != nil || exists {
+				fetch.finish(exists, err)
+				return
+			}
+
+			// manifest is committed to the cache before waiting pulls are released
+			exists, mediaType, digest, content, err = svc.readImageManifest(reqCtx, namespace, repository,
+				tagOrDigest, skipContent, false)
+			fetch.finish(exists, err)
+			return
+		}
 
+		if err = fetch.wait(reqCtx); err != nil {
+			return false, "", "", nil, err
+		}
+		if fetch.err != nil || !fetch.found {
+			return false, "", "", nil, fetch.err
+		}
+	}
+}
+
+// readImageManifest loads the manifest in a transaction. If cacheOnly is set, the upstream registry isn't contacted.
+func (svc *RegistryService) readImageManifest(reqCtx context.Context, namespace, repository, tagOrDigest string,
+	skipContent, cacheOnly bool) (exists bool, mediaType, digest string, content []byte, err error) {
 	tx, err := svc.store.Begin(reqCtx)
 	if err != nil {
-		log.Logger().Error().Err(err).Msg("Failed to check manifest due to database transaction errors")
-		return false, "", "", err
+		log.Logger().Error().Err(err).Msg("Failed to retrieve manifest due to database transaction errors")
+		return false, "", "", nil, err
 	}
 	ctx := store.WithTxContext(reqCtx, tx)
 	defer func() {
@@ -655,8 +1160,12 @@ func (svc *RegistryService) manifestExists(reqCtx context.Context, namespace, re
 		}
 	}()
 
-	exists, mediaType, digest, _, err = svc.loadImageManifest(ctx, namespace, repository, tagOrDigest, true)
-	return
+	if cacheOnly {
+		exists, digest, mediaType, content, err = svc.loadManifestFromCache(ctx, namespace, repository,
+			tagOrDigest, skipContent, svc.upstream.offline)
+		return
+	}
+	return svc.loadImageManifest(ctx, namespace, repository, tagOrDigest, skipContent)
 }
 
 func (svc *RegistryService) loadImageManifest(ctx context.Context, namespace, repository,
@@ -665,6 +1174,7 @@ func (svc *RegistryService) loadImageManifest(ctx context.Context, namespace, re
 		if utils.IsImageDigest(tagOrDigest) {
 			exists, content, mediaType, err = svc.loadManifestByDigest(ctx, namespace, repository, tagOrDigest,
 				skipContent)
+			digest = tagOrDigest
 
 		} else {
 			exists, digest, mediaType, content, err = svc.loadManifestByTag(ctx, namespace, repository, tagOrDigest,
@@ -678,9 +1188,19 @@ func (svc *RegistryService) loadImageManifest(ctx context.Context, namespace, re
 
 		return exists, mediaType, digest, content, err
 	} else {
+		if svc.upstream.offline {
+			// expired manifests are served as well since they can't be revalidated
+			exists, digest, mediaType, content, err = svc.loadManifestFromCache(ctx, namespace, repository,
+				tagOrDigest, skipContent, true)
+			if err != nil {
+				return false, "", "", nil, err
+			}
+			return exists, mediaType, digest, content, nil
+		}
+
 		if svc.upstream.cacheEnabled {
 			exists, digest, mediaType, content, err = svc.loadManifestFromCache(ctx, namespace, repository,
-				tagOrDigest, skipContent)
+				tagOrDigest, skipContent, false)
 			if err != nil {
 				return false, "", "", nil, err
 			}
@@ -688,7 +1208,13 @@ func (svc *RegistryService) loadImageManifest(ctx context.Context, namespace, re
 				return true, mediaType, digest, content, nil
 			}
 			content, mediaType, err := svc.client.GetManifest(namespace, repository, tagOrDigest)
+			if errors.Is(err, up.ErrNotFound) {
+				return false, "", "", nil, nil
+			}
 			if err != nil {
+				if svc.upstream.staleIfError && up.IsUnavailable(err) {
+					return svc.loadStaleManifest(ctx, namespace, repository, tagOrDigest, skipContent, err)
+				}
 				return false, "", "", nil, err
 			}
 
@@ -710,6 +1236,9 @@ func (svc *RegistryService) loadImageManifest(ctx context.Context, namespace, re
 				content, mediaType, err = svc.client.GetManifest(namespace, repository, tagOrDigest)
 				exists = true
 			}
+			if errors.Is(err, up.ErrNotFound) {
+				return false, "", "", nil, nil
+			}
 			if err != nil {
 				return false, "", "", nil, err
 			}
@@ -718,9 +1247,29 @@ func (svc *RegistryService) loadImageManifest(ctx context.Context, namespace, re
 	}
 }
 
+// loadStaleManifest serves the expired cache entry of the manifest when the upstream registry is unavailable.
+// upstreamErr is returned if the manifest isn't cached.
+func (svc *RegistryService) loadStaleManifest(ctx context.Context, namespace, repository, tagOrDigest string,
+	skipContent bool, upstreamErr error) (exists bool, mediaType, digest string, content []byte, err error) {
+	exists, digest, mediaType, content, err = svc.loadManifestFromCache(ctx, namespace, repository, tagOrDigest,
+		skipContent, true)
+	if err != nil {
+		return false, "", "", nil, err
+	}
+	if !exists {
+		return false, "", "", nil, upstreamErr
+	}
+
+	log.Logger().Warn().Err(upstreamErr).Msgf("Serving stale manifest (%s/%s/%s:%s) since upstream is unavailable",
+		svc.registryName, namespace, repository, tagOrDigest)
+	return true, mediaType, digest, content, nil
+}
+
+// loadManifestFromCache loads the manifest from the cache of the upstream registry. Expired entries are reported as
+// missing unless allowExpired is set.
 func (svc *RegistryService) loadManifestFromCache(ctx context.Context, namsespace, repository,
 	tagOrDigest string,
-	skipContent bool) (exists bool, digest, mediaType string, content []byte, err error) {
+	skipContent, allowExpired bool) (exists bool, digest, mediaType string, content []byte, err error) {
 	_, repoId, err := svc.getNameSpaceIdAndRepositoryId(ctx, namsespace, repository)
 	if err != nil {
 		return false, "", "", nil, err
@@ -736,18 +1285,31 @@ func (svc *RegistryService) loadManifestFromCache(ctx context.Context, namsespac
 	}
 
 	// TODO stable tag
-	if cacheModel.ExpiresAt.Before(time.Now()) {
+	if !allowExpired && cacheModel.ExpiresAt.Before(time.Now()) {
 		// We won't delete the cache entry. Even if the time expired, manifest may not be changed in upstream
 		// After retriving manifest from upstream proxy, we'll check digest values. if they are same, We'll
 		// refresh the cache entry instead of deleting and adding again.
 		return false, "", "", nil, nil
 	}
 
+	digest = cacheModel.Digest
 	if utils.IsImageDigest(tagOrDigest) {
 		exists, content, mediaType, err = svc.loadManifestByDigest(ctx, namsespace, repository, tagOrDigest, skipContent)
 	} else {
 		exists, _, mediaType, content, err = svc.loadManifestByTag(ctx, namsespace, repository, tagOrDigest, skipContent)
 	}
+	if err != nil {
+		return false, "", "", nil, err
+	}
+
+	if exists && !skipContent {
+		// least recently pulled manifests are evicted first once the cache exceeds its storage limit. The pull isn't
+		// failed if the access time can't be recorded.
+		if err := svc.store.Manifests().MarkAccessed(ctx, repoId, digest); err != nil {
+			log.Logger().Warn().Err(err).Msgf("Failed to record access of cached manifest: %s/%s@%s", namsespace,
+				repository, digest)
+		}
+	}
 
 	if !exists {
 		log.Logger().Warn().Msgf("Cache reference for manifest: (%s/%s/%s@%s) exists but actual manifest is not available in the database",
@@ -773,72 +1335,76 @@ func (svc *RegistryService) cacheManifest(ctx context.Context, namespace, reposi
 		return err
 	}
 
-	cacheEntry, err := svc.store.Cache().Get(ctx, repositoryId, digest)
+	// cache entries are keyed by the tag or digest pulled
+	cacheEntry, err := svc.store.Cache().Get(ctx, repositoryId, identifier)
 	if err != nil {
 		return err
 	}
 
-	var cacheMiss bool
 	if cacheEntry == nil { // no cache entry
 		err = svc.store.Cache().Create(ctx, svc.registryId, nsId, repositoryId, identifier, digest, validTill)
-		cacheMiss = true
-	} else { // cache entry exists
-		if cacheEntry.Digest == digest { // digest is same
-			err = svc.store.Cache().Refresh(ctx, repositoryId, identifier, validTill)
-		} else { // digest has changed. so let's add new manifest, identifier is a tag
-			cacheMiss = true
-			err = svc.store.Cache().Delete(ctx, repositoryId, identifier)
-			if err != nil {
-				return err
-			}
-			err = svc.store.Manifests().DeleteByDigest(ctx, repositoryId, digest)
-			if err != nil {
-				return err
-			}
-			err = svc.store.Cache().Create(ctx, svc.registryId, nsId, repositoryId, identifier, digest, validTill)
+	} else if cacheEntry.Digest == digest { // revalidated, the manifest is unchanged
+		err = svc.store.Cache().Refresh(ctx, repositoryId, identifier, validTill)
+	} else { // digest has changed, identifier is a tag moved to another manifest
+		err = svc.store.Cache().Delete(ctx, repositoryId, identifier)
+		if err != nil {
+			return err
 		}
+		err = svc.store.Cache().Create(ctx, svc.registryId, nsId, repositoryId, identifier, digest, validTill)
+	}
+	if err != nil {
+		return err
 	}
 
+	// the manifest may be cached already through another tag or its digest. The manifest previously tagged is left
+	// to eviction since other tags may still refer it.
+	manifest, err := svc.store.Manifests().GetByDigest(ctx, false, repositoryId, digest)
 	if err != nil {
 		return err
 	}
 
-	// for upstream manifests, unique-digest = digest
-	if cacheMiss {
-		manifestID, err := svc.store.Manifests().Create(ctx, svc.registryId, nsId, repositoryId, digest, mediaType, digest,
-			int64(len(content)), content)
+	var manifestID string
+	if manifest != nil {
+		manifestID = manifest.ID
+	} else {
+		// for upstream manifests, unique-digest = digest
+		manifestID, err = svc.store.Manifests().Create(ctx, svc.registryId, nsId, repositoryId, digest, mediaType,
+			digest, int64(len(content)), content)
 		if err != nil {
 			return err
 		}
 
-		// if identifier is tag, link the tag and manifest
-		if !utils.IsImageDigest(identifier) {
-			tag, err := svc.store.Tags().Get(ctx, repositoryId, identifier)
-			if err != nil {
-				return err
-			}
+		_, err = svc.linkReferrer(ctx, manifestID, repositoryId, mediaType, content)
+		if err != nil {
+			return err
+		}
+	}
 
-			if tag == nil {
-				tagID, err := svc.store.Tags().Create(ctx, svc.registryId, nsId, repositoryId, identifier)
-				if err != nil {
-					return err
-				}
+	err = svc.store.Manifests().MarkAccessed(ctx, repositoryId, digest)
+	if err != nil {
+		return err
+	}
 
-				err = svc.store.Tags().LinkManifest(ctx, tagID, manifestID)
-				if err != nil {
-					return err
-				}
-			} else {
-				err = svc.store.Tags().UnlinkManifest(ctx, tag.Id)
-				if err != nil {
-					return err
-				}
-				err = svc.store.Tags().LinkManifest(ctx, tag.Id, manifestID)
-				if err != nil {
-					return err
-				}
+	// if identifier is tag, link the tag and manifest
+	if !utils.IsImageDigest(identifier) {
+		tag, err := svc.store.Tags().Get(ctx, repositoryId, identifier)
+		if err != nil {
+			return err
+		}
+
+		tagID := ""
+		if tag == nil {
+			tagID, err = svc.store.Tags().Create(ctx, svc.registryId, nsId, repositoryId, identifier)
+			if err != nil {
+				return err
 			}
+		} else {
+			tagID = tag.Id
+		}
 
+		err = svc.store.Tags().LinkManifest(ctx, tagID, manifestID)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -886,20 +1452,63 @@ type ManifestScanResult struct {
 	ManifestExists         bool
 	TagManifestLinkExists  bool
 	TagManifestLinkChanged bool
+	TagStable              bool
 	UniqueDigest           string
 	TagId                  string
 	ManifestId             string
+	ManifestDigest         string
+	LinkedManifestId       string // manifest the tag points to before the push
 	NamespaceId            string
 	RepositoryId           string
 }
 
-func (svc *RegistryService) updateManifest(reqCtx context.Context, namespace, repository, tag,
-	mediaType string, content []byte) (digest string, err error) {
+type manifestUpdateResult struct {
+	invalid      bool            // true if the media type isn't supported or the manifest can't be parsed
+	notFound     bool            // true if namespace or repository doesn't exist
+	digest       string          // digest of the manifest content
+	subject      string          // digest of the subject if the manifest refers another manifest
+	unknownBlobs []string        // blobs and child manifests referred by the manifest, but not pushed to repository
+	immutableTag bool            // true if the tag is immutable and points to another manifest
+	storedDigest string          // set if the manifest pushed by digest is already stored under another digest
+	quota        *quotaViolation // set if storing the manifest exceeds the quota of the repository or its namespace
+}
+
+// updateManifest stores the manifest. If tag is empty, the manifest is stored untagged; it can be pulled by digest.
+// Manifests pushed by digest keep the digest given by the client; sha256 digest is used otherwise. A tag pushed with
+// a manifest already stored under another digest is linked to the stored manifest.
+func (svc *RegistryService) updateManifest(reqCtx context.Context, namespace, repository, tag, digest,
+	mediaType string, content []byte) (result *manifestUpdateResult, err error) {
+	contentLock.RLock()
+	defer contentLock.RUnlock()
+
+	var namespaceID string
+	defer func() {
+		if err == nil && result.digest != "" {
+			svc.notify(reqCtx, namespaceID, constants.WebhookActionPush,
+				manifestEventTarget(namespace, repository, tag, mediaType, result.digest, content))
+		}
+	}()
+
+	result = &manifestUpdateResult{}
+
+	uniqueDigest, err := UniqueDigest(mediaType, content)
+	if err != nil {
+		log.Logger().Warn().Err(err).Msgf("Rejected invalid manifest of %s/%s", namespace, repository)
+		result.invalid = true
+		return result, nil
+	}
+
+	blobs, manifests, err := manifestReferences(mediaType, content)
+	if err != nil {
+		log.Logger().Warn().Err(err).Msgf("Rejected invalid manifest of %s/%s", namespace, repository)
+		result.invalid = true
+		return result, nil
+	}
 
 	tx, err := svc.store.Begin(reqCtx)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("Failed to update manifest due to database transaction errors")
-		return "", err
+		return nil, err
 	}
 	ctx := store.WithTxContext(reqCtx, tx)
 	defer func() {
@@ -910,47 +1519,181 @@ func (svc *RegistryService) updateManifest(reqCtx context.Context, namespace, re
 		}
 	}()
 
-	uniqueDigest, err := UniqueDigest(mediaType, content)
+	res, err := svc.scanManifest(ctx, uniqueDigest, namespace, repository, tag)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	res, err := svc.scanManifest(ctx, uniqueDigest, namespace, repository, tag)
+	// namespace or repository do not exist
+	if res.NamespaceId == "" || res.RepositoryId == "" {
+		result.notFound = true
+		return result, nil
+	}
+
+	result.unknownBlobs, err = svc.findUnknownReferences(ctx, res.RepositoryId, blobs, manifests)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if len(result.unknownBlobs) > 0 {
+		return result, nil
+	}
+
+	manifestDigest := digest
+	if manifestDigest == "" {
+		manifestDigest = utils.CalcuateDigest(content)
 	}
 
-	if !res.TagExists {
+	// Manifests differing only in bytes (e.g. annotations or whitespace) share the unique digest, only the first one
+	// is stored. Tag pushes link the stored manifest, but a digest push can't be served by the given digest.
+	if res.ManifestExists && res.ManifestDigest != manifestDigest {
+		if digest != "" {
+			result.storedDigest = res.ManifestDigest
+			return result, nil
+		}
+		manifestDigest = res.ManifestDigest
+	}
+
+	if res.TagManifestLinkChanged {
+		result.immutableTag, err = svc.isImmutableTag(ctx, res.RepositoryId, tag, res.TagStable)
+		if err != nil {
+			return nil, err
+		}
+		if result.immutableTag {
+			return result, nil
+		}
+	}
+
+	var addedBytes, addedImages int64
+	if !res.ManifestExists {
+		addedBytes = int64(len(content))
+	}
+	addedImages, err = svc.addedImages(ctx, res, tag)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to count images added by manifest")
+		return nil, err
+	}
+
+	if addedBytes > 0 || addedImages > 0 {
+		result.quota, err = svc.checkQuota(ctx, namespace, repository, res.NamespaceId, res.RepositoryId,
+			addedBytes, addedImages)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to check quota of repository")
+			return nil, err
+		}
+		if result.quota != nil {
+			return result, nil
+		}
+	}
+
+	if tag != "" && !res.TagExists {
 		tagId, err := svc.store.Tags().Create(ctx, svc.registryId, res.NamespaceId, res.RepositoryId, tag)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		res.TagId = tagId
+	} else if tag != "" {
+		// push time of the tag is used by retention rules
+		err = svc.store.Tags().Touch(ctx, res.TagId)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	manifestDigest := utils.CalcuateDigest(content)
-
 	if !res.ManifestExists {
 		manifestId, err := svc.store.Manifests().Create(ctx, svc.registryId, res.NamespaceId, res.RepositoryId,
 			manifestDigest, mediaType, res.UniqueDigest, int64(len(content)), content)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		res.ManifestId = manifestId
+	}
 
-		svc.store.Tags().LinkManifest(ctx, res.TagId, res.ManifestId)
+	// other tags pointing to the previous manifest are not affected
+	if tag != "" && (!res.TagManifestLinkExists || res.TagManifestLinkChanged) {
+		err = svc.store.Tags().LinkManifest(ctx, res.TagId, res.ManifestId)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
-	if res.TagManifestLinkChanged {
-		err = svc.store.Tags().UpdateManifest(ctx, res.TagId, res.ManifestId)
+	result.subject, err = svc.linkReferrer(ctx, res.ManifestId, res.RepositoryId, mediaType, content)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceID = res.NamespaceId
+	result.digest = manifestDigest
+	return result, nil
+}
+
+// isImmutableTag reports whether the tag can't be moved to another manifest or deleted. Tags marked stable and tags
+// matching immutable tag patterns of the repository are immutable.
+func (svc *RegistryService) isImmutableTag(ctx context.Context, repositoryId, tag string, stable bool) (bool, error) {
+	if stable {
+		return true, nil
+	}
+
+	patterns, err := svc.store.Repositories().GetImmutableTagPatterns(ctx, repositoryId)
+	if err != nil {
+		return false, err
+	}
+	return matchesAnyTagPattern(patterns, tag), nil
+}
+
+func matchesAnyTagPattern(patterns []string, tag string) bool {
+	for _, pattern := range patterns {
+		if utils.MatchTagPattern(pattern, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// findUnknownReferences returns digests of the blobs and the child manifests which are referred by the manifest,
+// but don't exist in the repository.
+func (svc *RegistryService) findUnknownReferences(ctx context.Context, repositoryID string, blobs,
+	manifests []string) (unknown []string, err error) {
+	for _, digest := range blobs {
+		blobMeta, err := svc.store.Blobs().Get(ctx, digest, repositoryID)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		if blobMeta == nil {
+			unknown = append(unknown, digest)
 		}
 	}
-	return manifestDigest, nil
+
+	for _, digest := range manifests {
+		manifest, err := svc.store.Manifests().GetByDigest(ctx, false, repositoryID, digest)
+		if err != nil {
+			return nil, err
+		}
+		if manifest == nil {
+			unknown = append(unknown, digest)
+		}
+	}
+
+	return unknown, nil
+}
+
+// linkReferrer records the subject of the manifest so that the manifest is listed as a referrer of the subject.
+// Digest of the subject is returned if the manifest has one.
+func (svc *RegistryService) linkReferrer(ctx context.Context, manifestID, repositoryID, mediaType string,
+	content []byte) (subjectDigest string, err error) {
+	subject, artifactType, err := manifestSubject(mediaType, content)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to parse subject of manifest")
+		return "", err
+	}
+	if subject == nil {
+		return "", nil
+	}
+
+	err = svc.store.Manifests().CreateReferrer(ctx, manifestID, repositoryID, subject.Digest, artifactType)
+	if err != nil {
+		return "", err
+	}
+	return subject.Digest, nil
 }
 
 func (svc *RegistryService) scanManifest(ctx context.Context, uniqueDigest, namespace, repository,
@@ -961,22 +1704,27 @@ func (svc *RegistryService) scanManifest(ctx context.Context, uniqueDigest, name
 		return nil, err
 	}
 
+	result := &ManifestScanResult{
+		UniqueDigest: uniqueDigest,
+		NamespaceId:  nsId,
+		RepositoryId: repoId,
+	}
+
 	// Check manifest existence
 	manifestModel, err := svc.store.Manifests().GetByUniqueDigest(ctx, false, repoId, uniqueDigest)
 	if err != nil {
 		return nil, err
 	}
 
-	if manifestModel == nil {
-		return &ManifestScanResult{ManifestExists: false}, nil
+	if manifestModel != nil {
+		result.ManifestExists = true
+		result.ManifestId = manifestModel.ID
+		result.ManifestDigest = manifestModel.Digest
 	}
 
-	result := &ManifestScanResult{
-		ManifestExists: true,
-		UniqueDigest:   uniqueDigest,
-		ManifestId:     manifestModel.ID,
-		NamespaceId:    nsId,
-		RepositoryId:   repoId,
+	// untagged manifest
+	if tag == "" {
+		return result, nil
 	}
 
 	// Check tag existence
@@ -985,20 +1733,473 @@ func (svc *RegistryService) scanManifest(ctx context.Context, uniqueDigest, name
 		return nil, err
 	}
 
-	if tagModel == nil {
-		result.TagExists = false
-	} else {
+	if tagModel != nil {
 		result.TagExists = true
 		result.TagId = tagModel.Id
+		result.TagStable = tagModel.IsStable
 		// Check tag->manifest link
 		oldManifestId, err := svc.store.Tags().GetManifestID(ctx, tagModel.Id)
 		if err != nil {
 			return nil, err
 		}
-		if oldManifestId == "" {
-			result.TagManifestLinkExists = false
+		result.LinkedManifestId = oldManifestId
+		result.TagManifestLinkExists = oldManifestId != ""
+		result.TagManifestLinkChanged = result.TagManifestLinkExists &&
+			(!result.ManifestExists || oldManifestId != result.ManifestId)
+	}
+
+	return result, nil
+}
+
+// addedImages returns the number of images added by linking the tag to the manifest. Images are counted as the
+// tagged manifests, so untagged manifests, such as children of an index, don't add images. Moving the last tag of a
+// manifest to another manifest doesn't add an image either.
+func (svc *RegistryService) addedImages(ctx context.Context, res *ManifestScanResult, tag string) (int64, error) {
+	if tag == "" || (res.TagManifestLinkExists && !res.TagManifestLinkChanged) {
+		return 0, nil
+	}
+
+	if res.ManifestExists {
+		tags, err := svc.store.Tags().ListByManifest(ctx, res.ManifestId)
+		if err != nil {
+			return 0, err
+		}
+		if len(tags) > 0 {
+			return 0, nil
+		}
+	}
+
+	if res.TagManifestLinkChanged {
+		tags, err := svc.store.Tags().ListByManifest(ctx, res.LinkedManifestId)
+		if err != nil {
+			return 0, err
+		}
+		if len(tags) == 1 {
+			return 0, nil
+		}
+	}
+	return 1, nil
+}
+
+type manifestDeleteResult struct {
+	notFound     bool   // true if repository or manifest or tag doesn't exist
+	immutableTag string // set if the tag, or a tag pointing to the manifest, is immutable
+}
+
+// deleteManifest deletes the manifest by digest along with tags pointing to it. If a tag is given, only the tag
+// is deleted and the manifest is kept so that it can still be pulled by digest.
+func (svc *RegistryService) deleteManifest(reqCtx context.Context, namespace, repository,
+	tagOrDigest string) (result *manifestDeleteResult, err error) {
+	var namespaceID string
+	var target dockerv2.EventTarget
+	defer func() {
+		if err == nil && !result.notFound && result.immutableTag == "" {
+			svc.notify(reqCtx, namespaceID, constants.WebhookActionDelete, target)
+		}
+	}()
+
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to delete manifest due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
 		}
+	}()
+
+	result = &manifestDeleteResult{}
+
+	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve repository from database")
+		return nil, err
+	}
+	if repositoryID == "" {
+		result.notFound = true
+		return result, nil
+	}
+
+	namespaceID, err = svc.getNamespaceID(ctx, namespace)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve namespace from database")
+		return nil, err
 	}
 
+	if utils.IsImageDigest(tagOrDigest) {
+		manifest, err := svc.store.Manifests().GetByDigest(ctx, false, repositoryID, tagOrDigest)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to retrieve manifest from database")
+			return nil, err
+		}
+		if manifest == nil {
+			result.notFound = true
+			return result, nil
+		}
+
+		// deleting the manifest deletes its tags; immutable tags must not be re-pushed with another manifest
+		tags, err := svc.store.Tags().ListByManifest(ctx, manifest.ID)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to retrieve tags of manifest from database")
+			return nil, err
+		}
+		result.immutableTag, err = svc.findImmutableTag(ctx, repositoryID, tags)
+		if err != nil {
+			return nil, err
+		}
+		if result.immutableTag != "" {
+			return result, nil
+		}
+
+		err = svc.store.Manifests().DeleteByDigest(ctx, repositoryID, tagOrDigest)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to delete manifest: %s/%s@%s", namespace, repository,
+				tagOrDigest)
+			return nil, err
+		}
+		target = dockerv2.EventTarget{
+			MediaType:  manifest.MediaType,
+			Digest:     tagOrDigest,
+			Repository: namespace + "/" + repository,
+		}
+		return result, nil
+	}
+
+	tag, err := svc.store.Tags().Get(ctx, repositoryID, tagOrDigest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve tag from database")
+		return nil, err
+	}
+	if tag == nil {
+		result.notFound = true
+		return result, nil
+	}
+
+	result.immutableTag, err = svc.findImmutableTag(ctx, repositoryID, []*models.ImageTagModel{tag})
+	if err != nil {
+		return nil, err
+	}
+	if result.immutableTag != "" {
+		return result, nil
+	}
+
+	deleted, err := svc.deleteTag(ctx, namespace, repository, repositoryID, tagOrDigest)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		result.notFound = true
+		return result, nil
+	}
+	target = tagEventTarget(namespace, repository, tagOrDigest)
+
+	return result, nil
+}
+
+// findImmutableTag returns the first immutable tag among the tags of the repository. Empty string is returned if
+// none of them is immutable.
+func (svc *RegistryService) findImmutableTag(ctx context.Context, repositoryId string,
+	tags []*models.ImageTagModel) (string, error) {
+	for _, tag := range tags {
+		immutable, err := svc.isImmutableTag(ctx, repositoryId, tag.Tag, tag.IsStable)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to retrieve immutable tag patterns of repository")
+			return "", err
+		}
+		if immutable {
+			return tag.Tag, nil
+		}
+	}
+	return "", nil
+}
+
+// deleteTag deletes the tag and keeps the manifest it points to. It returns false if the tag doesn't exist. The
+// caller owns the transaction and notifies the delete event once it is committed.
+func (svc *RegistryService) deleteTag(ctx context.Context, namespace, repository, repositoryID,
+	tag string) (bool, error) {
+	existing, err := svc.store.Tags().Get(ctx, repositoryID, tag)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve tag from database")
+		return false, err
+	}
+	if existing == nil {
+		return false, nil
+	}
+
+	err = svc.store.Tags().Delete(ctx, repositoryID, tag)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to delete tag: %s/%s:%s", namespace, repository, tag)
+		return false, err
+	}
+	return true, nil
+}
+
+func tagEventTarget(namespace, repository, tag string) dockerv2.EventTarget {
+	return dockerv2.EventTarget{
+		Repository: namespace + "/" + repository,
+		Tag:        tag,
+	}
+}
+
+type tagListResult struct {
+	notFound bool // true if repository doesn't exist
+	tags     []string
+	hasMore  bool // true if there are more tags after the last tag of the result
+}
+
+// listTags lists tags of the repository in lexical order. n less than 1 returns all tags after `last`.
+func (svc *RegistryService) listTags(reqCtx context.Context, namespace, repository string, n int,
+	last string) (result *tagListResult, err error) {
+	if svc.registryId != constants.HostedRegistryID {
+		if svc.upstream.offline {
+			return svc.listStoredTags(reqCtx, namespace, repository, n, last)
+		}
+
+		result, err = svc.listTagsFromUpstream(namespace, repository, n, last)
+		if err != nil && svc.upstream.staleIfError && up.IsUnavailable(err) {
+			log.Logger().Warn().Err(err).Msgf("Listing cached tags of %s/%s since upstream is unavailable",
+				namespace, repository)
+			return svc.listStoredTags(reqCtx, namespace, repository, n, last)
+		}
+		return result, err
+	}
+	return svc.listStoredTags(reqCtx, namespace, repository, n, last)
+}
+
+// listStoredTags lists tags of the repository from the database. For upstream registries, these are the tags of
+// cached manifests.
+func (svc *RegistryService) listStoredTags(reqCtx context.Context, namespace, repository string, n int,
+	last string) (result *tagListResult, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list tags due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	result = &tagListResult{}
+
+	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve repository from database")
+		return nil, err
+	}
+	if repositoryID == "" {
+		result.notFound = true
+		return result, nil
+	}
+
+	if n == 0 {
+		result.tags = []string{}
+		return result, nil
+	}
+
+	// one more tag is fetched to find out whether the next page exists
+	limit := n
+	if n > 0 {
+		limit = n + 1
+	}
+
+	tags, err := svc.store.Tags().List(ctx, repositoryID, last, limit)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list tags from database")
+		return nil, err
+	}
+
+	if n > 0 && len(tags) > n {
+		tags = tags[:n]
+		result.hasMore = true
+	}
+	result.tags = tags
+
 	return result, nil
 }
+
+// cachedTagList is the value stored in tag list cache for upstream registries
+type cachedTagList struct {
+	Tags    []string `json:"tags"`
+	HasMore bool     `json:"has_more"`
+}
+
+func (svc *RegistryService) listTagsFromUpstream(namespace, repository string, n int,
+	last string) (*tagListResult, error) {
+	if n == 0 {
+		return &tagListResult{tags: []string{}}, nil
+	}
+
+	cacheKey := fmt.Sprintf("%s/%s?n=%d&last=%s", namespace, repository, n, last)
+
+	if svc.tagListCache != nil {
+		if val := svc.tagListCache.Get(cacheKey); val != "" {
+			var cached cachedTagList
+			if err := json.Unmarshal([]byte(val), &cached); err == nil {
+				return &tagListResult{tags: cached.Tags, hasMore: cached.HasMore}, nil
+			}
+			log.Logger().Warn().Str("key", cacheKey).Msg("Ignoring corrupted tag list cache entry")
+		}
+	}
+
+	tags, hasMore, err := svc.client.ListTags(namespace, repository, n, last)
+	if err != nil {
+		if errors.Is(err, up.ErrNotFound) {
+			return &tagListResult{notFound: true}, nil
+		}
+		log.Logger().Error().Err(err).Msgf("Failed to list tags of %s/%s from upstream", namespace, repository)
+		return nil, err
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	if svc.tagListCache != nil {
+		val, err := json.Marshal(cachedTagList{Tags: tags, HasMore: hasMore})
+		if err == nil {
+			svc.tagListCache.Set(cacheKey, string(val), time.Duration(svc.upstream.cacheTTL)*time.Second)
+		}
+	}
+
+	return &tagListResult{tags: tags, hasMore: hasMore}, nil
+}
+
+type catalogResult struct {
+	repositories []string
+	hasMore      bool // true if there are more repositories after the last repository of the result
+}
+
+// listCatalog lists `namespace/repository` names of the registry that the user in request context can pull from.
+// n less than 1 returns all repositories after `last`.
+func (svc *RegistryService) listCatalog(reqCtx context.Context, n int, last string) (result *catalogResult,
+	err error) {
+	result = &catalogResult{repositories: []string{}}
+
+	username, _ := reqCtx.Value(constants.ContextUsername).(string)
+	role, _ := reqCtx.Value(constants.ContextRole).(string)
+	anonymous := username == "" || username == constants.AnonymousUsername
+
+	if n == 0 {
+		return result, nil
+	}
+
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list catalog due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	// any user is allowed to pull from upstream registries. See checkAccess
+	includeAll := (!anonymous && role == constants.RoleAdmin) || svc.registryId != constants.HostedRegistryID
+
+	var userId string
+	if !anonymous && !includeAll {
+		user, err := svc.store.Users().GetByUsername(ctx, username)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to retrieve user: %s", username)
+			return nil, err
+		}
+		if user != nil {
+			userId = user.Id
+		}
+	}
+
+	// one more repository is fetched to find out whether the next page exists
+	limit := n
+	if n > 0 {
+		limit = n + 1
+	}
+
+	names, err := svc.store.ImageQueries().ListRepositoryNames(ctx, svc.registryId, userId, includeAll, last, limit)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list repositories from database")
+		return nil, err
+	}
+
+	if n > 0 && len(names) > n {
+		names = names[:n]
+		result.hasMore = true
+	}
+	result.repositories = names
+
+	return result, nil
+}
+
+type referrersResult struct {
+	notFound  bool // true if repository doesn't exist
+	referrers []oci.OCIDescriptor
+}
+
+// listReferrers returns descriptors of manifests which refer the subject. If artifactType is not empty, only the
+// referrers of the given artifact type are returned.
+func (svc *RegistryService) listReferrers(reqCtx context.Context, namespace, repository, subjectDigest,
+	artifactType string) (result *referrersResult, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list referrers due to database transaction errors")
+		return nil, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	result = &referrersResult{}
+
+	repositoryID, err := svc.getRepositoryID(ctx, namespace, repository)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve repository from database")
+		return nil, err
+	}
+	if repositoryID == "" {
+		result.notFound = true
+		return result, nil
+	}
+
+	referrers, err := svc.store.Manifests().ListReferrers(ctx, repositoryID, subjectDigest, artifactType)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list referrers from database")
+		return nil, err
+	}
+
+	result.referrers = []oci.OCIDescriptor{}
+	for _, referrer := range referrers {
+		// annotations of the referrer are copied to its descriptor
+		var manifest struct {
+			Annotations map[string]string `json:"annotations,omitempty"`
+		}
+		if err := json.Unmarshal([]byte(referrer.Content), &manifest); err != nil {
+			log.Logger().Warn().Err(err).Msgf("Unable to parse annotations of referrer: %s", referrer.Digest)
+		}
+
+		result.referrers = append(result.referrers, oci.OCIDescriptor{
+			MediaType:    referrer.MediaType,
+			Size:         referrer.Size,
+			Digest:       referrer.Digest,
+			ArtifactType: referrer.ArtifactType,
+			Annotations:  manifest.Annotations,
+		})
+	}
+
+	return result, nil
+}
\ No newline at end of file
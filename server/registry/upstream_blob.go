@@ -0,0 +1,301 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/storage"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+// errBlobDigestMismatch is returned at the end of an upstream blob whose content doesn't match its digest
+var errBlobDigestMismatch = errors.New("upstream blob content does not match the digest")
+
+// blobStream is the blob content streamed from the upstream registry. Unlike cached blobs it can't be seeked,
+// therefore range requests aren't supported. size is -1 if the upstream registry didn't report it.
+type blobStream struct {
+	io.ReadCloser
+	size int64
+}
+
+// cachingBlobReader streams the blob from the upstream registry to the client while writing it into a temporary
+// file in storage. The digest is verified as the content streams; the blob is committed to the cache only when the
+// whole blob is read and the digest matches. Otherwise, the temporary file is removed.
+type cachingBlobReader struct {
+	svc        *RegistryService
+	ctx        context.Context
+	namespace  string
+	repository string
+	digest     string
+
+	upstream     io.ReadCloser
+	hasher       *uploadHasher
+	tempLocation string
+	pw           *io.PipeWriter
+	// created receives the result of writing the temporary file
+	created chan error
+	// caching is false once writing the temporary file fails. The blob is still served to the client.
+	caching bool
+	read    int64
+	done    bool
+	err     error
+	// fetch is released once the blob is cached or the stream ends without caching it
+	fetch *upstreamFetch
+}
+
+// newCachingBlobReader starts writing the upstream blob into a temporary file. The blob is cached for the
+// repository once it is read completely.
+func (svc *RegistryService) newCachingBlobReader(ctx context.Context, namespace, repository, digest string,
+	upstream io.ReadCloser) (*cachingBlobReader, error) {
+	hasher, err := newUploadHasher(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+
+	r := &cachingBlobReader{
+		svc: svc,
+		// the cache entry is committed after the response is written; it mustn't be cancelled along with the request
+		ctx:          context.WithoutCancel(ctx),
+		namespace:    namespace,
+		repository:   repository,
+		digest:       digest,
+		upstream:     upstream,
+		hasher:       hasher,
+		tempLocation: utils.StorageLocation("blobs", svc.registryName, namespace, repository, uuid.NewString()),
+		pw:           pw,
+		created:      make(chan error, 1),
+		caching:      true,
+	}
+
+	go func() {
+		// partially written file is removed by the storage if the write fails or is aborted
+		_, err := storage.Create(r.tempLocation, pr)
+		pr.CloseWithError(err)
+		r.created <- err
+	}()
+
+	return r, nil
+}
+
+func (r *cachingBlobReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, r.err
+	}
+
+	n, err := r.upstream.Read(p)
+	if n > 0 {
+		r.hasher.Write(p[:n])
+		r.read += int64(n)
+
+		if r.caching {
+			if _, werr := r.pw.Write(p[:n]); werr != nil {
+				log.Logger().Warn().Err(werr).Str("digest", r.digest).
+					Msg("Writing upstream blob into storage failed. Blob will be served without caching")
+				r.caching = false
+			}
+		}
+	}
+
+	switch {
+	case err == io.EOF:
+		r.done = true
+		r.err = r.finish()
+		return n, r.err
+	case err != nil:
+		r.done = true
+		r.err = err
+		r.abort(err)
+		r.fetch.finish(false, err)
+		return n, err
+	}
+	return n, nil
+}
+
+// Close closes the upstream response. A blob which wasn't read completely isn't cached.
+func (r *cachingBlobReader) Close() error {
+	if !r.done {
+		r.done = true
+		r.err = io.ErrClosedPipe
+		r.abort(fmt.Errorf("upstream blob %s was not read completely", r.digest))
+		// blob isn't cached; one of the waiting pulls fetches it again
+		r.fetch.finish(true, nil)
+	}
+	return r.upstream.Close()
+}
+
+// finish verifies the digest of the blob and commits it to the cache. io.EOF is returned if the content is valid,
+// even if caching fails.
+func (r *cachingBlobReader) finish() error {
+	r.pw.Close()
+	createErr := <-r.created
+
+	if !r.hasher.verify(r.digest) {
+		log.Logger().Warn().
+			Str("namespace", r.namespace).
+			Str("repository", r.repository).
+			Str("blob digest", r.digest).
+			Msg("Content of upstream blob does not match the digest")
+		if createErr == nil {
+			r.removeTempFile()
+		}
+		r.fetch.finish(false, errBlobDigestMismatch)
+		return errBlobDigestMismatch
+	}
+
+	// waiting pulls fetch the blob again if it isn't cached
+	defer r.fetch.finish(true, nil)
+
+	if !r.caching || createErr != nil {
+		if createErr == nil {
+			r.removeTempFile()
+		}
+		return io.EOF
+	}
+
+	err := r.svc.commitCachedBlob(r.ctx, r.namespace, r.repository, r.digest, r.tempLocation, r.read)
+	if err != nil {
+		log.Logger().Error().Err(err).Str("digest", r.digest).Msg("Failed to cache upstream blob")
+		r.removeTempFile()
+	}
+	return io.EOF
+}
+
+// abort stops writing the temporary file. The storage removes the partially written file.
+func (r *cachingBlobReader) abort(err error) {
+	r.pw.CloseWithError(err)
+	<-r.created
+}
+
+func (r *cachingBlobReader) removeTempFile() {
+	if err := storage.DeleteFile(r.tempLocation); err != nil {
+		log.Logger().Warn().Err(err).Msgf("Failed to remove temporary blob file: %s", r.tempLocation)
+	}
+}
+
+// commitCachedBlob moves the verified blob from its temporary location to its content addressed location and adds
+// the blob to the repository
+func (svc *RegistryService) commitCachedBlob(reqCtx context.Context, namespace, repository, digest,
+	tempLocation string, size int64) (err error) {
+	// held until the transaction is committed so that the content isn't removed in between
+	contentLock.RLock()
+	defer contentLock.RUnlock()
+
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to cache blob due to database transaction errors")
+		return err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	nsId, repoId, err := svc.getNameSpaceIdAndRepositoryId(ctx, namespace, repository)
+	if err != nil {
+		return err
+	}
+
+	blobMeta, err := svc.store.Blobs().Get(ctx, digest, repoId)
+	if err != nil {
+		return err
+	}
+
+	content, err := svc.store.Blobs().GetContent(ctx, digest)
+	if err != nil {
+		return err
+	}
+
+	// content may be cached already for another repository or by a concurrent request
+	location := utils.BlobLocation(digest)
+	if content == nil {
+		err = storage.RenameFile(tempLocation, location)
+	} else {
+		err = storage.DeleteFile(tempLocation)
+	}
+	if err != nil {
+		return err
+	}
+
+	if blobMeta != nil {
+		return nil
+	}
+	err = svc.store.Blobs().Create(ctx, svc.registryId, nsId, repoId, digest, location, size)
+	if err != nil {
+		return err
+	}
+	// caching is the first access; CREATED_AT isn't precise enough to order blobs cached within a second
+	return svc.store.Blobs().MarkAccessed(ctx, repoId, digest)
+}
+
+// linkCachedBlob adds the blob to the repository if its content is already cached for another repository. The
+// upstream registry is asked whether the repository has the blob so that content isn't served to repositories which
+// don't have it. linked is false if the content isn't cached or the repository doesn't exist in the cache.
+func (svc *RegistryService) linkCachedBlob(reqCtx context.Context, namespace, repository,
+	digest string) (linked bool, err error) {
+	content, err := svc.store.Blobs().GetContent(reqCtx, digest)
+	if err != nil || content == nil {
+		return false, err
+	}
+
+	exists, err := svc.client.HeadBlob(namespace, repository, digest)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	// held until the transaction is committed so that the content isn't removed in between
+	contentLock.RLock()
+	defer contentLock.RUnlock()
+
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to cache blob due to database transaction errors")
+		return false, err
+	}
+	ctx := store.WithTxContext(reqCtx, tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	nsId, repoId, err := svc.getNameSpaceIdAndRepositoryId(ctx, namespace, repository)
+	if err != nil || nsId == "" || repoId == "" {
+		return false, err
+	}
+
+	// content may have been removed since it was looked up
+	content, err = svc.store.Blobs().GetContent(ctx, digest)
+	if err != nil || content == nil {
+		return false, err
+	}
+
+	blobMeta, err := svc.store.Blobs().Get(ctx, digest, repoId)
+	if err != nil {
+		return false, err
+	}
+	if blobMeta == nil {
+		err = svc.store.Blobs().Create(ctx, svc.registryId, nsId, repoId, digest, content.Location, content.Size)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = svc.store.Blobs().MarkAccessed(ctx, repoId, digest)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
\ No newline at end of file
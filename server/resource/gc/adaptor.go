@@ -0,0 +1,27 @@
+package gc
+
+import (
+	"github.com/ksankeerth/open-image-registry/registry"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+)
+
+func toGCResponse(report *registry.GCReport) *mgmt.GCResponse {
+	return &mgmt.GCResponse{
+		DryRun:     report.DryRun,
+		Manifests:  toGCItems(report.Manifests),
+		Blobs:      toGCItems(report.Blobs),
+		FreedBytes: report.FreedBytes,
+	}
+}
+
+func toGCItems(items []*registry.GCItem) []mgmt.GCItem {
+	res := make([]mgmt.GCItem, len(items))
+	for i, item := range items {
+		res[i] = mgmt.GCItem{
+			Repository: item.Repository,
+			Digest:     item.Digest,
+			Size:       item.Size,
+		}
+	}
+	return res
+}
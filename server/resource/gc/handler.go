@@ -0,0 +1,60 @@
+package gc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/httperrors"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/registry"
+)
+
+// GCHandler serves the management API which triggers garbage collection
+type GCHandler struct {
+	svc *gcService
+}
+
+func NewHandler(gc *registry.GarbageCollector) *GCHandler {
+	return &GCHandler{
+		svc: &gcService{
+			gc: gc,
+		},
+	}
+}
+
+func (h *GCHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/", h.collectGarbage)
+	return r
+}
+
+func (h *GCHandler) collectGarbage(w http.ResponseWriter, r *http.Request) {
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	dryRun, valid, errMsg := validateDryRun(r.URL.Query().Get("dry_run"))
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	res, err := h.svc.collectGarbage(r.Context(), dryRun)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
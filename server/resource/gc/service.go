@@ -0,0 +1,21 @@
+package gc
+
+import (
+	"context"
+
+	"github.com/ksankeerth/open-image-registry/registry"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+)
+
+type gcService struct {
+	gc *registry.GarbageCollector
+}
+
+// collectGarbage runs garbage collection within the server so that it is coordinated with in-flight pushes
+func (svc *gcService) collectGarbage(ctx context.Context, dryRun bool) (*mgmt.GCResponse, error) {
+	report, err := svc.gc.Collect(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return toGCResponse(report), nil
+}
@@ -0,0 +1,16 @@
+package gc
+
+import "strconv"
+
+// validateDryRun parses `dry_run` query param. Garbage collection removes content unless it is set to true.
+func validateDryRun(value string) (dryRun, valid bool, errMsg string) {
+	if value == "" {
+		return false, true, ""
+	}
+
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false, "Invalid dry_run"
+	}
+	return dryRun, true, ""
+}
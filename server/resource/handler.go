@@ -3,10 +3,13 @@ package resource
 import (
 	"github.com/go-chi/chi/v5"
 
+	"github.com/ksankeerth/open-image-registry/lib"
+	"github.com/ksankeerth/open-image-registry/registry"
 	acesss "github.com/ksankeerth/open-image-registry/resource/access"
 	"github.com/ksankeerth/open-image-registry/resource/namespace"
 	"github.com/ksankeerth/open-image-registry/resource/repository"
 	"github.com/ksankeerth/open-image-registry/resource/upstream"
+	"github.com/ksankeerth/open-image-registry/resource/webhook"
 	"github.com/ksankeerth/open-image-registry/store"
 )
 
@@ -14,13 +17,17 @@ type RegistryResourceHandler struct {
 	namespaceHandler  *namespace.NamespaceHandler
 	repositoryHandler *repository.RepositoryHandler
 	upstreamHandler   *upstream.UpstreamAccessHandler
+	webhookHandler    *webhook.WebhookHandler
 }
 
-func NewRegistryResourceHandler(s store.Store, accessManager *acesss.Manager) *RegistryResourceHandler {
+func NewRegistryResourceHandler(s store.Store, jwtProvider lib.JWTProvider, accessManager *acesss.Manager,
+	retention *registry.RetentionManager, events *registry.EventDispatcher,
+	evictor *registry.CacheEvictor) *RegistryResourceHandler {
 	return &RegistryResourceHandler{
-		namespaceHandler:  namespace.NewHandler(s, accessManager),
-		repositoryHandler: repository.NewHandler(s, accessManager),
-		upstreamHandler:   upstream.NewHandler(s),
+		namespaceHandler:  namespace.NewHandler(s, accessManager, retention),
+		repositoryHandler: repository.NewHandler(s, accessManager, retention, events),
+		upstreamHandler:   upstream.NewHandler(s, jwtProvider, events, evictor),
+		webhookHandler:    webhook.NewHandler(s),
 	}
 }
 
@@ -30,6 +37,7 @@ func (h *RegistryResourceHandler) Routes() chi.Router {
 		r.Mount("/upstreams", h.upstreamHandler.Routes())
 		r.Mount("/namespaces", h.namespaceHandler.Routes())
 		r.Mount("/repositories", h.repositoryHandler.Routes())
+		r.Mount("/webhooks", h.webhookHandler.Routes())
 	})
 
 	return router
@@ -1,6 +1,7 @@
 package namespace
 
 import (
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
 	"github.com/ksankeerth/open-image-registry/types/models"
 )
@@ -42,4 +43,56 @@ func toNamespaceViewDTO(view *models.NamespaceView) *mgmt.NamespaceViewDTO {
 		Developers:  view.Developers,
 		Maintainers: view.Maintainers,
 	}
+}
+
+func toQuotaResponse(m *models.QuotaModel) *mgmt.QuotaResponse {
+	if m == nil {
+		return nil
+	}
+
+	return &mgmt.QuotaResponse{
+		MaxBytes:   m.MaxBytes,
+		UsedBytes:  m.UsedBytes,
+		MaxImages:  m.MaxImages,
+		UsedImages: m.UsedImages,
+	}
+}
+
+func toRetentionRules(rules []*models.RetentionRuleModel) []mgmt.RetentionRule {
+	res := make([]mgmt.RetentionRule, len(rules))
+	for i, rule := range rules {
+		res[i] = mgmt.RetentionRule{
+			TagPattern: rule.TagPattern,
+			KeepLast:   rule.KeepLast,
+			MaxAgeDays: rule.MaxAgeDays,
+		}
+	}
+	return res
+}
+
+func toRetentionRuleModels(rules []mgmt.RetentionRule) []*models.RetentionRuleModel {
+	res := make([]*models.RetentionRuleModel, len(rules))
+	for i, rule := range rules {
+		res[i] = &models.RetentionRuleModel{
+			TagPattern: rule.TagPattern,
+			KeepLast:   rule.KeepLast,
+			MaxAgeDays: rule.MaxAgeDays,
+		}
+	}
+	return res
+}
+
+func toRetentionReportResponse(report *registry.RetentionReport) *mgmt.RetentionReportResponse {
+	res := &mgmt.RetentionReportResponse{
+		DryRun: report.DryRun,
+		Tags:   make([]mgmt.RetentionItem, len(report.Tags)),
+	}
+	for i, item := range report.Tags {
+		res.Tags[i] = mgmt.RetentionItem{
+			Repository: item.Repository,
+			Tag:        item.Tag,
+			Digest:     item.Digest,
+		}
+	}
+	return res
 }
\ No newline at end of file
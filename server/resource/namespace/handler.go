@@ -11,6 +11,7 @@ import (
 	"github.com/ksankeerth/open-image-registry/errors/httperrors"
 	"github.com/ksankeerth/open-image-registry/lib"
 	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/resource/access"
 	"github.com/ksankeerth/open-image-registry/resource/repository"
 	"github.com/ksankeerth/open-image-registry/store"
@@ -22,10 +23,12 @@ type NamespaceHandler struct {
 	svc *namespaceService
 }
 
-func NewHandler(s store.Store, accessManager *access.Manager) *NamespaceHandler {
+func NewHandler(s store.Store, accessManager *access.Manager,
+	retention *registry.RetentionManager) *NamespaceHandler {
 	svc := &namespaceService{
 		store:         s,
 		accessManager: accessManager,
+		retention:     retention,
 	}
 	return &NamespaceHandler{
 		svc,
@@ -45,6 +48,10 @@ func (h *NamespaceHandler) Routes() chi.Router {
 		r.Delete("/", h.deleteNamespace)
 		r.Patch("/state", h.changeState)
 		r.Patch("/visibility", h.changeVisiblity)
+		r.Put("/quota", h.setQuota)
+		r.Get("/retention-rules", h.getRetentionRules)
+		r.Put("/retention-rules", h.setRetentionRules)
+		r.Post("/retention-rules/run", h.applyRetentionRules)
 
 		r.Get("/users", h.listUserAccess)
 		r.Get("/repositories", h.listRepositories)
@@ -194,7 +201,7 @@ func (h *NamespaceHandler) deleteNamespace(w http.ResponseWriter, r *http.Reques
 func (h *NamespaceHandler) getNamespace(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	ns, err := h.svc.getNamespace(r.Context(), id)
+	ns, quota, err := h.svc.getNamespace(r.Context(), id)
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
 		httperrors.InternalError(w, 500, "Request aborted due to errors")
@@ -207,6 +214,7 @@ func (h *NamespaceHandler) getNamespace(w http.ResponseWriter, r *http.Request)
 	}
 
 	res := makeGetNamespaceResponse(ns)
+	res.Quota = toQuotaResponse(quota)
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
@@ -437,4 +445,146 @@ func (h *NamespaceHandler) listUserAccess(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Error occured when writing response: %s", r.RequestURI)
 	}
+}
+
+func (h *NamespaceHandler) setQuota(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.QuotaRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update quota request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := repository.ValidateQuotaRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	notFound, err := h.svc.setQuota(r.Context(), id, &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Request aborted due to errors")
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *NamespaceHandler) getRetentionRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	rules, notFound, err := h.svc.getRetentionRules(r.Context(), id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	res := mgmt.RetentionRulesResponse{
+		Rules: toRetentionRules(rules),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+func (h *NamespaceHandler) setRetentionRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.RetentionRulesRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update retention rules request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := repository.ValidateRetentionRulesRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	notFound, err := h.svc.setRetentionRules(r.Context(), id, &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Request aborted due to errors")
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *NamespaceHandler) applyRetentionRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			httperrors.BadRequest(w, 400, "Invalid dry_run")
+			return
+		}
+	}
+
+	report, notFound, err := h.svc.applyRetentionRules(r.Context(), id, dryRun)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(toRetentionReportResponse(report))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
 }
\ No newline at end of file
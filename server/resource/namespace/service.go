@@ -7,6 +7,7 @@ import (
 
 	"github.com/ksankeerth/open-image-registry/constants"
 	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/resource/access"
 	"github.com/ksankeerth/open-image-registry/store"
 	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
@@ -16,6 +17,7 @@ import (
 type namespaceService struct {
 	store         store.Store
 	accessManager *access.Manager
+	retention     *registry.RetentionManager
 }
 
 type createNsResult struct {
@@ -133,14 +135,24 @@ func (svc *namespaceService) deleteNamespace(reqCtx context.Context, identifier
 	return false, nil
 }
 
-func (svc *namespaceService) getNamespace(reqCtx context.Context, identifier string) (m *models.NamespaceModel, err error) {
+func (svc *namespaceService) getNamespace(reqCtx context.Context, identifier string) (m *models.NamespaceModel,
+	quota *models.QuotaModel, err error) {
 	m, err = svc.store.Namespaces().GetByIdentifier(reqCtx, constants.HostedRegistryID, identifier)
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Error in retriving namespace: %s", identifier)
-		return nil, err
+		return nil, nil, err
+	}
+	if m == nil {
+		return nil, nil, nil
+	}
+
+	quota, err = svc.store.Namespaces().GetQuota(reqCtx, m.Id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error in retriving quota of namespace: %s", identifier)
+		return nil, nil, err
 	}
 
-	return m, nil
+	return m, quota, nil
 }
 
 func (svc *namespaceService) updateNamespace(reqCtx context.Context, identifier string, req *mgmt.UpdateNamespaceRequest) (notFound bool, err error) {
@@ -543,4 +555,116 @@ func (svc *namespaceService) checkNameAvailability(reqCtx context.Context, name
 	}
 
 	return available, nil
+}
+
+// setQuota replaces the quota of the namespace. Only administrators can change quotas.
+func (svc *namespaceService) setQuota(reqCtx context.Context, identifier string,
+	req *mgmt.QuotaRequest) (notFound bool, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to update namespace quota due to transaction errors")
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	m, err := svc.store.Namespaces().GetByIdentifier(ctx, constants.HostedRegistryID, identifier)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update quota due to database errors: %s", identifier)
+		return false, err
+	}
+	if m == nil {
+		return true, nil
+	}
+
+	err = svc.store.Namespaces().SetQuota(ctx, m.Id, req.MaxBytes, req.MaxImages)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update quota of namespace: %s", identifier)
+		return false, err
+	}
+	return false, nil
+}
+
+func (svc *namespaceService) getRetentionRules(reqCtx context.Context,
+	identifier string) (rules []*models.RetentionRuleModel, notFound bool, err error) {
+	m, err := svc.store.Namespaces().GetByIdentifier(reqCtx, constants.HostedRegistryID, identifier)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve retention rules due to database errors: %s", identifier)
+		return nil, false, err
+	}
+	if m == nil {
+		return nil, true, nil
+	}
+
+	rules, err = svc.store.Namespaces().GetRetentionRules(reqCtx, m.Id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve retention rules of namespace: %s", identifier)
+		return nil, false, err
+	}
+	return rules, false, nil
+}
+
+// setRetentionRules replaces the retention rules of the namespace. Only administrators can change retention rules.
+func (svc *namespaceService) setRetentionRules(reqCtx context.Context, identifier string,
+	req *mgmt.RetentionRulesRequest) (notFound bool, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to update retention rules due to transaction errors")
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	m, err := svc.store.Namespaces().GetByIdentifier(ctx, constants.HostedRegistryID, identifier)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update retention rules due to database errors: %s", identifier)
+		return false, err
+	}
+	if m == nil {
+		return true, nil
+	}
+
+	err = svc.store.Namespaces().SetRetentionRules(ctx, m.Id, toRetentionRuleModels(req.Rules))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update retention rules of namespace: %s", identifier)
+		return false, err
+	}
+	return false, nil
+}
+
+// applyRetentionRules applies retention rules to the repositories of the namespace. If dryRun is true, nothing is
+// deleted.
+func (svc *namespaceService) applyRetentionRules(reqCtx context.Context, identifier string,
+	dryRun bool) (report *registry.RetentionReport, notFound bool, err error) {
+	m, err := svc.store.Namespaces().GetByIdentifier(reqCtx, constants.HostedRegistryID, identifier)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to apply retention rules due to database errors: %s", identifier)
+		return nil, false, err
+	}
+	if m == nil {
+		return nil, true, nil
+	}
+
+	report, err = svc.retention.ApplyToNamespace(reqCtx, m.Id, dryRun)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to apply retention rules to namespace: %s", identifier)
+		return nil, false, err
+	}
+	return report, false, nil
 }
\ No newline at end of file
@@ -10,6 +10,7 @@ import (
 	"github.com/ksankeerth/open-image-registry/errors/httperrors"
 	"github.com/ksankeerth/open-image-registry/lib"
 	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/resource/access"
 	"github.com/ksankeerth/open-image-registry/store"
 	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
@@ -20,10 +21,13 @@ type RepositoryHandler struct {
 	svc *repositoryService
 }
 
-func NewHandler(s store.Store, accessManager *access.Manager) *RepositoryHandler {
+func NewHandler(s store.Store, accessManager *access.Manager,
+	retention *registry.RetentionManager, events *registry.EventDispatcher) *RepositoryHandler {
 	svc := &repositoryService{
 		store:         s,
 		accessManager: accessManager,
+		retention:     retention,
+		events:        events,
 	}
 	return &RepositoryHandler{
 		svc,
@@ -49,6 +53,16 @@ func (h *RepositoryHandler) Routes() chi.Router {
 		r.Post("/users", h.grantUserAccess)
 		r.Delete("/users/{userID}", h.revokeUserAccess)
 
+		r.Get("/immutable-tags", h.getImmutableTags)
+		r.Put("/immutable-tags", h.updateImmutableTags)
+		r.Patch("/tags/{tag}/stability", h.changeTagStability)
+
+		r.Put("/quota", h.setQuota)
+
+		r.Get("/retention-rules", h.getRetentionRules)
+		r.Put("/retention-rules", h.setRetentionRules)
+		r.Post("/retention-rules/run", h.applyRetentionRules)
+
 		// r.Get("/tags", h.listTags) TODO: after https://github.com/ksankeerth/open-image-registry/issues/24
 	})
 	return r
@@ -185,7 +199,15 @@ func (h *RepositoryHandler) getRepository(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	quota, err := h.svc.getQuota(r.Context(), model.ID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
 	res := makeGetRepositoryResponse(model)
+	res.Quota = toQuotaResponse(quota)
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
@@ -450,4 +472,229 @@ func (h *RepositoryHandler) checkNameAvailability(w http.ResponseWriter, r *http
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Marshalling response failed due to errors: %s", r.RequestURI)
 	}
+}
+
+func (h *RepositoryHandler) getImmutableTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	patterns, notFound, err := h.svc.getImmutableTags(r.Context(), id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	res := mgmt.ImmutableTagsResponse{
+		Patterns: patterns,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+func (h *RepositoryHandler) updateImmutableTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req mgmt.ImmutableTagsRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Parsing request body of update immutable tags request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateImmutableTagsRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	notFound, err := h.svc.updateImmutableTags(r.Context(), id, &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Request aborted due to errors")
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *RepositoryHandler) changeTagStability(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	tag := chi.URLParam(r, "tag")
+	stable, err := strconv.ParseBool(r.URL.Query().Get("stable"))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Changing stability of tag(%s) failed due to invalid query param", tag)
+		httperrors.BadRequest(w, 400, "Invalid query param")
+		return
+	}
+
+	result, err := h.svc.changeTagStability(r.Context(), id, tag, stable)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Request aborted due to errors")
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+	if result.success {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	httperrors.SendError(w, result.httpStatusCode, result.httpErrorMsg)
+}
+
+func (h *RepositoryHandler) setQuota(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.QuotaRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Parsing request body of update quota request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := ValidateQuotaRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	notFound, err := h.svc.setQuota(r.Context(), id, &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Request aborted due to errors")
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *RepositoryHandler) getRetentionRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	rules, notFound, err := h.svc.getRetentionRules(r.Context(), id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	res := mgmt.RetentionRulesResponse{
+		Rules: toRetentionRules(rules),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+func (h *RepositoryHandler) setRetentionRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.RetentionRulesRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update retention rules request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := ValidateRetentionRulesRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	notFound, err := h.svc.setRetentionRules(r.Context(), id, &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Request aborted due to errors")
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *RepositoryHandler) applyRetentionRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			httperrors.BadRequest(w, 400, "Invalid dry_run")
+			return
+		}
+	}
+
+	report, notFound, err := h.svc.applyRetentionRules(r.Context(), id, dryRun)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(toRetentionReportResponse(report))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
 }
\ No newline at end of file
@@ -7,15 +7,20 @@ import (
 
 	"github.com/ksankeerth/open-image-registry/constants"
 	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/resource/access"
 	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
 	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
 	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/ksankeerth/open-image-registry/utils"
 )
 
 type repositoryService struct {
 	store         store.Store
 	accessManager *access.Manager
+	retention     *registry.RetentionManager
+	events        *registry.EventDispatcher
 }
 
 type createRepoResult struct {
@@ -29,6 +34,14 @@ type createRepoResult struct {
 
 func (svc *repositoryService) createRepository(reqCtx context.Context, req *mgmt.CreateRepositoryRequest) (*createRepoResult,
 	error) {
+	var repositoryName string
+	var err error
+	defer func() {
+		if err == nil && repositoryName != "" {
+			svc.events.Notify(req.NamespaceId, registry.NewEvent(reqCtx, constants.WebhookActionCreate,
+				dockerv2.EventTarget{Repository: repositoryName}))
+		}
+	}()
 
 	tx, err := svc.store.Begin(reqCtx)
 	if err != nil {
@@ -109,6 +122,7 @@ func (svc *repositoryService) createRepository(reqCtx context.Context, req *mgmt
 	}
 
 	res.id = id
+	repositoryName = ns.Name + "/" + req.Name
 
 	return res, nil
 }
@@ -146,6 +160,16 @@ func (svc *repositoryService) repositoryExists(reqCtx context.Context, identifie
 	return
 }
 
+// getQuota returns the quota of the repository along with its usage
+func (svc *repositoryService) getQuota(reqCtx context.Context, id string) (*models.QuotaModel, error) {
+	quota, err := svc.store.Repositories().GetQuota(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error in retriving quota of repository: %s", id)
+		return nil, err
+	}
+	return quota, nil
+}
+
 func (svc *repositoryService) getRepository(reqCtx context.Context, identifier, namespaceId string) (*models.RepositoryModel, error) {
 	if namespaceId == "" {
 		return svc.store.Repositories().Get(reqCtx, identifier)
@@ -597,3 +621,232 @@ func (svc *repositoryService) checkNameAvailability(reqCtx context.Context, nsId
 
 	return !exists, nil
 }
+
+func (svc *repositoryService) getImmutableTags(reqCtx context.Context, id string) (patterns []string, notFound bool,
+	err error) {
+	exists, err := svc.store.Repositories().Exists(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve immutable tags due to database errors: %s", id)
+		return nil, false, err
+	}
+
+	if !exists {
+		return nil, true, nil
+	}
+
+	patterns, err = svc.store.Repositories().GetImmutableTagPatterns(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve immutable tags of repository: %s", id)
+		return nil, false, err
+	}
+	return patterns, false, nil
+}
+
+func (svc *repositoryService) updateImmutableTags(reqCtx context.Context, id string,
+	req *mgmt.ImmutableTagsRequest) (notFound bool, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Updating immutable tags failed due to transaction errors")
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	exists, err := svc.store.Repositories().Exists(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Updating immutable tags failed due to database errors: %s", id)
+		return false, err
+	}
+
+	if !exists {
+		return true, nil
+	}
+
+	err = svc.store.Repositories().SetImmutableTagPatterns(ctx, id, utils.RemoveDuplicateKeys(req.Patterns))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Updating immutable tags of repository(%s) failed", id)
+		return false, err
+	}
+	return false, nil
+}
+
+// changeTagStability marks the tag stable or unstable. Stable tags can't be moved to another manifest by pushes or
+// be deleted.
+func (svc *repositoryService) changeTagStability(reqCtx context.Context, id, tag string,
+	stable bool) (result *patchResult, err error) {
+	result = &patchResult{}
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to change tag stability due to transaction errors")
+		return nil, err
+	}
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	exists, err := svc.store.Repositories().Exists(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to change tag stability due to database errors: %s", id)
+		return nil, err
+	}
+
+	if !exists {
+		result.httpErrorMsg = "Repository " + id + " is not found"
+		result.httpStatusCode = http.StatusNotFound
+		return result, nil
+	}
+
+	tagModel, err := svc.store.Tags().Get(ctx, id, tag)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to change stability of tag(%s) due to database errors", tag)
+		return nil, err
+	}
+
+	if tagModel == nil {
+		result.httpErrorMsg = "Tag " + tag + " is not found"
+		result.httpStatusCode = http.StatusNotFound
+		return result, nil
+	}
+
+	if tagModel.IsStable != stable {
+		err = svc.store.Tags().SetStable(ctx, tagModel.Id, stable)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to change stability of tag(%s) to stable=%t", tag, stable)
+			return nil, err
+		}
+	}
+
+	result.success = true
+	return result, nil
+}
+
+// setQuota replaces the quota of the repository. Only administrators can change quotas.
+func (svc *repositoryService) setQuota(reqCtx context.Context, id string, req *mgmt.QuotaRequest) (notFound bool,
+	err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Updating repository quota failed due to transaction errors")
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	exists, err := svc.store.Repositories().Exists(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Updating quota failed due to database errors: %s", id)
+		return false, err
+	}
+
+	if !exists {
+		return true, nil
+	}
+
+	err = svc.store.Repositories().SetQuota(ctx, id, req.MaxBytes, req.MaxImages)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Updating quota of repository(%s) failed", id)
+		return false, err
+	}
+	return false, nil
+}
+
+func (svc *repositoryService) getRetentionRules(reqCtx context.Context,
+	id string) (rules []*models.RetentionRuleModel, notFound bool, err error) {
+	exists, err := svc.store.Repositories().Exists(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve retention rules due to database errors: %s", id)
+		return nil, false, err
+	}
+
+	if !exists {
+		return nil, true, nil
+	}
+
+	rules, err = svc.store.Repositories().GetRetentionRules(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve retention rules of repository: %s", id)
+		return nil, false, err
+	}
+	return rules, false, nil
+}
+
+// setRetentionRules replaces the retention rules of the repository. Only administrators can change retention rules.
+func (svc *repositoryService) setRetentionRules(reqCtx context.Context, id string,
+	req *mgmt.RetentionRulesRequest) (notFound bool, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Updating retention rules failed due to transaction errors")
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	exists, err := svc.store.Repositories().Exists(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Updating retention rules failed due to database errors: %s", id)
+		return false, err
+	}
+
+	if !exists {
+		return true, nil
+	}
+
+	err = svc.store.Repositories().SetRetentionRules(ctx, id, toRetentionRuleModels(req.Rules))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Updating retention rules of repository(%s) failed", id)
+		return false, err
+	}
+	return false, nil
+}
+
+// applyRetentionRules applies retention rules of the repository and its namespace to the repository. If dryRun is
+// true, nothing is deleted.
+func (svc *repositoryService) applyRetentionRules(reqCtx context.Context, id string,
+	dryRun bool) (report *registry.RetentionReport, notFound bool, err error) {
+	exists, err := svc.store.Repositories().Exists(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to apply retention rules due to database errors: %s", id)
+		return nil, false, err
+	}
+
+	if !exists {
+		return nil, true, nil
+	}
+
+	report, err = svc.retention.ApplyToRepository(reqCtx, id, dryRun)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to apply retention rules to repository: %s", id)
+		return nil, false, err
+	}
+	return report, false, nil
+}
\ No newline at end of file
@@ -123,5 +123,50 @@ func validateRepositoryGrantAccessRequest(req *mgmt.AccessGrantRequest) (valid b
 		return false, "Invalid Access Level"
 	}
 
+	return true, ""
+}
+
+func validateImmutableTagsRequest(req *mgmt.ImmutableTagsRequest) (valid bool, errMsg string) {
+	for _, pattern := range req.Patterns {
+		if !utils.IsValidTagPattern(pattern) {
+			return false, fmt.Sprintf("Invalid tag pattern: %s", pattern)
+		}
+	}
+	return true, ""
+}
+
+// ValidateQuotaRequest validates the quota of a namespace or a repository.
+func ValidateQuotaRequest(req *mgmt.QuotaRequest) (valid bool, errMsg string) {
+	if req.MaxBytes != nil && *req.MaxBytes < 0 {
+		return false, "Invalid max_bytes"
+	}
+	if req.MaxImages != nil && *req.MaxImages < 0 {
+		return false, "Invalid max_images"
+	}
+	return true, ""
+}
+
+// ValidateRetentionRulesRequest validates the retention rules of a namespace or a repository.
+func ValidateRetentionRulesRequest(req *mgmt.RetentionRulesRequest) (valid bool, errMsg string) {
+	patterns := make(map[string]bool)
+	for _, rule := range req.Rules {
+		if !utils.IsValidTagPattern(rule.TagPattern) {
+			return false, fmt.Sprintf("Invalid tag pattern: %s", rule.TagPattern)
+		}
+		if patterns[rule.TagPattern] {
+			return false, fmt.Sprintf("Duplicate tag pattern: %s", rule.TagPattern)
+		}
+		patterns[rule.TagPattern] = true
+
+		if rule.KeepLast == nil && rule.MaxAgeDays == nil {
+			return false, fmt.Sprintf("Either keep_last or max_age_days is required: %s", rule.TagPattern)
+		}
+		if rule.KeepLast != nil && *rule.KeepLast < 0 {
+			return false, "Invalid keep_last"
+		}
+		if rule.MaxAgeDays != nil && *rule.MaxAgeDays < 0 {
+			return false, "Invalid max_age_days"
+		}
+	}
 	return true, ""
 }
\ No newline at end of file
@@ -0,0 +1,221 @@
+package upstream
+
+import (
+	"encoding/json"
+
+	"github.com/ksankeerth/open-image-registry/registry"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+// Defaults of upstream configs. They are same as the defaults of the database schema.
+const (
+	defaultTTLSeconds             = 3600
+	defaultStorageLimit           = 100
+	defaultCleanupThreshold       = 80
+	defaultConnectionTimeout      = 10
+	defaultReadTimeout            = 30
+	defaultWriteTimeout           = 30
+	defaultMaxConnections         = 100
+	defaultMaxIdleConnections     = 10
+	defaultMaxRetries             = 3
+	defaultRetryDelay             = 5
+	defaultRetryBackOffMultiplier = 2
+)
+
+// upstreamCredentials is the stored auth config of upstream registries. It is understood by both Docker Hub and OCI
+// upstream clients.
+type upstreamCredentials struct {
+	Username      string `json:"username,omitempty"`
+	Credential    string `json:"credential,omitempty"`
+	TokenEndpoint string `json:"token_endpoint,omitempty"`
+}
+
+func toAuthConfigModel(registryID string, dto *mgmt.UpstreamAuthConfigDTO) (*models.UpstreamRegistryAuthConfig,
+	error) {
+	credentials := upstreamCredentials{
+		TokenEndpoint: dto.TokenEndpoint,
+	}
+	credentials.Username, _ = dto.CredentialJson["username"].(string)
+	credentials.Credential, _ = dto.CredentialJson["password"].(string)
+
+	configJSON, err := json.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UpstreamRegistryAuthConfig{
+		RegistryID: registryID,
+		AuthType:   dto.AuthType,
+		ConfigJSON: configJSON,
+	}, nil
+}
+
+func toCacheConfigModel(registryID string, storage *mgmt.UpstreamStorageConfigDTO,
+	cache *mgmt.UpstreamCacheConfigDTO) *models.UpstreamRegistryCacheStoreConfig {
+	m := &models.UpstreamRegistryCacheStoreConfig{
+		RegistryID:       registryID,
+		CacheEnabled:     cache.Enabled == nil || *cache.Enabled,
+		TTLSeconds:       cache.TtlInSeconds,
+		StorageLimit:     storage.StorageLimitInMbs,
+		CleanupThreshold: storage.CleanupThreshold,
+		StaleIfError:     cache.StaleIfError,
+		OfflineMode:      cache.OfflineMode,
+	}
+
+	if m.TTLSeconds == 0 {
+		m.TTLSeconds = defaultTTLSeconds
+	}
+	if m.StorageLimit == 0 {
+		m.StorageLimit = defaultStorageLimit
+	}
+	if m.CleanupThreshold == 0 {
+		m.CleanupThreshold = defaultCleanupThreshold
+	}
+	return m
+}
+
+// toNetworkConfigModel applies dto to the current network config. Settings which can't be set through the API are
+// kept as they are. current is nil for new upstream registries.
+func toNetworkConfigModel(registryID string, dto *mgmt.UpstreamAccessConfigDTO,
+	current *models.UpstreamRegistryNetworkConfig) *models.UpstreamRegistryNetworkConfig {
+	m := &models.UpstreamRegistryNetworkConfig{
+		RegistryID:             registryID,
+		ConnectionTimeout:      dto.ConnectionTimeoutInSeconds,
+		ReadTimeout:            dto.ReadTimeoutInSeconds,
+		WriteTimeout:           defaultWriteTimeout,
+		MaxConnections:         dto.MaxConnections,
+		MaxIdleConnections:     defaultMaxIdleConnections,
+		MaxRetries:             defaultMaxRetries,
+		RetryDelay:             dto.RetryDelayInSeconds,
+		RetryBackOffMultiplier: defaultRetryBackOffMultiplier,
+	}
+
+	if current != nil {
+		m.WriteTimeout = current.WriteTimeout
+		m.MaxIdleConnections = current.MaxIdleConnections
+		m.RetryBackOffMultiplier = current.RetryBackOffMultiplier
+	}
+
+	if dto.MaxRetries != nil {
+		m.MaxRetries = *dto.MaxRetries
+	}
+	if m.ConnectionTimeout == 0 {
+		m.ConnectionTimeout = defaultConnectionTimeout
+	}
+	if m.ReadTimeout == 0 {
+		m.ReadTimeout = defaultReadTimeout
+	}
+	if m.MaxConnections == 0 {
+		m.MaxConnections = defaultMaxConnections
+	}
+	if m.RetryDelay == 0 {
+		m.RetryDelay = defaultRetryDelay
+	}
+	return m
+}
+
+func toUpstreamSummaryDTO(view *models.UpstreamRegistryView) *mgmt.UpstreamRegistrySummaryDTO {
+	return &mgmt.UpstreamRegistrySummaryDTO{
+		Id:                view.ID,
+		Name:              view.Name,
+		Description:       view.Description,
+		Vendor:            view.Vendor,
+		Port:              int(view.Port),
+		Status:            view.State,
+		UpstreamUrl:       view.UpstreamURL,
+		CachedImagesCount: view.CachedImagesCount,
+		CreatedAt:         view.CreatedAt,
+		UpdatedAt:         view.UpdatedAt,
+	}
+}
+
+// toUpstreamRegistryResponse converts the upstream registry along with its configs. Passwords are never included in
+// the response.
+func toUpstreamRegistryResponse(d *upstreamDetails) *mgmt.UpstreamRegistryResponse {
+	res := &mgmt.UpstreamRegistryResponse{
+		Id:          d.registry.ID,
+		Name:        d.registry.Name,
+		Description: d.registry.Description,
+		Vendor:      d.registry.Vendor,
+		Port:        int(d.registry.Port),
+		Status:      d.registry.State,
+		UpstreamUrl: d.registry.UpstreamURL,
+		CreatedAt:   d.registry.CreatedAt,
+		UpdatedAt:   d.registry.UpdatedAt,
+	}
+
+	if d.auth != nil {
+		var credentials upstreamCredentials
+		// auth configs created through the database may not be valid json. They are shown without credentials.
+		json.Unmarshal(d.auth.ConfigJSON, &credentials)
+
+		res.AuthConfig.AuthType = d.auth.AuthType
+		res.AuthConfig.TokenEndpoint = credentials.TokenEndpoint
+		if credentials.Username != "" {
+			res.AuthConfig.CredentialJson = map[string]interface{}{
+				"username": credentials.Username,
+			}
+		}
+		res.AuthConfig.CreatedAt = d.auth.CreatedAt
+		res.AuthConfig.UpdatedAt = d.auth.UpdatedAt
+	}
+
+	if d.network != nil {
+		maxRetries := d.network.MaxRetries
+		res.AccessConfig.UpstreamAccessConfigDTO = mgmt.UpstreamAccessConfigDTO{
+			ConnectionTimeoutInSeconds: d.network.ConnectionTimeout,
+			ReadTimeoutInSeconds:       d.network.ReadTimeout,
+			MaxConnections:             d.network.MaxConnections,
+			MaxRetries:                 &maxRetries,
+			RetryDelayInSeconds:        d.network.RetryDelay,
+		}
+		res.AccessConfig.CreatedAt = d.network.CreatedAt
+		res.AccessConfig.UpdatedAt = d.network.UpdatedAt
+	}
+
+	if d.cache != nil {
+		enabled := d.cache.CacheEnabled
+		res.CacheConfig.UpstreamCacheConfigDTO = mgmt.UpstreamCacheConfigDTO{
+			Enabled:      &enabled,
+			TtlInSeconds: d.cache.TTLSeconds,
+			StaleIfError: d.cache.StaleIfError,
+			OfflineMode:  d.cache.OfflineMode,
+		}
+		res.CacheConfig.CreatedAt = d.cache.CreatedAt
+		res.CacheConfig.UpdatedAt = d.cache.UpdatedAt
+
+		res.StorageConfig.UpstreamStorageConfigDTO = mgmt.UpstreamStorageConfigDTO{
+			StorageLimitInMbs: d.cache.StorageLimit,
+			CleanupThreshold:  d.cache.CleanupThreshold,
+		}
+		res.StorageConfig.UsedBytes = d.usedBytes
+		res.StorageConfig.CreatedAt = d.cache.CreatedAt
+		res.StorageConfig.UpdatedAt = d.cache.UpdatedAt
+	}
+
+	return res
+}
+
+func toCacheEvictionResponse(report *registry.EvictionReport) *mgmt.CacheEvictionResponse {
+	return &mgmt.CacheEvictionResponse{
+		DryRun:         report.DryRun,
+		UsedBytes:      report.UsedBytes,
+		ThresholdBytes: report.ThresholdBytes,
+		Manifests:      toEvictionItems(report.Manifests),
+		Blobs:          toEvictionItems(report.Blobs),
+		FreedBytes:     report.FreedBytes,
+	}
+}
+
+func toEvictionItems(items []*registry.EvictionItem) []mgmt.EvictionItem {
+	res := make([]mgmt.EvictionItem, len(items))
+	for i, item := range items {
+		res[i] = mgmt.EvictionItem{
+			Repository: item.Repository,
+			Digest:     item.Digest,
+			Size:       item.Size,
+		}
+	}
+	return res
+}
\ No newline at end of file
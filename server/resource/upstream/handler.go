@@ -1,19 +1,35 @@
 package upstream
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/httperrors"
+	"github.com/ksankeerth/open-image-registry/lib"
+	"github.com/ksankeerth/open-image-registry/listeners"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
 )
 
+// UpstreamAccessHandler serves the management API of upstream registries. Upstream registries can only be managed
+// by administrators.
 type UpstreamAccessHandler struct {
 	svc *upstreamService
 }
 
-func NewHandler(s store.Store) *UpstreamAccessHandler {
+func NewHandler(s store.Store, jwtProvider lib.JWTProvider, events *registry.EventDispatcher,
+	evictor *registry.CacheEvictor) *UpstreamAccessHandler {
 	svc := &upstreamService{
-		s,
+		store:       s,
+		jwtProvider: jwtProvider,
+		events:      events,
+		evictor:     evictor,
+		listeners:   listeners.GetListenerManager(),
 	}
 	return &UpstreamAccessHandler{
 		svc,
@@ -21,37 +37,433 @@ func NewHandler(s store.Store) *UpstreamAccessHandler {
 }
 
 func (u *UpstreamAccessHandler) Routes() chi.Router {
-	return chi.NewRouter()
+	r := chi.NewRouter()
+
+	r.Post("/", u.CreateUpstreamRegistry)
+	r.Get("/", u.ListUpstreamRegistries)
+
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", u.GetUpstreamRegistry)
+		r.Put("/", u.UpdateUpstreamRegistry)
+		r.Delete("/", u.DeleteUpstreamRegistry)
+		r.Patch("/state", u.ChangeUpstreamRegistryState)
+		r.Put("/auth-config", u.UpdateUpstreamRegistryAuthConfig)
+		r.Put("/cache-config", u.UpdateUpstreamRegistryCacheConfig)
+		r.Put("/network-config", u.UpdateUpstreamRegistryNetworkConfig)
+		r.Get("/cache/pins", u.ListCachePins)
+		r.Post("/cache/pins", u.PinCachedImage)
+		r.Delete("/cache/pins", u.UnpinCachedImage)
+		r.Post("/cache/evict", u.EvictCache)
+	})
+	return r
 }
 
 func (u *UpstreamAccessHandler) CreateUpstreamRegistry(w http.ResponseWriter, r *http.Request) {
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.CreateUpstreamRegistryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of create upstream registry request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateCreateUpstreamRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	res, err := u.svc.createUpstream(r.Context(), &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if res.statusCode == http.StatusConflict {
+		httperrors.AlreadyExist(w, 409, res.errMsg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(mgmt.CreateUpstreamRegistryResponse{
+		RegId:   res.id,
+		RegName: req.Name,
+	})
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
 
+func (u *UpstreamAccessHandler) ListUpstreamRegistries(w http.ResponseWriter, r *http.Request) {
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	cond := lib.ParseListConditions(r, map[string]store.FilterOperator{})
+
+	ok, errMsg := validateListUpstreamCondition(cond)
+	if !ok {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	registries, total, err := u.svc.listUpstreams(r.Context(), cond)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	res := mgmt.ListUpstreamsResponse{
+		Total:      total,
+		Page:       int(cond.Page),
+		Limit:      int(cond.Limit),
+		Registries: make([]*mgmt.UpstreamRegistrySummaryDTO, len(registries)),
+	}
+	for i, m := range registries {
+		res.Registries[i] = toUpstreamSummaryDTO(m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+func (u *UpstreamAccessHandler) GetUpstreamRegistry(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	d, err := u.svc.getUpstream(r.Context(), id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if d == nil {
+		httperrors.NotFound(w, 404, "Upstream registry not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(toUpstreamRegistryResponse(d))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
 }
 
 func (u *UpstreamAccessHandler) DeleteUpstreamRegistry(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
 
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	res, err := u.svc.deleteUpstream(r.Context(), id)
+	u.writeResult(w, r, res, err)
 }
 
 func (u *UpstreamAccessHandler) UpdateUpstreamRegistry(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
 
+	var req mgmt.UpdateUpstreamRegistryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update upstream registry request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateUpdateUpstreamRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	res, err := u.svc.updateUpstream(r.Context(), id, &req)
+	u.writeResult(w, r, res, err)
 }
 
 func (u *UpstreamAccessHandler) UpdateUpstreamRegistryAuthConfig(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.UpstreamAuthConfigDTO
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update upstream auth config request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateAuthConfig(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
 
+	res, err := u.svc.updateAuthConfig(r.Context(), id, &req)
+	u.writeResult(w, r, res, err)
 }
 
 func (u *UpstreamAccessHandler) UpdateUpstreamRegistryCacheConfig(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.UpdateUpstreamCacheConfigRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update upstream cache config request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateCacheConfig(&req.StorageConfig, &req.CacheConfig)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
 
+	res, err := u.svc.updateCacheConfig(r.Context(), id, &req)
+	u.writeResult(w, r, res, err)
 }
 
 func (u *UpstreamAccessHandler) UpdateUpstreamRegistryNetworkConfig(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
 
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.UpstreamAccessConfigDTO
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update upstream network config request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateNetworkConfig(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	res, err := u.svc.updateNetworkConfig(r.Context(), id, &req)
+	u.writeResult(w, r, res, err)
 }
 
 func (u *UpstreamAccessHandler) ChangeUpstreamRegistryState(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	state := r.URL.Query().Get("state")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	if state == "" {
+		httperrors.BadRequest(w, 400, "Missing query param state in request")
+		return
+	}
+
+	if !isValidState(state) {
+		httperrors.BadRequest(w, 400, "Invalid upstream registry state")
+		return
+	}
+
+	res, err := u.svc.changeState(r.Context(), id, state)
+	u.writeResult(w, r, res, err)
+}
 
+func (u *UpstreamAccessHandler) ListCachePins(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	pins, err := u.svc.listPins(r.Context(), id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if pins == nil {
+		httperrors.NotFound(w, 404, "Upstream registry not found")
+		return
+	}
+
+	res := mgmt.ListCachePinsResponse{
+		Pins: make([]*mgmt.CachePinDTO, len(pins)),
+	}
+	for i, m := range pins {
+		res.Pins[i] = &mgmt.CachePinDTO{
+			Namespace:  m.Namespace,
+			Repository: m.Repository,
+			Reference:  m.Reference,
+			CreatedAt:  m.CreatedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
 }
 
-func (u *UpstreamAccessHandler) GetUserAccessList(w http.ResponseWriter, r *http.Request) {
+func (u *UpstreamAccessHandler) PinCachedImage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
 
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.PinCachedImageRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of pin cached image request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateCachePin(req.Namespace, req.Repository, req.Reference)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	res, err := u.svc.pin(r.Context(), id, &req)
+	u.writeResult(w, r, res, err)
+}
+
+func (u *UpstreamAccessHandler) UnpinCachedImage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+	namespace, repository, reference := query.Get("namespace"), query.Get("repository"), query.Get("reference")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	valid, errMsg := validateCachePin(namespace, repository, reference)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	res, err := u.svc.unpin(r.Context(), id, namespace, repository, reference)
+	u.writeResult(w, r, res, err)
+}
+
+// EvictCache evicts the least recently pulled content if the cache exceeds the cleanup threshold of its storage
+// limit. Content is evicted in background as well; this is useful right after the storage limit is lowered.
+func (u *UpstreamAccessHandler) EvictCache(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			httperrors.BadRequest(w, 400, "Invalid dry_run")
+			return
+		}
+	}
+
+	report, err := u.svc.evict(r.Context(), id, dryRun)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if report == nil {
+		httperrors.NotFound(w, 404, "Upstream registry not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(toCacheEvictionResponse(report))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+// writeResult writes the response of requests which don't have a response body on success
+func (u *UpstreamAccessHandler) writeResult(w http.ResponseWriter, r *http.Request, res *upstreamResult, err error) {
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	switch res.statusCode {
+	case http.StatusOK:
+		w.WriteHeader(http.StatusOK)
+	case http.StatusCreated:
+		w.WriteHeader(http.StatusCreated)
+	case http.StatusNotFound:
+		httperrors.NotFound(w, 404, res.errMsg)
+	case http.StatusConflict:
+		httperrors.AlreadyExist(w, 409, res.errMsg)
+	default:
+		httperrors.SendError(w, res.statusCode, res.errMsg)
+	}
 }
\ No newline at end of file
@@ -1,7 +1,610 @@
 package upstream
 
-import "github.com/ksankeerth/open-image-registry/store"
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
 
+	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/dberrors"
+	"github.com/ksankeerth/open-image-registry/lib"
+	"github.com/ksankeerth/open-image-registry/listeners"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/registry"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+const (
+	minPort = 1025
+	maxPort = 65535
+
+	// listenerShutdownTimeout is the time in seconds to wait until the listener of an upstream registry stops
+	listenerShutdownTimeout = 30
+)
+
+// upstreamService manages upstream registries along with their listeners. Each upstream registry which isn't
+// disabled serves its proxy on its own port. Listeners are started and stopped only after changes are committed.
 type upstreamService struct {
-	s store.Store
-}
\ No newline at end of file
+	store       store.Store
+	jwtProvider lib.JWTProvider
+	events      *registry.EventDispatcher
+	evictor     *registry.CacheEvictor
+	listeners   *listeners.ListenerManager
+}
+
+type upstreamDetails struct {
+	registry *models.UpstreamRegistry
+	auth     *models.UpstreamRegistryAuthConfig
+	cache    *models.UpstreamRegistryCacheStoreConfig
+	network  *models.UpstreamRegistryNetworkConfig
+	// usedBytes is the size of the content cached from the upstream registry
+	usedBytes int64
+}
+
+type createUpstreamResult struct {
+	id         string
+	statusCode int
+	errMsg     string
+}
+
+type upstreamResult struct {
+	statusCode int
+	errMsg     string
+}
+
+func (svc *upstreamService) createUpstream(reqCtx context.Context,
+	req *mgmt.CreateUpstreamRegistryRequest) (res *createUpstreamResult, err error) {
+	m := &models.UpstreamRegistry{
+		Name:        req.Name,
+		Description: req.Description,
+		Vendor:      req.Vendor,
+		State:       req.Status,
+		Port:        uint(req.Port),
+		UpstreamURL: req.UpstreamUrl,
+	}
+	if m.Vendor == "" {
+		m.Vendor = constants.RegistryVendorCustom
+	}
+	if m.State == "" {
+		m.State = constants.ResourceStateActive
+	}
+
+	res = &createUpstreamResult{}
+
+	if m.State != constants.ResourceStateDisabled && !svc.portAvailable(m.Port) {
+		res.statusCode = http.StatusConflict
+		res.errMsg = fmt.Sprintf("Port %d is not available", m.Port)
+		return res, nil
+	}
+
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to create upstream registry due to transaction errors")
+		return nil, err
+	}
+
+	// listener is started once the upstream registry is committed since it loads the configs
+	defer func() {
+		if err == nil && res.statusCode == http.StatusCreated && m.State != constants.ResourceStateDisabled {
+			svc.startListener(res.id, m.Name, m.Port)
+		}
+	}()
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	res.id, err = svc.store.Upstreams().CreateRegistry(ctx, m)
+	if err != nil {
+		if yes, column := dberrors.IsUniqueConstraint(err); yes {
+			res.statusCode = http.StatusConflict
+			res.errMsg = uniqueConflictMessage(column)
+			return res, nil
+		}
+		log.Logger().Error().Err(err).Msgf("Failed to create upstream registry: %s", req.Name)
+		return nil, err
+	}
+
+	authConfig, err := toAuthConfigModel(res.id, &req.AuthConfig)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to encode auth config of upstream registry: %s", req.Name)
+		return nil, err
+	}
+
+	err = svc.store.Upstreams().PersistRegistryAuthConfig(ctx, authConfig)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to persist auth config of upstream registry: %s", req.Name)
+		return nil, err
+	}
+
+	err = svc.store.Upstreams().PersistRegistryCacheConfig(ctx, toCacheConfigModel(res.id, &req.StorageConfig,
+		&req.CacheConfig))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to persist cache config of upstream registry: %s", req.Name)
+		return nil, err
+	}
+
+	err = svc.store.Upstreams().PersistRegistryNetworkConfig(ctx, toNetworkConfigModel(res.id, &req.AccessConfig,
+		nil))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to persist network config of upstream registry: %s", req.Name)
+		return nil, err
+	}
+
+	res.statusCode = http.StatusCreated
+	return res, nil
+}
+
+func (svc *upstreamService) getUpstream(ctx context.Context, id string) (*upstreamDetails, error) {
+	m, err := svc.store.Upstreams().GetRegistry(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve upstream registry: %s", id)
+		return nil, err
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	d := &upstreamDetails{
+		registry: m,
+	}
+
+	d.auth, err = svc.store.Upstreams().GetRegistryAuthConfig(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve auth config of upstream registry: %s", id)
+		return nil, err
+	}
+
+	d.cache, err = svc.store.Upstreams().GetRegistryCacheConfig(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve cache config of upstream registry: %s", id)
+		return nil, err
+	}
+
+	d.network, err = svc.store.Upstreams().GetRegistryNetworkConfig(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve network config of upstream registry: %s", id)
+		return nil, err
+	}
+
+	d.usedBytes, err = svc.store.ImageQueries().GetCacheUsage(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to compute cache usage of upstream registry: %s", id)
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (svc *upstreamService) listUpstreams(ctx context.Context,
+	cond *store.ListQueryConditions) ([]*models.UpstreamRegistryView, int, error) {
+	registries, total, err := svc.store.Upstreams().ListRegistries(ctx, cond)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list upstream registries")
+		return nil, 0, err
+	}
+	return registries, total, nil
+}
+
+func (svc *upstreamService) updateUpstream(reqCtx context.Context, id string,
+	req *mgmt.UpdateUpstreamRegistryRequest) (res *upstreamResult, err error) {
+	return svc.update(reqCtx, id, func(ctx context.Context, m *models.UpstreamRegistry) (*upstreamResult, error) {
+		port := uint(req.Port)
+		if port != m.Port && m.State != constants.ResourceStateDisabled && !svc.portAvailable(port) {
+			return &upstreamResult{
+				statusCode: http.StatusConflict,
+				errMsg:     fmt.Sprintf("Port %d is not available", port),
+			}, nil
+		}
+
+		m.Description = req.Description
+		m.Port = port
+		m.UpstreamURL = req.UpstreamUrl
+
+		err := svc.store.Upstreams().UpdateRegistry(ctx, m)
+		if err != nil {
+			if yes, column := dberrors.IsUniqueConstraint(err); yes {
+				return &upstreamResult{
+					statusCode: http.StatusConflict,
+					errMsg:     uniqueConflictMessage(column),
+				}, nil
+			}
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+func (svc *upstreamService) updateAuthConfig(reqCtx context.Context, id string,
+	req *mgmt.UpstreamAuthConfigDTO) (res *upstreamResult, err error) {
+	return svc.update(reqCtx, id, func(ctx context.Context, m *models.UpstreamRegistry) (*upstreamResult, error) {
+		current, err := svc.store.Upstreams().GetRegistryAuthConfig(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		authConfig, err := toAuthConfigModel(id, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil {
+			return nil, svc.store.Upstreams().PersistRegistryAuthConfig(ctx, authConfig)
+		}
+		return nil, svc.store.Upstreams().UpdateRegistryAuthConfig(ctx, authConfig)
+	})
+}
+
+func (svc *upstreamService) updateCacheConfig(reqCtx context.Context, id string,
+	req *mgmt.UpdateUpstreamCacheConfigRequest) (res *upstreamResult, err error) {
+	return svc.update(reqCtx, id, func(ctx context.Context, m *models.UpstreamRegistry) (*upstreamResult, error) {
+		current, err := svc.store.Upstreams().GetRegistryCacheConfig(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		cacheConfig := toCacheConfigModel(id, &req.StorageConfig, &req.CacheConfig)
+		if current == nil {
+			return nil, svc.store.Upstreams().PersistRegistryCacheConfig(ctx, cacheConfig)
+		}
+		return nil, svc.store.Upstreams().UpdateRegistryCacheConfig(ctx, cacheConfig)
+	})
+}
+
+func (svc *upstreamService) updateNetworkConfig(reqCtx context.Context, id string,
+	req *mgmt.UpstreamAccessConfigDTO) (res *upstreamResult, err error) {
+	return svc.update(reqCtx, id, func(ctx context.Context, m *models.UpstreamRegistry) (*upstreamResult, error) {
+		current, err := svc.store.Upstreams().GetRegistryNetworkConfig(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		networkConfig := toNetworkConfigModel(id, req, current)
+		if current == nil {
+			return nil, svc.store.Upstreams().PersistRegistryNetworkConfig(ctx, networkConfig)
+		}
+		return nil, svc.store.Upstreams().UpdateRegistryNetworkConfig(ctx, networkConfig)
+	})
+}
+
+// update applies changes to the upstream registry in a transaction. apply returns a result only if the changes are
+// rejected. The listener of the upstream registry is restarted once the changes are committed because the registry
+// service loads configs only when it is created.
+func (svc *upstreamService) update(reqCtx context.Context, id string,
+	apply func(ctx context.Context, m *models.UpstreamRegistry) (*upstreamResult, error)) (res *upstreamResult,
+	err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to update upstream registry due to transaction errors")
+		return nil, err
+	}
+
+	var m *models.UpstreamRegistry
+
+	defer func() {
+		if err == nil && res.statusCode == http.StatusOK && m.State != constants.ResourceStateDisabled {
+			svc.stopListener(id)
+			svc.startListener(id, m.Name, m.Port)
+		}
+	}()
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	m, err = svc.store.Upstreams().GetRegistry(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update upstream registry due to database errors: %s", id)
+		return nil, err
+	}
+	if m == nil {
+		return &upstreamResult{
+			statusCode: http.StatusNotFound,
+			errMsg:     "Upstream registry not found",
+		}, nil
+	}
+
+	res, err = apply(ctx, m)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update upstream registry: %s", m.Name)
+		return nil, err
+	}
+	if res != nil {
+		return res, nil
+	}
+
+	return &upstreamResult{statusCode: http.StatusOK}, nil
+}
+
+// changeState changes the state of the upstream registry. The listener is stopped when the upstream registry is
+// disabled and started again when it is re-enabled.
+func (svc *upstreamService) changeState(reqCtx context.Context, id, state string) (res *upstreamResult,
+	err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to change state of upstream registry due to transaction errors")
+		return nil, err
+	}
+
+	var m *models.UpstreamRegistry
+	var oldState string
+
+	defer func() {
+		if err != nil || res.statusCode != http.StatusOK || oldState == state {
+			return
+		}
+		if state == constants.ResourceStateDisabled {
+			svc.stopListener(id)
+		} else if oldState == constants.ResourceStateDisabled {
+			svc.startListener(id, m.Name, m.Port)
+		}
+	}()
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	m, err = svc.store.Upstreams().GetRegistry(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to change state of upstream registry due to database errors: %s",
+			id)
+		return nil, err
+	}
+	if m == nil {
+		return &upstreamResult{
+			statusCode: http.StatusNotFound,
+			errMsg:     "Upstream registry not found",
+		}, nil
+	}
+	oldState = m.State
+
+	if oldState == state {
+		log.Logger().Debug().Msgf("No changes in state. Updating state of upstream registry(%s) is skipped", m.Name)
+		return &upstreamResult{statusCode: http.StatusOK}, nil
+	}
+
+	if oldState == constants.ResourceStateDisabled && !svc.portAvailable(m.Port) {
+		return &upstreamResult{
+			statusCode: http.StatusConflict,
+			errMsg:     fmt.Sprintf("Port %d is not available", m.Port),
+		}, nil
+	}
+
+	err = svc.store.Upstreams().ChangeRegistryState(ctx, id, state)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to change state of upstream registry: %s", m.Name)
+		return nil, err
+	}
+
+	return &upstreamResult{statusCode: http.StatusOK}, nil
+}
+
+// deleteUpstream deletes the upstream registry along with its configs and cached content. Files of cached blobs
+// which aren't shared with other repositories are removed as well.
+func (svc *upstreamService) deleteUpstream(ctx context.Context, id string) (*upstreamResult, error) {
+	found, err := svc.evictor.DeleteRegistry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &upstreamResult{
+			statusCode: http.StatusNotFound,
+			errMsg:     "Upstream registry not found",
+		}, nil
+	}
+
+	svc.stopListener(id)
+	return &upstreamResult{statusCode: http.StatusOK}, nil
+}
+
+// listPins returns the pinned images of the upstream registry. nil is returned if the upstream registry doesn't
+// exist.
+func (svc *upstreamService) listPins(ctx context.Context, id string) ([]*models.CachePinModel, error) {
+	m, err := svc.store.Upstreams().GetRegistry(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve upstream registry: %s", id)
+		return nil, err
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	pins, err := svc.store.Cache().ListPins(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to list pinned images of upstream registry: %s", m.Name)
+		return nil, err
+	}
+	return pins, nil
+}
+
+// pin protects the image from eviction. The repository must have been pulled through the upstream registry, but the
+// image needn't be cached yet.
+func (svc *upstreamService) pin(reqCtx context.Context, id string, req *mgmt.PinCachedImageRequest) (
+	res *upstreamResult, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to pin cached image due to transaction errors")
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	res, repoId, err := svc.getCachedRepository(ctx, id, req.Namespace, req.Repository)
+	if err != nil || res != nil {
+		return res, err
+	}
+
+	err = svc.store.Cache().Pin(ctx, id, repoId, req.Reference)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to pin cached image: %s/%s:%s", req.Namespace, req.Repository,
+			req.Reference)
+		return nil, err
+	}
+
+	return &upstreamResult{statusCode: http.StatusCreated}, nil
+}
+
+// unpin removes the pin of the image. The image is evicted like any other image afterwards.
+func (svc *upstreamService) unpin(ctx context.Context, id, namespace, repository, reference string) (
+	*upstreamResult, error) {
+	res, repoId, err := svc.getCachedRepository(ctx, id, namespace, repository)
+	if err != nil || res != nil {
+		return res, err
+	}
+
+	deleted, err := svc.store.Cache().Unpin(ctx, repoId, reference)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to unpin cached image: %s/%s:%s", namespace, repository,
+			reference)
+		return nil, err
+	}
+	if !deleted {
+		return &upstreamResult{
+			statusCode: http.StatusNotFound,
+			errMsg:     "Image is not pinned",
+		}, nil
+	}
+
+	return &upstreamResult{statusCode: http.StatusOK}, nil
+}
+
+// evict enforces the storage limit of the upstream registry. nil is returned if the upstream registry doesn't exist.
+func (svc *upstreamService) evict(ctx context.Context, id string, dryRun bool) (*registry.EvictionReport, error) {
+	m, err := svc.store.Upstreams().GetRegistry(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve upstream registry: %s", id)
+		return nil, err
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	report, err := svc.evictor.Evict(ctx, id, dryRun)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to evict cache of upstream registry: %s", m.Name)
+		return nil, err
+	}
+	// storage of upstream registries without cache config isn't limited
+	if report == nil {
+		report = &registry.EvictionReport{
+			DryRun:    dryRun,
+			Manifests: []*registry.EvictionItem{},
+			Blobs:     []*registry.EvictionItem{},
+		}
+	}
+	return report, nil
+}
+
+// getCachedRepository returns the id of the repository of the upstream registry. A result is returned instead if the
+// upstream registry or the repository doesn't exist.
+func (svc *upstreamService) getCachedRepository(ctx context.Context, id, namespace, repository string) (
+	*upstreamResult, string, error) {
+	m, err := svc.store.Upstreams().GetRegistry(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve upstream registry: %s", id)
+		return nil, "", err
+	}
+	if m == nil {
+		return &upstreamResult{
+			statusCode: http.StatusNotFound,
+			errMsg:     "Upstream registry not found",
+		}, "", nil
+	}
+
+	notFound := &upstreamResult{
+		statusCode: http.StatusNotFound,
+		errMsg:     "Repository not found in upstream registry",
+	}
+
+	nsId, err := svc.store.Namespaces().GetID(ctx, id, namespace)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve namespace: %s", namespace)
+		return nil, "", err
+	}
+	if nsId == "" {
+		return notFound, "", nil
+	}
+
+	repoId, err := svc.store.Repositories().GetID(ctx, nsId, repository)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve repository: %s/%s", namespace, repository)
+		return nil, "", err
+	}
+	if repoId == "" {
+		return notFound, "", nil
+	}
+
+	return nil, repoId, nil
+}
+
+// portAvailable reports whether an upstream registry can listen on the port. The port must not be used by the
+// management API, the hosted registry, other registry listeners or any other process.
+func (svc *upstreamService) portAvailable(port uint) bool {
+	if port == config.GetMgmtServerConfig().Port || port == config.GetImageRegistryConfig().Port {
+		return false
+	}
+
+	if svc.listeners.PortInUse(port) {
+		return false
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		log.Logger().Warn().Err(err).Msgf("Port %d is not available for upstream registries", port)
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+func (svc *upstreamService) startListener(id, name string, port uint) {
+	err := svc.listeners.RegisterListener(id, name, port,
+		registry.NewRegistryHandler(id, name, svc.store, svc.jwtProvider, svc.events).Routes(), 0)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Unable to start listener for upstream registry: %s", name)
+	}
+}
+
+func (svc *upstreamService) stopListener(id string) {
+	err := svc.listeners.UnregisterListener(id, listenerShutdownTimeout)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Unable to stop listener of upstream registry: %s", id)
+	}
+}
+
+func uniqueConflictMessage(column string) string {
+	if column == "PORT" {
+		return "Another upstream registry is available with same port"
+	}
+	return "Another upstream registry is available with same name"
+}
@@ -0,0 +1,183 @@
+package upstream
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+var vendors = []string{
+	constants.RegistryVendorDockerHub, constants.RegistryVendorGCR, constants.RegistryVendorECR,
+	constants.RegistryVendorACR, constants.RegistryVendorGHCR, constants.RegistryVendorGitLab,
+	constants.RegistryVendorQuay, constants.RegistryVendorHarbor, constants.RegistryVendorArtifactory,
+	constants.RegistryVendorNexus, constants.RegistryVendorCustom,
+}
+
+var authTypes = []string{
+	constants.UpstreamAuthTypeAnonymous, constants.UpstreamAuthTypeBasic, constants.UpstreamAuthTypeBearer,
+}
+
+func validateCreateUpstreamRequest(req *mgmt.CreateUpstreamRegistryRequest) (valid bool, errMsg string) {
+	if len(req.Name) < 3 || len(req.Name) > 255 || !utils.IsValidRegistry(req.Name) {
+		return false, "Invalid upstream registry name"
+	}
+
+	if req.Vendor != "" && !slices.Contains(vendors, req.Vendor) {
+		return false, fmt.Sprintf("Invalid vendor: %s", req.Vendor)
+	}
+
+	if req.Status != "" && !isValidState(req.Status) {
+		return false, "Invalid upstream registry state"
+	}
+
+	valid, errMsg = validateUpstream(req.Description, req.Port, req.UpstreamUrl)
+	if !valid {
+		return false, errMsg
+	}
+
+	valid, errMsg = validateAuthConfig(&req.AuthConfig)
+	if !valid {
+		return false, errMsg
+	}
+
+	valid, errMsg = validateNetworkConfig(&req.AccessConfig)
+	if !valid {
+		return false, errMsg
+	}
+
+	return validateCacheConfig(&req.StorageConfig, &req.CacheConfig)
+}
+
+func validateUpdateUpstreamRequest(req *mgmt.UpdateUpstreamRegistryRequest) (valid bool, errMsg string) {
+	return validateUpstream(req.Description, req.Port, req.UpstreamUrl)
+}
+
+func validateUpstream(description string, port int, upstreamURL string) (valid bool, errMsg string) {
+	if len(description) > 1000 {
+		return false, "Description must not exceed 1000 characters"
+	}
+
+	if port < minPort || port > maxPort {
+		return false, fmt.Sprintf("Port must be between %d and %d", minPort, maxPort)
+	}
+
+	u, err := url.Parse(upstreamURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(upstreamURL) > 2048 {
+		return false, "Invalid upstream url"
+	}
+	return true, ""
+}
+
+func validateAuthConfig(req *mgmt.UpstreamAuthConfigDTO) (valid bool, errMsg string) {
+	if !slices.Contains(authTypes, req.AuthType) {
+		return false, fmt.Sprintf("Invalid auth type: %s", req.AuthType)
+	}
+
+	if req.AuthType == constants.UpstreamAuthTypeAnonymous {
+		return true, ""
+	}
+
+	username, _ := req.CredentialJson["username"].(string)
+	password, _ := req.CredentialJson["password"].(string)
+	if username == "" || password == "" {
+		return false, fmt.Sprintf("Username and password are required for auth type: %s", req.AuthType)
+	}
+
+	if req.TokenEndpoint != "" {
+		u, err := url.Parse(req.TokenEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return false, "Invalid token endpoint"
+		}
+	}
+	return true, ""
+}
+
+func validateNetworkConfig(req *mgmt.UpstreamAccessConfigDTO) (valid bool, errMsg string) {
+	if req.ProxyEnabled {
+		return false, "Proxy is not supported"
+	}
+
+	if req.ConnectionTimeoutInSeconds < 0 || req.ConnectionTimeoutInSeconds > 300 {
+		return false, "Connection timeout must be between 1 and 300 seconds"
+	}
+
+	if req.ReadTimeoutInSeconds < 0 || req.ReadTimeoutInSeconds > 600 {
+		return false, "Read timeout must be between 1 and 600 seconds"
+	}
+
+	if req.MaxConnections < 0 || req.MaxConnections > 1000 {
+		return false, "Max connections must be between 1 and 1000"
+	}
+
+	if req.MaxRetries != nil && (*req.MaxRetries < 0 || *req.MaxRetries > 10) {
+		return false, "Max retries must be between 0 and 10"
+	}
+
+	if req.RetryDelayInSeconds < 0 || req.RetryDelayInSeconds > 60 {
+		return false, "Retry delay must be between 1 and 60 seconds"
+	}
+	return true, ""
+}
+
+func validateCacheConfig(storage *mgmt.UpstreamStorageConfigDTO,
+	cache *mgmt.UpstreamCacheConfigDTO) (valid bool, errMsg string) {
+	if cache.OfflineMode && cache.Enabled != nil && !*cache.Enabled {
+		return false, "Offline mode requires caching to be enabled"
+	}
+
+	if cache.TtlInSeconds != 0 && (cache.TtlInSeconds < 60 || cache.TtlInSeconds > 2592000) {
+		return false, "TTL must be between 60 and 2592000 seconds"
+	}
+
+	if storage.StorageLimitInMbs != 0 && storage.StorageLimitInMbs < 1 {
+		return false, "Storage limit must be at least 1 MB"
+	}
+
+	if storage.CleanupThreshold != 0 && (storage.CleanupThreshold < 50 || storage.CleanupThreshold > 95) {
+		return false, "Cleanup threshold must be between 50 and 95 percent"
+	}
+	return true, ""
+}
+
+// tagRegex follows the tag grammar of OCI distribution spec
+var tagRegex = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+
+func validateCachePin(namespace, repository, reference string) (valid bool, errMsg string) {
+	if !utils.IsValidNamespace(namespace) {
+		return false, "Invalid namespace"
+	}
+
+	if !utils.IsValidExistingRepository(repository) {
+		return false, "Invalid repository"
+	}
+
+	if !tagRegex.MatchString(reference) && !utils.IsValidDigest(reference) {
+		return false, "Reference must be a tag or a digest"
+	}
+	return true, ""
+}
+
+func validateListUpstreamCondition(cond *store.ListQueryConditions) (bool, string) {
+	if cond.SortField != "" && !slices.Contains(constants.AllowedUpstreamSortFields, cond.SortField) {
+		return false, fmt.Sprintf("Not allowed sort field: %s", cond.SortField)
+	}
+
+	for _, f := range cond.Filters {
+		if !slices.Contains(constants.AllowedUpstreamFilterFields, f.Field) {
+			return false, fmt.Sprintf("Not allowed filter field: %s", f.Field)
+		}
+	}
+
+	return true, ""
+}
+
+func isValidState(state string) bool {
+	return state == constants.ResourceStateActive || state == constants.ResourceStateDeprecated ||
+		state == constants.ResourceStateDisabled
+}
\ No newline at end of file
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+func toWebhookResponse(m *models.WebhookModel) mgmt.WebhookResponse {
+	return mgmt.WebhookResponse{
+		ID:          m.ID,
+		Name:        m.Name,
+		URL:         m.URL,
+		NamespaceID: m.NamespaceID,
+		Namespace:   m.Namespace,
+		Events:      m.Events,
+		Enabled:     m.Enabled,
+		HasSecret:   m.Secret != "",
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func toWebhookListResponse(webhooks []*models.WebhookModel) mgmt.WebhookListResponse {
+	res := mgmt.WebhookListResponse{
+		Webhooks: make([]mgmt.WebhookResponse, len(webhooks)),
+	}
+	for i, m := range webhooks {
+		res.Webhooks[i] = toWebhookResponse(m)
+	}
+	return res
+}
+
+func toWebhookDeliveryListResponse(deliveries []*models.WebhookDeliveryModel) mgmt.WebhookDeliveryListResponse {
+	res := mgmt.WebhookDeliveryListResponse{
+		Deliveries: make([]mgmt.WebhookDeliveryResponse, len(deliveries)),
+	}
+	for i, m := range deliveries {
+		res.Deliveries[i] = mgmt.WebhookDeliveryResponse{
+			ID:           m.ID,
+			EventID:      m.EventID,
+			Action:       m.Action,
+			Payload:      m.Payload,
+			Status:       m.Status,
+			Attempts:     m.Attempts,
+			ResponseCode: m.ResponseCode,
+			Error:        m.Error,
+			CreatedAt:    m.CreatedAt,
+			UpdatedAt:    m.UpdatedAt,
+		}
+	}
+	return res
+}
\ No newline at end of file
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/httperrors"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// WebhookHandler serves the management API of webhooks receiving registry events. Webhooks can only be managed by
+// administrators.
+type WebhookHandler struct {
+	svc *webhookService
+}
+
+func NewHandler(s store.Store) *WebhookHandler {
+	return &WebhookHandler{
+		svc: &webhookService{
+			store: s,
+		},
+	}
+}
+
+func (h *WebhookHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/", h.createWebhook)
+	r.Get("/", h.listWebhooks)
+
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", h.getWebhook)
+		r.Put("/", h.updateWebhook)
+		r.Delete("/", h.deleteWebhook)
+		r.Get("/deliveries", h.listDeliveries)
+	})
+	return r
+}
+
+func (h *WebhookHandler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.CreateWebhookRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of create webhook request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateCreateWebhookRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	res, err := h.svc.createWebhook(r.Context(), &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if res.statusCode == http.StatusConflict {
+		httperrors.AlreadyExist(w, 409, res.errMsg)
+		return
+	}
+	if res.statusCode == http.StatusUnprocessableEntity {
+		httperrors.UnprocessableEntity(w, 422, res.errMsg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(mgmt.CreateWebhookResponse{ID: res.id})
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+func (h *WebhookHandler) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	webhooks, notFound, err := h.svc.listWebhooks(r.Context(), r.URL.Query().Get("namespace"))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Namespace not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(toWebhookListResponse(webhooks))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+func (h *WebhookHandler) getWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	m, err := h.svc.getWebhook(r.Context(), id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if m == nil {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(toWebhookResponse(m))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
+
+func (h *WebhookHandler) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	var req mgmt.UpdateWebhookRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Parsing request body of update webhook request failed")
+		httperrors.BadRequest(w, 400, "Bad request")
+		return
+	}
+
+	valid, errMsg := validateUpdateWebhookRequest(&req)
+	if !valid {
+		httperrors.BadRequest(w, 400, errMsg)
+		return
+	}
+
+	notFound, err := h.svc.updateWebhook(r.Context(), id, &req)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *WebhookHandler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	notFound, err := h.svc.deleteWebhook(r.Context(), id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *WebhookHandler) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	role, _ := r.Context().Value(constants.ContextRole).(string)
+	if role != constants.RoleAdmin {
+		httperrors.NotAllowed(w, 403, "Only administrators are authorized to perform this operation")
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			httperrors.BadRequest(w, 400, "Invalid limit")
+			return
+		}
+	}
+
+	deliveries, notFound, err := h.svc.listDeliveries(r.Context(), id, limit)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Request aborted due to errors: %s", r.RequestURI)
+		httperrors.InternalError(w, 500, "Request aborted due to errors")
+		return
+	}
+
+	if notFound {
+		httperrors.NotFound(w, 404, "Not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(toWebhookDeliveryListResponse(deliveries))
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occurred when writing response :%s", r.RequestURI)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/errors/dberrors"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+type webhookService struct {
+	store store.Store
+}
+
+type createWebhookResult struct {
+	id         string
+	statusCode int
+	errMsg     string
+}
+
+func (svc *webhookService) createWebhook(reqCtx context.Context,
+	req *mgmt.CreateWebhookRequest) (res *createWebhookResult, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to create webhook due to transaction errors")
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	res = &createWebhookResult{}
+
+	m := &models.WebhookModel{
+		Name:    req.Name,
+		URL:     req.URL,
+		Secret:  req.Secret,
+		Events:  req.Events,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+
+	if req.Namespace != "" {
+		ns, err := svc.store.Namespaces().GetByIdentifier(ctx, constants.HostedRegistryID, req.Namespace)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to create webhook due to database errors: %s", req.Name)
+			return nil, err
+		}
+		if ns == nil {
+			res.statusCode = http.StatusUnprocessableEntity
+			res.errMsg = "Namespace not found"
+			return res, nil
+		}
+		m.NamespaceID = ns.Id
+	}
+
+	res.id, err = svc.store.Webhooks().Create(ctx, m)
+	if err != nil {
+		if yes, _ := dberrors.IsUniqueConstraint(err); yes {
+			res.statusCode = http.StatusConflict
+			res.errMsg = "Another webhook is available with same name"
+			return res, nil
+		}
+		log.Logger().Error().Err(err).Msgf("Failed to create webhook: %s", req.Name)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (svc *webhookService) getWebhook(reqCtx context.Context, id string) (*models.WebhookModel, error) {
+	m, err := svc.store.Webhooks().Get(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to retrieve webhook: %s", id)
+		return nil, err
+	}
+	return m, nil
+}
+
+// listWebhooks lists webhooks of the namespace. All webhooks are listed if namespace is empty.
+func (svc *webhookService) listWebhooks(reqCtx context.Context,
+	namespace string) (webhooks []*models.WebhookModel, notFound bool, err error) {
+	namespaceID := ""
+	if namespace != "" {
+		ns, err := svc.store.Namespaces().GetByIdentifier(reqCtx, constants.HostedRegistryID, namespace)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("Failed to list webhooks due to database errors: %s", namespace)
+			return nil, false, err
+		}
+		if ns == nil {
+			return nil, true, nil
+		}
+		namespaceID = ns.Id
+	}
+
+	webhooks, err = svc.store.Webhooks().List(reqCtx, namespaceID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to list webhooks")
+		return nil, false, err
+	}
+	return webhooks, false, nil
+}
+
+func (svc *webhookService) updateWebhook(reqCtx context.Context, id string,
+	req *mgmt.UpdateWebhookRequest) (notFound bool, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to update webhook due to transaction errors")
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	m, err := svc.store.Webhooks().Get(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update webhook due to database errors: %s", id)
+		return false, err
+	}
+	if m == nil {
+		return true, nil
+	}
+
+	m.URL = req.URL
+	m.Events = req.Events
+	m.Enabled = req.Enabled
+	if req.Secret != nil {
+		m.Secret = *req.Secret
+	}
+
+	err = svc.store.Webhooks().Update(ctx, m)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to update webhook: %s", id)
+		return false, err
+	}
+	return false, nil
+}
+
+func (svc *webhookService) deleteWebhook(reqCtx context.Context, id string) (notFound bool, err error) {
+	tx, err := svc.store.Begin(reqCtx)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to delete webhook due to transaction errors")
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	ctx := store.WithTxContext(reqCtx, tx)
+
+	m, err := svc.store.Webhooks().Get(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to delete webhook due to database errors: %s", id)
+		return false, err
+	}
+	if m == nil {
+		return true, nil
+	}
+
+	err = svc.store.Webhooks().Delete(ctx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to delete webhook: %s", id)
+		return false, err
+	}
+	return false, nil
+}
+
+// listDeliveries returns the latest deliveries of the webhook, most recent first
+func (svc *webhookService) listDeliveries(reqCtx context.Context, id string,
+	limit int) (deliveries []*models.WebhookDeliveryModel, notFound bool, err error) {
+	m, err := svc.store.Webhooks().Get(reqCtx, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to list deliveries due to database errors: %s", id)
+		return nil, false, err
+	}
+	if m == nil {
+		return nil, true, nil
+	}
+
+	deliveries, err = svc.store.Webhooks().ListDeliveries(reqCtx, id, limit)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Failed to list deliveries of webhook: %s", id)
+		return nil, false, err
+	}
+	return deliveries, false, nil
+}
\ No newline at end of file
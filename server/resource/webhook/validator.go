@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+)
+
+var webhookActions = []string{
+	constants.WebhookActionPush,
+	constants.WebhookActionPull,
+	constants.WebhookActionDelete,
+	constants.WebhookActionCreate,
+}
+
+func validateCreateWebhookRequest(req *mgmt.CreateWebhookRequest) (valid bool, errMsg string) {
+	if len(req.Name) < 3 || len(req.Name) > 255 {
+		return false, "Name must be between 3 and 255 characters"
+	}
+	return validateWebhook(req.URL, req.Events)
+}
+
+func validateUpdateWebhookRequest(req *mgmt.UpdateWebhookRequest) (valid bool, errMsg string) {
+	return validateWebhook(req.URL, req.Events)
+}
+
+func validateWebhook(webhookURL string, events []string) (valid bool, errMsg string) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false, "Invalid url"
+	}
+
+	for i, event := range events {
+		if !slices.Contains(webhookActions, event) {
+			return false, fmt.Sprintf("Invalid event: %s", event)
+		}
+		if slices.Contains(events[:i], event) {
+			return false, fmt.Sprintf("Duplicate event: %s", event)
+		}
+	}
+	return true, ""
+}
\ No newline at end of file
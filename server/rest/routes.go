@@ -16,14 +16,17 @@ import (
 	"github.com/ksankeerth/open-image-registry/lib"
 	"github.com/ksankeerth/open-image-registry/log"
 	"github.com/ksankeerth/open-image-registry/middleware"
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/resource"
 	"github.com/ksankeerth/open-image-registry/resource/access"
+	"github.com/ksankeerth/open-image-registry/resource/gc"
 	"github.com/ksankeerth/open-image-registry/store"
 	"github.com/ksankeerth/open-image-registry/user"
 )
 
 func AppRouter(webappConfig *config.WebAppConfig, store store.Store, jwtProvider lib.JWTProvider,
-	accessManager *access.Manager, ec *email.EmailClient) *chi.Mux {
+	accessManager *access.Manager, ec *email.EmailClient, garbageCollector *registry.GarbageCollector,
+	retention *registry.RetentionManager, events *registry.EventDispatcher, evictor *registry.CacheEvictor) *chi.Mux {
 	router := chi.NewRouter()
 
 	// Middleware setup
@@ -43,12 +46,15 @@ func AppRouter(webappConfig *config.WebAppConfig, store store.Store, jwtProvider
 	})))
 
 	router.Use(middleware.EnforceJSON)
+	router.Use(registry.CaptureEventRequest)
 
 	authMiddleware := middleware.NewAuthenticator(store, jwtProvider)
 
 	authHandler := auth.NewAuthAPIHandler(store, jwtProvider, authMiddleware)
 	userHandler := user.NewUserAPIHandler(store, ec)
-	registryResourceHandler := resource.NewRegistryResourceHandler(store, accessManager)
+	registryResourceHandler := resource.NewRegistryResourceHandler(store, jwtProvider, accessManager, retention, events,
+		evictor)
+	gcHandler := gc.NewHandler(garbageCollector)
 
 	// API routes
 	router.Route("/api/v1", func(r chi.Router) {
@@ -56,6 +62,7 @@ func AppRouter(webappConfig *config.WebAppConfig, store store.Store, jwtProvider
 		r.Mount("/users", authMiddleware.Authenticate(userHandler.Routes()))
 		r.Mount("/auth", authHandler.Routes())
 		r.Mount("/resource", authMiddleware.Authenticate(registryResourceHandler.Routes()))
+		r.Mount("/gc", authMiddleware.Authenticate(gcHandler.Routes()))
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 			//TODO: develop health check endpoint later
 			w.WriteHeader(http.StatusOK)
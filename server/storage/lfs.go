@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	storage_errors "github.com/ksankeerth/open-image-registry/errors/storage"
 	"github.com/ksankeerth/open-image-registry/lib"
@@ -63,7 +64,8 @@ func (lfs *localFileStorage) Init() error {
 	}
 }
 
-func (lfs *localFileStorage) ReadFile(location string) ([]byte, error) {
+// Open opens the file for reading. The caller is responsible for closing the returned reader.
+func (lfs *localFileStorage) Open(location string) (io.ReadSeekCloser, error) {
 	targetPath := filepath.Join(lfs.storageDir, location)
 
 	file, err := os.Open(targetPath)
@@ -73,52 +75,45 @@ func (lfs *localFileStorage) ReadFile(location string) ([]byte, error) {
 		} else if errors.Is(err, os.ErrPermission) {
 			log.Logger().Error().Err(err).Msgf("Permission denied to access location: %s.", targetPath)
 		}
-		return nil, err
-	}
-	defer file.Close()
-
-	data, err := io.ReadAll(file)
-	if err != nil {
-		log.Logger().Error().Err(err).Msgf("File: %s exists but error occured when reading the file.", targetPath)
-		return nil, err
+		return nil, storage_errors.ClassifyError(err, "open", targetPath)
 	}
 
-	return data, nil
+	return file, nil
 }
 
-func (lfs *localFileStorage) PutFile(location string, data []byte) error {
+func (lfs *localFileStorage) Create(location string, r io.Reader) (int64, error) {
 	targetPath := filepath.Join(lfs.storageDir, location)
 
 	if !lfs.fileLocks.Lock(targetPath) {
-		return storage_errors.ConcurrentAccessDeniedError("put", targetPath)
+		return 0, storage_errors.ConcurrentAccessDeniedError("create", targetPath)
 	}
 	defer lfs.fileLocks.Unlock(targetPath)
 
 	err := os.MkdirAll(filepath.Dir(targetPath), os.FileMode(DirPermissions))
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Unable to create directories: %s.", filepath.Dir(targetPath))
-		return storage_errors.NotDirectoryError("mkdirall", filepath.Dir(targetPath))
+		return 0, storage_errors.NotDirectoryError("mkdirall", filepath.Dir(targetPath))
 	}
 
 	file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(FilePermissions))
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Unable to create file: %s", targetPath)
-		return storage_errors.ClassifyError(err, "open", targetPath)
+		return 0, storage_errors.ClassifyError(err, "open", targetPath)
 	}
 	defer file.Close()
 
-	n, err := file.Write(data)
-
-	if err != nil || n != len(data) {
+	n, err := io.Copy(file, r)
+	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Unable to write data into file: %s. Partially written file will be removed", targetPath)
+		file.Close()
 		err1 := os.Remove(targetPath)
 		if err1 != nil {
 			log.Logger().Error().Err(err1).Msgf("Removing partially wrritten file failed: %s", targetPath)
 		}
-		return storage_errors.ClassifyError(err, "write", targetPath)
+		return 0, storage_errors.ClassifyError(err, "write", targetPath)
 	}
 
-	return nil
+	return n, nil
 }
 
 func (lfs *localFileStorage) ListFiles(location string) ([]string, error) {
@@ -146,6 +141,28 @@ func (lfs *localFileStorage) ListFiles(location string) ([]string, error) {
 	return files, nil
 }
 
+func (lfs *localFileStorage) ListDirs(location string) ([]string, error) {
+	var dirs []string
+
+	targetPath := filepath.Join(lfs.storageDir, location)
+
+	entries, err := os.ReadDir(targetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dirs, nil
+		}
+		log.Logger().Error().Err(err).Msgf("Unable to read the directory: %s", targetPath)
+		return dirs, storage_errors.ClassifyError(err, "open", targetPath)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs, nil
+}
+
 func (lfs *localFileStorage) RenameFile(oldLocation, newLocation string) error {
 	if oldLocation == newLocation {
 		return nil
@@ -200,18 +217,32 @@ func (lfs *localFileStorage) Size(location string) (int64, error) {
 	return fileInfo.Size(), nil
 }
 
-func (lfs *localFileStorage) PutFileChunk(location string, chunk []byte, offset int64) error {
+func (lfs *localFileStorage) ModTime(location string) (time.Time, error) {
+	targetPath := filepath.Join(lfs.storageDir, location)
+
+	fileInfo, err := os.Stat(targetPath)
+	if err != nil {
+		log.Logger().Error().Err(err).Msgf("Error occured when checking file: %s", targetPath)
+		return time.Time{}, storage_errors.ClassifyError(err, "stat", targetPath)
+	}
+	return fileInfo.ModTime(), nil
+}
+
+// AppendFrom appends the content of r to the file at location. offset must be equal to the
+// current size of the file; a new file will be created when offset is 0 and the file does not exist.
+// If copying fails midway, the file is truncated back to offset so the client can retry the same chunk.
+func (lfs *localFileStorage) AppendFrom(location string, offset int64, r io.Reader) (int64, error) {
 	targetPath := filepath.Join(lfs.storageDir, location)
 
 	if offset < 0 {
 		log.Logger().Warn().Msgf("Offset is negative value for chunk file write")
-		return storage_errors.InvalidOffsetError("put_chunk", targetPath)
+		return 0, storage_errors.InvalidOffsetError("append", targetPath)
 	}
 
 	locked := lfs.fileLocks.Lock(targetPath)
 	if !locked {
 		// We'll let the caller to make decision whether to retry or remove the partial updates
-		return storage_errors.ConcurrentAccessDeniedError("put_chunk", targetPath)
+		return 0, storage_errors.ConcurrentAccessDeniedError("append", targetPath)
 	}
 	defer lfs.fileLocks.Unlock(targetPath)
 
@@ -221,16 +252,15 @@ func (lfs *localFileStorage) PutFileChunk(location string, chunk []byte, offset
 		if os.IsNotExist(err) && offset == 0 {
 			createFile = true
 		} else {
-			createFile = false
 			log.Logger().Error().Err(err).Msgf("Error when checking file: %s", targetPath)
-			return storage_errors.ClassifyError(err, "stat", targetPath)
+			return 0, storage_errors.ClassifyError(err, "stat", targetPath)
 		}
 	}
 
 	if createFile {
 		if err = os.MkdirAll(filepath.Dir(targetPath), DirPermissions); err != nil {
 			log.Logger().Error().Err(err).Msgf("Unable to create directories: %s", filepath.Dir(targetPath))
-			return storage_errors.ClassifyError(err, "mkdirall", filepath.Dir(targetPath))
+			return 0, storage_errors.ClassifyError(err, "mkdirall", filepath.Dir(targetPath))
 		}
 	} else if fileInfo.Size() != offset {
 		// file seems to be corrupted, therefore we'll remove it. So next
@@ -238,39 +268,32 @@ func (lfs *localFileStorage) PutFileChunk(location string, chunk []byte, offset
 		log.Logger().Warn().Msgf("File: %s seems to be corrupted. Threfore It will be removed", targetPath)
 		err1 := os.Remove(targetPath)
 		if err1 != nil {
-			log.Logger().Error().Err(err).Msgf("Removing file: %s failed due to errors.", targetPath)
-			return storage_errors.ClassifyError(err1, "remove", targetPath)
+			log.Logger().Error().Err(err1).Msgf("Removing file: %s failed due to errors.", targetPath)
+			return 0, storage_errors.ClassifyError(err1, "remove", targetPath)
 		}
-		return storage_errors.FileCorruptedError("put_chunk", targetPath)
+		return 0, storage_errors.FileCorruptedError("append", targetPath)
 	}
 
 	file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY, FilePermissions)
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Error occured when opening file: %s", targetPath)
-		return storage_errors.ClassifyError(err, "open", targetPath)
+		return 0, storage_errors.ClassifyError(err, "open", targetPath)
 	}
 	defer file.Close()
 
 	if _, err := file.Seek(offset, io.SeekStart); err != nil {
 		log.Logger().Error().Err(err).Msgf("Error occurred when seeking file: %s, offset: %d", targetPath, offset)
-		return storage_errors.ClassifyError(err, "seek", targetPath)
+		return 0, storage_errors.ClassifyError(err, "seek", targetPath)
 	}
 
-	n, err := file.Write(chunk)
+	n, err := io.Copy(file, r)
 	if err != nil {
 		log.Logger().Error().Err(err).Msgf("Error occured when writing chunk to file: %s, offset: %d", targetPath, offset)
-		return storage_errors.ClassifyError(err, "write", targetPath)
-	}
-
-	if n != len(chunk) {
-		log.Logger().Warn().Msgf("File: %s write is incomplete. Threfore, the file will be removed rather having corrupted file.", targetPath)
-		err = os.Remove(targetPath)
-		if err != nil {
-			log.Logger().Error().Err(err).Msgf("Unable to remove file: %s", targetPath)
-			return storage_errors.ClassifyError(err, "delete", targetPath)
+		if err1 := file.Truncate(offset); err1 != nil {
+			log.Logger().Error().Err(err1).Msgf("Unable to truncate file: %s to offset: %d", targetPath, offset)
 		}
-		return storage_errors.FileCorruptedError("write", targetPath)
+		return 0, storage_errors.ClassifyError(err, "write", targetPath)
 	}
 
-	return nil
-}
+	return n, nil
+}
\ No newline at end of file
@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"io"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/ksankeerth/open-image-registry/config"
 )
@@ -10,19 +12,29 @@ import (
 type BlobStorage interface {
 	Init() error
 
-	ReadFile(location string) ([]byte, error)
+	// Open opens the file at location for reading. The caller must close the returned reader.
+	Open(location string) (io.ReadSeekCloser, error)
 
-	PutFile(location string, data []byte) error
+	// Create writes the content of r into the file at location, replacing any existing content.
+	Create(location string, r io.Reader) (int64, error)
 
 	ListFiles(location string) ([]string, error)
 
+	// ListDirs returns names of the directories at location. A missing location has no directories.
+	ListDirs(location string) ([]string, error)
+
 	RenameFile(oldLocation string, newLocation string) error
 
-	PutFileChunk(location string, chunk []byte, offset int64) error
+	// AppendFrom appends the content of r to the file at location. offset must match the current
+	// size of the file.
+	AppendFrom(location string, offset int64, r io.Reader) (int64, error)
 
 	DeleteFile(location string) error
 
 	Size(location string) (int64, error)
+
+	// ModTime returns the time the file at location was last modified
+	ModTime(location string) (time.Time, error)
 }
 
 var storage BlobStorage
@@ -50,24 +62,28 @@ func Init(config *config.StorageConfig) (err error) {
 	return err
 }
 
-func ReadFile(location string) ([]byte, error) {
-	return storage.ReadFile(location)
+func Open(location string) (io.ReadSeekCloser, error) {
+	return storage.Open(location)
 }
 
-func PutFile(location string, data []byte) error {
-	return storage.PutFile(location, data)
+func Create(location string, r io.Reader) (int64, error) {
+	return storage.Create(location, r)
 }
 
 func ListFiles(location string) ([]string, error) {
 	return storage.ListFiles(location)
 }
 
+func ListDirs(location string) ([]string, error) {
+	return storage.ListDirs(location)
+}
+
 func RenameFile(oldLocation string, newLocation string) error {
 	return storage.RenameFile(oldLocation, newLocation)
 }
 
-func PutFileChunk(location string, chunk []byte, offset int64) error {
-	return storage.PutFileChunk(location, chunk, offset)
+func AppendFrom(location string, offset int64, r io.Reader) (int64, error) {
+	return storage.AppendFrom(location, offset, r)
 }
 
 func DeleteFile(location string) error {
@@ -76,4 +92,8 @@ func DeleteFile(location string) error {
 
 func Size(location string) (int64, error) {
 	return storage.Size(location)
+}
+
+func ModTime(location string) (time.Time, error) {
+	return storage.ModTime(location)
 }
\ No newline at end of file
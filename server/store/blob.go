@@ -9,11 +9,36 @@ import (
 type BlobMetaStore interface {
 	Get(ctx context.Context, digest, repositoryId string) (*models.ImageBlobMetaModel, error)
 
+	// Create links the blob to the repository. Content of the blob is stored once at location and the links
+	// referring it are counted.
 	Create(ctx context.Context, registryId, namespaceId, repositoryId, digest, location string, size int64) (err error)
 
+	// Delete unlinks the blob from the repository. unreferenced is true if no repository refers the content of the
+	// blob anymore; the caller must remove it from storage.
+	Delete(ctx context.Context, repositoryId, digest string) (unreferenced bool, err error)
+
+	// GetContent returns the stored content of the blob regardless of the repositories referring it
+	GetContent(ctx context.Context, digest string) (*models.ImageBlobModel, error)
+
+	// DeleteContent removes the stored content of the blob if no repository links it. deleted is false if the
+	// content is still linked; the caller must keep it in storage.
+	DeleteContent(ctx context.Context, digest string) (deleted bool, err error)
+
+	// Relocate moves the link to the content addressed location of the blob and counts it. It is used to migrate
+	// blobs stored per repository.
+	Relocate(ctx context.Context, repositoryId, digest, location string, size int64) error
+
+	// Touch updates the modified time of the blob so that garbage collection treats it as recently uploaded
+	Touch(ctx context.Context, repositoryId, digest string) error
+
+	// MarkAccessed records a pull of the blob so that least recently pulled blobs of upstream registries are evicted
+	// first
+	MarkAccessed(ctx context.Context, repositoryId, digest string) error
+
 	CreateUploadSession(ctx context.Context, sessionID, namespaceID, repositoryID string) error
 
-	UpdateUploadSession(ctx context.Context, sessionID string, bytesReceived int) error
+	// UpdateUploadSession records the bytes received so far and the states of the running hashes
+	UpdateUploadSession(ctx context.Context, sessionID string, bytesReceived int, sha256State, sha512State []byte) error
 
 	DeleteUploadSession(ctx context.Context, sessionID string) error
 
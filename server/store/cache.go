@@ -15,4 +15,16 @@ type RegistryCacheStore interface {
 	Delete(ctx context.Context, repositoryId, identifier string) (err error)
 
 	Refresh(ctx context.Context, repositoryId, identifier string, expiresAt time.Time) error
+
+	// DeleteByDigest deletes the entries of the tags and the digest referring the manifest
+	DeleteByDigest(ctx context.Context, repositoryId, digest string) error
+
+	// Pin protects the cached image referred by reference (a tag or a digest) from eviction. Pinning an image twice
+	// has no effect.
+	Pin(ctx context.Context, registryId, repositoryId, reference string) error
+
+	// Unpin removes the pin. deleted is false if the image wasn't pinned.
+	Unpin(ctx context.Context, repositoryId, reference string) (deleted bool, err error)
+
+	ListPins(ctx context.Context, registryId string) ([]*models.CachePinModel, error)
 }
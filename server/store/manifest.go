@@ -15,4 +15,16 @@ type ManifestStore interface {
 	GetByDigest(ctx context.Context, withContent bool, repositoryId, digest string) (*models.ImageManifestModel, error)
 
 	DeleteByDigest(ctx context.Context, repositoryId, digest string) error
+
+	// MarkAccessed records a pull of the manifest so that least recently pulled manifests of upstream registries are
+	// evicted first
+	MarkAccessed(ctx context.Context, repositoryId, digest string) error
+
+	// CreateReferrer records that the manifest refers the subject manifest
+	CreateReferrer(ctx context.Context, manifestId, repositoryId, subjectDigest, artifactType string) error
+
+	// ListReferrers returns manifests referring the subject. If artifactType is not empty, only the referrers
+	// of the given artifact type are returned.
+	ListReferrers(ctx context.Context, repositoryId, subjectDigest, artifactType string) ([]*models.ImageReferrerModel,
+		error)
 }
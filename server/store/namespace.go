@@ -34,6 +34,18 @@ type NamespaceStore interface {
 
 	List(ctx context.Context, conditions *ListQueryConditions) (users []*models.NamespaceView, total int, err error)
 
+	// GetQuota returns the quota of the namespace along with the usage of all its repositories
+	GetQuota(ctx context.Context, id string) (*models.QuotaModel, error)
+
+	// SetQuota replaces the quota of the namespace. nil limits are unlimited.
+	SetQuota(ctx context.Context, id string, maxBytes, maxImages *int64) error
+
+	// GetRetentionRules returns retention rules of the namespace ordered by tag pattern
+	GetRetentionRules(ctx context.Context, id string) ([]*models.RetentionRuleModel, error)
+
+	// SetRetentionRules replaces retention rules of the namespace
+	SetRetentionRules(ctx context.Context, id string, rules []*models.RetentionRuleModel) error
+
 	// DeleteAll deletes all the records from table. The implementation should only this method only
 	// when `testing.allow_delete_all` is set to true.
 	DeleteAll(ctx context.Context) error
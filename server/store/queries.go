@@ -24,4 +24,45 @@ type ImageQueries interface {
 	GetManifestByTag(ctx context.Context, withContent bool, repositoryId, tag string) (*models.ImageManifestModel, error)
 
 	GetRepositoryByNames(ctx context.Context, namespace, repository string) (*models.RepositoryModel, error)
+
+	// ListRepositoryNames returns `namespace/repository` names of the registry in lexical order, starting after
+	// `last`. Unless includeAll is true, only the repositories the user can pull from are returned. If limit is
+	// less than 1, all remaining names will be returned.
+	ListRepositoryNames(ctx context.Context, registryId, userId string, includeAll bool, last string,
+		limit int) ([]string, error)
+
+	// ListIdleUploadSessions returns the blob upload sessions which haven't been updated for idleSeconds
+	ListIdleUploadSessions(ctx context.Context, idleSeconds int64) ([]*models.IdleUploadSessionModel, error)
+
+	// ListGCManifests returns all manifests of the registry. Manifests created within graceSeconds are marked recent.
+	ListGCManifests(ctx context.Context, registryId string, graceSeconds int64) ([]*models.GCManifestModel, error)
+
+	// ListGCBlobs returns all blobs of the registry. Blobs updated within graceSeconds are marked recent.
+	ListGCBlobs(ctx context.Context, registryId string, graceSeconds int64) ([]*models.GCBlobModel, error)
+
+	// ListUnlinkedBlobContents returns stored content of blobs which aren't linked to any repository. Links are
+	// counted rather than relying on the reference count.
+	ListUnlinkedBlobContents(ctx context.Context) ([]*models.ImageBlobModel, error)
+
+	// GetCacheUsage returns the bytes of the manifests and the blobs cached from the upstream registry. Content of a
+	// blob cached for many repositories is counted once.
+	GetCacheUsage(ctx context.Context, registryId string) (int64, error)
+
+	// ListCachedManifests returns the manifests cached from the upstream registry along with their last access
+	ListCachedManifests(ctx context.Context, registryId string) ([]*models.CachedManifestModel, error)
+
+	// ListCachedBlobs returns the blobs cached for repositories of the upstream registry along with their last access
+	ListCachedBlobs(ctx context.Context, registryId string) ([]*models.CachedBlobModel, error)
+
+	// ListRetentionRepositories returns the repositories of the registry which have retention rules of their own or
+	// of their namespace. Empty namespaceId or repositoryId doesn't filter by them.
+	ListRetentionRepositories(ctx context.Context, registryId, namespaceId,
+		repositoryId string) ([]*models.RetentionRepositoryModel, error)
+
+	// ListRetentionTags returns tags of the repository along with the digests they point to, most recently pushed
+	// first
+	ListRetentionTags(ctx context.Context, repositoryId string) ([]*models.RetentionTagModel, error)
+
+	// ListUnmigratedBlobs returns the blobs which are not stored at their content addressed location yet
+	ListUnmigratedBlobs(ctx context.Context) ([]*models.ImageBlobMetaModel, error)
 }
@@ -36,6 +36,24 @@ type RepositoryStore interface {
 	// identifier can be name or id
 	DeleteByIdentifier(ctx context.Context, namesapceID, identifier string) error
 
+	// GetImmutableTagPatterns returns patterns of tags which can't be moved to another manifest once pushed
+	GetImmutableTagPatterns(ctx context.Context, id string) (patterns []string, err error)
+
+	// SetImmutableTagPatterns replaces immutable tag patterns of the repository
+	SetImmutableTagPatterns(ctx context.Context, id string, patterns []string) error
+
+	// GetQuota returns the quota of the repository along with its usage
+	GetQuota(ctx context.Context, id string) (*models.QuotaModel, error)
+
+	// SetQuota replaces the quota of the repository. nil limits are unlimited.
+	SetQuota(ctx context.Context, id string, maxBytes, maxImages *int64) error
+
+	// GetRetentionRules returns retention rules of the repository ordered by tag pattern
+	GetRetentionRules(ctx context.Context, id string) ([]*models.RetentionRuleModel, error)
+
+	// SetRetentionRules replaces retention rules of the repository
+	SetRetentionRules(ctx context.Context, id string, rules []*models.RetentionRuleModel) error
+
 	// identifier can be name or id
 	GetByIdentifier(ctx context.Context, namesapceID, identifier string) (*models.RepositoryModel, error)
 }
\ No newline at end of file
@@ -28,7 +28,9 @@ func (b *blobMetaStore) getQuerier(ctx context.Context) store.Querier {
 }
 
 func (b *blobMetaStore) Get(ctx context.Context, digest, repositoryId string) (*models.ImageBlobMetaModel, error) {
-	row := b.db.QueryRowContext(ctx, BlobMetaGetQuery, repositoryId, digest)
+	q := b.getQuerier(ctx)
+
+	row := q.QueryRowContext(ctx, BlobMetaGetQuery, repositoryId, digest)
 
 	var m models.ImageBlobMetaModel
 	err := row.Scan(
@@ -53,13 +55,134 @@ func (b *blobMetaStore) Get(ctx context.Context, digest, repositoryId string) (*
 }
 
 func (b *blobMetaStore) Create(ctx context.Context, registryId, namespaceId, repositoryId, digest, location string, size int64) (err error) {
-	_, err = b.db.ExecContext(ctx, BlobMetaCreateQuery,
+	q := b.getQuerier(ctx)
+
+	_, err = q.ExecContext(ctx, BlobMetaCreateQuery,
 		namespaceId, registryId, repositoryId, digest, size, location,
 	)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("failed to create image blob meta")
 		return dberrors.ClassifyError(err, BlobMetaCreateQuery)
 	}
+
+	_, err = q.ExecContext(ctx, BlobContentLinkQuery, digest, size, location)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to link image blob")
+		return dberrors.ClassifyError(err, BlobContentLinkQuery)
+	}
+	return nil
+}
+
+func (b *blobMetaStore) Delete(ctx context.Context, repositoryId, digest string) (unreferenced bool, err error) {
+	q := b.getQuerier(ctx)
+
+	res, err := q.ExecContext(ctx, BlobMetaDeleteQuery, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete image blob meta")
+		return false, dberrors.ClassifyError(err, BlobMetaDeleteQuery)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete image blob meta")
+		return false, dberrors.ClassifyError(err, BlobMetaDeleteQuery)
+	}
+	if deleted == 0 {
+		return false, nil
+	}
+
+	// content is unlinked by trg_unlink_image_blob
+	var refCount int
+	err = q.QueryRowContext(ctx, BlobContentRefsQuery, digest).Scan(&refCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// content is not shared
+			return true, nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to unlink image blob")
+		return false, dberrors.ClassifyError(err, BlobContentRefsQuery)
+	}
+	if refCount > 0 {
+		return false, nil
+	}
+
+	_, err = q.ExecContext(ctx, BlobContentDeleteQuery, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete image blob")
+		return false, dberrors.ClassifyError(err, BlobContentDeleteQuery)
+	}
+	return true, nil
+}
+
+func (b *blobMetaStore) GetContent(ctx context.Context, digest string) (*models.ImageBlobModel, error) {
+	q := b.getQuerier(ctx)
+
+	var m models.ImageBlobModel
+	err := q.QueryRowContext(ctx, BlobContentGetQuery, digest).Scan(&m.Digest, &m.Size, &m.Location, &m.RefCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to retrieve image blob")
+		return nil, dberrors.ClassifyError(err, BlobContentGetQuery)
+	}
+
+	return &m, nil
+}
+
+func (b *blobMetaStore) DeleteContent(ctx context.Context, digest string) (deleted bool, err error) {
+	q := b.getQuerier(ctx)
+
+	res, err := q.ExecContext(ctx, BlobContentDeleteUnlinkedQuery, digest, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete image blob")
+		return false, dberrors.ClassifyError(err, BlobContentDeleteUnlinkedQuery)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete image blob")
+		return false, dberrors.ClassifyError(err, BlobContentDeleteUnlinkedQuery)
+	}
+	return rows > 0, nil
+}
+
+func (b *blobMetaStore) Relocate(ctx context.Context, repositoryId, digest, location string, size int64) error {
+	q := b.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, BlobMetaRelocateQuery, location, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to relocate image blob meta")
+		return dberrors.ClassifyError(err, BlobMetaRelocateQuery)
+	}
+
+	_, err = q.ExecContext(ctx, BlobContentLinkQuery, digest, size, location)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to link image blob")
+		return dberrors.ClassifyError(err, BlobContentLinkQuery)
+	}
+	return nil
+}
+
+func (b *blobMetaStore) Touch(ctx context.Context, repositoryId, digest string) error {
+	q := b.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, BlobMetaTouchQuery, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to touch image blob meta")
+		return dberrors.ClassifyError(err, BlobMetaTouchQuery)
+	}
+	return nil
+}
+
+func (b *blobMetaStore) MarkAccessed(ctx context.Context, repositoryId, digest string) error {
+	q := b.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, BlobMetaMarkAccessedQuery, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to mark image blob meta accessed")
+		return dberrors.ClassifyError(err, BlobMetaMarkAccessedQuery)
+	}
 	return nil
 }
 
@@ -75,10 +198,11 @@ func (b *blobMetaStore) CreateUploadSession(ctx context.Context, sessionID, name
 	return nil
 }
 
-func (b *blobMetaStore) UpdateUploadSession(ctx context.Context, sessionID string, bytesReceived int) error {
+func (b *blobMetaStore) UpdateUploadSession(ctx context.Context, sessionID string, bytesReceived int, sha256State,
+	sha512State []byte) error {
 	q := b.getQuerier(ctx)
 
-	_, err := q.ExecContext(ctx, BlobSessionUpdateQuery, bytesReceived, sessionID)
+	_, err := q.ExecContext(ctx, BlobSessionUpdateQuery, bytesReceived, sha256State, sha512State, sessionID)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("failed to update image blob upload session")
 		return dberrors.ClassifyError(err, BlobSessionUpdateQuery)
@@ -106,7 +230,7 @@ func (b *blobMetaStore) GetUploadSession(ctx context.Context, sessionID string)
 
 	var session models.ImageBlobUploadSessionModel
 	err := q.QueryRowContext(ctx, BlobSessionGetQuery, sessionID).Scan(&session.SessionID, &session.NamespaceID,
-		&session.RepositoryID, &session.BytesReceived, &createdAt, &updatedAt)
+		&session.RepositoryID, &session.BytesReceived, &session.SHA256State, &session.SHA512State, &createdAt, &updatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
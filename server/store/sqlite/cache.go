@@ -115,3 +115,84 @@ func (c *registryCacheStore) Refresh(ctx context.Context, repositoryId, identifi
 
 	return nil
 }
+
+func (c *registryCacheStore) DeleteByDigest(ctx context.Context, repositoryId, digest string) error {
+	q := c.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, CacheDeleteByDigestQuery, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete registry cache entries of manifest")
+		return dberrors.ClassifyError(err, CacheDeleteByDigestQuery)
+	}
+
+	return nil
+}
+
+func (c *registryCacheStore) Pin(ctx context.Context, registryId, repositoryId, reference string) error {
+	q := c.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, CachePinQuery, registryId, repositoryId, reference)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to pin cached image")
+		return dberrors.ClassifyError(err, CachePinQuery)
+	}
+
+	return nil
+}
+
+func (c *registryCacheStore) Unpin(ctx context.Context, repositoryId, reference string) (deleted bool, err error) {
+	q := c.getQuerier(ctx)
+
+	res, err := q.ExecContext(ctx, CacheUnpinQuery, repositoryId, reference)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to unpin cached image")
+		return false, dberrors.ClassifyError(err, CacheUnpinQuery)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to unpin cached image")
+		return false, dberrors.ClassifyError(err, CacheUnpinQuery)
+	}
+
+	return rows > 0, nil
+}
+
+func (c *registryCacheStore) ListPins(ctx context.Context, registryId string) ([]*models.CachePinModel, error) {
+	q := c.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, CacheListPinsQuery, registryId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list pinned images")
+		return nil, dberrors.ClassifyError(err, CacheListPinsQuery)
+	}
+	defer rows.Close()
+
+	pins := []*models.CachePinModel{}
+	for rows.Next() {
+		var m models.CachePinModel
+		var createdAt string
+		if err := rows.Scan(&m.RepositoryID, &m.Namespace, &m.Repository, &m.Reference, &createdAt); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan pinned image")
+			return nil, dberrors.ClassifyError(err, CacheListPinsQuery)
+		}
+
+		createdTime, err := utils.ParseSqliteTimestamp(createdAt)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to parse created_at")
+			return nil, dberrors.ClassifyError(err, CacheListPinsQuery)
+		}
+		if createdTime != nil {
+			m.CreatedAt = *createdTime
+		}
+
+		pins = append(pins, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate pinned images")
+		return nil, dberrors.ClassifyError(err, CacheListPinsQuery)
+	}
+
+	return pins, nil
+}
\ No newline at end of file
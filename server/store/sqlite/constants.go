@@ -3,7 +3,7 @@ package sqlite
 const (
 	NamespaceCreateQuery             = `INSERT INTO REGISTRY_NAMESPACE (REGISTRY_ID, NAME, DESCRIPTION, PURPOSE, IS_PUBLIC, CREATED_BY) VALUES (?, ?, ?, ?, ?, ?) RETURNING ID`
 	NamespaceGetQuery                = `SELECT REGISTRY_ID, NAME, DESCRIPTION, PURPOSE, IS_PUBLIC, STATE, CREATED_AT, UPDATED_AT, CREATED_BY FROM REGISTRY_NAMESPACE WHERE ID = ?`
-	NamespaceGetByNameQuery          = `SELECT REGISTRY_ID, NAME, DESCRIPTION, PURPOSE, IS_PUBLIC, STATE, CREATED_AT, UPDATED_AT, CREATED_BY FROM REGISTRY_NAMESPACE WHERE REGISTRY_ID = ? AND NAME = ?`
+	NamespaceGetByNameQuery          = `SELECT ID, REGISTRY_ID, NAME, DESCRIPTION, PURPOSE, IS_PUBLIC, STATE, CREATED_AT, UPDATED_AT, CREATED_BY FROM REGISTRY_NAMESPACE WHERE REGISTRY_ID = ? AND NAME = ?`
 	NamespaceGetIDQuery              = `SELECT ID FROM REGISTRY_NAMESPACE WHERE REGISTRY_ID = ? AND NAME = ?`
 	NamespaceDeleteQuery             = `DELETE FROM REGISTRY_NAMESPACE WHERE REGISTRY_ID = ? AND ID = ?`
 	NamespaceDeleteByIdentifierQuery = `DELETE FROM REGISTRY_NAMESPACE WHERE REGISTRY_ID = ? AND (ID = ? OR NAME = ?)`
@@ -13,6 +13,15 @@ const (
 	NamespaceSetStateQuery           = `UPDATE REGISTRY_NAMESPACE SET STATE = ? WHERE ID = ?`
 	NamespaceUpdateQuery             = `UPDATE REGISTRY_NAMESPACE SET DESCRIPTION = ?, PURPOSE = ? WHERE ID = ?`
 	NamespaceSetVisiblityQuery       = `UPDATE REGISTRY_NAMESPACE SET IS_PUBLIC = ? WHERE ID = ?`
+	NamespaceGetQuotaQuery           = `SELECT q.MAX_BYTES, q.MAX_IMAGES,
+	(SELECT COALESCE(SUM(SIZE), 0) FROM IMAGE_BLOB_META WHERE NAMESPACE_ID = ?) + (SELECT COALESCE(SUM(SIZE), 0) FROM IMAGE_MANIFEST WHERE NAMESPACE_ID = ?),
+	(SELECT COUNT(*) FROM IMAGE_MANIFEST m WHERE m.NAMESPACE_ID = ? AND EXISTS (SELECT 1 FROM IMAGE_MANIFEST_TAG_MAPPING mt WHERE mt.MANIFEST_ID = m.ID))
+	FROM (SELECT 1) LEFT JOIN REGISTRY_NAMESPACE_QUOTA q ON q.NAMESPACE_ID = ?`
+	NamespaceSetQuotaQuery             = `INSERT INTO REGISTRY_NAMESPACE_QUOTA(NAMESPACE_ID, MAX_BYTES, MAX_IMAGES) VALUES(?, ?, ?) ON CONFLICT(NAMESPACE_ID) DO UPDATE SET MAX_BYTES = excluded.MAX_BYTES, MAX_IMAGES = excluded.MAX_IMAGES, UPDATED_AT = CURRENT_TIMESTAMP`
+	NamespaceDeleteQuotaQuery          = `DELETE FROM REGISTRY_NAMESPACE_QUOTA WHERE NAMESPACE_ID = ?`
+	NamespaceGetRetentionRulesQuery    = `SELECT TAG_PATTERN, KEEP_LAST, MAX_AGE_DAYS FROM REGISTRY_NAMESPACE_RETENTION_RULE WHERE NAMESPACE_ID = ? ORDER BY TAG_PATTERN`
+	NamespaceDeleteRetentionRulesQuery = `DELETE FROM REGISTRY_NAMESPACE_RETENTION_RULE WHERE NAMESPACE_ID = ?`
+	NamespaceAddRetentionRuleQuery     = `INSERT INTO REGISTRY_NAMESPACE_RETENTION_RULE(NAMESPACE_ID, TAG_PATTERN, KEEP_LAST, MAX_AGE_DAYS) VALUES(?, ?, ?, ?)`
 	// IMPORTANT: Base list query avoids WHERE keywords because if we have it in one of the subquery, it will confuse query
 	// builder. Current query builder checks WHERE keyword exists or not (not intelligent enough to understand sub queries)
 	// then append WHERE at the end of base if needed
@@ -51,13 +60,25 @@ const (
 	RepositoryUpdateQuery                  = `UPDATE REGISTRY_REPOSITORY SET DESCRIPTION = ? WHERE ID = ?`
 	RepositoryGetIDQuery                   = `SELECT ID FROM REGISTRY_REPOSITORY WHERE NAMESPACE_ID = ? AND NAME = ?`
 	RepositoryExistsQuery                  = `SELECT 1 FROM REGISTRY_REPOSITORY WHERE ID = ?`
-	RepositoryGetByIdentifierQuery         = `SELECT ID, NAME, DESCRIPTION, IS_PUBLIC, STATE, NAMESPACE_ID, REGISTRY_ID, CREATED_AT, UPDATED_AT, CREATED_BY  WHERE NAMESPACE_ID = ? AND (ID = ? OR NAME = ?)`
+	RepositoryGetByIdentifierQuery         = `SELECT ID, NAME, DESCRIPTION, IS_PUBLIC, STATE, NAMESPACE_ID, REGISTRY_ID, CREATED_AT, UPDATED_AT, CREATED_BY FROM REGISTRY_REPOSITORY WHERE NAMESPACE_ID = ? AND (ID = ? OR NAME = ?)`
 	RepositoryExistsByIdentifierQuery      = `SELECT 1 FROM REGISTRY_REPOSITORY WHERE NAMESPACE_ID = ? AND (ID = ? OR NAME = ?)`
 	RepositoryDeleteByIdentifierQuery      = `DELETE FROM REGISTRY_REPOSITORY WHERE NAMESPACE_ID = ? AND (ID = ? OR NAME = ?)`
 	RepositorySetStateQuery                = `UPDATE REGISTRY_REPOSITORY SET STATE = ? WHERE ID = ?`
 	RepositorySetVisiblityQuery            = `UPDATE REGISTRY_REPOSITORY SET IS_PUBLIC = ? WHERE ID = ?`
 	RepositorySetStateByNamespaceQuery     = `UPDATE REGISTRY_REPOSITORY SET STATE = ? WHERE NAMESPACE_ID = ?`
 	RepositorySetVisiblityByNamespaceQuery = `UPDATE REGISTRY_REPOSITORY SET IS_PUBLIC = ? WHERE NAMESPACE_ID = ?`
+	RepositoryGetImmutableTagsQuery        = `SELECT PATTERN FROM REGISTRY_REPOSITORY_IMMUTABLE_TAG WHERE REPOSITORY_ID = ? ORDER BY PATTERN`
+	RepositoryDeleteImmutableTagsQuery     = `DELETE FROM REGISTRY_REPOSITORY_IMMUTABLE_TAG WHERE REPOSITORY_ID = ?`
+	RepositoryAddImmutableTagQuery         = `INSERT INTO REGISTRY_REPOSITORY_IMMUTABLE_TAG(REPOSITORY_ID, PATTERN) VALUES(?, ?) ON CONFLICT DO NOTHING`
+	RepositoryGetQuotaQuery                = `SELECT q.MAX_BYTES, q.MAX_IMAGES,
+	(SELECT COALESCE(SUM(SIZE), 0) FROM IMAGE_BLOB_META WHERE REPOSITORY_ID = ?) + (SELECT COALESCE(SUM(SIZE), 0) FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ?),
+	(SELECT COUNT(*) FROM IMAGE_MANIFEST m WHERE m.REPOSITORY_ID = ? AND EXISTS (SELECT 1 FROM IMAGE_MANIFEST_TAG_MAPPING mt WHERE mt.MANIFEST_ID = m.ID))
+	FROM (SELECT 1) LEFT JOIN REGISTRY_REPOSITORY_QUOTA q ON q.REPOSITORY_ID = ?`
+	RepositorySetQuotaQuery             = `INSERT INTO REGISTRY_REPOSITORY_QUOTA(REPOSITORY_ID, MAX_BYTES, MAX_IMAGES) VALUES(?, ?, ?) ON CONFLICT(REPOSITORY_ID) DO UPDATE SET MAX_BYTES = excluded.MAX_BYTES, MAX_IMAGES = excluded.MAX_IMAGES, UPDATED_AT = CURRENT_TIMESTAMP`
+	RepositoryDeleteQuotaQuery          = `DELETE FROM REGISTRY_REPOSITORY_QUOTA WHERE REPOSITORY_ID = ?`
+	RepositoryGetRetentionRulesQuery    = `SELECT TAG_PATTERN, KEEP_LAST, MAX_AGE_DAYS FROM REGISTRY_REPOSITORY_RETENTION_RULE WHERE REPOSITORY_ID = ? ORDER BY TAG_PATTERN`
+	RepositoryDeleteRetentionRulesQuery = `DELETE FROM REGISTRY_REPOSITORY_RETENTION_RULE WHERE REPOSITORY_ID = ?`
+	RepositoryAddRetentionRuleQuery     = `INSERT INTO REGISTRY_REPOSITORY_RETENTION_RULE(REPOSITORY_ID, TAG_PATTERN, KEEP_LAST, MAX_AGE_DAYS) VALUES(?, ?, ?, ?)`
 	// IMPORTANT: Base list query avoids WHERE keywords because if we have it in one of the subquery, it will confuse query
 	// builder. Current query builder checks WHERE keyword exists or not (not intelligent enough to understand sub queries)
 	// then append WHERE at the end of base if needed
@@ -85,13 +106,18 @@ const (
 )
 
 const (
-	TagCreateQuery         = `INSERT INTO IMAGE_TAG(REGISTRY_ID, NAMESPACE_ID, REPOSITORY_ID, IS_STABLE, TAG) VALUES(?, ?, ?, ?, ?) RETURNING ID`
-	TagGetQuery            = `SELECT REGISTRY_ID, NAMESPACE_ID, ID, TAG, IS_STABLE, CREATED_AT, UPDATED_AT FROM IMAGE_TAG WHERE REPOSITORY_ID = ? AND TAG = ?`
-	TagDeleteQuery         = `DELETE FROM IMAGE_TAG WHERE REPOSITORY_ID = ? AND TAG = ?`
-	TagLinkManifestQuery   = `INSERT INTO IMAGE_MANIFEST_TAG_MAPPING(MANIFEST_ID, TAG_ID) VALUES(?, ?)`
-	TagUpdateManifestQuery = `UPDATE IMAGE_MANIFEST_TAG_MAPPING SET MANIFEST_ID = ? WHERE TAG_ID = ?`
+	TagCreateQuery        = `INSERT INTO IMAGE_TAG(REGISTRY_ID, NAMESPACE_ID, REPOSITORY_ID, TAG, PUSHED_AT) VALUES(?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING ID`
+	TagGetQuery           = `SELECT ID, REGISTRY_ID, NAMESPACE_ID, REPOSITORY_ID, TAG, IS_STABLE, CREATED_AT, UPDATED_AT FROM IMAGE_TAG WHERE REPOSITORY_ID = ? AND TAG = ?`
+	TagDeleteQuery        = `DELETE FROM IMAGE_TAG WHERE REPOSITORY_ID = ? AND TAG = ?`
+	TagDeleteMappingQuery = `DELETE FROM IMAGE_MANIFEST_TAG_MAPPING WHERE TAG_ID IN (SELECT ID FROM IMAGE_TAG WHERE REPOSITORY_ID = ? AND TAG = ?)`
+	TagLinkManifestQuery  = `INSERT INTO IMAGE_MANIFEST_TAG_MAPPING(MANIFEST_ID, TAG_ID) VALUES(?, ?)
+		ON CONFLICT(TAG_ID) DO UPDATE SET MANIFEST_ID = excluded.MANIFEST_ID`
 	TagUnlinkManifestQuery = `DELETE FROM IMAGE_MANIFEST_TAG_MAPPING WHERE TAG_ID = ?`
 	TagGetManifestID       = `SELECT MANIFEST_ID FROM IMAGE_MANIFEST_TAG_MAPPING WHERE TAG_ID = ?`
+	TagListByManifestQuery = `SELECT it.TAG, it.IS_STABLE FROM IMAGE_TAG it JOIN IMAGE_MANIFEST_TAG_MAPPING mt ON mt.TAG_ID = it.ID WHERE mt.MANIFEST_ID = ? ORDER BY it.TAG`
+	TagListQuery           = `SELECT TAG FROM IMAGE_TAG WHERE REPOSITORY_ID = ? AND TAG > ? ORDER BY TAG LIMIT ?`
+	TagSetStableQuery      = `UPDATE IMAGE_TAG SET IS_STABLE = ?, UPDATED_AT = CURRENT_TIMESTAMP WHERE ID = ?`
+	TagTouchQuery          = `UPDATE IMAGE_TAG SET PUSHED_AT = CURRENT_TIMESTAMP, UPDATED_AT = CURRENT_TIMESTAMP WHERE ID = ?`
 )
 
 const (
@@ -104,29 +130,55 @@ const (
 )
 
 const (
-	ManifestCreateQuery                       = `INSERT INTO IMAGE_MANIFEST(DIGEST, SIZE, MEDIA_TYPE, MANIFEST_CONTENT, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`
-	ManifestGetbyUniqueDigestWithContentQuery = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, MANIFEST_CONTENT, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE UNIQUE_DIGEST = ?`
-	ManifestGetbyUniqueDigestQuery            = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE UNIQUE_DIGEST = ?`
-	ManifestGetbyDigestWithContentQuery       = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, MANIFEST_CONTENT, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE DIGEST = ?`
-	ManifestGetbyDigestQuery                  = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE UNIQUE_DIGEST = ?`
+	ManifestCreateQuery                       = `INSERT INTO IMAGE_MANIFEST(DIGEST, SIZE, MEDIA_TYPE, MANIFEST_CONTENT, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST) VALUES(?, ?, ?, ?, ?, ?, ?, ?) RETURNING ID`
+	ManifestGetbyUniqueDigestWithContentQuery = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, MANIFEST_CONTENT, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ? AND UNIQUE_DIGEST = ?`
+	ManifestGetbyUniqueDigestQuery            = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ? AND UNIQUE_DIGEST = ?`
+	ManifestGetbyDigestWithContentQuery       = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, MANIFEST_CONTENT, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ? AND DIGEST = ?`
+	ManifestGetbyDigestQuery                  = `SELECT ID, DIGEST, SIZE, MEDIA_TYPE, NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, UNIQUE_DIGEST, CREATED_AT, UPDATED_AT FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ? AND DIGEST = ?`
+	ManifestDeleteTagsByDigest                = `DELETE FROM IMAGE_TAG WHERE ID IN (SELECT imtm.TAG_ID FROM IMAGE_MANIFEST_TAG_MAPPING imtm JOIN IMAGE_MANIFEST im ON im.ID = imtm.MANIFEST_ID WHERE im.REPOSITORY_ID = ? AND im.DIGEST = ?)`
+	ManifestUnlinkTagsByDigest                = `DELETE FROM IMAGE_MANIFEST_TAG_MAPPING WHERE MANIFEST_ID IN (SELECT ID FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ? AND DIGEST = ?)`
 	ManifestDeleteByDigest                    = `DELETE FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ? AND DIGEST = ?`
+	ManifestDeleteReferrerByDigest            = `DELETE FROM IMAGE_MANIFEST_REFERRER WHERE MANIFEST_ID IN (SELECT ID FROM IMAGE_MANIFEST WHERE REPOSITORY_ID = ? AND DIGEST = ?)`
+	ManifestMarkAccessedQuery                 = `UPDATE IMAGE_MANIFEST SET LAST_ACCESSED_AT = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE REPOSITORY_ID = ? AND DIGEST = ?`
+	ManifestCreateReferrerQuery               = `INSERT OR IGNORE INTO IMAGE_MANIFEST_REFERRER(MANIFEST_ID, REPOSITORY_ID, SUBJECT_DIGEST, ARTIFACT_TYPE) VALUES(?, ?, ?, ?)`
+	ManifestListReferrersQuery                = `SELECT im.DIGEST, im.SIZE, im.MEDIA_TYPE, imr.ARTIFACT_TYPE, im.MANIFEST_CONTENT FROM IMAGE_MANIFEST_REFERRER imr JOIN IMAGE_MANIFEST im ON im.ID = imr.MANIFEST_ID WHERE imr.REPOSITORY_ID = ? AND imr.SUBJECT_DIGEST = ? AND (? = '' OR imr.ARTIFACT_TYPE = ?) ORDER BY imr.CREATED_AT, im.DIGEST`
 )
 
 const (
-	BlobMetaCreateQuery = `INSERT INTO IMAGE_BLOB_META(NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, BLOB_DIGEST, SIZE, LOCATION) VALUES(?, ?, ?, ?, ?, ?)`
-	BlobMetaGetQuery    = `SELECT NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, BLOB_DIGEST, SIZE, LOCATION, CREATED_AT, UPDATED_AT FROM IMAGE_BLOB_META WHERE REPOSITORY_ID = ? AND BLOB_DIGEST = ?`
+	BlobMetaCreateQuery       = `INSERT INTO IMAGE_BLOB_META(NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, BLOB_DIGEST, SIZE, LOCATION) VALUES(?, ?, ?, ?, ?, ?)`
+	BlobMetaGetQuery          = `SELECT NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, BLOB_DIGEST, SIZE, LOCATION, CREATED_AT, UPDATED_AT FROM IMAGE_BLOB_META WHERE REPOSITORY_ID = ? AND BLOB_DIGEST = ?`
+	BlobMetaDeleteQuery       = `DELETE FROM IMAGE_BLOB_META WHERE REPOSITORY_ID = ? AND BLOB_DIGEST = ?`
+	BlobMetaTouchQuery        = `UPDATE IMAGE_BLOB_META SET UPDATED_AT = CURRENT_TIMESTAMP WHERE REPOSITORY_ID = ? AND BLOB_DIGEST = ?`
+	BlobMetaMarkAccessedQuery = `UPDATE IMAGE_BLOB_META SET LAST_ACCESSED_AT = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE REPOSITORY_ID = ? AND BLOB_DIGEST = ?`
+	BlobMetaRelocateQuery     = `UPDATE IMAGE_BLOB_META SET LOCATION = ? WHERE REPOSITORY_ID = ? AND BLOB_DIGEST = ?`
+
+	BlobContentGetQuery    = `SELECT DIGEST, SIZE, LOCATION, REF_COUNT FROM IMAGE_BLOB WHERE DIGEST = ?`
+	BlobContentLinkQuery   = `INSERT INTO IMAGE_BLOB(DIGEST, SIZE, LOCATION, REF_COUNT) VALUES(?, ?, ?, 1) ON CONFLICT(DIGEST) DO UPDATE SET REF_COUNT = REF_COUNT + 1, UPDATED_AT = CURRENT_TIMESTAMP`
+	BlobContentRefsQuery   = `SELECT REF_COUNT FROM IMAGE_BLOB WHERE DIGEST = ?`
+	BlobContentDeleteQuery = `DELETE FROM IMAGE_BLOB WHERE DIGEST = ? AND REF_COUNT <= 0`
+	// content is removed by counting its links since reference counts of earlier versions may be stale
+	BlobContentDeleteUnlinkedQuery = `DELETE FROM IMAGE_BLOB WHERE DIGEST = ? AND NOT EXISTS (SELECT 1 FROM IMAGE_BLOB_META WHERE BLOB_DIGEST = ?)`
 
 	BlobSessionCreateQuery = `INSERT INTO IMAGE_BLOB_UPLOAD_SESSION(SESSION_ID, NAMESPACE_ID, REPOSITORY_ID) VALUES(?, ?, ?)`
-	BlobSessionUpdateQuery = `UPDATE IMAGE_BLOB_UPLOAD_SESSION SET BYTES_RECEIVED = ? WHERE SESSION_ID = ?`
+	BlobSessionUpdateQuery = `UPDATE IMAGE_BLOB_UPLOAD_SESSION SET BYTES_RECEIVED = ?, SHA256_STATE = ?, SHA512_STATE = ? WHERE SESSION_ID = ?`
 	BlobSessionDeleteQuery = `DELETE FROM IMAGE_BLOB_UPLOAD_SESSION WHERE SESSION_ID = ?`
-	BlobSessionGetQuery    = `SELECT SESSION_ID, NAMESPACE_ID, REPOSITORY_ID, BYTES_RECEIVED, CREATED_AT, UPDATED_AT FROM IMAGE_BLOB_UPLOAD_SESSION WHERE SESSION_ID = ?`
+	BlobSessionGetQuery    = `SELECT SESSION_ID, NAMESPACE_ID, REPOSITORY_ID, BYTES_RECEIVED, SHA256_STATE, SHA512_STATE, CREATED_AT, UPDATED_AT FROM IMAGE_BLOB_UPLOAD_SESSION WHERE SESSION_ID = ?`
 )
 
 const (
-	CacheCreateEntryQuery  = `INSERT INTO IMAGE_REGISTRY_CACHE(NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, IDENTIFIER, DIGEST, EXPIRES_AT) VALUES (?, ?, ?, ?, ?, ?)`
-	CacheGetEntryQuery     = `SELECT NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, IDENTIFIER, DIGEST, EXPIRES_AT, CREATED_AT, UPDATED_AT FROM IMAGE_REGISTRY_CACHE WHERE REPOSITORY_ID = ? AND IDENTIFIER = ?`
-	CacheDeleteEntryQuery  = `DELETE FROM IMAGE_REGISTRY_CACHE WHERE REPOSITORY_ID = ? AND IDENTIFIER = ?`
-	CacheRefreshEntryQuery = `UPDATE IMAGE_REGISTRY_CACHE SET EXPIRES_AT = ? WHERE REPOSITORY_ID = ? AND IDENTIFIER = ?`
+	CacheCreateEntryQuery    = `INSERT INTO IMAGE_REGISTRY_CACHE(NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, IDENTIFIER, DIGEST, EXPIRES_AT) VALUES (?, ?, ?, ?, ?, ?)`
+	CacheGetEntryQuery       = `SELECT NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, IDENTIFIER, DIGEST, EXPIRES_AT, CREATED_AT, UPDATED_AT FROM IMAGE_REGISTRY_CACHE WHERE REPOSITORY_ID = ? AND IDENTIFIER = ?`
+	CacheDeleteEntryQuery    = `DELETE FROM IMAGE_REGISTRY_CACHE WHERE REPOSITORY_ID = ? AND IDENTIFIER = ?`
+	CacheRefreshEntryQuery   = `UPDATE IMAGE_REGISTRY_CACHE SET EXPIRES_AT = ? WHERE REPOSITORY_ID = ? AND IDENTIFIER = ?`
+	CacheDeleteByDigestQuery = `DELETE FROM IMAGE_REGISTRY_CACHE WHERE REPOSITORY_ID = ? AND DIGEST = ?`
+
+	CachePinQuery      = `INSERT OR IGNORE INTO IMAGE_REGISTRY_CACHE_PIN(REGISTRY_ID, REPOSITORY_ID, REFERENCE) VALUES(?, ?, ?)`
+	CacheUnpinQuery    = `DELETE FROM IMAGE_REGISTRY_CACHE_PIN WHERE REPOSITORY_ID = ? AND REFERENCE = ?`
+	CacheListPinsQuery = `SELECT p.REPOSITORY_ID, rn.NAME, rr.NAME, p.REFERENCE, p.CREATED_AT FROM IMAGE_REGISTRY_CACHE_PIN p
+	JOIN REGISTRY_REPOSITORY rr ON rr.ID = p.REPOSITORY_ID
+	JOIN REGISTRY_NAMESPACE rn ON rn.ID = rr.NAMESPACE_ID
+	WHERE p.REGISTRY_ID = ?
+	ORDER BY rn.NAME, rr.NAME, p.REFERENCE`
 )
 
 const (
@@ -251,7 +303,7 @@ const (
 		JOIN IMAGE_TAG it ON it.ID = imtm.TAG_ID
 		WHERE im.REPOSITORY_ID  = ? AND it.TAG = ?`
 
-	GetManifestByTagQuery = `SELECT im.ID, im.DIGEST, im.SIZE, im.MEDIA_TYPE, im.MANIFEST_CONTENT,
+	GetManifestByTagQuery = `SELECT im.ID, im.DIGEST, im.SIZE, im.MEDIA_TYPE,
 	  im.NAMESPACE_ID, im.REGISTRY_ID, im.REPOSITORY_ID, im.UNIQUE_DIGEST, im.CREATED_AT, im.UPDATED_AT
 		FROM IMAGE_MANIFEST im 
 		JOIN IMAGE_MANIFEST_TAG_MAPPING imtm  on imtm.MANIFEST_ID = im.ID
@@ -263,26 +315,186 @@ const (
 	JOIN REGISTRY_NAMESPACE ON rr.NAMESPACE_ID = rn.ID
 	WHERE rn.NAME = ? AND rr.NAME = ?
 	`
+
+	// A repository is readable if it is public or the user has been granted access to the repository or
+	// its namespace. Repositories under disabled namespaces and disabled repositories are not readable.
+	ListRepositoryNamesQuery = `SELECT rn.NAME || '/' || rr.NAME FROM REGISTRY_REPOSITORY rr
+	JOIN REGISTRY_NAMESPACE rn ON rr.NAMESPACE_ID = rn.ID
+	WHERE rr.REGISTRY_ID = ? AND rn.NAME || '/' || rr.NAME > ?
+	AND (? = 1 OR (rn.STATE != ? AND rr.STATE != ? AND (rn.IS_PUBLIC = 1 OR rr.IS_PUBLIC = 1 OR EXISTS (
+		SELECT 1 FROM RESOURCE_ACCESS ra WHERE ra.USER_ID = ? AND (
+			(ra.RESOURCE_TYPE = ? AND ra.RESOURCE_ID = rn.ID) OR
+			(ra.RESOURCE_TYPE = ? AND ra.RESOURCE_ID = rr.ID))))))
+	ORDER BY rn.NAME || '/' || rr.NAME LIMIT ?`
+
+	ListIdleUploadSessionsQuery = `SELECT s.SESSION_ID, COALESCE(rn.NAME, ''), COALESCE(rr.NAME, '')
+	FROM IMAGE_BLOB_UPLOAD_SESSION s
+	LEFT JOIN REGISTRY_NAMESPACE rn ON s.NAMESPACE_ID = rn.ID
+	LEFT JOIN REGISTRY_REPOSITORY rr ON s.REPOSITORY_ID = rr.ID
+	WHERE s.UPDATED_AT < datetime('now', ?)`
+	ListGCManifestsQuery = `SELECT im.ID, im.REPOSITORY_ID, COALESCE(rn.NAME || '/' || rr.NAME, ''), im.DIGEST, im.MEDIA_TYPE,
+	COALESCE(imr.SUBJECT_DIGEST, ''),
+	EXISTS(SELECT 1 FROM IMAGE_MANIFEST_TAG_MAPPING imtm JOIN IMAGE_TAG it ON it.ID = imtm.TAG_ID WHERE imtm.MANIFEST_ID = im.ID),
+	rr.ID IS NOT NULL, im.CREATED_AT >= datetime('now', ?)
+	FROM IMAGE_MANIFEST im
+	LEFT JOIN REGISTRY_REPOSITORY rr ON im.REPOSITORY_ID = rr.ID
+	LEFT JOIN REGISTRY_NAMESPACE rn ON rr.NAMESPACE_ID = rn.ID
+	LEFT JOIN IMAGE_MANIFEST_REFERRER imr ON imr.MANIFEST_ID = im.ID
+	WHERE im.REGISTRY_ID = ?`
+	ListGCBlobsQuery = `SELECT ibm.REPOSITORY_ID, COALESCE(rn.NAME || '/' || rr.NAME, ''), ibm.BLOB_DIGEST, ibm.SIZE, ibm.LOCATION,
+	COALESCE(ib.REF_COUNT, 0), ibm.UPDATED_AT >= datetime('now', ?)
+	FROM IMAGE_BLOB_META ibm
+	LEFT JOIN IMAGE_BLOB ib ON ibm.BLOB_DIGEST = ib.DIGEST
+	LEFT JOIN REGISTRY_REPOSITORY rr ON ibm.REPOSITORY_ID = rr.ID
+	LEFT JOIN REGISTRY_NAMESPACE rn ON rr.NAMESPACE_ID = rn.ID
+	WHERE ibm.REGISTRY_ID = ?`
+	ListUnlinkedBlobContentsQuery = `SELECT ib.DIGEST, ib.SIZE, ib.LOCATION, ib.REF_COUNT FROM IMAGE_BLOB ib
+	WHERE NOT EXISTS (SELECT 1 FROM IMAGE_BLOB_META ibm WHERE ibm.BLOB_DIGEST = ib.DIGEST)`
+	// Usage of an upstream registry counts content of a blob once even if it is cached for many repositories.
+	GetCacheUsageQuery = `SELECT (SELECT COALESCE(SUM(SIZE), 0) FROM IMAGE_MANIFEST WHERE REGISTRY_ID = ?) +
+	(SELECT COALESCE(SUM(SIZE), 0) FROM (SELECT MAX(SIZE) AS SIZE FROM IMAGE_BLOB_META WHERE REGISTRY_ID = ? GROUP BY BLOB_DIGEST))`
+	// last access is returned in unix milliseconds. Content which hasn't been pulled since it was cached is
+	// accessed when it was created.
+	ListCachedManifestsQuery = `SELECT im.REPOSITORY_ID, rn.NAME || '/' || rr.NAME, im.DIGEST, im.MEDIA_TYPE, im.SIZE,
+	COALESCE(imr.SUBJECT_DIGEST, ''),
+	EXISTS(SELECT 1 FROM IMAGE_REGISTRY_CACHE_PIN p WHERE p.REPOSITORY_ID = im.REPOSITORY_ID AND (p.REFERENCE = im.DIGEST
+		OR p.REFERENCE IN (SELECT it.TAG FROM IMAGE_MANIFEST_TAG_MAPPING imtm JOIN IMAGE_TAG it ON it.ID = imtm.TAG_ID
+			WHERE imtm.MANIFEST_ID = im.ID))),
+	CAST((julianday(COALESCE(im.LAST_ACCESSED_AT, im.CREATED_AT)) - 2440587.5) * 86400000 AS INTEGER)
+	FROM IMAGE_MANIFEST im
+	JOIN REGISTRY_REPOSITORY rr ON im.REPOSITORY_ID = rr.ID
+	JOIN REGISTRY_NAMESPACE rn ON rr.NAMESPACE_ID = rn.ID
+	LEFT JOIN IMAGE_MANIFEST_REFERRER imr ON imr.MANIFEST_ID = im.ID
+	WHERE im.REGISTRY_ID = ?`
+	ListCachedBlobsQuery = `SELECT ibm.REPOSITORY_ID, rn.NAME || '/' || rr.NAME, ibm.BLOB_DIGEST, ibm.SIZE, ibm.LOCATION,
+	CAST((julianday(COALESCE(ibm.LAST_ACCESSED_AT, ibm.CREATED_AT)) - 2440587.5) * 86400000 AS INTEGER)
+	FROM IMAGE_BLOB_META ibm
+	JOIN REGISTRY_REPOSITORY rr ON ibm.REPOSITORY_ID = rr.ID
+	JOIN REGISTRY_NAMESPACE rn ON rr.NAMESPACE_ID = rn.ID
+	WHERE ibm.REGISTRY_ID = ?`
+	ListUnmigratedBlobsQuery = `SELECT ibm.NAMESPACE_ID, ibm.REGISTRY_ID, ibm.REPOSITORY_ID, ibm.BLOB_DIGEST, ibm.SIZE, ibm.LOCATION,
+	ibm.CREATED_AT, ibm.UPDATED_AT
+	FROM IMAGE_BLOB_META ibm
+	LEFT JOIN IMAGE_BLOB ib ON ibm.BLOB_DIGEST = ib.DIGEST
+	WHERE ib.LOCATION IS NULL OR ib.LOCATION != ibm.LOCATION`
+	ListRetentionRepositoriesQuery = `SELECT rr.ID, rr.NAMESPACE_ID, rn.NAME, rr.NAME FROM REGISTRY_REPOSITORY rr
+	JOIN REGISTRY_NAMESPACE rn ON rr.NAMESPACE_ID = rn.ID
+	WHERE rr.REGISTRY_ID = ? AND (? = '' OR rr.NAMESPACE_ID = ?) AND (? = '' OR rr.ID = ?)
+	AND (EXISTS (SELECT 1 FROM REGISTRY_REPOSITORY_RETENTION_RULE r WHERE r.REPOSITORY_ID = rr.ID)
+		OR EXISTS (SELECT 1 FROM REGISTRY_NAMESPACE_RETENTION_RULE r WHERE r.NAMESPACE_ID = rr.NAMESPACE_ID))
+	ORDER BY rn.NAME, rr.NAME`
+	ListRetentionTagsQuery = `SELECT it.TAG, COALESCE(im.DIGEST, ''), it.IS_STABLE, COALESCE(it.PUSHED_AT, it.CREATED_AT)
+	FROM IMAGE_TAG it
+	LEFT JOIN IMAGE_MANIFEST_TAG_MAPPING imtm ON imtm.TAG_ID = it.ID
+	LEFT JOIN IMAGE_MANIFEST im ON im.ID = imtm.MANIFEST_ID
+	WHERE it.REPOSITORY_ID = ?
+	ORDER BY COALESCE(it.PUSHED_AT, it.CREATED_AT) DESC, it.ROWID DESC`
+)
+
+// Schema migrations of databases created by earlier versions
+const (
+	TableSchemaQuery = `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`
+
+	AddSha256StateQuery = `ALTER TABLE IMAGE_BLOB_UPLOAD_SESSION ADD COLUMN SHA256_STATE BLOB`
+	AddSha512StateQuery = `ALTER TABLE IMAGE_BLOB_UPLOAD_SESSION ADD COLUMN SHA512_STATE BLOB`
+
+	// repositories share content of blobs since blobs are content addressed. The table is recreated from a copy
+	// rather than renamed since renaming validates every trigger of the schema.
+	BlobMetaDropUniqueLocationQuery = `CREATE TEMP TABLE IMAGE_BLOB_META_COPY AS SELECT * FROM IMAGE_BLOB_META;
+	DROP TABLE IMAGE_BLOB_META;
+	CREATE TABLE IMAGE_BLOB_META (
+	NAMESPACE_ID TEXT NOT NULL,
+	REGISTRY_ID TEXT NOT NULL,
+	REPOSITORY_ID TEXT NOT NULL,
+	BLOB_DIGEST TEXT NOT NULL,
+	SIZE INTEGER NOT NULL,
+	LOCATION TEXT NOT NULL,
+	CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UPDATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (REGISTRY_ID, NAMESPACE_ID, REPOSITORY_ID, BLOB_DIGEST),
+	FOREIGN KEY (REGISTRY_ID) REFERENCES UPSTREAM_REGISTRY(ID) ON DELETE CASCADE,
+	FOREIGN KEY (NAMESPACE_ID) REFERENCES REGISTRY_NAMESPACE(ID) ON DELETE CASCADE,
+	FOREIGN KEY (REPOSITORY_ID) REFERENCES REGISTRY_REPOSITORY(ID) ON DELETE CASCADE
+	);
+	INSERT INTO IMAGE_BLOB_META(NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, BLOB_DIGEST, SIZE, LOCATION, CREATED_AT, UPDATED_AT)
+	SELECT NAMESPACE_ID, REGISTRY_ID, REPOSITORY_ID, BLOB_DIGEST, SIZE, LOCATION, CREATED_AT, UPDATED_AT FROM IMAGE_BLOB_META_COPY;
+	DROP TABLE IMAGE_BLOB_META_COPY;`
+
+	// tags of a repository can point to the same manifest
+	TagMappingDropUniqueManifestQuery = `CREATE TEMP TABLE IMAGE_MANIFEST_TAG_MAPPING_COPY AS SELECT * FROM IMAGE_MANIFEST_TAG_MAPPING;
+	DROP TABLE IMAGE_MANIFEST_TAG_MAPPING;
+	CREATE TABLE IMAGE_MANIFEST_TAG_MAPPING (
+	MANIFEST_ID  TEXT NOT NULL,
+	TAG_ID TEXT NOT NULL UNIQUE,
+	FOREIGN KEY (MANIFEST_ID) REFERENCES IMAGE_MANIFEST(ID),
+	FOREIGN KEY (TAG_ID) REFERENCES IMAGE_TAG(ID)
+	);
+	INSERT INTO IMAGE_MANIFEST_TAG_MAPPING(MANIFEST_ID, TAG_ID)
+	SELECT MANIFEST_ID, TAG_ID FROM IMAGE_MANIFEST_TAG_MAPPING_COPY;
+	DROP TABLE IMAGE_MANIFEST_TAG_MAPPING_COPY;`
+
+	// non-constant defaults can't be added to existing tables; NULL is read as CREATED_AT
+	AddLastAccessedAtQuery = `ALTER TABLE %s ADD COLUMN LAST_ACCESSED_AT TIMESTAMP`
+	AddStaleIfErrorQuery   = `ALTER TABLE UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG ADD COLUMN STALE_IF_ERROR INTEGER NOT NULL DEFAULT 0 CHECK(STALE_IF_ERROR IN (0, 1))`
+	AddOfflineModeQuery    = `ALTER TABLE UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG ADD COLUMN OFFLINE_MODE INTEGER NOT NULL DEFAULT 0 CHECK(OFFLINE_MODE IN (0, 1))`
+	AddTagPushedAtQuery    = `ALTER TABLE IMAGE_TAG ADD COLUMN PUSHED_AT TIMESTAMP`
+
+	// tags were touched on push before, along with changes of stability
+	TagBackfillPushedAtQuery = `UPDATE IMAGE_TAG SET PUSHED_AT = COALESCE(UPDATED_AT, CREATED_AT)`
 )
 
 const (
 	UpstreamCreateQuery      = `INSERT INTO UPSTREAM_REGISTRY(NAME, DESCRIPTION, VENDOR, STATE, PORT, UPSTREAM_URL) VALUES(?, ?, ?, ?, ?, ?) RETURNING ID`
-	UpstreamUpdateQuery      = `UPDATE UPSTREAM_REGISTRY DESCRIPTION = ?, STATE = ?, PORT = ?, UPSTREAM_URL = ? WHERE REGISTRY_ID = ?`
-	UpstreamDeleteQuery      = `DELETE FROM UPSTREAM_REGISTRY WHERE REGISTRY_ID = ?`
-	UpstreamGetQuery         = `SELECT ID, NAME, DESCRIPTION, VENDOR, STATE, PORT, UPSTREAM_URL, CREATED_AT, UPDATED_AT WHERE REGISTRY_ID = ?`
-	UpstreamChangeStateQuery = `UPDATE UPSTREAM_REGISTRY SET STATE = ? WHERE REGISTRY_ID = ?`
+	UpstreamUpdateQuery      = `UPDATE UPSTREAM_REGISTRY SET DESCRIPTION = ?, STATE = ?, PORT = ?, UPSTREAM_URL = ? WHERE ID = ?`
+	UpstreamDeleteQuery      = `DELETE FROM UPSTREAM_REGISTRY WHERE ID = ?`
+	UpstreamGetQuery         = `SELECT ID, NAME, DESCRIPTION, VENDOR, STATE, PORT, UPSTREAM_URL, CREATED_AT, UPDATED_AT FROM UPSTREAM_REGISTRY WHERE ID = ?`
+	UpstreamChangeStateQuery = `UPDATE UPSTREAM_REGISTRY SET STATE = ? WHERE ID = ?`
 
 	UpstreamPersistAuthConfigQuery = `INSERT INTO UPSTREAM_REGISTRY_AUTH_CONFIG(AUTH_TYPE, CONFIG_JSON, REGISTRY_ID) VALUES (?, ?, ?)`
 	UpstreamUpdateAuthConfigQuery  = `UPDATE UPSTREAM_REGISTRY_AUTH_CONFIG SET AUTH_TYPE = ?, CONFIG_JSON = ? WHERE REGISTRY_ID = ?`
 	UpstreamGetAuthConfigQuery     = `SELECT AUTH_TYPE, CONFIG_JSON, CREATED_AT, UPDATED_AT FROM UPSTREAM_REGISTRY_AUTH_CONFIG WHERE REGISTRY_ID = ?`
 
-	UpstreamPersistCacheConfigQuery = `INSERT INTO UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG(REGISTRY_ID, CACHE_ENABLED, TTL_SECONDS, STORAGE_LIMIT, CLEANUP_THRESHOLD_PERCENTAGE) VALUES(?, ?, ?, ?, ?)`
-	UpstreamUpdateCacheConfigQuery  = `UPDATE UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG SET CACHE_ENABLED = ?, TTL_SECONDS = ?, STORAGE_LIMIT = ?, CLEANUP_THRESHOLD_PERCENTAGE = ? WHERE REGISTRY_ID = ?`
-	UpstreamGetCacheConfigQuery     = `SELECT CACHE_ENABLED, TTL_SECONDS, STORAGE_LIMIT, CLEANUP_THRESHOLD_PERCENTAGE, CREATED_AT, UPDATED_AT FROM UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG WHERE REGISTRY_ID = ?`
+	UpstreamPersistCacheConfigQuery = `INSERT INTO UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG(REGISTRY_ID, CACHE_ENABLED, TTL_SECONDS, STORAGE_LIMIT, CLEANUP_THRESHOLD_PERCENTAGE, STALE_IF_ERROR, OFFLINE_MODE) VALUES(?, ?, ?, ?, ?, ?, ?)`
+	UpstreamUpdateCacheConfigQuery  = `UPDATE UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG SET CACHE_ENABLED = ?, TTL_SECONDS = ?, STORAGE_LIMIT = ?, CLEANUP_THRESHOLD_PERCENTAGE = ?, STALE_IF_ERROR = ?, OFFLINE_MODE = ? WHERE REGISTRY_ID = ?`
+	UpstreamGetCacheConfigQuery     = `SELECT CACHE_ENABLED, TTL_SECONDS, STORAGE_LIMIT, CLEANUP_THRESHOLD_PERCENTAGE, STALE_IF_ERROR, OFFLINE_MODE, CREATED_AT, UPDATED_AT FROM UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG WHERE REGISTRY_ID = ?`
+	UpstreamListCacheConfigsQuery   = `SELECT REGISTRY_ID, CACHE_ENABLED, TTL_SECONDS, STORAGE_LIMIT, CLEANUP_THRESHOLD_PERCENTAGE, STALE_IF_ERROR, OFFLINE_MODE, CREATED_AT, UPDATED_AT FROM UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG ORDER BY REGISTRY_ID`
 
 	UpstreamPersistNetworkConfigQuery = `INSERT INTO UPSTREAM_REGISTRY_NETWORK_CONFIG(REGISTRY_ID, CONNECTION_TIMEOUT, READ_TIMEOUT, WRITE_TIMEOUT, MAX_CONNECTIONS, MAX_IDLE_CONNECTIONS, MAX_RETRIES, RETRY_DELAY, RETRY_BACKOFF_MULTIPLIER) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	UpstreamUpdateNetworkConfigQuery  = `UPDATE UPSTREAM_REGISTRY_NETWORK_CONFIG SET CONNECTION_TIMEOUT = ? , READ_TIMEOUT = ? , WRITE_TIMEOUT = ? , MAX_CONNECTIONS = ? , MAX_IDLE_CONNECTIONS = ?, MAX_RETRIES = ?, RETRY_DELAY = ?, RETRY_BACKOFF_MULTIPLIER = ? WHERE REGISTRY_ID = ?`
 	UpstreamGetNetworkConfigQuery     = `SELECT CONNECTION_TIMEOUT, READ_TIMEOUT, WRITE_TIMEOUT, MAX_CONNECTIONS, MAX_IDLE_CONNECTIONS, MAX_RETRIES, RETRY_DELAY, RETRY_BACKOFF_MULTIPLIER, CREATED_AT, UPDATED_AT FROM UPSTREAM_REGISTRY_NETWORK_CONFIG WHERE REGISTRY_ID = ?`
 
-	UpstreamGetAllAddresses = `SELECT ID, NAME, PORT, UPSTREAM_URL FROM UPSTREAM_REGISTRY`
+	UpstreamGetAllAddresses = `SELECT ID, NAME, PORT, UPSTREAM_URL FROM UPSTREAM_REGISTRY WHERE STATE <> 'Disabled'`
+
+	UpstreamListBaseQuery = `
+	SELECT
+		ur.ID AS ID,
+		ur.NAME AS NAME,
+		ur.DESCRIPTION AS DESCRIPTION,
+		ur.VENDOR AS VENDOR,
+		ur.STATE AS STATE,
+		ur.PORT AS PORT,
+		ur.UPSTREAM_URL AS UPSTREAM_URL,
+		COALESCE(ci.CACHED_IMAGES, 0) AS CACHED_IMAGES,
+		ur.CREATED_AT AS CREATED_AT,
+		ur.UPDATED_AT AS UPDATED_AT
+	FROM UPSTREAM_REGISTRY ur
+	LEFT JOIN (
+		SELECT REGISTRY_ID, COUNT(DISTINCT DIGEST) AS CACHED_IMAGES
+		FROM IMAGE_REGISTRY_CACHE
+		GROUP BY REGISTRY_ID
+	) AS ci ON ci.REGISTRY_ID = ur.ID`
+	UpstreamCountBaseQuery = `SELECT count(*) FROM UPSTREAM_REGISTRY ur `
+)
+
+const (
+	WebhookCreateQuery          = `INSERT INTO WEBHOOK(NAME, URL, SECRET, NAMESPACE_ID, EVENTS, ENABLED) VALUES(?, ?, ?, ?, ?, ?) RETURNING ID`
+	WebhookUpdateQuery          = `UPDATE WEBHOOK SET URL = ?, SECRET = ?, EVENTS = ?, ENABLED = ?, UPDATED_AT = CURRENT_TIMESTAMP WHERE ID = ?`
+	WebhookDeleteQuery          = `DELETE FROM WEBHOOK WHERE ID = ?`
+	WebhookGetQuery             = `SELECT w.ID, w.NAME, w.URL, w.SECRET, COALESCE(w.NAMESPACE_ID, ''), COALESCE(rn.NAME, ''), w.EVENTS, w.ENABLED, w.CREATED_AT, w.UPDATED_AT FROM WEBHOOK w LEFT JOIN REGISTRY_NAMESPACE rn ON rn.ID = w.NAMESPACE_ID WHERE w.ID = ?`
+	WebhookListQuery            = `SELECT w.ID, w.NAME, w.URL, w.SECRET, COALESCE(w.NAMESPACE_ID, ''), COALESCE(rn.NAME, ''), w.EVENTS, w.ENABLED, w.CREATED_AT, w.UPDATED_AT FROM WEBHOOK w LEFT JOIN REGISTRY_NAMESPACE rn ON rn.ID = w.NAMESPACE_ID WHERE (? = '' OR w.NAMESPACE_ID = ?) ORDER BY w.CREATED_AT, w.NAME`
+	WebhookListSubscribersQuery = `SELECT w.ID, w.NAME, w.URL, w.SECRET, COALESCE(w.NAMESPACE_ID, ''), COALESCE(rn.NAME, ''), w.EVENTS, w.ENABLED, w.CREATED_AT, w.UPDATED_AT FROM WEBHOOK w LEFT JOIN REGISTRY_NAMESPACE rn ON rn.ID = w.NAMESPACE_ID WHERE w.ENABLED = 1 AND (w.NAMESPACE_ID IS NULL OR w.NAMESPACE_ID = ?)`
+
+	WebhookCreateDeliveryQuery = `INSERT INTO WEBHOOK_DELIVERY(WEBHOOK_ID, EVENT_ID, ACTION, PAYLOAD, STATUS) VALUES(?, ?, ?, ?, ?) RETURNING ID`
+	WebhookUpdateDeliveryQuery = `UPDATE WEBHOOK_DELIVERY SET STATUS = ?, ATTEMPTS = ?, RESPONSE_CODE = ?, ERROR = ?, UPDATED_AT = CURRENT_TIMESTAMP WHERE ID = ?`
+	WebhookListDeliveriesQuery = `SELECT ID, WEBHOOK_ID, EVENT_ID, ACTION, PAYLOAD, STATUS, ATTEMPTS, RESPONSE_CODE, ERROR, CREATED_AT, UPDATED_AT FROM WEBHOOK_DELIVERY WHERE WEBHOOK_ID = ? ORDER BY CREATED_AT DESC, ROWID DESC LIMIT ?`
 )
\ No newline at end of file
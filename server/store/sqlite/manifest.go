@@ -51,9 +51,9 @@ func (m *manifestStore) GetByUniqueDigest(ctx context.Context, withContent bool,
 
 	var row *sql.Row
 	if withContent {
-		row = q.QueryRowContext(ctx, ManifestGetbyUniqueDigestWithContentQuery, digest)
+		row = q.QueryRowContext(ctx, ManifestGetbyUniqueDigestWithContentQuery, repositoryId, digest)
 	} else {
-		row = q.QueryRowContext(ctx, ManifestGetbyUniqueDigestQuery, digest)
+		row = q.QueryRowContext(ctx, ManifestGetbyUniqueDigestQuery, repositoryId, digest)
 	}
 
 	var createdAt, updatedAt string
@@ -108,9 +108,9 @@ func (m *manifestStore) GetByDigest(ctx context.Context, withContent bool, repos
 
 	var row *sql.Row
 	if withContent {
-		row = q.QueryRowContext(ctx, ManifestGetbyDigestWithContentQuery, digest)
+		row = q.QueryRowContext(ctx, ManifestGetbyDigestWithContentQuery, repositoryId, digest)
 	} else {
-		row = q.QueryRowContext(ctx, ManifestGetbyDigestQuery, digest)
+		row = q.QueryRowContext(ctx, ManifestGetbyDigestQuery, repositoryId, digest)
 	}
 
 	var createdAt, updatedAt string
@@ -158,14 +158,89 @@ func (m *manifestStore) GetByDigest(ctx context.Context, withContent bool, repos
 	return &model, nil
 }
 
+// DeleteByDigest deletes the manifest along with the tags pointing to it
 func (m *manifestStore) DeleteByDigest(ctx context.Context, repositoryId, digest string) error {
 	q := m.getQuerier(ctx)
 
-	_, err := q.ExecContext(ctx, ManifestDeleteByDigest, repositoryId, digest)
+	_, err := q.ExecContext(ctx, ManifestDeleteTagsByDigest, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete tags of manifest")
+		return dberrors.ClassifyError(err, ManifestDeleteTagsByDigest)
+	}
+
+	_, err = q.ExecContext(ctx, ManifestUnlinkTagsByDigest, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to unlink tags of manifest")
+		return dberrors.ClassifyError(err, ManifestUnlinkTagsByDigest)
+	}
+
+	_, err = q.ExecContext(ctx, ManifestDeleteReferrerByDigest, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete referrer of manifest")
+		return dberrors.ClassifyError(err, ManifestDeleteReferrerByDigest)
+	}
+
+	_, err = q.ExecContext(ctx, ManifestDeleteByDigest, repositoryId, digest)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("failed to delete manifest")
 		return dberrors.ClassifyError(err, ManifestDeleteByDigest)
 	}
 
 	return nil
+}
+
+func (m *manifestStore) MarkAccessed(ctx context.Context, repositoryId, digest string) error {
+	q := m.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, ManifestMarkAccessedQuery, repositoryId, digest)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to mark manifest accessed")
+		return dberrors.ClassifyError(err, ManifestMarkAccessedQuery)
+	}
+	return nil
+}
+
+func (m *manifestStore) CreateReferrer(ctx context.Context, manifestId, repositoryId, subjectDigest,
+	artifactType string) error {
+	q := m.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, ManifestCreateReferrerQuery, manifestId, repositoryId, subjectDigest, artifactType)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to create manifest referrer")
+		return dberrors.ClassifyError(err, ManifestCreateReferrerQuery)
+	}
+
+	return nil
+}
+
+func (m *manifestStore) ListReferrers(ctx context.Context, repositoryId, subjectDigest,
+	artifactType string) ([]*models.ImageReferrerModel, error) {
+	q := m.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, ManifestListReferrersQuery, repositoryId, subjectDigest, artifactType,
+		artifactType)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list manifest referrers")
+		return nil, dberrors.ClassifyError(err, ManifestListReferrersQuery)
+	}
+	defer rows.Close()
+
+	referrers := []*models.ImageReferrerModel{}
+	for rows.Next() {
+		var referrer models.ImageReferrerModel
+		err := rows.Scan(&referrer.Digest, &referrer.Size, &referrer.MediaType, &referrer.ArtifactType,
+			&referrer.Content)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan manifest referrer")
+			return nil, dberrors.ClassifyError(err, ManifestListReferrersQuery)
+		}
+		referrers = append(referrers, &referrer)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate manifest referrers")
+		return nil, dberrors.ClassifyError(err, ManifestListReferrersQuery)
+	}
+
+	return referrers, nil
 }
\ No newline at end of file
@@ -0,0 +1,243 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ksankeerth/open-image-registry/log"
+)
+
+// migrate upgrades tables which can't be upgraded by the schema script. Sqlite can't alter constraints of a table;
+// therefore, such tables are rebuilt.
+func migrate(db *sql.DB) error {
+	if err := migrateUploadHashState(db); err != nil {
+		return err
+	}
+	if err := migrateBlobMetaLocation(db); err != nil {
+		return err
+	}
+	if err := migrateTagMappingManifest(db); err != nil {
+		return err
+	}
+	if err := migrateLastAccessedAt(db); err != nil {
+		return err
+	}
+	if err := migrateTagPushedAt(db); err != nil {
+		return err
+	}
+	return migrateCacheModes(db)
+}
+
+// migrateUploadHashState adds hash states to upload sessions so that digests of chunked uploads are computed
+// incrementally. Uploads in progress during the upgrade have no hash state; they fail digest verification and are
+// restarted by clients.
+func migrateUploadHashState(db *sql.DB) error {
+	var schema string
+	err := db.QueryRow(TableSchemaQuery, "IMAGE_BLOB_UPLOAD_SESSION").Scan(&schema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// new database
+			return nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to read schema of IMAGE_BLOB_UPLOAD_SESSION")
+		return err
+	}
+
+	if strings.Contains(schema, "SHA256_STATE") {
+		return nil
+	}
+
+	log.Logger().Info().Msg("Migrating IMAGE_BLOB_UPLOAD_SESSION to verify digests during uploads")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{AddSha256StateQuery, AddSha512StateQuery} {
+		_, err = tx.Exec(query)
+		if err != nil {
+			tx.Rollback()
+			log.Logger().Error().Err(err).Msg("failed to migrate IMAGE_BLOB_UPLOAD_SESSION")
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// migrateBlobMetaLocation removes the unique constraint of IMAGE_BLOB_META.LOCATION so that repositories can share
+// content of blobs. Blobs are moved to content addressed locations afterwards, by the registry.
+func migrateBlobMetaLocation(db *sql.DB) error {
+	var schema string
+	err := db.QueryRow(TableSchemaQuery, "IMAGE_BLOB_META").Scan(&schema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// new database
+			return nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to read schema of IMAGE_BLOB_META")
+		return err
+	}
+
+	if !strings.Contains(schema, "LOCATION TEXT NOT NULL UNIQUE") {
+		return nil
+	}
+
+	log.Logger().Info().Msg("Migrating IMAGE_BLOB_META to share blob content across repositories")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(BlobMetaDropUniqueLocationQuery)
+	if err != nil {
+		tx.Rollback()
+		log.Logger().Error().Err(err).Msg("failed to migrate IMAGE_BLOB_META")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrateTagMappingManifest removes the unique constraint of IMAGE_MANIFEST_TAG_MAPPING.MANIFEST_ID so that multiple
+// tags can point to the same manifest.
+func migrateTagMappingManifest(db *sql.DB) error {
+	var schema string
+	err := db.QueryRow(TableSchemaQuery, "IMAGE_MANIFEST_TAG_MAPPING").Scan(&schema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// new database
+			return nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to read schema of IMAGE_MANIFEST_TAG_MAPPING")
+		return err
+	}
+
+	if !strings.Contains(schema, "MANIFEST_ID  TEXT NOT NULL UNIQUE") {
+		return nil
+	}
+
+	log.Logger().Info().Msg("Migrating IMAGE_MANIFEST_TAG_MAPPING to allow multiple tags of a manifest")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(TagMappingDropUniqueManifestQuery)
+	if err != nil {
+		tx.Rollback()
+		log.Logger().Error().Err(err).Msg("failed to migrate IMAGE_MANIFEST_TAG_MAPPING")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrateLastAccessedAt adds LAST_ACCESSED_AT to manifests and blobs so that least recently pulled content of
+// upstream registries can be evicted. Existing content is treated as accessed when it was created.
+func migrateLastAccessedAt(db *sql.DB) error {
+	for _, table := range []string{"IMAGE_MANIFEST", "IMAGE_BLOB_META"} {
+		var schema string
+		err := db.QueryRow(TableSchemaQuery, table).Scan(&schema)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// new database
+				continue
+			}
+			log.Logger().Error().Err(err).Msgf("failed to read schema of %s", table)
+			return err
+		}
+
+		if strings.Contains(schema, "LAST_ACCESSED_AT") {
+			continue
+		}
+
+		log.Logger().Info().Msgf("Migrating %s to track last access of cached content", table)
+
+		_, err = db.Exec(fmt.Sprintf(AddLastAccessedAtQuery, table))
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("failed to migrate %s", table)
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTagPushedAt adds PUSHED_AT to tags so that retention rules aren't affected by other updates of tags.
+// Push time of existing tags is taken from their last update.
+func migrateTagPushedAt(db *sql.DB) error {
+	var schema string
+	err := db.QueryRow(TableSchemaQuery, "IMAGE_TAG").Scan(&schema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// new database
+			return nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to read schema of IMAGE_TAG")
+		return err
+	}
+
+	if strings.Contains(schema, "PUSHED_AT") {
+		return nil
+	}
+
+	log.Logger().Info().Msg("Migrating IMAGE_TAG to track push time of tags")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{AddTagPushedAtQuery, TagBackfillPushedAtQuery} {
+		_, err = tx.Exec(query)
+		if err != nil {
+			tx.Rollback()
+			log.Logger().Error().Err(err).Msg("failed to migrate IMAGE_TAG")
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// migrateCacheModes adds stale-if-error and offline modes to cache configs of upstream registries. Both are off for
+// existing upstream registries.
+func migrateCacheModes(db *sql.DB) error {
+	var schema string
+	err := db.QueryRow(TableSchemaQuery, "UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG").Scan(&schema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// new database
+			return nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to read schema of UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG")
+		return err
+	}
+
+	if strings.Contains(schema, "OFFLINE_MODE") {
+		return nil
+	}
+
+	log.Logger().Info().Msg("Migrating UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG to support stale-if-error and offline modes")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{AddStaleIfErrorQuery, AddOfflineModeQuery} {
+		_, err = tx.Exec(query)
+		if err != nil {
+			tx.Rollback()
+			log.Logger().Error().Err(err).Msg("failed to migrate UPSTREAM_REGISTRY_CACHE_STORAGE_CONFIG")
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
\ No newline at end of file
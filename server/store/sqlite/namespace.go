@@ -95,7 +95,7 @@ func (n *namespaceStore) GetByName(ctx context.Context, regId, name string) (*mo
 	var createdAt, updatedAt string
 
 	var m models.NamespaceModel
-	err := row.Scan(&m.RegistryId, &m.Name, &m.Description, &m.Purpose, &m.IsPublic, &m.State, &createdAt, &updatedAt, &m.CreatedBy)
+	err := row.Scan(&m.Id, &m.RegistryId, &m.Name, &m.Description, &m.Purpose, &m.IsPublic, &m.State, &createdAt, &updatedAt, &m.CreatedBy)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // not found
@@ -259,6 +259,79 @@ func (n *namespaceStore) SetVisiblityByID(ctx context.Context, id string, public
 	return nil
 }
 
+func (n *namespaceStore) GetQuota(ctx context.Context, id string) (*models.QuotaModel, error) {
+	q := n.getQuerier(ctx)
+
+	var maxBytes, maxImages sql.NullInt64
+	var m models.QuotaModel
+	err := q.QueryRowContext(ctx, NamespaceGetQuotaQuery, id, id, id, id).Scan(&maxBytes, &maxImages, &m.UsedBytes,
+		&m.UsedImages)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to retrieve namespace quota")
+		return nil, dberrors.ClassifyError(err, NamespaceGetQuotaQuery)
+	}
+
+	if maxBytes.Valid {
+		m.MaxBytes = &maxBytes.Int64
+	}
+	if maxImages.Valid {
+		m.MaxImages = &maxImages.Int64
+	}
+	return &m, nil
+}
+
+func (n *namespaceStore) SetQuota(ctx context.Context, id string, maxBytes, maxImages *int64) error {
+	q := n.getQuerier(ctx)
+
+	if maxBytes == nil && maxImages == nil {
+		_, err := q.ExecContext(ctx, NamespaceDeleteQuotaQuery, id)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to delete namespace quota")
+			return dberrors.ClassifyError(err, NamespaceDeleteQuotaQuery)
+		}
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, NamespaceSetQuotaQuery, id, maxBytes, maxImages)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to update namespace quota")
+		return dberrors.ClassifyError(err, NamespaceSetQuotaQuery)
+	}
+	return nil
+}
+
+func (n *namespaceStore) GetRetentionRules(ctx context.Context, id string) ([]*models.RetentionRuleModel, error) {
+	q := n.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, NamespaceGetRetentionRulesQuery, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to retrieve namespace retention rules")
+		return nil, dberrors.ClassifyError(err, NamespaceGetRetentionRulesQuery)
+	}
+	defer rows.Close()
+
+	return scanRetentionRules(rows, NamespaceGetRetentionRulesQuery)
+}
+
+func (n *namespaceStore) SetRetentionRules(ctx context.Context, id string, rules []*models.RetentionRuleModel) error {
+	q := n.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, NamespaceDeleteRetentionRulesQuery, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete namespace retention rules")
+		return dberrors.ClassifyError(err, NamespaceDeleteRetentionRulesQuery)
+	}
+
+	for _, rule := range rules {
+		_, err = q.ExecContext(ctx, NamespaceAddRetentionRuleQuery, id, rule.TagPattern, rule.KeepLast, rule.MaxAgeDays)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to add namespace retention rule")
+			return dberrors.ClassifyError(err, NamespaceAddRetentionRuleQuery)
+		}
+	}
+	return nil
+}
+
 func (n *namespaceStore) List(ctx context.Context, conditions *store.ListQueryConditions) (namespaces []*models.NamespaceView,
 	total int, err error) {
 	qb := store.NewQueryBuilder(store.DBTypeSqlite).
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/ksankeerth/open-image-registry/constants"
 	"github.com/ksankeerth/open-image-registry/errors/dberrors"
 	"github.com/ksankeerth/open-image-registry/log"
 	"github.com/ksankeerth/open-image-registry/store"
@@ -168,7 +170,7 @@ func (q *queries) GetManifestByTag(ctx context.Context, withContent bool, reposi
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, err
+			return nil, nil
 		}
 		log.Logger().Error().Err(err).Msg("failed to retrieve manifest by tag")
 		return nil, dberrors.ClassifyError(err, query)
@@ -239,3 +241,325 @@ func (q *queries) GetRepositoryByNames(ctx context.Context, namespace, repositor
 
 	return &m, nil
 }
+
+func (q *queries) ListRepositoryNames(ctx context.Context, registryId, userId string, includeAll bool, last string,
+	limit int) ([]string, error) {
+	qr := q.getQuerier(ctx)
+
+	// sqlite treats negative limit as no limit
+	if limit < 1 {
+		limit = -1
+	}
+
+	rows, err := qr.QueryContext(ctx, ListRepositoryNamesQuery, registryId, last, includeAll,
+		constants.ResourceStateDisabled, constants.ResourceStateDisabled, userId, constants.ResourceTypeNamespace,
+		constants.ResourceTypeRepository, limit)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list repository names")
+		return nil, dberrors.ClassifyError(err, ListRepositoryNamesQuery)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan repository name")
+			return nil, dberrors.ClassifyError(err, ListRepositoryNamesQuery)
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate repository names")
+		return nil, dberrors.ClassifyError(err, ListRepositoryNamesQuery)
+	}
+
+	return names, nil
+}
+
+func (q *queries) ListIdleUploadSessions(ctx context.Context, idleSeconds int64) ([]*models.IdleUploadSessionModel,
+	error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListIdleUploadSessionsQuery, fmt.Sprintf("-%d seconds", idleSeconds))
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list idle upload sessions")
+		return nil, dberrors.ClassifyError(err, ListIdleUploadSessionsQuery)
+	}
+	defer rows.Close()
+
+	sessions := []*models.IdleUploadSessionModel{}
+	for rows.Next() {
+		var session models.IdleUploadSessionModel
+		if err := rows.Scan(&session.SessionID, &session.Namespace, &session.Repository); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan idle upload session")
+			return nil, dberrors.ClassifyError(err, ListIdleUploadSessionsQuery)
+		}
+		sessions = append(sessions, &session)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate idle upload sessions")
+		return nil, dberrors.ClassifyError(err, ListIdleUploadSessionsQuery)
+	}
+
+	return sessions, nil
+}
+
+func (q *queries) ListGCManifests(ctx context.Context, registryId string,
+	graceSeconds int64) ([]*models.GCManifestModel, error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListGCManifestsQuery, fmt.Sprintf("-%d seconds", graceSeconds), registryId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list manifests for garbage collection")
+		return nil, dberrors.ClassifyError(err, ListGCManifestsQuery)
+	}
+	defer rows.Close()
+
+	manifests := []*models.GCManifestModel{}
+	for rows.Next() {
+		var m models.GCManifestModel
+		if err := rows.Scan(&m.ID, &m.RepositoryID, &m.Repository, &m.Digest, &m.MediaType, &m.SubjectDigest,
+			&m.Tagged, &m.RepositoryExists, &m.Recent); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan manifest for garbage collection")
+			return nil, dberrors.ClassifyError(err, ListGCManifestsQuery)
+		}
+		manifests = append(manifests, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate manifests for garbage collection")
+		return nil, dberrors.ClassifyError(err, ListGCManifestsQuery)
+	}
+
+	return manifests, nil
+}
+
+func (q *queries) ListGCBlobs(ctx context.Context, registryId string, graceSeconds int64) ([]*models.GCBlobModel,
+	error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListGCBlobsQuery, fmt.Sprintf("-%d seconds", graceSeconds), registryId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list blobs for garbage collection")
+		return nil, dberrors.ClassifyError(err, ListGCBlobsQuery)
+	}
+	defer rows.Close()
+
+	blobs := []*models.GCBlobModel{}
+	for rows.Next() {
+		var b models.GCBlobModel
+		if err := rows.Scan(&b.RepositoryID, &b.Repository, &b.Digest, &b.Size, &b.Location, &b.RefCount,
+			&b.Recent); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan blob for garbage collection")
+			return nil, dberrors.ClassifyError(err, ListGCBlobsQuery)
+		}
+		blobs = append(blobs, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate blobs for garbage collection")
+		return nil, dberrors.ClassifyError(err, ListGCBlobsQuery)
+	}
+
+	return blobs, nil
+}
+
+func (q *queries) ListUnlinkedBlobContents(ctx context.Context) ([]*models.ImageBlobModel, error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListUnlinkedBlobContentsQuery)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list unlinked blob contents")
+		return nil, dberrors.ClassifyError(err, ListUnlinkedBlobContentsQuery)
+	}
+	defer rows.Close()
+
+	blobs := []*models.ImageBlobModel{}
+	for rows.Next() {
+		var b models.ImageBlobModel
+		if err := rows.Scan(&b.Digest, &b.Size, &b.Location, &b.RefCount); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan unlinked blob content")
+			return nil, dberrors.ClassifyError(err, ListUnlinkedBlobContentsQuery)
+		}
+		blobs = append(blobs, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate unlinked blob contents")
+		return nil, dberrors.ClassifyError(err, ListUnlinkedBlobContentsQuery)
+	}
+
+	return blobs, nil
+}
+
+func (q *queries) GetCacheUsage(ctx context.Context, registryId string) (int64, error) {
+	qr := q.getQuerier(ctx)
+
+	var usage int64
+	err := qr.QueryRowContext(ctx, GetCacheUsageQuery, registryId, registryId).Scan(&usage)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to calculate cache usage")
+		return 0, dberrors.ClassifyError(err, GetCacheUsageQuery)
+	}
+
+	return usage, nil
+}
+
+func (q *queries) ListCachedManifests(ctx context.Context, registryId string) ([]*models.CachedManifestModel,
+	error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListCachedManifestsQuery, registryId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list cached manifests")
+		return nil, dberrors.ClassifyError(err, ListCachedManifestsQuery)
+	}
+	defer rows.Close()
+
+	manifests := []*models.CachedManifestModel{}
+	for rows.Next() {
+		var m models.CachedManifestModel
+		var lastAccessed int64
+		if err := rows.Scan(&m.RepositoryID, &m.Repository, &m.Digest, &m.MediaType, &m.Size, &m.SubjectDigest,
+			&m.Pinned, &lastAccessed); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan cached manifest")
+			return nil, dberrors.ClassifyError(err, ListCachedManifestsQuery)
+		}
+		m.LastAccessed = time.UnixMilli(lastAccessed)
+		manifests = append(manifests, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate cached manifests")
+		return nil, dberrors.ClassifyError(err, ListCachedManifestsQuery)
+	}
+
+	return manifests, nil
+}
+
+func (q *queries) ListCachedBlobs(ctx context.Context, registryId string) ([]*models.CachedBlobModel, error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListCachedBlobsQuery, registryId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list cached blobs")
+		return nil, dberrors.ClassifyError(err, ListCachedBlobsQuery)
+	}
+	defer rows.Close()
+
+	blobs := []*models.CachedBlobModel{}
+	for rows.Next() {
+		var b models.CachedBlobModel
+		var lastAccessed int64
+		if err := rows.Scan(&b.RepositoryID, &b.Repository, &b.Digest, &b.Size, &b.Location,
+			&lastAccessed); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan cached blob")
+			return nil, dberrors.ClassifyError(err, ListCachedBlobsQuery)
+		}
+		b.LastAccessed = time.UnixMilli(lastAccessed)
+		blobs = append(blobs, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate cached blobs")
+		return nil, dberrors.ClassifyError(err, ListCachedBlobsQuery)
+	}
+
+	return blobs, nil
+}
+
+func (q *queries) ListUnmigratedBlobs(ctx context.Context) ([]*models.ImageBlobMetaModel, error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListUnmigratedBlobsQuery)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list unmigrated blobs")
+		return nil, dberrors.ClassifyError(err, ListUnmigratedBlobsQuery)
+	}
+	defer rows.Close()
+
+	blobs := []*models.ImageBlobMetaModel{}
+	for rows.Next() {
+		var b models.ImageBlobMetaModel
+		if err := rows.Scan(&b.NamespaceID, &b.RegistryID, &b.RepositoryID, &b.Digest, &b.Size, &b.Location,
+			&b.CreatedAt, &b.UpdatedAt); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan unmigrated blob")
+			return nil, dberrors.ClassifyError(err, ListUnmigratedBlobsQuery)
+		}
+		blobs = append(blobs, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate unmigrated blobs")
+		return nil, dberrors.ClassifyError(err, ListUnmigratedBlobsQuery)
+	}
+
+	return blobs, nil
+}
+func (q *queries) ListRetentionRepositories(ctx context.Context, registryId, namespaceId,
+	repositoryId string) ([]*models.RetentionRepositoryModel, error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListRetentionRepositoriesQuery, registryId, namespaceId, namespaceId,
+		repositoryId, repositoryId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list repositories for retention")
+		return nil, dberrors.ClassifyError(err, ListRetentionRepositoriesQuery)
+	}
+	defer rows.Close()
+
+	repositories := []*models.RetentionRepositoryModel{}
+	for rows.Next() {
+		var r models.RetentionRepositoryModel
+		if err := rows.Scan(&r.ID, &r.NamespaceID, &r.Namespace, &r.Name); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan repository for retention")
+			return nil, dberrors.ClassifyError(err, ListRetentionRepositoriesQuery)
+		}
+		repositories = append(repositories, &r)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate repositories for retention")
+		return nil, dberrors.ClassifyError(err, ListRetentionRepositoriesQuery)
+	}
+
+	return repositories, nil
+}
+
+func (q *queries) ListRetentionTags(ctx context.Context, repositoryId string) ([]*models.RetentionTagModel, error) {
+	qr := q.getQuerier(ctx)
+
+	rows, err := qr.QueryContext(ctx, ListRetentionTagsQuery, repositoryId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list tags for retention")
+		return nil, dberrors.ClassifyError(err, ListRetentionTagsQuery)
+	}
+	defer rows.Close()
+
+	tags := []*models.RetentionTagModel{}
+	for rows.Next() {
+		var t models.RetentionTagModel
+		var pushedAt string
+		if err := rows.Scan(&t.Tag, &t.Digest, &t.IsStable, &pushedAt); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan tag for retention")
+			return nil, dberrors.ClassifyError(err, ListRetentionTagsQuery)
+		}
+		parsed, err := utils.ParseSqliteTimestamp(pushedAt)
+		if err != nil {
+			log.Logger().Error().Err(err).Msgf("failed to parse push time of tag: %s", t.Tag)
+			return nil, dberrors.ClassifyError(err, ListRetentionTagsQuery)
+		}
+		t.PushedAt = *parsed
+		tags = append(tags, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate tags for retention")
+		return nil, dberrors.ClassifyError(err, ListRetentionTagsQuery)
+	}
+
+	return tags, nil
+}
\ No newline at end of file
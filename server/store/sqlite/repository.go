@@ -318,6 +318,152 @@ func (r *repositoryStore) DeleteByIdentifier(ctx context.Context, namesapceID, i
 	return nil
 }
 
+func (r *repositoryStore) GetImmutableTagPatterns(ctx context.Context, id string) ([]string, error) {
+	q := r.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, RepositoryGetImmutableTagsQuery, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to retrieve immutable tag patterns")
+		return nil, dberrors.ClassifyError(err, RepositoryGetImmutableTagsQuery)
+	}
+	defer rows.Close()
+
+	patterns := []string{}
+	for rows.Next() {
+		var pattern string
+		if err := rows.Scan(&pattern); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan immutable tag pattern")
+			return nil, dberrors.ClassifyError(err, RepositoryGetImmutableTagsQuery)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate immutable tag patterns")
+		return nil, dberrors.ClassifyError(err, RepositoryGetImmutableTagsQuery)
+	}
+
+	return patterns, nil
+}
+
+func (r *repositoryStore) SetImmutableTagPatterns(ctx context.Context, id string, patterns []string) error {
+	q := r.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, RepositoryDeleteImmutableTagsQuery, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete immutable tag patterns")
+		return dberrors.ClassifyError(err, RepositoryDeleteImmutableTagsQuery)
+	}
+
+	for _, pattern := range patterns {
+		_, err = q.ExecContext(ctx, RepositoryAddImmutableTagQuery, id, pattern)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to add immutable tag pattern")
+			return dberrors.ClassifyError(err, RepositoryAddImmutableTagQuery)
+		}
+	}
+	return nil
+}
+
+func (r *repositoryStore) GetQuota(ctx context.Context, id string) (*models.QuotaModel, error) {
+	q := r.getQuerier(ctx)
+
+	var maxBytes, maxImages sql.NullInt64
+	var m models.QuotaModel
+	err := q.QueryRowContext(ctx, RepositoryGetQuotaQuery, id, id, id, id).Scan(&maxBytes, &maxImages, &m.UsedBytes,
+		&m.UsedImages)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to retrieve repository quota")
+		return nil, dberrors.ClassifyError(err, RepositoryGetQuotaQuery)
+	}
+
+	if maxBytes.Valid {
+		m.MaxBytes = &maxBytes.Int64
+	}
+	if maxImages.Valid {
+		m.MaxImages = &maxImages.Int64
+	}
+	return &m, nil
+}
+
+func (r *repositoryStore) SetQuota(ctx context.Context, id string, maxBytes, maxImages *int64) error {
+	q := r.getQuerier(ctx)
+
+	if maxBytes == nil && maxImages == nil {
+		_, err := q.ExecContext(ctx, RepositoryDeleteQuotaQuery, id)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to delete repository quota")
+			return dberrors.ClassifyError(err, RepositoryDeleteQuotaQuery)
+		}
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, RepositorySetQuotaQuery, id, maxBytes, maxImages)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to update repository quota")
+		return dberrors.ClassifyError(err, RepositorySetQuotaQuery)
+	}
+	return nil
+}
+
+func (r *repositoryStore) GetRetentionRules(ctx context.Context, id string) ([]*models.RetentionRuleModel, error) {
+	q := r.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, RepositoryGetRetentionRulesQuery, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to retrieve repository retention rules")
+		return nil, dberrors.ClassifyError(err, RepositoryGetRetentionRulesQuery)
+	}
+	defer rows.Close()
+
+	return scanRetentionRules(rows, RepositoryGetRetentionRulesQuery)
+}
+
+func (r *repositoryStore) SetRetentionRules(ctx context.Context, id string, rules []*models.RetentionRuleModel) error {
+	q := r.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, RepositoryDeleteRetentionRulesQuery, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete repository retention rules")
+		return dberrors.ClassifyError(err, RepositoryDeleteRetentionRulesQuery)
+	}
+
+	for _, rule := range rules {
+		_, err = q.ExecContext(ctx, RepositoryAddRetentionRuleQuery, id, rule.TagPattern, rule.KeepLast, rule.MaxAgeDays)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to add repository retention rule")
+			return dberrors.ClassifyError(err, RepositoryAddRetentionRuleQuery)
+		}
+	}
+	return nil
+}
+
+// scanRetentionRules reads retention rules of a namespace or a repository
+func scanRetentionRules(rows *sql.Rows, query string) ([]*models.RetentionRuleModel, error) {
+	rules := []*models.RetentionRuleModel{}
+	for rows.Next() {
+		var keepLast, maxAgeDays sql.NullInt64
+		var m models.RetentionRuleModel
+		if err := rows.Scan(&m.TagPattern, &keepLast, &maxAgeDays); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan retention rule")
+			return nil, dberrors.ClassifyError(err, query)
+		}
+		if keepLast.Valid {
+			m.KeepLast = &keepLast.Int64
+		}
+		if maxAgeDays.Valid {
+			m.MaxAgeDays = &maxAgeDays.Int64
+		}
+		rules = append(rules, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate retention rules")
+		return nil, dberrors.ClassifyError(err, query)
+	}
+	return rules, nil
+}
+
 // identifier can be name or id
 func (r *repositoryStore) GetByIdentifier(ctx context.Context, namesapceID, identifier string) (*models.RepositoryModel,
 	error) {
@@ -328,7 +474,8 @@ func (r *repositoryStore) GetByIdentifier(ctx context.Context, namesapceID, iden
 	var createdAt, updatedAt string
 
 	var m models.RepositoryModel
-	err := row.Scan(&m.ID, &m.Name, &m.Description, &m.IsPublic, &m.NamespaceID, &m.RegistryID, &createdAt, &updatedAt)
+	err := row.Scan(&m.ID, &m.Name, &m.Description, &m.IsPublic, &m.State, &m.NamespaceID, &m.RegistryID, &createdAt, &updatedAt,
+		&m.CreatedBy)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // not found
@@ -26,6 +26,7 @@ type Store struct {
 	tag        *imageTagStore
 	user       *userStore
 	upstream   *upstreamStore
+	webhook    *webhookStore
 
 	queries *queries
 }
@@ -48,6 +49,14 @@ func New(config config.DatabaseConfig) (*Store, error) {
 		return nil, err
 	}
 
+	// Upgrade tables created by earlier versions before the schema script recreates their triggers
+	if err = migrate(database); err != nil {
+		database.Close()
+		database = nil
+		log.Logger().Error().Err(err).Msgf("Error occured when migrating schema")
+		return nil, err
+	}
+
 	// Run schema migrations
 	contentBytes, err := os.ReadFile(config.ScriptsPath)
 	if err != nil {
@@ -81,6 +90,7 @@ func NewWithDB(db *sql.DB) *Store {
 	s.upstream = newUpstreamStore(db)
 	s.user = newUserStore(db)
 	s.tag = newImageStore(db)
+	s.webhook = newWebhookStore(db)
 
 	s.queries = newQueries(db)
 
@@ -122,6 +132,10 @@ func (s *Store) Upstreams() store.UpstreamRegistyStore {
 	return s.upstream
 }
 
+func (s *Store) Webhooks() store.WebhookStore {
+	return s.webhook
+}
+
 func (s *Store) ImageQueries() store.ImageQueries {
 	return s.queries
 }
@@ -92,12 +92,18 @@ func (t *imageTagStore) Get(
 	return &m, nil
 }
 
-// Delete removes a tag
+// Delete removes a tag and its link to manifest
 func (t *imageTagStore) Delete(ctx context.Context, repositoryId, tag string) error {
 
 	q := t.getQuerier(ctx)
 
-	_, err := q.ExecContext(ctx, TagDeleteQuery, repositoryId, tag)
+	_, err := q.ExecContext(ctx, TagDeleteMappingQuery, repositoryId, tag)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to unlink manifest of image tag")
+		return dberrors.ClassifyError(err, TagDeleteMappingQuery)
+	}
+
+	_, err = q.ExecContext(ctx, TagDeleteQuery, repositoryId, tag)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("failed to delete image tag")
 		return dberrors.ClassifyError(err, TagDeleteQuery)
@@ -117,18 +123,6 @@ func (t *imageTagStore) LinkManifest(ctx context.Context, tagId, manifestId stri
 	return nil
 }
 
-func (t *imageTagStore) UpdateManifest(ctx context.Context, tagId, manifestId string) error {
-
-	q := t.getQuerier(ctx)
-
-	_, err := q.ExecContext(ctx, TagUpdateManifestQuery, manifestId, tagId)
-	if err != nil {
-		log.Logger().Error().Err(err).Msg("failed to update manifest link")
-		return dberrors.ClassifyError(err, TagUpdateManifestQuery)
-	}
-	return nil
-}
-
 func (t *imageTagStore) UnlinkManifest(ctx context.Context, tagId string) error {
 
 	q := t.getQuerier(ctx)
@@ -158,3 +152,92 @@ func (t *imageTagStore) GetManifestID(ctx context.Context, tagId string) (string
 
 	return manifestId, nil
 }
+
+func (t *imageTagStore) ListByManifest(ctx context.Context, manifestId string) ([]*models.ImageTagModel, error) {
+	q := t.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, TagListByManifestQuery, manifestId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list tags of manifest")
+		return nil, dberrors.ClassifyError(err, TagListByManifestQuery)
+	}
+	defer rows.Close()
+
+	var tags []*models.ImageTagModel
+	for rows.Next() {
+		var m models.ImageTagModel
+		if err := rows.Scan(&m.Tag, &m.IsStable); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan tag of manifest")
+			return nil, dberrors.ClassifyError(err, TagListByManifestQuery)
+		}
+		tags = append(tags, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate tags of manifest")
+		return nil, dberrors.ClassifyError(err, TagListByManifestQuery)
+	}
+
+	return tags, nil
+}
+
+func (t *imageTagStore) SetStable(ctx context.Context, tagId string, stable bool) error {
+	q := t.getQuerier(ctx)
+
+	var isStable = 0
+	if stable {
+		isStable = 1
+	}
+
+	_, err := q.ExecContext(ctx, TagSetStableQuery, isStable, tagId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to change tag stability")
+		return dberrors.ClassifyError(err, TagSetStableQuery)
+	}
+	return nil
+}
+
+// Touch records that the tag was pushed now. Retention rules use it as the push time of the tag.
+func (t *imageTagStore) Touch(ctx context.Context, tagId string) error {
+	q := t.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, TagTouchQuery, tagId)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to update push time of tag")
+		return dberrors.ClassifyError(err, TagTouchQuery)
+	}
+	return nil
+}
+
+func (t *imageTagStore) List(ctx context.Context, repositoryId, last string, limit int) ([]string, error) {
+	q := t.getQuerier(ctx)
+
+	// sqlite treats negative limit as no limit
+	if limit < 1 {
+		limit = -1
+	}
+
+	rows, err := q.QueryContext(ctx, TagListQuery, repositoryId, last, limit)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list image tags")
+		return nil, dberrors.ClassifyError(err, TagListQuery)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan image tag")
+			return nil, dberrors.ClassifyError(err, TagListQuery)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate image tags")
+		return nil, dberrors.ClassifyError(err, TagListQuery)
+	}
+
+	return tags, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/ksankeerth/open-image-registry/errors/dberrors"
 	"github.com/ksankeerth/open-image-registry/log"
@@ -67,7 +68,7 @@ func (u *upstreamStore) GetRegistry(ctx context.Context, registryID string) (*mo
 		return nil, dberrors.ClassifyError(err, UpstreamGetQuery)
 	}
 
-	if createdAt == "" {
+	if createdAt != "" {
 		createdTime, err := utils.ParseSqliteTimestamp(createdAt)
 		if err != nil {
 			log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
@@ -76,7 +77,7 @@ func (u *upstreamStore) GetRegistry(ctx context.Context, registryID string) (*mo
 		m.CreatedAt = *createdTime
 	}
 
-	if updatedAt == "" {
+	if updatedAt != "" {
 		m.UpdatedAt, err = utils.ParseSqliteTimestamp(updatedAt)
 		if err != nil {
 			log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
@@ -124,10 +125,10 @@ func (u *upstreamStore) PersistRegistryAuthConfig(ctx context.Context, m *models
 func (u *upstreamStore) UpdateRegistryAuthConfig(ctx context.Context, m *models.UpstreamRegistryAuthConfig) error {
 	q := u.getQuerier(ctx)
 
-	_, err := q.ExecContext(ctx, UpstreamGetAuthConfigQuery, m.AuthType, m.ConfigJSON, m.RegistryID)
+	_, err := q.ExecContext(ctx, UpstreamUpdateAuthConfigQuery, m.AuthType, m.ConfigJSON, m.RegistryID)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("failed to update upstream registry auth config")
-		return dberrors.ClassifyError(err, UpstreamGetAuthConfigQuery)
+		return dberrors.ClassifyError(err, UpstreamUpdateAuthConfigQuery)
 	}
 
 	return nil
@@ -136,18 +137,27 @@ func (u *upstreamStore) UpdateRegistryAuthConfig(ctx context.Context, m *models.
 func (u *upstreamStore) GetRegistryAuthConfig(ctx context.Context, registryID string) (*models.UpstreamRegistryAuthConfig, error) {
 	q := u.getQuerier(ctx)
 
-	var m models.UpstreamRegistryAuthConfig
+	m := models.UpstreamRegistryAuthConfig{
+		RegistryID: registryID,
+	}
 
 	var createdAt, updatedAt string
 
 	err := q.QueryRowContext(ctx, UpstreamGetAuthConfigQuery, registryID).
 		Scan(&m.AuthType, &m.ConfigJSON, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to retrieve registry auth config")
+		return nil, dberrors.ClassifyError(err, UpstreamGetAuthConfigQuery)
+	}
 
 	if createdAt != "" {
 		createdTime, err := utils.ParseSqliteTimestamp(createdAt)
 		if err != nil {
 			log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
-			return nil, dberrors.ClassifyError(err, UpstreamGetCacheConfigQuery)
+			return nil, dberrors.ClassifyError(err, UpstreamGetAuthConfigQuery)
 		}
 		m.CreatedAt = *createdTime
 	}
@@ -156,7 +166,7 @@ func (u *upstreamStore) GetRegistryAuthConfig(ctx context.Context, registryID st
 		m.UpdatedAt, err = utils.ParseSqliteTimestamp(updatedAt)
 		if err != nil {
 			log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
-			return nil, dberrors.ClassifyError(err, UpstreamGetCacheConfigQuery)
+			return nil, dberrors.ClassifyError(err, UpstreamGetAuthConfigQuery)
 		}
 	}
 
@@ -166,11 +176,18 @@ func (u *upstreamStore) GetRegistryAuthConfig(ctx context.Context, registryID st
 func (u *upstreamStore) PersistRegistryCacheConfig(ctx context.Context, m *models.UpstreamRegistryCacheStoreConfig) error {
 	q := u.getQuerier(ctx)
 
-	var cacheEnabled int
+	var cacheEnabled, staleIfError, offlineMode int
 	if m.CacheEnabled {
 		cacheEnabled = 1
 	}
-	_, err := q.ExecContext(ctx, UpstreamPersistCacheConfigQuery, m.RegistryID, cacheEnabled, m.TTLSeconds, m.StorageLimit, m.CleanupThreshold)
+	if m.StaleIfError {
+		staleIfError = 1
+	}
+	if m.OfflineMode {
+		offlineMode = 1
+	}
+	_, err := q.ExecContext(ctx, UpstreamPersistCacheConfigQuery, m.RegistryID, cacheEnabled, m.TTLSeconds, m.StorageLimit, m.CleanupThreshold,
+		staleIfError, offlineMode)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("failed to persist upstream registry cache config")
 		return dberrors.ClassifyError(err, UpstreamPersistCacheConfigQuery)
@@ -182,12 +199,18 @@ func (u *upstreamStore) PersistRegistryCacheConfig(ctx context.Context, m *model
 func (u *upstreamStore) UpdateRegistryCacheConfig(ctx context.Context, m *models.UpstreamRegistryCacheStoreConfig) error {
 	q := u.getQuerier(ctx)
 
-	var cacheEnabled int
+	var cacheEnabled, staleIfError, offlineMode int
 	if m.CacheEnabled {
 		cacheEnabled = 1
 	}
+	if m.StaleIfError {
+		staleIfError = 1
+	}
+	if m.OfflineMode {
+		offlineMode = 1
+	}
 	_, err := q.ExecContext(ctx, UpstreamUpdateCacheConfigQuery, cacheEnabled, m.TTLSeconds, m.StorageLimit,
-		m.CleanupThreshold, m.RegistryID)
+		m.CleanupThreshold, staleIfError, offlineMode, m.RegistryID)
 	if err != nil {
 		log.Logger().Error().Err(err).Msg("failed to update upstream registry cache config")
 		return dberrors.ClassifyError(err, UpstreamUpdateCacheConfigQuery)
@@ -199,12 +222,15 @@ func (u *upstreamStore) UpdateRegistryCacheConfig(ctx context.Context, m *models
 func (u *upstreamStore) GetRegistryCacheConfig(ctx context.Context, registryID string) (*models.UpstreamRegistryCacheStoreConfig, error) {
 	q := u.getQuerier(ctx)
 
-	var m models.UpstreamRegistryCacheStoreConfig
+	m := models.UpstreamRegistryCacheStoreConfig{
+		RegistryID: registryID,
+	}
 	var createdAt, updatedAt string
-	var cacheEnabled int
+	var cacheEnabled, staleIfError, offlineMode int
 
 	err := q.QueryRowContext(ctx, UpstreamGetCacheConfigQuery, registryID).
-		Scan(&cacheEnabled, &m.TTLSeconds, &m.StorageLimit, &m.CleanupThreshold, &createdAt, &updatedAt)
+		Scan(&cacheEnabled, &m.TTLSeconds, &m.StorageLimit, &m.CleanupThreshold, &staleIfError, &offlineMode,
+			&createdAt, &updatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -233,10 +259,65 @@ func (u *upstreamStore) GetRegistryCacheConfig(ctx context.Context, registryID s
 	if cacheEnabled == 1 {
 		m.CacheEnabled = true
 	}
+	m.StaleIfError = staleIfError == 1
+	m.OfflineMode = offlineMode == 1
 
 	return &m, nil
 }
 
+func (u *upstreamStore) ListRegistryCacheConfigs(ctx context.Context) ([]*models.UpstreamRegistryCacheStoreConfig,
+	error) {
+	q := u.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, UpstreamListCacheConfigsQuery)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list registry cache configs")
+		return nil, dberrors.ClassifyError(err, UpstreamListCacheConfigsQuery)
+	}
+	defer rows.Close()
+
+	configs := []*models.UpstreamRegistryCacheStoreConfig{}
+	for rows.Next() {
+		var m models.UpstreamRegistryCacheStoreConfig
+		var createdAt, updatedAt string
+		var cacheEnabled, staleIfError, offlineMode int
+
+		err = rows.Scan(&m.RegistryID, &cacheEnabled, &m.TTLSeconds, &m.StorageLimit, &m.CleanupThreshold,
+			&staleIfError, &offlineMode, &createdAt, &updatedAt)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan registry cache config")
+			return nil, dberrors.ClassifyError(err, UpstreamListCacheConfigsQuery)
+		}
+
+		createdTime, err := utils.ParseSqliteTimestamp(createdAt)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
+			return nil, dberrors.ClassifyError(err, UpstreamListCacheConfigsQuery)
+		}
+		if createdTime != nil {
+			m.CreatedAt = *createdTime
+		}
+
+		m.UpdatedAt, err = utils.ParseSqliteTimestamp(updatedAt)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
+			return nil, dberrors.ClassifyError(err, UpstreamListCacheConfigsQuery)
+		}
+
+		m.CacheEnabled = cacheEnabled == 1
+		m.StaleIfError = staleIfError == 1
+		m.OfflineMode = offlineMode == 1
+		configs = append(configs, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate registry cache configs")
+		return nil, dberrors.ClassifyError(err, UpstreamListCacheConfigsQuery)
+	}
+
+	return configs, nil
+}
+
 func (u *upstreamStore) PersistRegistryNetworkConfig(ctx context.Context, m *models.UpstreamRegistryNetworkConfig) error {
 	q := u.getQuerier(ctx)
 
@@ -265,7 +346,9 @@ func (u *upstreamStore) UpdateRegistryNetworkConfig(ctx context.Context, m *mode
 
 func (u *upstreamStore) GetRegistryNetworkConfig(ctx context.Context, registryID string) (*models.UpstreamRegistryNetworkConfig, error) {
 	q := u.getQuerier(ctx)
-	var m models.UpstreamRegistryNetworkConfig
+	m := models.UpstreamRegistryNetworkConfig{
+		RegistryID: registryID,
+	}
 	var createdAt, updatedAt string
 
 	err := q.QueryRowContext(ctx, UpstreamGetNetworkConfigQuery, registryID).Scan(&m.ConnectionTimeout, &m.ReadTimeout, &m.WriteTimeout, &m.MaxConnections,
@@ -317,6 +400,77 @@ func (u *upstreamStore) GetAllUpstreamRegistryAddresses(ctx context.Context) (ad
 			log.Logger().Error().Err(err).Msg("failed to read upstream addresses")
 			return nil, dberrors.ClassifyError(err, UpstreamGetAllAddresses)
 		}
+		addresses = append(addresses, &addr)
 	}
 	return addresses, nil
 }
+
+func (u *upstreamStore) ListRegistries(ctx context.Context, conditions *store.ListQueryConditions) (registries []*models.UpstreamRegistryView,
+	total int, err error) {
+	qb := store.NewQueryBuilder(store.DBTypeSqlite).
+		WithSearchFields("NAME", "DESCRIPTION", "UPSTREAM_URL").
+		WithAllowedFilterFields("VENDOR", "STATE").
+		WithAllowedSortFields("NAME", "PORT", "CREATED_AT")
+
+	listQuery, countQuery, args, err := qb.Build(UpstreamListBaseQuery, UpstreamCountBaseQuery, conditions)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to build upstream registry list query")
+		return nil, 0, fmt.Errorf("build query: %w", err)
+	}
+
+	countArgs := args[:len(args)-2]
+
+	q := u.getQuerier(ctx)
+
+	err = q.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to count total upstream registries")
+		return nil, 0, dberrors.ClassifyError(err, countQuery)
+	}
+
+	rows, err := q.QueryContext(ctx, listQuery, args...)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("Failed to retrieve upstream registries")
+		return nil, 0, dberrors.ClassifyError(err, listQuery)
+	}
+	defer rows.Close()
+
+	registries = make([]*models.UpstreamRegistryView, 0)
+	for rows.Next() {
+		var m models.UpstreamRegistryView
+		var createdAt, updatedAt sql.NullString
+
+		err = rows.Scan(&m.ID, &m.Name, &m.Description, &m.Vendor, &m.State, &m.Port, &m.UpstreamURL,
+			&m.CachedImagesCount, &createdAt, &updatedAt)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("Failed to scan upstream registry")
+			return nil, 0, dberrors.ClassifyError(err, listQuery)
+		}
+
+		if createdAt.Valid {
+			createdTime, err := utils.ParseSqliteTimestamp(createdAt.String)
+			if err != nil {
+				log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
+				return nil, 0, dberrors.ClassifyError(err, listQuery)
+			}
+			m.CreatedAt = *createdTime
+		}
+
+		if updatedAt.Valid {
+			m.UpdatedAt, err = utils.ParseSqliteTimestamp(updatedAt.String)
+			if err != nil {
+				log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
+				return nil, 0, dberrors.ClassifyError(err, listQuery)
+			}
+		}
+
+		registries = append(registries, &m)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("Error during row iteration")
+		return nil, 0, dberrors.ClassifyError(err, listQuery)
+	}
+
+	return registries, total, nil
+}
\ No newline at end of file
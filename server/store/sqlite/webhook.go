@@ -0,0 +1,230 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/errors/dberrors"
+	"github.com/ksankeerth/open-image-registry/log"
+	"github.com/ksankeerth/open-image-registry/store"
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/ksankeerth/open-image-registry/utils"
+)
+
+type webhookStore struct {
+	db *sql.DB
+}
+
+func newWebhookStore(db *sql.DB) *webhookStore {
+	return &webhookStore{db: db}
+}
+
+func (w *webhookStore) getQuerier(ctx context.Context) store.Querier {
+	if tx, ok := store.TxFromContext(ctx); ok {
+		return tx
+	}
+	return w.db
+}
+
+func (w *webhookStore) Create(ctx context.Context, m *models.WebhookModel) (id string, err error) {
+	q := w.getQuerier(ctx)
+
+	var namespaceId any
+	if m.NamespaceID != "" {
+		namespaceId = m.NamespaceID
+	}
+	var enabled = 0
+	if m.Enabled {
+		enabled = 1
+	}
+
+	err = q.QueryRowContext(ctx, WebhookCreateQuery, m.Name, m.URL, m.Secret, namespaceId,
+		strings.Join(m.Events, ","), enabled).Scan(&id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to create webhook")
+		return "", dberrors.ClassifyError(err, WebhookCreateQuery)
+	}
+	return id, nil
+}
+
+func (w *webhookStore) Get(ctx context.Context, id string) (*models.WebhookModel, error) {
+	q := w.getQuerier(ctx)
+
+	m, err := scanWebhook(q.QueryRowContext(ctx, WebhookGetQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Logger().Error().Err(err).Msg("failed to get webhook")
+		return nil, dberrors.ClassifyError(err, WebhookGetQuery)
+	}
+	return m, nil
+}
+
+func (w *webhookStore) Update(ctx context.Context, m *models.WebhookModel) error {
+	q := w.getQuerier(ctx)
+
+	var enabled = 0
+	if m.Enabled {
+		enabled = 1
+	}
+
+	_, err := q.ExecContext(ctx, WebhookUpdateQuery, m.URL, m.Secret, strings.Join(m.Events, ","),
+		enabled, m.ID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to update webhook")
+		return dberrors.ClassifyError(err, WebhookUpdateQuery)
+	}
+	return nil
+}
+
+func (w *webhookStore) Delete(ctx context.Context, id string) error {
+	q := w.getQuerier(ctx)
+
+	_, err := q.ExecContext(ctx, WebhookDeleteQuery, id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to delete webhook")
+		return dberrors.ClassifyError(err, WebhookDeleteQuery)
+	}
+	return nil
+}
+
+func (w *webhookStore) List(ctx context.Context, namespaceId string) ([]*models.WebhookModel, error) {
+	return w.list(ctx, WebhookListQuery, namespaceId, namespaceId)
+}
+
+func (w *webhookStore) ListSubscribers(ctx context.Context, namespaceId string) ([]*models.WebhookModel, error) {
+	return w.list(ctx, WebhookListSubscribersQuery, namespaceId)
+}
+
+func (w *webhookStore) list(ctx context.Context, query string, args ...any) ([]*models.WebhookModel, error) {
+	q := w.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list webhooks")
+		return nil, dberrors.ClassifyError(err, query)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.WebhookModel{}
+	for rows.Next() {
+		m, err := scanWebhook(rows)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan webhook")
+			return nil, dberrors.ClassifyError(err, query)
+		}
+		webhooks = append(webhooks, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate webhooks")
+		return nil, dberrors.ClassifyError(err, query)
+	}
+	return webhooks, nil
+}
+
+func (w *webhookStore) CreateDelivery(ctx context.Context, m *models.WebhookDeliveryModel) (id string, err error) {
+	q := w.getQuerier(ctx)
+
+	err = q.QueryRowContext(ctx, WebhookCreateDeliveryQuery, m.WebhookID, m.EventID, m.Action, m.Payload,
+		m.Status).Scan(&id)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to create webhook delivery")
+		return "", dberrors.ClassifyError(err, WebhookCreateDeliveryQuery)
+	}
+	return id, nil
+}
+
+func (w *webhookStore) UpdateDelivery(ctx context.Context, m *models.WebhookDeliveryModel) error {
+	q := w.getQuerier(ctx)
+
+	var responseCode any
+	if m.ResponseCode != 0 {
+		responseCode = m.ResponseCode
+	}
+
+	_, err := q.ExecContext(ctx, WebhookUpdateDeliveryQuery, m.Status, m.Attempts, responseCode, m.Error, m.ID)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to update webhook delivery")
+		return dberrors.ClassifyError(err, WebhookUpdateDeliveryQuery)
+	}
+	return nil
+}
+
+func (w *webhookStore) ListDeliveries(ctx context.Context, webhookId string,
+	limit int) ([]*models.WebhookDeliveryModel, error) {
+	q := w.getQuerier(ctx)
+
+	rows, err := q.QueryContext(ctx, WebhookListDeliveriesQuery, webhookId, limit)
+	if err != nil {
+		log.Logger().Error().Err(err).Msg("failed to list webhook deliveries")
+		return nil, dberrors.ClassifyError(err, WebhookListDeliveriesQuery)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDeliveryModel{}
+	for rows.Next() {
+		var m models.WebhookDeliveryModel
+		var responseCode sql.NullInt64
+		var createdAt, updatedAt string
+		err = rows.Scan(&m.ID, &m.WebhookID, &m.EventID, &m.Action, &m.Payload, &m.Status, &m.Attempts,
+			&responseCode, &m.Error, &createdAt, &updatedAt)
+		if err != nil {
+			log.Logger().Error().Err(err).Msg("failed to scan webhook delivery")
+			return nil, dberrors.ClassifyError(err, WebhookListDeliveriesQuery)
+		}
+		m.ResponseCode = int(responseCode.Int64)
+
+		if err = parseTimestamps(&m.CreatedAt, &m.UpdatedAt, createdAt, updatedAt); err != nil {
+			log.Logger().Error().Err(err).Msg("failed to parse sqlite timestamp")
+			return nil, dberrors.ClassifyError(err, WebhookListDeliveriesQuery)
+		}
+		deliveries = append(deliveries, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Logger().Error().Err(err).Msg("failed to iterate webhook deliveries")
+		return nil, dberrors.ClassifyError(err, WebhookListDeliveriesQuery)
+	}
+	return deliveries, nil
+}
+
+// scanWebhook reads a webhook from the result of WebhookGetQuery, WebhookListQuery or WebhookListSubscribersQuery
+func scanWebhook(row interface{ Scan(dest ...any) error }) (*models.WebhookModel, error) {
+	var m models.WebhookModel
+	var events, createdAt, updatedAt string
+	var enabled int
+	err := row.Scan(&m.ID, &m.Name, &m.URL, &m.Secret, &m.NamespaceID, &m.Namespace, &events, &enabled,
+		&createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Enabled = enabled == 1
+	m.Events = []string{}
+	if events != "" {
+		m.Events = strings.Split(events, ",")
+	}
+
+	if err = parseTimestamps(&m.CreatedAt, &m.UpdatedAt, createdAt, updatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func parseTimestamps(created *time.Time, updated **time.Time, createdAt, updatedAt string) error {
+	createdTime, err := utils.ParseSqliteTimestamp(createdAt)
+	if err != nil {
+		return err
+	}
+	if createdTime != nil {
+		*created = *createdTime
+	}
+
+	*updated, err = utils.ParseSqliteTimestamp(updatedAt)
+	return err
+}
\ No newline at end of file
@@ -17,6 +17,7 @@ type Store interface {
 	AccountRecovery() AccountRecoveryStore
 	Auth() AuthStore
 	Upstreams() UpstreamRegistyStore
+	Webhooks() WebhookStore
 
 	// Queries
 	ImageQueries() ImageQueries
@@ -13,14 +13,24 @@ type ImageTagStore interface {
 
 	Delete(ctx context.Context, repositoryId, tag string) (err error)
 
+	// LinkManifest points the tag to the manifest. If the tag already points to another manifest, it is moved.
+	// A manifest can be pointed by any number of tags.
 	LinkManifest(ctx context.Context, tagId, manifestId string) error
 
-	UpdateManifest(ctx context.Context, tagId, newManifestId string) error
-
 	UnlinkManifest(ctx context.Context, tagId string) error
 
 	GetManifestID(ctx context.Context, tagId string) (string, error)
 
-	// List(ctx context.Context, conditions *ListQueryConditions) (tags []*models.RepositoryView,
-	// 	total int, err error)
+	// ListByManifest returns the tags pointing to the manifest. Only names and stability of the tags are loaded.
+	ListByManifest(ctx context.Context, manifestId string) ([]*models.ImageTagModel, error)
+
+	// Touch records that the tag was pushed now
+	Touch(ctx context.Context, tagId string) error
+
+	// SetStable marks the tag stable. Stable tags can't be moved to another manifest.
+	SetStable(ctx context.Context, tagId string, stable bool) error
+
+	// List returns tag names of the repository in lexical order, starting after `last`.
+	// If limit is less than 1, all remaining tags will be returned.
+	List(ctx context.Context, repositoryId, last string, limit int) (tags []string, err error)
 }
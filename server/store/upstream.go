@@ -29,11 +29,18 @@ type UpstreamRegistyStore interface {
 
 	GetRegistryCacheConfig(ctx context.Context, registryID string) (*models.UpstreamRegistryCacheStoreConfig, error)
 
+	// ListRegistryCacheConfigs returns cache configs of all upstream registries
+	ListRegistryCacheConfigs(ctx context.Context) ([]*models.UpstreamRegistryCacheStoreConfig, error)
+
 	PersistRegistryNetworkConfig(ctx context.Context, m *models.UpstreamRegistryNetworkConfig) error
 
 	UpdateRegistryNetworkConfig(ctx context.Context, m *models.UpstreamRegistryNetworkConfig) error
 
 	GetRegistryNetworkConfig(ctx context.Context, registryID string) (*models.UpstreamRegistryNetworkConfig, error)
 
+	// GetAllUpstreamRegistryAddresses returns addresses of upstream registries which are not disabled
 	GetAllUpstreamRegistryAddresses(ctx context.Context) (addresses []*models.UpstreamAddressView, err error)
+
+	ListRegistries(ctx context.Context, conditions *ListQueryConditions) (registries []*models.UpstreamRegistryView,
+		total int, err error)
 }
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+
+	"github.com/ksankeerth/open-image-registry/types/models"
+)
+
+type WebhookStore interface {
+	Create(ctx context.Context, m *models.WebhookModel) (id string, err error)
+
+	Get(ctx context.Context, id string) (*models.WebhookModel, error)
+
+	Update(ctx context.Context, m *models.WebhookModel) error
+
+	Delete(ctx context.Context, id string) error
+
+	// List returns webhooks of the namespace. All webhooks are returned if namespaceId is empty.
+	List(ctx context.Context, namespaceId string) ([]*models.WebhookModel, error)
+
+	// ListSubscribers returns enabled webhooks receiving events of the namespace; global webhooks and webhooks of
+	// the namespace.
+	ListSubscribers(ctx context.Context, namespaceId string) ([]*models.WebhookModel, error)
+
+	CreateDelivery(ctx context.Context, m *models.WebhookDeliveryModel) (id string, err error)
+
+	UpdateDelivery(ctx context.Context, m *models.WebhookDeliveryModel) error
+
+	// ListDeliveries returns the latest deliveries of the webhook, most recent first
+	ListDeliveries(ctx context.Context, webhookId string, limit int) ([]*models.WebhookDeliveryModel, error)
+}
\ No newline at end of file
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http/httptest"
@@ -12,7 +13,9 @@ import (
 
 	"github.com/ksankeerth/open-image-registry/client/email"
 	"github.com/ksankeerth/open-image-registry/config"
+	"github.com/ksankeerth/open-image-registry/constants"
 	"github.com/ksankeerth/open-image-registry/lib"
+	"github.com/ksankeerth/open-image-registry/registry"
 	"github.com/ksankeerth/open-image-registry/resource/access"
 	"github.com/ksankeerth/open-image-registry/rest"
 	"github.com/ksankeerth/open-image-registry/storage"
@@ -21,10 +24,15 @@ import (
 	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
 	"github.com/ksankeerth/open-image-registry/tests/integration/seeder"
 	v1 "github.com/ksankeerth/open-image-registry/tests/integration/v1"
+	v2 "github.com/ksankeerth/open-image-registry/tests/integration/v2"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
 )
 
 var (
 	testServer      *httptest.Server
+	registryServer  *httptest.Server
+	uploadReaper    *registry.UploadSessionReaper
+	eventDispatcher *registry.EventDispatcher
 	testStore       store.Store
 	testConfig      *config.AppConfig
 	testBaseURL     string
@@ -75,6 +83,21 @@ func TestIntegrationV1(t *testing.T) {
 		v1.NewAuthTestSuite(seeder, testBaseURL),
 		v1.NewNamespaceTestSuite(seeder, testBaseURL),
 		v1.NewRepositorySuite(seeder, testBaseURL),
+		v1.NewGCTestSuite(seeder, testBaseURL),
+		v1.NewWebhookTestSuite(seeder, testBaseURL),
+		v1.NewUpstreamTestSuite(seeder, testBaseURL),
+	}
+
+	for _, suite := range suites {
+		t.Run(fmt.Sprintf("%s: %s", suite.Name(), suite.APIVersion()), suite.Run)
+	}
+}
+
+func TestIntegrationV2(t *testing.T) {
+	seeder := seeder.NewTestDataSeeder(testBaseURL, testStore, jwtProvider)
+
+	suites := []APITestSuite{
+		v2.NewRegistryTestSuite(seeder, registryServer.URL),
 	}
 
 	for _, suite := range suites {
@@ -129,6 +152,10 @@ func setupTestEnvironment() error {
 	}
 	log.Println("├─ Storage initialized")
 
+	if err := registry.MigrateBlobStorage(context.Background(), store); err != nil {
+		return fmt.Errorf("failed to migrate blob storage: %w", err)
+	}
+
 	log.Println("├─ Creating admin user account...")
 	if err := helpers.InitializeAdminUserAccount(store, &appConfig.Admin); err != nil {
 		return fmt.Errorf("failed to create admin account: %w", err)
@@ -158,8 +185,14 @@ func setupTestEnvironment() error {
 
 	jwtProvider = jwtAuth
 
+	eventDispatcher = registry.NewEventDispatcher(store, appConfig.Notification.Webhook)
+	eventDispatcher.Start()
+
 	log.Println("├─ Creating HTTP server...")
-	appRouter := rest.AppRouter(&appConfig.WebApp, store, jwtAuth, accessManager, testEmailClient)
+	appRouter := rest.AppRouter(&appConfig.WebApp, store, jwtAuth, accessManager, testEmailClient,
+		registry.NewGarbageCollector(store, appConfig.ImageRegistry.GC),
+		registry.NewRetentionManager(store, appConfig.ImageRegistry.Retention, eventDispatcher), eventDispatcher,
+		registry.NewCacheEvictor(store, appConfig.UpstreamRegistry.CacheEviction))
 
 	testServer = httptest.NewServer(appRouter)
 	testBaseURL = testServer.URL
@@ -167,7 +200,17 @@ func setupTestEnvironment() error {
 	if err := helpers.WaitForServer(testBaseURL, 10*time.Second); err != nil {
 		return fmt.Errorf("server failed to start: %w", err)
 	}
-	log.Printf("└─ Server ready at: %s", testBaseURL)
+	log.Printf("├─ Server ready at: %s", testBaseURL)
+
+	appConfig.ImageRegistry.TokenRealm = testBaseURL + testdata.EndpointToken
+	registryHandler := registry.NewRegistryHandler(constants.HostedRegistryID, constants.HostedRegistryName, store, jwtAuth,
+		eventDispatcher)
+	registryServer = httptest.NewServer(registryHandler.Routes())
+	log.Printf("├─ Registry ready at: %s", registryServer.URL)
+
+	uploadReaper = registry.NewUploadSessionReaper(store, appConfig.ImageRegistry.UploadSession)
+	uploadReaper.Start()
+	log.Println("└─ Upload session reaper started")
 
 	return nil
 }
@@ -181,6 +224,18 @@ func teardownTestEnvironment() error {
 		}
 	}
 
+	if registryServer != nil {
+		registryServer.Close()
+	}
+
+	if eventDispatcher != nil {
+		eventDispatcher.Stop()
+	}
+
+	if uploadReaper != nil {
+		uploadReaper.Stop()
+	}
+
 	if testConfig == nil {
 		return nil
 	}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 
 	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
 	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,4 +78,40 @@ func (s *TestDataSeeder) SetNamespaceDisabled(t *testing.T, id string) {
 
 	err := s.store.Namespaces().SetStateByID(context.Background(), id, "Disabled")
 	require.NoError(t, err)
+}
+
+// SetNamespaceQuota replaces the quota of the namespace. nil limits are unlimited.
+func (s *TestDataSeeder) SetNamespaceQuota(t *testing.T, id string, maxBytes, maxImages *int64) {
+	t.Helper()
+
+	err := s.store.Namespaces().SetQuota(context.Background(), id, maxBytes, maxImages)
+	require.NoError(t, err)
+}
+
+// NamespaceQuota returns the quota and the usage of the namespace reported by management API
+func (s *TestDataSeeder) NamespaceQuota(t *testing.T, id string) *mgmt.QuotaResponse {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, s.baseURL+fmt.Sprintf(testdata.EndpointNamespaceByID, id), nil)
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when retrieving namespace")
+
+	var body mgmt.NamespaceResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.NotNil(t, body.Quota)
+
+	return body.Quota
+}
+
+// SetNamespaceRetentionRules replaces the retention rules of the namespace
+func (s *TestDataSeeder) SetNamespaceRetentionRules(t *testing.T, id string, rules ...*models.RetentionRuleModel) {
+	t.Helper()
+
+	err := s.store.Namespaces().SetRetentionRules(context.Background(), id, rules)
+	require.NoError(t, err)
 }
\ No newline at end of file
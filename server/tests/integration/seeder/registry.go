@@ -0,0 +1,117 @@
+package seeder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/stretchr/testify/require"
+)
+
+// RegistryToken obtains a registry bearer token from token endpoint. If username is empty, an anonymous
+// token will be issued.
+func (s *TestDataSeeder) RegistryToken(t *testing.T, username, password string, scopes ...string) string {
+	t.Helper()
+
+	query := url.Values{}
+	query.Set("service", "open-image-registry")
+	for _, scope := range scopes {
+		query.Add("scope", scope)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.baseURL+testdata.EndpointToken+"?"+query.Encode(), nil)
+	require.NoError(t, err)
+
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when requesting registry token")
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	require.NoError(t, err)
+	require.NotEmpty(t, body.Token)
+
+	return body.Token
+}
+
+// CollectGarbage triggers garbage collection of the hosted registry as an administrator
+func (s *TestDataSeeder) CollectGarbage(t *testing.T, dryRun bool) *mgmt.GCResponse {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s?dry_run=%t", s.baseURL, testdata.EndpointGC,
+		dryRun), nil)
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: constants.AuthTokenCookie, Value: s.AdminToken(t)})
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when collecting garbage")
+
+	var body mgmt.GCResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	require.NoError(t, err)
+
+	return &body
+}
+
+// CreateTags seeds image tags for a repository of the hosted registry
+func (s *TestDataSeeder) CreateTags(t *testing.T, nsId, repoId string, tags ...string) {
+	t.Helper()
+
+	for _, tag := range tags {
+		_, err := s.store.Tags().Create(context.Background(), constants.HostedRegistryID, nsId, repoId, tag)
+		require.NoError(t, err, "failed to create tag %s", tag)
+	}
+}
+
+// BlobContent returns the stored content of the blob shared by repositories. nil is returned if the content isn't
+// stored.
+func (s *TestDataSeeder) BlobContent(t *testing.T, digest string) *models.ImageBlobModel {
+	t.Helper()
+
+	content, err := s.store.Blobs().GetContent(context.Background(), digest)
+	require.NoError(t, err, "failed to retrieve content of blob %s", digest)
+
+	return content
+}
+
+// ApplyRetention applies retention rules of the namespace or the repository as an administrator. endpoint is either
+// testdata.EndpointNamespaceRetentionRun or testdata.EndpointRepositoryRetentionRun.
+func (s *TestDataSeeder) ApplyRetention(t *testing.T, endpoint, id string,
+	dryRun bool) *mgmt.RetentionReportResponse {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s?dry_run=%t", s.baseURL, fmt.Sprintf(endpoint, id),
+		dryRun), nil)
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: constants.AuthTokenCookie, Value: s.AdminToken(t)})
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when applying retention rules")
+
+	var body mgmt.RetentionReportResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	require.NoError(t, err)
+
+	return &body
+}
\ No newline at end of file
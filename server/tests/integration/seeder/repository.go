@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 
+	"github.com/ksankeerth/open-image-registry/constants"
 	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
 	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -48,6 +52,32 @@ func (s *TestDataSeeder) CreateRepository(t *testing.T, name, description, creat
 	return respBody.Id
 }
 
+// CreateLegacyRepository stores the repository directly so that names rejected by the management API, such as names
+// of repositories created by earlier versions, can be used.
+func (s *TestDataSeeder) CreateLegacyRepository(t *testing.T, name, createdBy, nsId string) (id string) {
+	t.Helper()
+
+	id, err := s.store.Repositories().Create(context.Background(), constants.HostedRegistryID, nsId, name, "", false,
+		createdBy)
+	require.NoError(t, err)
+	return id
+}
+
+// DeleteRepository deletes the repository as an administrator
+func (s *TestDataSeeder) DeleteRepository(t *testing.T, id string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodDelete, s.baseURL+fmt.Sprintf(testdata.EndpointRepositoryByID, id), nil)
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when deleting repository")
+}
+
 func (s *TestDataSeeder) SetRepositoryDeprecated(t *testing.T, id string) {
 	t.Helper()
 
@@ -61,3 +91,57 @@ func (s *TestDataSeeder) SetRepositoryDisabled(t *testing.T, id string) {
 	err := s.store.Repositories().SetState(context.Background(), id, "Disabled")
 	require.NoError(t, err)
 }
+
+func (s *TestDataSeeder) SetImmutableTagPatterns(t *testing.T, id string, patterns ...string) {
+	t.Helper()
+
+	err := s.store.Repositories().SetImmutableTagPatterns(context.Background(), id, patterns)
+	require.NoError(t, err)
+}
+
+func (s *TestDataSeeder) SetTagStable(t *testing.T, repoId, tag string, stable bool) {
+	t.Helper()
+
+	tagModel, err := s.store.Tags().Get(context.Background(), repoId, tag)
+	require.NoError(t, err)
+	require.NotNil(t, tagModel, "tag %s not found", tag)
+
+	err = s.store.Tags().SetStable(context.Background(), tagModel.Id, stable)
+	require.NoError(t, err)
+}
+
+// SetRepositoryQuota replaces the quota of the repository. nil limits are unlimited.
+func (s *TestDataSeeder) SetRepositoryQuota(t *testing.T, id string, maxBytes, maxImages *int64) {
+	t.Helper()
+
+	err := s.store.Repositories().SetQuota(context.Background(), id, maxBytes, maxImages)
+	require.NoError(t, err)
+}
+
+// RepositoryQuota returns the quota and the usage of the repository reported by management API
+func (s *TestDataSeeder) RepositoryQuota(t *testing.T, id string) *mgmt.QuotaResponse {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, s.baseURL+fmt.Sprintf(testdata.EndpointRepositoryByID, id), nil)
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when retrieving repository")
+
+	var body mgmt.RepositoryResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.NotNil(t, body.Quota)
+
+	return body.Quota
+}
+
+// SetRepositoryRetentionRules replaces the retention rules of the repository
+func (s *TestDataSeeder) SetRepositoryRetentionRules(t *testing.T, id string, rules ...*models.RetentionRuleModel) {
+	t.Helper()
+
+	err := s.store.Repositories().SetRetentionRules(context.Background(), id, rules)
+	require.NoError(t, err)
+}
\ No newline at end of file
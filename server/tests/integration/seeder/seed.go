@@ -32,5 +32,18 @@ func (s *TestDataSeeder) AdminToken(t *testing.T) string {
 	})
 	require.NoError(t, err)
 
+	return token
+}
+
+// UserToken issues a management API token for the user without logging in
+func (s *TestDataSeeder) UserToken(t *testing.T, username, role string) string {
+	t.Helper()
+
+	token, err := s.jwtProvider.Sign(map[string]any{
+		constants.ClaimRole:    role,
+		constants.ClaimSubject: username,
+	})
+	require.NoError(t, err)
+
 	return token
 }
\ No newline at end of file
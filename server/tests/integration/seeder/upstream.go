@@ -0,0 +1,184 @@
+package seeder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/stretchr/testify/require"
+)
+
+// CreateUpstream creates an anonymous upstream registry of upstreamURL as an administrator. The upstream registry
+// starts listening on port.
+func (s *TestDataSeeder) CreateUpstream(t *testing.T, name string, port uint, upstreamURL string,
+	cacheEnabled bool) (id string) {
+	t.Helper()
+
+	reqBytes, err := json.Marshal(map[string]any{
+		"name":         name,
+		"port":         port,
+		"upstream_url": upstreamURL,
+		"auth_config":  map[string]any{"auth_type": "anonymous"},
+		"cache_config": map[string]any{"enabled": cacheEnabled},
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, s.baseURL+testdata.EndpointUpstreams, bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "unexpected status code when creating upstream registry")
+
+	var body mgmt.CreateUpstreamRegistryResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	require.NoError(t, err)
+
+	return body.RegId
+}
+
+// DeleteUpstream deletes the upstream registry as an administrator. The listener of the upstream registry is stopped.
+func (s *TestDataSeeder) DeleteUpstream(t *testing.T, id string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodDelete, s.baseURL+fmt.Sprintf(testdata.EndpointUpstreamByID, id), nil)
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when deleting upstream registry")
+}
+
+// CreateUpstreamRepository seeds a public namespace and repository of the upstream registry, in which pulled
+// artifacts are cached
+func (s *TestDataSeeder) CreateUpstreamRepository(t *testing.T, registryID, namespace,
+	repository string) (nsId, repoId string) {
+	t.Helper()
+
+	nsId, err := s.store.Namespaces().Create(context.Background(), registryID, namespace,
+		constants.NamespacePurposeProject, "", true, "admin")
+	require.NoError(t, err)
+
+	repoId, err = s.store.Repositories().Create(context.Background(), registryID, nsId, repository, "", true, "admin")
+	require.NoError(t, err)
+
+	return nsId, repoId
+}
+
+// CachedBlob returns the blob cached for the repository. nil is returned if the blob isn't cached.
+func (s *TestDataSeeder) CachedBlob(t *testing.T, repoId, digest string) *models.ImageBlobMetaModel {
+	t.Helper()
+
+	blob, err := s.store.Blobs().Get(context.Background(), digest, repoId)
+	require.NoError(t, err, "failed to retrieve cached blob %s", digest)
+
+	return blob
+}
+
+// SetUpstreamStorageLimit limits the storage of the content cached from the upstream registry. The config is updated
+// in the database so that the listener of the upstream registry isn't restarted.
+func (s *TestDataSeeder) SetUpstreamStorageLimit(t *testing.T, registryID string, limitInMbs,
+	cleanupThreshold float32) {
+	t.Helper()
+
+	cacheConfig, err := s.store.Upstreams().GetRegistryCacheConfig(context.Background(), registryID)
+	require.NoError(t, err)
+	require.NotNil(t, cacheConfig, "cache config of upstream registry %s not found", registryID)
+
+	cacheConfig.RegistryID = registryID
+	cacheConfig.StorageLimit = limitInMbs
+	cacheConfig.CleanupThreshold = cleanupThreshold
+	err = s.store.Upstreams().UpdateRegistryCacheConfig(context.Background(), cacheConfig)
+	require.NoError(t, err)
+}
+
+// PinCachedImage pins the image of the upstream registry as an administrator
+func (s *TestDataSeeder) PinCachedImage(t *testing.T, registryID, namespace, repository, reference string) {
+	t.Helper()
+
+	reqBytes, err := json.Marshal(mgmt.PinCachedImageRequest{
+		Namespace:  namespace,
+		Repository: repository,
+		Reference:  reference,
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, s.baseURL+fmt.Sprintf(testdata.EndpointUpstreamCachePins, registryID),
+		bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "unexpected status code when pinning cached image")
+}
+
+// EvictUpstreamCache enforces the storage limit of the upstream registry as an administrator
+func (s *TestDataSeeder) EvictUpstreamCache(t *testing.T, registryID string,
+	dryRun bool) *mgmt.CacheEvictionResponse {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s?dry_run=%t", s.baseURL,
+		fmt.Sprintf(testdata.EndpointUpstreamCacheEvict, registryID), dryRun), nil)
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when evicting upstream cache")
+
+	var body mgmt.CacheEvictionResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	require.NoError(t, err)
+
+	return &body
+}
+
+// UpdateUpstreamCacheConfig updates the cache config of the upstream registry as an administrator. The listener of
+// the upstream registry is restarted.
+func (s *TestDataSeeder) UpdateUpstreamCacheConfig(t *testing.T, registryID string,
+	cacheConfig mgmt.UpstreamCacheConfigDTO) {
+	t.Helper()
+
+	reqBytes, err := json.Marshal(mgmt.UpdateUpstreamCacheConfigRequest{CacheConfig: cacheConfig})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut, s.baseURL+fmt.Sprintf(testdata.EndpointUpstreamCacheConfig, registryID),
+		bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, s.AdminToken(t))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code when updating cache config")
+}
+
+// ExpireCachedManifest expires the cache entry of the tag or digest so that the manifest is revalidated with the
+// upstream registry on the next pull
+func (s *TestDataSeeder) ExpireCachedManifest(t *testing.T, repoId, tagOrDigest string) {
+	t.Helper()
+
+	err := s.store.Cache().Refresh(context.Background(), repoId, tagOrDigest, time.Now().Add(-time.Minute))
+	require.NoError(t, err, "failed to expire cache entry of %s", tagOrDigest)
+}
\ No newline at end of file
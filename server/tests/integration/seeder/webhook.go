@@ -0,0 +1,41 @@
+package seeder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/stretchr/testify/require"
+)
+
+// CreateWebhook registers an enabled webhook. Webhooks without nsId receive events of all namespaces.
+func (s *TestDataSeeder) CreateWebhook(t *testing.T, name, url, secret, nsId string, events ...string) string {
+	t.Helper()
+
+	id, err := s.store.Webhooks().Create(context.Background(), &models.WebhookModel{
+		Name:        name,
+		URL:         url,
+		Secret:      secret,
+		NamespaceID: nsId,
+		Events:      events,
+		Enabled:     true,
+	})
+	require.NoError(t, err)
+	return id
+}
+
+func (s *TestDataSeeder) DeleteWebhook(t *testing.T, id string) {
+	t.Helper()
+
+	err := s.store.Webhooks().Delete(context.Background(), id)
+	require.NoError(t, err)
+}
+
+// WebhookDeliveries returns deliveries of the webhook, most recent first
+func (s *TestDataSeeder) WebhookDeliveries(t *testing.T, id string) []*models.WebhookDeliveryModel {
+	t.Helper()
+
+	deliveries, err := s.store.Webhooks().ListDeliveries(context.Background(), id, 100)
+	require.NoError(t, err)
+	return deliveries
+}
\ No newline at end of file
@@ -31,6 +31,7 @@ func NewAuthTestSuite(seeder *seeder.TestDataSeeder, baseURL string) *AuthTestSu
 
 func (a *AuthTestSuite) Run(t *testing.T) {
 	t.Run("LoginAndLogout", a.testAuthFlowSuccess)
+	t.Run("RegistryToken", a.testRegistryToken)
 }
 
 func (a *AuthTestSuite) Name() string {
@@ -110,4 +111,75 @@ func (a *AuthTestSuite) testAuthFlowSuccess(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, resp3.StatusCode, "Should be unauthorized after logout")
 		resp3.Body.Close()
 	})
-}
\ No newline at end of file
+}
+func (a *AuthTestSuite) testRegistryToken(t *testing.T) {
+	username := "registry-token-user"
+	password := "SecurePass123!"
+	a.seeder.ProvisionUserWithPassword(t, username, "registrytoken@t.com", "Developer", password)
+
+	tokenURL := a.testBaseURL + testdata.EndpointToken
+
+	tests := []struct {
+		name           string
+		username       string
+		password       string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "Valid credentials with scope",
+			username:       username,
+			password:       password,
+			query:          "?service=open-image-registry&scope=repository:library/nginx:pull,push",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Anonymous token",
+			query:          "?service=open-image-registry&scope=repository:library/nginx:pull",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid password",
+			username:       username,
+			password:       "wrong-password",
+			query:          "?service=open-image-registry",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Unknown service",
+			username:       username,
+			password:       password,
+			query:          "?service=other-registry",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid scope",
+			username:       username,
+			password:       password,
+			query:          "?service=open-image-registry&scope=repository:library/nginx",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tokenURL+tt.query, nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus == http.StatusOK {
+				var body map[string]any
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.NotEmpty(t, body["token"])
+				assert.Equal(t, body["token"], body["access_token"])
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
+	"github.com/ksankeerth/open-image-registry/tests/integration/seeder"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type GCTestSuite struct {
+	name        string
+	apiVersion  string
+	seeder      *seeder.TestDataSeeder
+	testBaseURL string
+}
+
+func NewGCTestSuite(seeder *seeder.TestDataSeeder, baseURL string) *GCTestSuite {
+	return &GCTestSuite{
+		name:        "GarbageCollectionAPI",
+		apiVersion:  "v1",
+		seeder:      seeder,
+		testBaseURL: baseURL,
+	}
+}
+
+func (g *GCTestSuite) Run(t *testing.T) {
+	t.Run("CollectGarbage", g.testCollectGarbage)
+}
+
+func (g *GCTestSuite) Name() string {
+	return g.name
+}
+
+func (g *GCTestSuite) APIVersion() string {
+	return g.apiVersion
+}
+
+func (g *GCTestSuite) testCollectGarbage(t *testing.T) {
+	g.seeder.ProvisionUser(t, "gc-maintainer1", "gc-maintainer1@t.com", constants.RoleMaintainer)
+
+	tcs := []struct {
+		name       string
+		token      string
+		query      string
+		statusCode int
+	}{
+		{
+			name:       "Dry run by admin",
+			token:      g.seeder.AdminToken(t),
+			query:      "?dry_run=true",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Non-admin is not allowed",
+			token:      g.seeder.UserToken(t, "gc-maintainer1", constants.RoleMaintainer),
+			query:      "?dry_run=true",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "Invalid dry_run",
+			token:      g.seeder.AdminToken(t),
+			query:      "?dry_run=maybe",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, g.testBaseURL+testdata.EndpointGC+tc.query, nil)
+			require.NoError(t, err)
+			helpers.SetAuthCookie(req, tc.token)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			helpers.AssertStatusCode(t, resp, tc.statusCode)
+
+			if tc.statusCode == http.StatusOK {
+				var res mgmt.GCResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+				assert.True(t, res.DryRun)
+				assert.NotNil(t, res.Manifests)
+				assert.NotNil(t, res.Blobs)
+			}
+		})
+	}
+}
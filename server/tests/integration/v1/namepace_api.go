@@ -13,6 +13,7 @@ import (
 	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
 	"github.com/ksankeerth/open-image-registry/tests/integration/seeder"
 	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -54,6 +55,8 @@ func (n *NamespaceTestSuite) Run(t *testing.T) {
 	// State & Visibility
 	t.Run("StateChange", n.testNamespaceStateChange)
 	t.Run("VisibilityChange", n.testNamespaceVisibilityChange)
+	t.Run("Quota", n.testNamespaceQuota)
+	t.Run("RetentionRules", n.testNamespaceRetentionRules)
 
 	// Access Control
 	t.Run("UserAccess_List", n.testNamespaceUserAccessList)
@@ -1370,3 +1373,141 @@ func (n *NamespaceTestSuite) testNamespaceUserAccessList(t *testing.T) {
 func (n *NamespaceTestSuite) testNamespaceListRepositories(t *testing.T) {}
 func (n *NamespaceTestSuite) testNamespaceHTTPMethods(t *testing.T)      {}
 func (n *NamespaceTestSuite) testNamespaceContentType(t *testing.T)      {}
+
+func (n *NamespaceTestSuite) testNamespaceQuota(t *testing.T) {
+	m := n.seeder.ProvisionUser(t, "nsquota-maintainer1", "nsquota-maintainer1@t.com", "Maintainer")
+	nsId := n.seeder.CreateNamespace(t, "nsquota-1", "", "Team", false, m)
+
+	setQuota := func(t *testing.T, identifier, token string, body any) *http.Response {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPut, n.testBaseURL+fmt.Sprintf(testdata.EndpointNamespaceQuota,
+			identifier), bytes.NewReader(b))
+		require.NoError(t, err)
+		helpers.SetAuthCookie(req, token)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	adminToken := n.seeder.AdminToken(t)
+
+	t.Run("Unlimited by default", func(t *testing.T) {
+		quota := n.seeder.NamespaceQuota(t, nsId)
+		assert.Nil(t, quota.MaxBytes)
+		assert.Nil(t, quota.MaxImages)
+		assert.Zero(t, quota.UsedBytes)
+		assert.Zero(t, quota.UsedImages)
+	})
+
+	t.Run("Set quota by name", func(t *testing.T) {
+		resp := setQuota(t, "nsquota-1", adminToken, map[string]any{"max_bytes": 4096, "max_images": 5})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		quota := n.seeder.NamespaceQuota(t, nsId)
+		require.NotNil(t, quota.MaxBytes)
+		require.NotNil(t, quota.MaxImages)
+		assert.Equal(t, int64(4096), *quota.MaxBytes)
+		assert.Equal(t, int64(5), *quota.MaxImages)
+	})
+
+	t.Run("Non-admin can't set quota", func(t *testing.T) {
+		resp := setQuota(t, nsId, n.seeder.UserToken(t, "nsquota-maintainer1", "Maintainer"),
+			map[string]any{"max_bytes": 1})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+	})
+
+	t.Run("Negative limit", func(t *testing.T) {
+		resp := setQuota(t, nsId, adminToken, map[string]any{"max_bytes": -5})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("Non-existent namespace", func(t *testing.T) {
+		resp := setQuota(t, "non-existent-ns", adminToken, map[string]any{"max_bytes": 1})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
+
+func (n *NamespaceTestSuite) testNamespaceRetentionRules(t *testing.T) {
+	m := n.seeder.ProvisionUser(t, "nsretention-maintainer1", "nsretention-maintainer1@t.com", "Maintainer")
+	nsId := n.seeder.CreateNamespace(t, "nsretention-1", "", "Team", false, m)
+	r1 := n.seeder.CreateRepository(t, "app", "", "admin", nsId, false)
+	r2 := n.seeder.CreateRepository(t, "tools", "", "admin", nsId, false)
+
+	send := func(t *testing.T, method, endpoint, identifier, token string, body any) *http.Response {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(method, n.testBaseURL+fmt.Sprintf(endpoint, identifier), bytes.NewReader(b))
+		require.NoError(t, err)
+		helpers.SetAuthCookie(req, token)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	adminToken := n.seeder.AdminToken(t)
+
+	t.Run("Set rules by name", func(t *testing.T) {
+		resp := send(t, http.MethodPut, testdata.EndpointNamespaceRetention, "nsretention-1", adminToken,
+			map[string]any{"rules": []map[string]any{{"tag_pattern": "pr-*", "keep_last": 1}}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		resp = send(t, http.MethodGet, testdata.EndpointNamespaceRetention, nsId, adminToken, nil)
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		var body mgmt.RetentionRulesResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Rules, 1)
+		assert.Equal(t, "pr-*", body.Rules[0].TagPattern)
+		require.NotNil(t, body.Rules[0].KeepLast)
+		assert.Equal(t, int64(1), *body.Rules[0].KeepLast)
+	})
+
+	t.Run("Invalid rules", func(t *testing.T) {
+		resp := send(t, http.MethodPut, testdata.EndpointNamespaceRetention, nsId, adminToken,
+			map[string]any{"rules": []map[string]any{{"tag_pattern": "pr-*"}}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("Non-admin can't change rules", func(t *testing.T) {
+		resp := send(t, http.MethodPut, testdata.EndpointNamespaceRetention, nsId,
+			n.seeder.UserToken(t, "nsretention-maintainer1", "Maintainer"), map[string]any{"rules": []any{}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+	})
+
+	t.Run("Rules apply to all repositories", func(t *testing.T) {
+		n.seeder.CreateTags(t, nsId, r1, "pr-1", "pr-2")
+		n.seeder.CreateTags(t, nsId, r2, "pr-1", "pr-2", "latest")
+
+		report := n.seeder.ApplyRetention(t, testdata.EndpointNamespaceRetentionRun, "nsretention-1", false)
+		assert.False(t, report.DryRun)
+
+		deleted := []string{}
+		for _, item := range report.Tags {
+			deleted = append(deleted, item.Repository+":"+item.Tag)
+		}
+		assert.ElementsMatch(t, []string{"nsretention-1/app:pr-1", "nsretention-1/tools:pr-1"}, deleted)
+	})
+
+	t.Run("Non-existent namespace", func(t *testing.T) {
+		resp := send(t, http.MethodGet, testdata.EndpointNamespaceRetention, "non-existent-ns", adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+
+		resp = send(t, http.MethodPost, testdata.EndpointNamespaceRetentionRun, "non-existent-ns", adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
\ No newline at end of file
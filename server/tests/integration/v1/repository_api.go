@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
 	"github.com/ksankeerth/open-image-registry/tests/integration/seeder"
 	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -43,6 +45,10 @@ func (r *RepositorySuite) Run(t *testing.T) {
 	t.Run("GrantAccess", r.testGrantAccess)
 	t.Run("RevokeAccess", r.testRevokeAccess)
 	t.Run("ListUserAccess", r.testListUserAccess)
+	t.Run("ImmutableTags", r.testImmutableTags)
+	t.Run("ChangeTagStability", r.testChangeTagStability)
+	t.Run("Quota", r.testQuota)
+	t.Run("RetentionRules", r.testRetentionRules)
 }
 
 func (r *RepositorySuite) Name() string {
@@ -136,6 +142,28 @@ func (r *RepositorySuite) testCreateRepository(t *testing.T) {
 			},
 			statusCode: http.StatusCreated,
 		},
+		{
+			tcName: "Can create repository with multiple path components",
+			body: map[string]any{
+				"name":         "team-a/services/billing-api",
+				"description":  "",
+				"is_public":    false,
+				"namespace_id": nsId,
+				"created_by":   d1,
+			},
+			statusCode: http.StatusCreated,
+		},
+		{
+			tcName: "Cannot create repository with empty path component",
+			body: map[string]any{
+				"name":         "team-a//billing-api",
+				"description":  "",
+				"is_public":    false,
+				"namespace_id": nsId,
+				"created_by":   d1,
+			},
+			statusCode: http.StatusBadRequest,
+		},
 		{
 			tcName: "Cannot create repository under disabled namespace",
 			body: map[string]any{
@@ -1106,3 +1134,366 @@ func (r *RepositorySuite) testRevokeAccess(t *testing.T) {
 		})
 	}
 }
+
+func (r *RepositorySuite) testImmutableTags(t *testing.T) {
+	m := r.seeder.ProvisionUser(t, "repo-immutable-maintainer1", "repo-immutable-maintainer1@t.com", "Maintainer")
+	n1 := r.seeder.CreateNamespace(t, "repo-immutable-ns1", "", "Team", false, m)
+	r1 := r.seeder.CreateRepository(t, "repo-immutable-test1", "", "admin", n1, false)
+
+	do := func(t *testing.T, method, repoId string, body any) *http.Response {
+		var reqBody io.Reader
+		if body != nil {
+			b, err := json.Marshal(body)
+			require.NoError(t, err)
+			reqBody = bytes.NewReader(b)
+		}
+
+		req, err := http.NewRequest(method, r.testBaseURL+fmt.Sprintf(testdata.EndpointRepositoryImmutable, repoId),
+			reqBody)
+		require.NoError(t, err)
+		helpers.SetAuthCookie(req, r.seeder.AdminToken(t))
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	getPatterns := func(t *testing.T) []string {
+		resp := do(t, http.MethodGet, r1, nil)
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		var res mgmt.ImmutableTagsResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res.Patterns
+	}
+
+	t.Run("No patterns by default", func(t *testing.T) {
+		assert.Empty(t, getPatterns(t))
+	})
+
+	t.Run("Set patterns", func(t *testing.T) {
+		resp := do(t, http.MethodPut, r1, map[string]any{"patterns": []string{"v*", "semver", "v*"}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		assert.Equal(t, []string{"semver", "v*"}, getPatterns(t))
+	})
+
+	t.Run("Replace patterns", func(t *testing.T) {
+		resp := do(t, http.MethodPut, r1, map[string]any{"patterns": []string{"release-*"}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		assert.Equal(t, []string{"release-*"}, getPatterns(t))
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		resp := do(t, http.MethodPut, r1, map[string]any{"patterns": []string{"[v"}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+
+		assert.Equal(t, []string{"release-*"}, getPatterns(t))
+	})
+
+	t.Run("Non-existent repository", func(t *testing.T) {
+		resp := do(t, http.MethodPut, "non-existent-id", map[string]any{"patterns": []string{"v*"}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+
+		resp = do(t, http.MethodGet, "non-existent-id", nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
+
+func (r *RepositorySuite) testChangeTagStability(t *testing.T) {
+	m := r.seeder.ProvisionUser(t, "repo-stable-maintainer1", "repo-stable-maintainer1@t.com", "Maintainer")
+	n1 := r.seeder.CreateNamespace(t, "repo-stable-ns1", "", "Team", false, m)
+	r1 := r.seeder.CreateRepository(t, "repo-stable-test1", "", "admin", n1, false)
+	r.seeder.CreateTags(t, n1, r1, "1.0.0")
+
+	tcs := []struct {
+		name       string
+		repoId     string
+		tag        string
+		stable     string
+		statusCode int
+	}{
+		{
+			name:       "Mark tag stable",
+			repoId:     r1,
+			tag:        "1.0.0",
+			stable:     "true",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "No-op: Mark stable tag stable",
+			repoId:     r1,
+			tag:        "1.0.0",
+			stable:     "true",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Mark tag unstable",
+			repoId:     r1,
+			tag:        "1.0.0",
+			stable:     "false",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Invalid query param",
+			repoId:     r1,
+			tag:        "1.0.0",
+			stable:     "yes",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Non-existent tag",
+			repoId:     r1,
+			tag:        "2.0.0",
+			stable:     "true",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "Non-existent repository",
+			repoId:     "non-existent-id",
+			tag:        "1.0.0",
+			stable:     "true",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			url := r.testBaseURL + fmt.Sprintf(testdata.EndpointRepositoryTagStable, tc.repoId, tc.tag) +
+				"?stable=" + tc.stable
+
+			req, err := http.NewRequest(http.MethodPatch, url, nil)
+			require.NoError(t, err)
+			helpers.SetAuthCookie(req, r.seeder.AdminToken(t))
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			helpers.AssertStatusCode(t, resp, tc.statusCode)
+		})
+	}
+}
+
+func (r *RepositorySuite) testQuota(t *testing.T) {
+	m := r.seeder.ProvisionUser(t, "repo-quota-maintainer1", "repo-quota-maintainer1@t.com", "Maintainer")
+	n1 := r.seeder.CreateNamespace(t, "repo-quota-ns1", "", "Team", false, m)
+	r1 := r.seeder.CreateRepository(t, "repo-quota-test1", "", "admin", n1, false)
+
+	setQuota := func(t *testing.T, repoId, token string, body any) *http.Response {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPut, r.testBaseURL+fmt.Sprintf(testdata.EndpointRepositoryQuota, repoId),
+			bytes.NewReader(b))
+		require.NoError(t, err)
+		helpers.SetAuthCookie(req, token)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	adminToken := r.seeder.AdminToken(t)
+
+	t.Run("Unlimited by default", func(t *testing.T) {
+		quota := r.seeder.RepositoryQuota(t, r1)
+		assert.Nil(t, quota.MaxBytes)
+		assert.Nil(t, quota.MaxImages)
+		assert.Zero(t, quota.UsedBytes)
+		assert.Zero(t, quota.UsedImages)
+	})
+
+	t.Run("Set quota", func(t *testing.T) {
+		resp := setQuota(t, r1, adminToken, map[string]any{"max_bytes": 1048576, "max_images": 10})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		quota := r.seeder.RepositoryQuota(t, r1)
+		require.NotNil(t, quota.MaxBytes)
+		require.NotNil(t, quota.MaxImages)
+		assert.Equal(t, int64(1048576), *quota.MaxBytes)
+		assert.Equal(t, int64(10), *quota.MaxImages)
+	})
+
+	t.Run("Byte quota only", func(t *testing.T) {
+		resp := setQuota(t, r1, adminToken, map[string]any{"max_bytes": 2048})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		quota := r.seeder.RepositoryQuota(t, r1)
+		require.NotNil(t, quota.MaxBytes)
+		assert.Equal(t, int64(2048), *quota.MaxBytes)
+		assert.Nil(t, quota.MaxImages)
+	})
+
+	t.Run("Non-admin can't set quota", func(t *testing.T) {
+		resp := setQuota(t, r1, r.seeder.UserToken(t, "repo-quota-maintainer1", constants.RoleMaintainer),
+			map[string]any{"max_bytes": 1})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+	})
+
+	t.Run("Negative limit", func(t *testing.T) {
+		resp := setQuota(t, r1, adminToken, map[string]any{"max_images": -1})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("Remove quota", func(t *testing.T) {
+		resp := setQuota(t, r1, adminToken, map[string]any{})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		quota := r.seeder.RepositoryQuota(t, r1)
+		assert.Nil(t, quota.MaxBytes)
+		assert.Nil(t, quota.MaxImages)
+	})
+
+	t.Run("Non-existent repository", func(t *testing.T) {
+		resp := setQuota(t, "non-existent-id", adminToken, map[string]any{"max_bytes": 1})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
+
+func (r *RepositorySuite) testRetentionRules(t *testing.T) {
+	m := r.seeder.ProvisionUser(t, "repo-retention-maintainer1", "repo-retention-maintainer1@t.com", "Maintainer")
+	n1 := r.seeder.CreateNamespace(t, "repo-retention-ns1", "", "Team", false, m)
+	r1 := r.seeder.CreateRepository(t, "repo-retention-test1", "", "admin", n1, false)
+
+	send := func(t *testing.T, method, endpoint, repoId, token string, body any) *http.Response {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(method, r.testBaseURL+fmt.Sprintf(endpoint, repoId), bytes.NewReader(b))
+		require.NoError(t, err)
+		helpers.SetAuthCookie(req, token)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	getRules := func(t *testing.T, repoId string) []mgmt.RetentionRule {
+		resp := send(t, http.MethodGet, testdata.EndpointRepositoryRetention, repoId, r.seeder.AdminToken(t), nil)
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		var body mgmt.RetentionRulesResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body.Rules
+	}
+
+	adminToken := r.seeder.AdminToken(t)
+
+	t.Run("No rules by default", func(t *testing.T) {
+		assert.Empty(t, getRules(t, r1))
+	})
+
+	t.Run("Set rules", func(t *testing.T) {
+		resp := send(t, http.MethodPut, testdata.EndpointRepositoryRetention, r1, adminToken, map[string]any{
+			"rules": []map[string]any{
+				{"tag_pattern": "pr-*", "keep_last": 2},
+				{"tag_pattern": "nightly-*", "keep_last": 1, "max_age_days": 7},
+			},
+		})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		rules := getRules(t, r1)
+		require.Len(t, rules, 2)
+		assert.Equal(t, "nightly-*", rules[0].TagPattern)
+		require.NotNil(t, rules[0].KeepLast)
+		require.NotNil(t, rules[0].MaxAgeDays)
+		assert.Equal(t, int64(1), *rules[0].KeepLast)
+		assert.Equal(t, int64(7), *rules[0].MaxAgeDays)
+		assert.Equal(t, "pr-*", rules[1].TagPattern)
+		require.NotNil(t, rules[1].KeepLast)
+		assert.Equal(t, int64(2), *rules[1].KeepLast)
+		assert.Nil(t, rules[1].MaxAgeDays)
+	})
+
+	t.Run("Invalid rules", func(t *testing.T) {
+		for name, rule := range map[string]map[string]any{
+			"Invalid pattern":      {"tag_pattern": "pr-[", "keep_last": 1},
+			"Without condition":    {"tag_pattern": "pr-*"},
+			"Negative keep_last":   {"tag_pattern": "pr-*", "keep_last": -1},
+			"Negative max_age_day": {"tag_pattern": "pr-*", "max_age_days": -1},
+		} {
+			t.Run(name, func(t *testing.T) {
+				resp := send(t, http.MethodPut, testdata.EndpointRepositoryRetention, r1, adminToken,
+					map[string]any{"rules": []map[string]any{rule}})
+				resp.Body.Close()
+				helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+			})
+		}
+
+		resp := send(t, http.MethodPut, testdata.EndpointRepositoryRetention, r1, adminToken, map[string]any{
+			"rules": []map[string]any{
+				{"tag_pattern": "pr-*", "keep_last": 1},
+				{"tag_pattern": "pr-*", "max_age_days": 1},
+			},
+		})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+
+		assert.Len(t, getRules(t, r1), 2)
+	})
+
+	t.Run("Non-admin can't change rules", func(t *testing.T) {
+		token := r.seeder.UserToken(t, "repo-retention-maintainer1", constants.RoleMaintainer)
+
+		resp := send(t, http.MethodPut, testdata.EndpointRepositoryRetention, r1, token,
+			map[string]any{"rules": []map[string]any{}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+
+		resp = send(t, http.MethodPost, testdata.EndpointRepositoryRetentionRun, r1, token, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		r.seeder.CreateTags(t, n1, r1, "pr-1", "pr-2", "pr-3", "latest")
+
+		report := r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, r1, true)
+		assert.True(t, report.DryRun)
+		require.Len(t, report.Tags, 1)
+		assert.Equal(t, "repo-retention-ns1/repo-retention-test1", report.Tags[0].Repository)
+		assert.Equal(t, "pr-1", report.Tags[0].Tag)
+
+		report = r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, r1, false)
+		assert.False(t, report.DryRun)
+		require.Len(t, report.Tags, 1)
+		assert.Equal(t, "pr-1", report.Tags[0].Tag)
+
+		report = r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, r1, true)
+		assert.Empty(t, report.Tags)
+	})
+
+	t.Run("Remove rules", func(t *testing.T) {
+		resp := send(t, http.MethodPut, testdata.EndpointRepositoryRetention, r1, adminToken,
+			map[string]any{"rules": []map[string]any{}})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		assert.Empty(t, getRules(t, r1))
+	})
+
+	t.Run("Non-existent repository", func(t *testing.T) {
+		resp := send(t, http.MethodGet, testdata.EndpointRepositoryRetention, "non-existent-id", adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+
+		resp = send(t, http.MethodPost, testdata.EndpointRepositoryRetentionRun, "non-existent-id", adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
\ No newline at end of file
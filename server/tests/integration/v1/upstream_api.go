@@ -0,0 +1,552 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
+	"github.com/ksankeerth/open-image-registry/tests/integration/seeder"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type UpstreamTestSuite struct {
+	name        string
+	apiVersion  string
+	seeder      *seeder.TestDataSeeder
+	testBaseURL string
+	// upstreamURL is the url of a fake distribution registry which is used as the upstream of upstream registries
+	upstreamURL string
+}
+
+func NewUpstreamTestSuite(seeder *seeder.TestDataSeeder, baseURL string) *UpstreamTestSuite {
+	return &UpstreamTestSuite{
+		name:        "UpstreamAPI",
+		apiVersion:  "v1",
+		seeder:      seeder,
+		testBaseURL: baseURL,
+	}
+}
+
+func (s *UpstreamTestSuite) Run(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	s.upstreamURL = upstream.URL
+
+	t.Run("ManageUpstreams", s.testManageUpstreams)
+	t.Run("Listeners", s.testListeners)
+	t.Run("InvalidUpstreams", s.testInvalidUpstreams)
+	t.Run("CachePins", s.testCachePins)
+	t.Run("NonAdmin", s.testNonAdmin)
+}
+
+func (s *UpstreamTestSuite) Name() string {
+	return s.name
+}
+
+func (s *UpstreamTestSuite) APIVersion() string {
+	return s.apiVersion
+}
+
+func (s *UpstreamTestSuite) send(t *testing.T, method, path, token string, body any) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, s.testBaseURL+path, reader)
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func (s *UpstreamTestSuite) createUpstream(t *testing.T, body map[string]any) string {
+	t.Helper()
+
+	resp := s.send(t, http.MethodPost, testdata.EndpointUpstreams, s.seeder.AdminToken(t), body)
+	defer resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusCreated)
+
+	var res mgmt.CreateUpstreamRegistryResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.NotEmpty(t, res.RegId)
+	assert.Equal(t, body["name"], res.RegName)
+	return res.RegId
+}
+
+func (s *UpstreamTestSuite) getUpstream(t *testing.T, id string) *mgmt.UpstreamRegistryResponse {
+	t.Helper()
+
+	resp := s.send(t, http.MethodGet, fmt.Sprintf(testdata.EndpointUpstreamByID, id), s.seeder.AdminToken(t), nil)
+	defer resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+	var res mgmt.UpstreamRegistryResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	return &res
+}
+
+func (s *UpstreamTestSuite) upstreamBody(name string, port uint) map[string]any {
+	return map[string]any{
+		"name":         name,
+		"port":         port,
+		"upstream_url": s.upstreamURL,
+		"auth_config": map[string]any{
+			"auth_type": "anonymous",
+		},
+	}
+}
+
+// listening reports whether the registry listener on the port serves requests
+func listening(port uint) bool {
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v2/", port))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+func awaitListening(t *testing.T, port uint, expected bool) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		return listening(port) == expected
+	}, 5*time.Second, 50*time.Millisecond, "listening on port %d: %v", port, expected)
+}
+
+func (s *UpstreamTestSuite) testManageUpstreams(t *testing.T) {
+	adminToken := s.seeder.AdminToken(t)
+	port := helpers.FindFreePort()
+
+	var id string
+
+	t.Run("Create", func(t *testing.T) {
+		body := s.upstreamBody("upstream-ghcr", port)
+		body["description"] = "GitHub container registry"
+		body["vendor"] = constants.RegistryVendorGHCR
+		body["auth_config"] = map[string]any{
+			"auth_type":        "bearer",
+			"credentials_json": map[string]any{"username": "ghcr-user", "password": "ghcr-secret"},
+		}
+		id = s.createUpstream(t, body)
+
+		upstream := s.getUpstream(t, id)
+		assert.Equal(t, "upstream-ghcr", upstream.Name)
+		assert.Equal(t, "GitHub container registry", upstream.Description)
+		assert.Equal(t, constants.RegistryVendorGHCR, upstream.Vendor)
+		assert.Equal(t, constants.ResourceStateActive, upstream.Status)
+		assert.Equal(t, int(port), upstream.Port)
+		assert.Equal(t, s.upstreamURL, upstream.UpstreamUrl)
+
+		assert.Equal(t, "bearer", upstream.AuthConfig.AuthType)
+		// password is never returned
+		assert.Equal(t, map[string]any{"username": "ghcr-user"}, upstream.AuthConfig.CredentialJson)
+
+		// defaults are applied to omitted configs
+		require.NotNil(t, upstream.CacheConfig.Enabled)
+		assert.True(t, *upstream.CacheConfig.Enabled)
+		assert.Equal(t, 3600, upstream.CacheConfig.TtlInSeconds)
+		assert.False(t, upstream.CacheConfig.StaleIfError)
+		assert.False(t, upstream.CacheConfig.OfflineMode)
+		assert.Equal(t, float32(100), upstream.StorageConfig.StorageLimitInMbs)
+		assert.Equal(t, float32(80), upstream.StorageConfig.CleanupThreshold)
+		assert.Equal(t, 10, upstream.AccessConfig.ConnectionTimeoutInSeconds)
+		assert.Equal(t, 30, upstream.AccessConfig.ReadTimeoutInSeconds)
+		require.NotNil(t, upstream.AccessConfig.MaxRetries)
+		assert.Equal(t, 3, *upstream.AccessConfig.MaxRetries)
+
+		awaitListening(t, port, true)
+	})
+
+	t.Run("Duplicate name", func(t *testing.T) {
+		resp := s.send(t, http.MethodPost, testdata.EndpointUpstreams, adminToken,
+			s.upstreamBody("upstream-ghcr", helpers.FindFreePort()))
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusConflict)
+	})
+
+	t.Run("Duplicate port", func(t *testing.T) {
+		resp := s.send(t, http.MethodPost, testdata.EndpointUpstreams, adminToken,
+			s.upstreamBody("upstream-other", port))
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusConflict)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		resp := s.send(t, http.MethodGet, testdata.EndpointUpstreams+"?vendor=ghcr", adminToken, nil)
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		var res mgmt.ListUpstreamsResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		require.Len(t, res.Registries, 1)
+		assert.Equal(t, 1, res.Total)
+		assert.Equal(t, id, res.Registries[0].Id)
+		assert.Equal(t, "upstream-ghcr", res.Registries[0].Name)
+		assert.Equal(t, 0, res.Registries[0].CachedImagesCount)
+
+		resp = s.send(t, http.MethodGet, testdata.EndpointUpstreams+"?sort_by=upstream_url", adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("Update configs", func(t *testing.T) {
+		resp := s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointUpstreamAuthConfig, id), adminToken,
+			map[string]any{"auth_type": "anonymous"})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		resp = s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointUpstreamCacheConfig, id), adminToken,
+			map[string]any{
+				"cache_config":   map[string]any{"enabled": false, "ttl_seconds": 600, "stale_if_error": true},
+				"storage_config": map[string]any{"storage_limit": 2048, "cleanup_threshold": 90},
+			})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		resp = s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointUpstreamNetworkConfig, id), adminToken,
+			map[string]any{"connection_timeout": 5, "read_timeout": 60, "max_retries": 0})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		upstream := s.getUpstream(t, id)
+		assert.Equal(t, "anonymous", upstream.AuthConfig.AuthType)
+		assert.Empty(t, upstream.AuthConfig.CredentialJson)
+		assert.False(t, *upstream.CacheConfig.Enabled)
+		assert.Equal(t, 600, upstream.CacheConfig.TtlInSeconds)
+		assert.True(t, upstream.CacheConfig.StaleIfError)
+		assert.False(t, upstream.CacheConfig.OfflineMode)
+		assert.Equal(t, float32(2048), upstream.StorageConfig.StorageLimitInMbs)
+		assert.Equal(t, float32(90), upstream.StorageConfig.CleanupThreshold)
+		assert.Equal(t, 5, upstream.AccessConfig.ConnectionTimeoutInSeconds)
+		assert.Equal(t, 60, upstream.AccessConfig.ReadTimeoutInSeconds)
+		assert.Equal(t, 0, *upstream.AccessConfig.MaxRetries)
+		assert.Equal(t, 100, upstream.AccessConfig.MaxConnections)
+
+		// listener is restarted with new configs
+		awaitListening(t, port, true)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		resp := s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointUpstreamByID, id), adminToken,
+			map[string]any{
+				"description":  "ghcr.io",
+				"port":         port,
+				"upstream_url": s.upstreamURL + "/",
+			})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		upstream := s.getUpstream(t, id)
+		assert.Equal(t, "ghcr.io", upstream.Description)
+		assert.Equal(t, s.upstreamURL+"/", upstream.UpstreamUrl)
+		assert.NotNil(t, upstream.UpdatedAt)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		resp := s.send(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointUpstreamByID, id), adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+		awaitListening(t, port, false)
+
+		resp = s.send(t, http.MethodGet, fmt.Sprintf(testdata.EndpointUpstreamByID, id), adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+
+		resp = s.send(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointUpstreamByID, id), adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+
+		// port is released along with the upstream registry
+		s.createUpstream(t, s.upstreamBody("upstream-ghcr", port))
+		awaitListening(t, port, true)
+	})
+}
+
+func (s *UpstreamTestSuite) testListeners(t *testing.T) {
+	adminToken := s.seeder.AdminToken(t)
+	port := helpers.FindFreePort()
+
+	body := s.upstreamBody("upstream-quay", port)
+	body["status"] = constants.ResourceStateDisabled
+	id := s.createUpstream(t, body)
+
+	changeState := func(t *testing.T, state string, statusCode int) {
+		t.Helper()
+
+		resp := s.send(t, http.MethodPatch, fmt.Sprintf(testdata.EndpointUpstreamState, id)+"?state="+state,
+			adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, statusCode)
+	}
+
+	t.Run("Disabled upstream doesn't listen", func(t *testing.T) {
+		time.Sleep(200 * time.Millisecond)
+		assert.False(t, listening(port))
+	})
+
+	t.Run("Enable", func(t *testing.T) {
+		changeState(t, constants.ResourceStateActive, http.StatusOK)
+		awaitListening(t, port, true)
+
+		// deprecated upstream registries keep serving cached images
+		changeState(t, constants.ResourceStateDeprecated, http.StatusOK)
+		time.Sleep(200 * time.Millisecond)
+		assert.True(t, listening(port))
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		changeState(t, constants.ResourceStateDisabled, http.StatusOK)
+		awaitListening(t, port, false)
+		assert.Equal(t, constants.ResourceStateDisabled, s.getUpstream(t, id).Status)
+	})
+
+	t.Run("Move to another port", func(t *testing.T) {
+		changeState(t, constants.ResourceStateActive, http.StatusOK)
+		awaitListening(t, port, true)
+
+		newPort := helpers.FindFreePort()
+		resp := s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointUpstreamByID, id), adminToken,
+			map[string]any{"port": newPort, "upstream_url": s.upstreamURL})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		awaitListening(t, newPort, true)
+		awaitListening(t, port, false)
+		port = newPort
+	})
+
+	t.Run("Port taken by another process", func(t *testing.T) {
+		ln, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+		defer ln.Close()
+		takenPort := uint(ln.Addr().(*net.TCPAddr).Port)
+
+		resp := s.send(t, http.MethodPost, testdata.EndpointUpstreams, adminToken,
+			s.upstreamBody("upstream-port-taken", takenPort))
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusConflict)
+
+		resp = s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointUpstreamByID, id), adminToken,
+			map[string]any{"port": takenPort, "upstream_url": s.upstreamURL})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusConflict)
+
+		// disabled upstream registries can't be enabled while their port is taken
+		changeState(t, constants.ResourceStateDisabled, http.StatusOK)
+		awaitListening(t, port, false)
+
+		blocker, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		require.NoError(t, err)
+		changeState(t, constants.ResourceStateActive, http.StatusConflict)
+		blocker.Close()
+
+		changeState(t, constants.ResourceStateActive, http.StatusOK)
+		awaitListening(t, port, true)
+	})
+
+	t.Run("Invalid state", func(t *testing.T) {
+		changeState(t, "Archived", http.StatusBadRequest)
+	})
+
+	resp := s.send(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointUpstreamByID, id), adminToken, nil)
+	resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusOK)
+	awaitListening(t, port, false)
+}
+
+func (s *UpstreamTestSuite) testInvalidUpstreams(t *testing.T) {
+	adminToken := s.seeder.AdminToken(t)
+	port := helpers.FindFreePort()
+
+	invalid := func(change func(body map[string]any)) map[string]any {
+		body := s.upstreamBody("upstream-invalid", port)
+		change(body)
+		return body
+	}
+
+	for name, body := range map[string]map[string]any{
+		"Short name":        invalid(func(b map[string]any) { b["name"] = "up" }),
+		"Invalid name":      invalid(func(b map[string]any) { b["name"] = "upstream/invalid" }),
+		"Unknown vendor":    invalid(func(b map[string]any) { b["vendor"] = "docker" }),
+		"Invalid state":     invalid(func(b map[string]any) { b["status"] = "Archived" }),
+		"Privileged port":   invalid(func(b map[string]any) { b["port"] = 80 }),
+		"Invalid url":       invalid(func(b map[string]any) { b["upstream_url"] = "ftp://localhost" }),
+		"Unknown auth type": invalid(func(b map[string]any) { b["auth_config"] = map[string]any{"auth_type": "oauth2"} }),
+		"Missing password": invalid(func(b map[string]any) {
+			b["auth_config"] = map[string]any{"auth_type": "basic",
+				"credentials_json": map[string]any{"username": "user"}}
+		}),
+		"Proxy": invalid(func(b map[string]any) {
+			b["access_config"] = map[string]any{"proxy_enabled": true, "proxy_url": "http://localhost:3128"}
+		}),
+		"Max retries": invalid(func(b map[string]any) { b["access_config"] = map[string]any{"max_retries": 11} }),
+		"TTL":         invalid(func(b map[string]any) { b["cache_config"] = map[string]any{"ttl_seconds": 10} }),
+		"Offline without cache": invalid(func(b map[string]any) {
+			b["cache_config"] = map[string]any{"enabled": false, "offline_mode": true}
+		}),
+		"Cleanup threshold": invalid(func(b map[string]any) {
+			b["storage_config"] = map[string]any{"cleanup_threshold": 99}
+		}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := s.send(t, http.MethodPost, testdata.EndpointUpstreams, adminToken, body)
+			resp.Body.Close()
+			helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+		})
+	}
+
+	t.Run("Unknown upstream", func(t *testing.T) {
+		for method, path := range map[string]string{
+			http.MethodGet:    fmt.Sprintf(testdata.EndpointUpstreamByID, "unknown"),
+			http.MethodDelete: fmt.Sprintf(testdata.EndpointUpstreamByID, "unknown"),
+			http.MethodPatch:  fmt.Sprintf(testdata.EndpointUpstreamState, "unknown") + "?state=Active",
+		} {
+			resp := s.send(t, method, path, adminToken, nil)
+			resp.Body.Close()
+			helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+		}
+
+		resp := s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointUpstreamNetworkConfig, "unknown"), adminToken,
+			map[string]any{"connection_timeout": 5})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
+
+func (s *UpstreamTestSuite) testCachePins(t *testing.T) {
+	adminToken := s.seeder.AdminToken(t)
+	port := helpers.FindFreePort()
+
+	id := s.createUpstream(t, s.upstreamBody("upstream-pins", port))
+	defer s.seeder.DeleteUpstream(t, id)
+	s.seeder.CreateUpstreamRepository(t, id, "library", "alpine")
+
+	pinsPath := fmt.Sprintf(testdata.EndpointUpstreamCachePins, id)
+	pin := func(reference string) mgmt.PinCachedImageRequest {
+		return mgmt.PinCachedImageRequest{Namespace: "library", Repository: "alpine", Reference: reference}
+	}
+	unpinPath := func(repository, reference string) string {
+		return pinsPath + "?namespace=library&repository=" + repository + "&reference=" + reference
+	}
+
+	listPins := func(t *testing.T) []*mgmt.CachePinDTO {
+		t.Helper()
+
+		resp := s.send(t, http.MethodGet, pinsPath, adminToken, nil)
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		var res mgmt.ListCachePinsResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res.Pins
+	}
+
+	t.Run("Pin", func(t *testing.T) {
+		digest := "sha256:" + strings.Repeat("a", 64)
+		for _, reference := range []string{"3.20", digest, "3.20"} {
+			resp := s.send(t, http.MethodPost, pinsPath, adminToken, pin(reference))
+			resp.Body.Close()
+			helpers.AssertStatusCode(t, resp, http.StatusCreated)
+		}
+
+		pins := listPins(t)
+		require.Len(t, pins, 2)
+		assert.Equal(t, "library", pins[0].Namespace)
+		assert.Equal(t, "alpine", pins[0].Repository)
+		assert.Equal(t, "3.20", pins[0].Reference)
+		assert.Equal(t, digest, pins[1].Reference)
+	})
+
+	t.Run("Invalid pins", func(t *testing.T) {
+		resp := s.send(t, http.MethodPost, pinsPath, adminToken, pin("-invalid"))
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+
+		resp = s.send(t, http.MethodPost, pinsPath, adminToken, mgmt.PinCachedImageRequest{
+			Namespace: "library", Repository: "unknown", Reference: "latest"})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+
+		resp = s.send(t, http.MethodPost, fmt.Sprintf(testdata.EndpointUpstreamCachePins, "unknown"), adminToken,
+			pin("latest"))
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("Unpin", func(t *testing.T) {
+		resp := s.send(t, http.MethodDelete, unpinPath("alpine", "3.20"), adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+		assert.Len(t, listPins(t), 1)
+
+		resp = s.send(t, http.MethodDelete, unpinPath("alpine", "3.20"), adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		res := s.getUpstream(t, id)
+		assert.Equal(t, int64(0), res.StorageConfig.UsedBytes)
+
+		resp := s.send(t, http.MethodPost, fmt.Sprintf(testdata.EndpointUpstreamCacheEvict, id)+"?dry_run=true",
+			adminToken, nil)
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		var report mgmt.CacheEvictionResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		assert.True(t, report.DryRun)
+		assert.Empty(t, report.Blobs)
+		assert.Empty(t, report.Manifests)
+	})
+
+	t.Run("Non admin", func(t *testing.T) {
+		s.seeder.ProvisionUser(t, "upstream-pins-maintainer", "upstream-pins-maintainer@t.com",
+			constants.RoleMaintainer)
+		token := s.seeder.UserToken(t, "upstream-pins-maintainer", constants.RoleMaintainer)
+
+		resp := s.send(t, http.MethodPost, pinsPath, token, pin("latest"))
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+
+		resp = s.send(t, http.MethodPost, fmt.Sprintf(testdata.EndpointUpstreamCacheEvict, id), token, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+	})
+}
+
+func (s *UpstreamTestSuite) testNonAdmin(t *testing.T) {
+	s.seeder.ProvisionUser(t, "upstream-maintainer1", "upstream-maintainer1@t.com", constants.RoleMaintainer)
+	token := s.seeder.UserToken(t, "upstream-maintainer1", constants.RoleMaintainer)
+
+	resp := s.send(t, http.MethodPost, testdata.EndpointUpstreams, token,
+		s.upstreamBody("upstream-non-admin", helpers.FindFreePort()))
+	resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+
+	resp = s.send(t, http.MethodGet, testdata.EndpointUpstreams, token, nil)
+	resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+}
\ No newline at end of file
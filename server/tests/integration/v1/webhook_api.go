@@ -0,0 +1,290 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
+	"github.com/ksankeerth/open-image-registry/tests/integration/seeder"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type WebhookTestSuite struct {
+	name        string
+	apiVersion  string
+	seeder      *seeder.TestDataSeeder
+	testBaseURL string
+}
+
+func NewWebhookTestSuite(seeder *seeder.TestDataSeeder, baseURL string) *WebhookTestSuite {
+	return &WebhookTestSuite{
+		name:        "WebhookAPI",
+		apiVersion:  "v1",
+		seeder:      seeder,
+		testBaseURL: baseURL,
+	}
+}
+
+func (s *WebhookTestSuite) Run(t *testing.T) {
+	t.Run("ManageWebhooks", s.testManageWebhooks)
+	t.Run("InvalidWebhooks", s.testInvalidWebhooks)
+	t.Run("NonAdmin", s.testNonAdmin)
+}
+
+func (s *WebhookTestSuite) Name() string {
+	return s.name
+}
+
+func (s *WebhookTestSuite) APIVersion() string {
+	return s.apiVersion
+}
+
+func (s *WebhookTestSuite) send(t *testing.T, method, path, token string, body any) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, s.testBaseURL+path, reader)
+	require.NoError(t, err)
+	helpers.SetAuthCookie(req, token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func (s *WebhookTestSuite) getWebhook(t *testing.T, id string) *mgmt.WebhookResponse {
+	t.Helper()
+
+	resp := s.send(t, http.MethodGet, fmt.Sprintf(testdata.EndpointWebhookByID, id), s.seeder.AdminToken(t), nil)
+	defer resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+	var body mgmt.WebhookResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return &body
+}
+
+func (s *WebhookTestSuite) listWebhooks(t *testing.T, query string) []mgmt.WebhookResponse {
+	t.Helper()
+
+	resp := s.send(t, http.MethodGet, testdata.EndpointWebhooks+query, s.seeder.AdminToken(t), nil)
+	defer resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+	var body mgmt.WebhookListResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body.Webhooks
+}
+
+func (s *WebhookTestSuite) testManageWebhooks(t *testing.T) {
+	m := s.seeder.ProvisionUser(t, "webhook-maintainer1", "webhook-maintainer1@t.com", constants.RoleMaintainer)
+	nsId := s.seeder.CreateNamespace(t, "webhook-ns1", "", constants.NamespacePurposeTeam, false, m)
+
+	adminToken := s.seeder.AdminToken(t)
+
+	var globalId, namespaceId string
+
+	t.Run("Create global webhook", func(t *testing.T) {
+		resp := s.send(t, http.MethodPost, testdata.EndpointWebhooks, adminToken, map[string]any{
+			"name":    "webhook-global",
+			"url":     "http://localhost:1/events",
+			"secret":  "s3cret",
+			"events":  []string{"push", "delete"},
+			"enabled": false,
+		})
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusCreated)
+
+		var body mgmt.CreateWebhookResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.NotEmpty(t, body.ID)
+		globalId = body.ID
+
+		webhook := s.getWebhook(t, globalId)
+		assert.Equal(t, "webhook-global", webhook.Name)
+		assert.Equal(t, "http://localhost:1/events", webhook.URL)
+		assert.Equal(t, []string{"push", "delete"}, webhook.Events)
+		assert.Empty(t, webhook.NamespaceID)
+		assert.False(t, webhook.Enabled)
+		assert.True(t, webhook.HasSecret)
+	})
+
+	t.Run("Create namespace webhook", func(t *testing.T) {
+		resp := s.send(t, http.MethodPost, testdata.EndpointWebhooks, adminToken, map[string]any{
+			"name":      "webhook-ns1-hook",
+			"url":       "https://localhost:1/events",
+			"namespace": "webhook-ns1",
+		})
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusCreated)
+
+		var body mgmt.CreateWebhookResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		namespaceId = body.ID
+
+		webhook := s.getWebhook(t, namespaceId)
+		assert.Equal(t, nsId, webhook.NamespaceID)
+		assert.Equal(t, "webhook-ns1", webhook.Namespace)
+		assert.Empty(t, webhook.Events)
+		// webhooks are enabled unless stated otherwise
+		assert.True(t, webhook.Enabled)
+		assert.False(t, webhook.HasSecret)
+	})
+
+	t.Run("Duplicate name", func(t *testing.T) {
+		resp := s.send(t, http.MethodPost, testdata.EndpointWebhooks, adminToken, map[string]any{
+			"name": "webhook-global",
+			"url":  "http://localhost:1/other",
+		})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusConflict)
+	})
+
+	t.Run("Unknown namespace", func(t *testing.T) {
+		resp := s.send(t, http.MethodPost, testdata.EndpointWebhooks, adminToken, map[string]any{
+			"name":      "webhook-unknown-ns",
+			"url":       "http://localhost:1/events",
+			"namespace": "webhook-unknown-ns",
+		})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		ids := []string{}
+		for _, webhook := range s.listWebhooks(t, "") {
+			ids = append(ids, webhook.ID)
+		}
+		assert.Contains(t, ids, globalId)
+		assert.Contains(t, ids, namespaceId)
+
+		webhooks := s.listWebhooks(t, "?namespace="+nsId)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, namespaceId, webhooks[0].ID)
+
+		resp := s.send(t, http.MethodGet, testdata.EndpointWebhooks+"?namespace=webhook-unknown-ns", adminToken,
+			nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		resp := s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointWebhookByID, globalId), adminToken,
+			map[string]any{
+				"url":     "http://localhost:2/events",
+				"events":  []string{"pull"},
+				"enabled": true,
+			})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		webhook := s.getWebhook(t, globalId)
+		assert.Equal(t, "http://localhost:2/events", webhook.URL)
+		assert.Equal(t, []string{"pull"}, webhook.Events)
+		assert.True(t, webhook.Enabled)
+		// secret is kept if it is omitted
+		assert.True(t, webhook.HasSecret)
+
+		resp = s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointWebhookByID, globalId), adminToken,
+			map[string]any{
+				"url":     "http://localhost:2/events",
+				"secret":  "",
+				"enabled": false,
+			})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		webhook = s.getWebhook(t, globalId)
+		assert.False(t, webhook.HasSecret)
+		assert.False(t, webhook.Enabled)
+		assert.Empty(t, webhook.Events)
+	})
+
+	t.Run("Deliveries", func(t *testing.T) {
+		resp := s.send(t, http.MethodGet, fmt.Sprintf(testdata.EndpointWebhookDeliveries, namespaceId), adminToken,
+			nil)
+		defer resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+		var body mgmt.WebhookDeliveryListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Empty(t, body.Deliveries)
+
+		resp = s.send(t, http.MethodGet, fmt.Sprintf(testdata.EndpointWebhookDeliveries, namespaceId)+"?limit=0",
+			adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		for _, id := range []string{globalId, namespaceId} {
+			resp := s.send(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointWebhookByID, id), adminToken, nil)
+			resp.Body.Close()
+			helpers.AssertStatusCode(t, resp, http.StatusOK)
+
+			resp = s.send(t, http.MethodGet, fmt.Sprintf(testdata.EndpointWebhookByID, id), adminToken, nil)
+			resp.Body.Close()
+			helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+		}
+
+		resp := s.send(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointWebhookByID, globalId), adminToken, nil)
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
+
+func (s *WebhookTestSuite) testInvalidWebhooks(t *testing.T) {
+	adminToken := s.seeder.AdminToken(t)
+
+	for name, body := range map[string]map[string]any{
+		"Short name":       {"name": "wh", "url": "http://localhost:1/events"},
+		"Missing url":      {"name": "webhook-invalid"},
+		"Relative url":     {"name": "webhook-invalid", "url": "/events"},
+		"Unsupported url":  {"name": "webhook-invalid", "url": "ftp://localhost/events"},
+		"Unknown event":    {"name": "webhook-invalid", "url": "http://localhost:1/events", "events": []string{"mount"}},
+		"Duplicate events": {"name": "webhook-invalid", "url": "http://localhost:1/events", "events": []string{"push", "push"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := s.send(t, http.MethodPost, testdata.EndpointWebhooks, adminToken, body)
+			resp.Body.Close()
+			helpers.AssertStatusCode(t, resp, http.StatusBadRequest)
+		})
+	}
+
+	t.Run("Update unknown webhook", func(t *testing.T) {
+		resp := s.send(t, http.MethodPut, fmt.Sprintf(testdata.EndpointWebhookByID, "unknown"), adminToken,
+			map[string]any{"url": "http://localhost:1/events"})
+		resp.Body.Close()
+		helpers.AssertStatusCode(t, resp, http.StatusNotFound)
+	})
+}
+
+func (s *WebhookTestSuite) testNonAdmin(t *testing.T) {
+	s.seeder.ProvisionUser(t, "webhook-maintainer2", "webhook-maintainer2@t.com", constants.RoleMaintainer)
+	token := s.seeder.UserToken(t, "webhook-maintainer2", constants.RoleMaintainer)
+
+	resp := s.send(t, http.MethodPost, testdata.EndpointWebhooks, token, map[string]any{
+		"name": "webhook-non-admin",
+		"url":  "http://localhost:1/events",
+	})
+	resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+
+	resp = s.send(t, http.MethodGet, testdata.EndpointWebhooks, token, nil)
+	resp.Body.Close()
+	helpers.AssertStatusCode(t, resp, http.StatusForbidden)
+}
\ No newline at end of file
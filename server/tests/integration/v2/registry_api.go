@@ -0,0 +1,2201 @@
+package v2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/registry"
+	"github.com/ksankeerth/open-image-registry/storage"
+	"github.com/ksankeerth/open-image-registry/tests/integration/seeder"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/dockerv2"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/oci"
+	"github.com/ksankeerth/open-image-registry/types/models"
+	"github.com/ksankeerth/open-image-registry/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type RegistryTestSuite struct {
+	name            string
+	apiVersion      string
+	seeder          *seeder.TestDataSeeder
+	registryBaseURL string
+}
+
+func NewRegistryTestSuite(seeder *seeder.TestDataSeeder, registryBaseURL string) *RegistryTestSuite {
+	return &RegistryTestSuite{
+		name:            "RegistryAPI",
+		apiVersion:      "v2",
+		seeder:          seeder,
+		registryBaseURL: registryBaseURL,
+	}
+}
+
+func (r *RegistryTestSuite) Run(t *testing.T) {
+	t.Run("TokenChallenge", r.testTokenChallenge)
+	t.Run("PullAccess", r.testPullAccess)
+	t.Run("PushAccess", r.testPushAccess)
+	t.Run("ListTags", r.testListTags)
+	t.Run("Catalog", r.testCatalog)
+	t.Run("DeleteManifest", r.testDeleteManifest)
+	t.Run("MountAndDeleteBlob", r.testMountAndDeleteBlob)
+	t.Run("ChunkedUploadAndRange", r.testChunkedUploadAndRange)
+	t.Run("UploadSession", r.testUploadSession)
+	t.Run("Referrers", r.testReferrers)
+	t.Run("PushManifestByDigest", r.testPushManifestByDigest)
+	t.Run("MultipleTags", r.testMultipleTags)
+	t.Run("NestedRepository", r.testNestedRepository)
+	t.Run("ImmutableTags", r.testImmutableTags)
+	t.Run("GarbageCollection", r.testGarbageCollection)
+	t.Run("SharedBlobContent", r.testSharedBlobContent)
+	t.Run("Quota", r.testQuota)
+	t.Run("Retention", r.testRetention)
+	t.Run("Webhooks", r.testWebhooks)
+	t.Run("UpstreamBlobs", r.testUpstreamBlobs)
+	t.Run("UpstreamCoalescing", r.testUpstreamCoalescing)
+	t.Run("UpstreamCacheEviction", r.testUpstreamCacheEviction)
+	t.Run("UpstreamStaleCache", r.testUpstreamStaleCache)
+}
+
+func (r *RegistryTestSuite) Name() string {
+	return r.name
+}
+
+func (r *RegistryTestSuite) APIVersion() string {
+	return r.apiVersion
+}
+
+func (r *RegistryTestSuite) do(t *testing.T, method, path, token string, body io.Reader,
+	headers map[string]string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, r.registryBaseURL+path, body)
+	require.NoError(t, err)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
+func (r *RegistryTestSuite) testTokenChallenge(t *testing.T) {
+	t.Run("Without token", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryBase, "", nil, nil)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		challenge := resp.Header.Get("WWW-Authenticate")
+		assert.True(t, strings.HasPrefix(challenge, "Bearer realm="), "unexpected challenge: %s", challenge)
+		assert.Contains(t, challenge, `service="open-image-registry"`)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryBase, "invalid.token.value", nil, nil)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Management token is rejected", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryBase, r.seeder.AdminToken(t), nil, nil)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Anonymous token", func(t *testing.T) {
+		token := r.seeder.RegistryToken(t, "", "")
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryBase, token, nil, nil)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("User token", func(t *testing.T) {
+		r.seeder.ProvisionUserWithPassword(t, "registry-login-user", "registry-login@t.com", constants.RoleDeveloper,
+			"LoginPass123!")
+		token := r.seeder.RegistryToken(t, "registry-login-user", "LoginPass123!")
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryBase, token, nil, nil)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func (r *RegistryTestSuite) testPullAccess(t *testing.T) {
+	password := "PullAccess123!"
+	m1 := r.seeder.ProvisionUser(t, "registry-pull-m1", "registry-pull-m1@t.com", constants.RoleMaintainer)
+	g1 := r.seeder.ProvisionUserWithPassword(t, "registry-pull-g1", "registry-pull-g1@t.com", constants.RoleGuest, password)
+	r.seeder.ProvisionUserWithPassword(t, "registry-pull-g2", "registry-pull-g2@t.com", constants.RoleGuest, password)
+
+	privateNs := r.seeder.CreateNamespace(t, "registry-pull-private", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, privateNs, false)
+	r.seeder.GrantAccess(t, privateNs, constants.ResourceTypeNamespace, g1, constants.AccessLevelGuest)
+
+	publicNs := r.seeder.CreateNamespace(t, "registry-pull-public", "", constants.NamespacePurposeTeam, true, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, publicNs, true)
+	r.seeder.CreateRepository(t, "internal", "", m1, publicNs, false)
+
+	anonymous := r.seeder.RegistryToken(t, "", "")
+	guestWithAccess := r.seeder.RegistryToken(t, "registry-pull-g1", password)
+	guestWithoutAccess := r.seeder.RegistryToken(t, "registry-pull-g2", password)
+
+	tcs := []struct {
+		name       string
+		namespace  string
+		repository string
+		token      string
+		statusCode int
+	}{
+		{"Anonymous pull from private repository", "registry-pull-private", "app", anonymous, http.StatusUnauthorized},
+		{"Anonymous pull from missing private repository", "registry-pull-private", "missing", anonymous,
+			http.StatusUnauthorized},
+		{"Pull from private repository without access", "registry-pull-private", "app", guestWithoutAccess,
+			http.StatusForbidden},
+		// access is allowed. But no manifest is available
+		{"Pull from private repository with guest access", "registry-pull-private", "app", guestWithAccess,
+			http.StatusNotFound},
+		{"Anonymous pull from public repository", "registry-pull-public", "app", anonymous, http.StatusNotFound},
+		{"Pull from public repository without access", "registry-pull-public", "app", guestWithoutAccess,
+			http.StatusNotFound},
+		// repositories of public namespaces are public whether they exist or not
+		{"Anonymous pull from private repository of public namespace", "registry-pull-public", "internal",
+			anonymous, http.StatusNotFound},
+		{"Anonymous pull from missing repository of public namespace", "registry-pull-public", "missing",
+			anonymous, http.StatusNotFound},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			path := fmt.Sprintf(testdata.EndpointRegistryManifest, tc.namespace, tc.repository, "latest")
+			resp := r.do(t, http.MethodGet, path, tc.token, nil, nil)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.statusCode, resp.StatusCode)
+		})
+	}
+}
+
+func (r *RegistryTestSuite) testPushAccess(t *testing.T) {
+	password := "PushAccess123!"
+	m1 := r.seeder.ProvisionUser(t, "registry-push-m1", "registry-push-m1@t.com", constants.RoleMaintainer)
+	d1 := r.seeder.ProvisionUserWithPassword(t, "registry-push-d1", "registry-push-d1@t.com", constants.RoleDeveloper, password)
+	g1 := r.seeder.ProvisionUserWithPassword(t, "registry-push-g1", "registry-push-g1@t.com", constants.RoleGuest, password)
+	r.seeder.ProvisionUserWithPassword(t, "registry-push-d2", "registry-push-d2@t.com", constants.RoleDeveloper, password)
+
+	nsId := r.seeder.CreateNamespace(t, "registry-push-ns", "", constants.NamespacePurposeTeam, true, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, nsId, true)
+	r.seeder.GrantAccess(t, nsId, constants.ResourceTypeNamespace, d1, constants.AccessLevelDeveloper)
+	r.seeder.GrantAccess(t, nsId, constants.ResourceTypeNamespace, g1, constants.AccessLevelGuest)
+
+	tcs := []struct {
+		name       string
+		token      string
+		statusCode int
+	}{
+		{"Anonymous push", r.seeder.RegistryToken(t, "", ""), http.StatusUnauthorized},
+		{"Push with guest access", r.seeder.RegistryToken(t, "registry-push-g1", password), http.StatusForbidden},
+		{"Push without access", r.seeder.RegistryToken(t, "registry-push-d2", password), http.StatusForbidden},
+		{"Push with developer access", r.seeder.RegistryToken(t, "registry-push-d1", password), http.StatusAccepted},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			path := fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-push-ns", "app")
+			resp := r.do(t, http.MethodPost, path, tc.token, nil, nil)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.statusCode, resp.StatusCode)
+		})
+	}
+}
+
+func (r *RegistryTestSuite) testListTags(t *testing.T) {
+	m1 := r.seeder.ProvisionUser(t, "registry-tags-m1", "registry-tags-m1@t.com", constants.RoleMaintainer)
+
+	nsId := r.seeder.CreateNamespace(t, "registry-tags-ns", "", constants.NamespacePurposeTeam, true, m1)
+	repoId := r.seeder.CreateRepository(t, "app", "", m1, nsId, true)
+	r.seeder.CreateRepository(t, "empty", "", m1, nsId, true)
+	r.seeder.CreateTags(t, nsId, repoId, "v1.1", "latest", "v1.0", "v2.0", "dev")
+
+	token := r.seeder.RegistryToken(t, "", "")
+	basePath := fmt.Sprintf(testdata.EndpointRegistryTags, "registry-tags-ns", "app")
+
+	tcs := []struct {
+		name       string
+		path       string
+		statusCode int
+		tags       []string
+		link       string
+	}{
+		{
+			name:       "List all tags",
+			path:       basePath,
+			statusCode: http.StatusOK,
+			tags:       []string{"dev", "latest", "v1.0", "v1.1", "v2.0"},
+		},
+		{
+			name:       "List first page",
+			path:       basePath + "?n=2",
+			statusCode: http.StatusOK,
+			tags:       []string{"dev", "latest"},
+			link:       fmt.Sprintf(`<%s?last=latest&n=2>; rel="next"`, basePath),
+		},
+		{
+			name:       "List next page",
+			path:       basePath + "?n=2&last=latest",
+			statusCode: http.StatusOK,
+			tags:       []string{"v1.0", "v1.1"},
+			link:       fmt.Sprintf(`<%s?last=v1.1&n=2>; rel="next"`, basePath),
+		},
+		{
+			name:       "List last page",
+			path:       basePath + "?n=2&last=v1.1",
+			statusCode: http.StatusOK,
+			tags:       []string{"v2.0"},
+		},
+		{
+			name:       "List with page size equal to remaining tags",
+			path:       basePath + "?n=5",
+			statusCode: http.StatusOK,
+			tags:       []string{"dev", "latest", "v1.0", "v1.1", "v2.0"},
+		},
+		{
+			name:       "List with zero page size",
+			path:       basePath + "?n=0",
+			statusCode: http.StatusOK,
+			tags:       []string{},
+		},
+		{
+			name:       "List tags of empty repository",
+			path:       fmt.Sprintf(testdata.EndpointRegistryTags, "registry-tags-ns", "empty"),
+			statusCode: http.StatusOK,
+			tags:       []string{},
+		},
+		{
+			name:       "Invalid page size",
+			path:       basePath + "?n=abc",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Negative page size",
+			path:       basePath + "?n=-1",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Non-existing repository",
+			path:       fmt.Sprintf(testdata.EndpointRegistryTags, "registry-tags-ns", "unknown"),
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := r.do(t, http.MethodGet, tc.path, token, nil, nil)
+			defer resp.Body.Close()
+
+			require.Equal(t, tc.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.link, resp.Header.Get("Link"))
+
+			if tc.statusCode != http.StatusOK {
+				return
+			}
+
+			var body dockerv2.TagListResponse
+			err := json.NewDecoder(resp.Body).Decode(&body)
+			require.NoError(t, err)
+
+			assert.True(t, strings.HasPrefix(body.Name, "registry-tags-ns/"), "unexpected name: %s", body.Name)
+			assert.Equal(t, tc.tags, body.Tags)
+		})
+	}
+}
+
+func (r *RegistryTestSuite) testCatalog(t *testing.T) {
+	password := "CatalogList123!"
+	m1 := r.seeder.ProvisionUser(t, "registry-catalog-m1", "registry-catalog-m1@t.com", constants.RoleMaintainer)
+	g1 := r.seeder.ProvisionUserWithPassword(t, "registry-catalog-g1", "registry-catalog-g1@t.com", constants.RoleGuest,
+		password)
+	r.seeder.ProvisionUserWithPassword(t, "registry-catalog-g2", "registry-catalog-g2@t.com", constants.RoleGuest,
+		password)
+	r.seeder.ProvisionUserWithPassword(t, "registry-catalog-a1", "registry-catalog-a1@t.com", constants.RoleAdmin,
+		password)
+
+	publicNs := r.seeder.CreateNamespace(t, "catalog-public", "", constants.NamespacePurposeTeam, true, m1)
+	r.seeder.CreateRepository(t, "web", "", m1, publicNs, true)
+	r.seeder.CreateRepository(t, "db", "", m1, publicNs, false)
+	disabledRepo := r.seeder.CreateRepository(t, "old", "", m1, publicNs, true)
+	r.seeder.SetRepositoryDisabled(t, disabledRepo)
+
+	privateNs := r.seeder.CreateNamespace(t, "catalog-private", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "api", "", m1, privateNs, false)
+	r.seeder.GrantAccess(t, privateNs, constants.ResourceTypeNamespace, g1, constants.AccessLevelGuest)
+
+	listCatalog := func(t *testing.T, token, query string) ([]string, *http.Response) {
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryCatalog+query, token, nil, nil)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body dockerv2.CatalogResponse
+		err := json.NewDecoder(resp.Body).Decode(&body)
+		require.NoError(t, err)
+		return body.Repositories, resp
+	}
+
+	tcs := []struct {
+		name       string
+		token      string
+		visible    []string
+		notVisible []string
+	}{
+		{
+			name:       "Anonymous user",
+			token:      r.seeder.RegistryToken(t, "", ""),
+			visible:    []string{"catalog-public/web", "catalog-public/db"},
+			notVisible: []string{"catalog-public/old", "catalog-private/api"},
+		},
+		{
+			name:       "User without access",
+			token:      r.seeder.RegistryToken(t, "registry-catalog-g2", password),
+			visible:    []string{"catalog-public/web", "catalog-public/db"},
+			notVisible: []string{"catalog-public/old", "catalog-private/api"},
+		},
+		{
+			name:       "User with namespace access",
+			token:      r.seeder.RegistryToken(t, "registry-catalog-g1", password),
+			visible:    []string{"catalog-public/web", "catalog-public/db", "catalog-private/api"},
+			notVisible: []string{"catalog-public/old"},
+		},
+		{
+			name:    "Admin user",
+			token:   r.seeder.RegistryToken(t, "registry-catalog-a1", password),
+			visible: []string{"catalog-public/web", "catalog-public/db", "catalog-public/old", "catalog-private/api"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repositories, _ := listCatalog(t, tc.token, "")
+
+			for _, name := range tc.visible {
+				assert.Contains(t, repositories, name)
+			}
+			for _, name := range tc.notVisible {
+				assert.NotContains(t, repositories, name)
+			}
+		})
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		token := r.seeder.RegistryToken(t, "registry-catalog-a1", password)
+
+		repositories, resp := listCatalog(t, token, "?n=2&last=catalog-private/api")
+		assert.Equal(t, []string{"catalog-public/db", "catalog-public/old"}, repositories)
+		assert.Equal(t, `</v2/_catalog?last=catalog-public%2Fold&n=2>; rel="next"`, resp.Header.Get("Link"))
+
+		repositories, _ = listCatalog(t, token, "?n=0")
+		assert.Empty(t, repositories)
+	})
+
+	t.Run("Invalid pagination", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryCatalog+"?n=x", r.seeder.RegistryToken(t, "", ""),
+			nil, nil)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+// imageManifest returns an OCI image manifest referencing a config blob with the given digest
+func imageManifest(configDigest string) []byte {
+	return []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",`+
+		`"config":{"mediaType":"application/vnd.oci.image.config.v1+json","size":2,"digest":"%s"},"layers":[]}`,
+		configDigest))
+}
+
+func (r *RegistryTestSuite) pushManifest(t *testing.T, token, namespace, repository, reference string,
+	content []byte) (digest string) {
+	t.Helper()
+
+	path := fmt.Sprintf(testdata.EndpointRegistryManifest, namespace, repository, reference)
+	resp := r.do(t, http.MethodPut, path, token, bytes.NewReader(content),
+		map[string]string{"Content-Type": "application/vnd.oci.image.manifest.v1+json"})
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	digest = resp.Header.Get("Docker-Content-Digest")
+	require.NotEmpty(t, digest)
+	return digest
+}
+
+func (r *RegistryTestSuite) testDeleteManifest(t *testing.T) {
+	password := "DeleteManifest123!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-delete-m1", "registry-delete-m1@t.com",
+		constants.RoleMaintainer, password)
+	d1 := r.seeder.ProvisionUserWithPassword(t, "registry-delete-d1", "registry-delete-d1@t.com",
+		constants.RoleDeveloper, password)
+
+	nsId := r.seeder.CreateNamespace(t, "registry-delete-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+	r.seeder.GrantAccess(t, nsId, constants.ResourceTypeNamespace, d1, constants.AccessLevelDeveloper)
+
+	lockedNsId := r.seeder.CreateNamespace(t, "registry-delete-locked", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, lockedNsId, false)
+
+	maintainer := r.seeder.RegistryToken(t, "registry-delete-m1", password)
+	developer := r.seeder.RegistryToken(t, "registry-delete-d1", password)
+
+	manifestPath := func(namespace, reference string) string {
+		return fmt.Sprintf(testdata.EndpointRegistryManifest, namespace, "app", reference)
+	}
+
+	requestStatus := func(t *testing.T, method, path, token string) int {
+		resp := r.do(t, method, path, token, nil, nil)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("Delete by tag", func(t *testing.T) {
+		digest := r.pushManifest(t, developer, "registry-delete-ns", "app", "v1",
+			imageManifest(r.pushBlob(t, developer, "registry-delete-ns", "app", []byte("delete config 1"))))
+
+		assert.Equal(t, http.StatusForbidden, requestStatus(t, http.MethodDelete, manifestPath("registry-delete-ns", "v1"),
+			developer))
+		assert.Equal(t, http.StatusAccepted, requestStatus(t, http.MethodDelete, manifestPath("registry-delete-ns", "v1"),
+			maintainer))
+
+		assert.Equal(t, http.StatusNotFound, requestStatus(t, http.MethodGet, manifestPath("registry-delete-ns", "v1"),
+			developer))
+		// manifest is still available by digest
+		assert.Equal(t, http.StatusOK, requestStatus(t, http.MethodGet, manifestPath("registry-delete-ns", digest),
+			developer))
+
+		assert.Equal(t, http.StatusNotFound, requestStatus(t, http.MethodDelete, manifestPath("registry-delete-ns", "v1"),
+			maintainer))
+	})
+
+	t.Run("Delete by digest", func(t *testing.T) {
+		digest := r.pushManifest(t, developer, "registry-delete-ns", "app", "v2",
+			imageManifest(r.pushBlob(t, developer, "registry-delete-ns", "app", []byte("delete config 2"))))
+
+		assert.Equal(t, http.StatusForbidden, requestStatus(t, http.MethodDelete,
+			manifestPath("registry-delete-ns", digest), developer))
+		assert.Equal(t, http.StatusAccepted, requestStatus(t, http.MethodDelete,
+			manifestPath("registry-delete-ns", digest), maintainer))
+
+		assert.Equal(t, http.StatusNotFound, requestStatus(t, http.MethodGet, manifestPath("registry-delete-ns", digest),
+			developer))
+		// tags pointing to the manifest are deleted as well
+		assert.Equal(t, http.StatusNotFound, requestStatus(t, http.MethodGet, manifestPath("registry-delete-ns", "v2"),
+			developer))
+
+		assert.Equal(t, http.StatusNotFound, requestStatus(t, http.MethodDelete,
+			manifestPath("registry-delete-ns", digest), maintainer))
+	})
+
+	t.Run("Delete disabled for namespace", func(t *testing.T) {
+		r.pushManifest(t, maintainer, "registry-delete-locked", "app", "v1",
+			imageManifest(r.pushBlob(t, maintainer, "registry-delete-locked", "app", []byte("delete config 3"))))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, requestStatus(t, http.MethodDelete,
+			manifestPath("registry-delete-locked", "v1"), maintainer))
+		assert.Equal(t, http.StatusOK, requestStatus(t, http.MethodGet, manifestPath("registry-delete-locked", "v1"),
+			maintainer))
+	})
+}
+
+func blobDigest(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// pushBlob uploads the blob monolithically and returns its digest
+func (r *RegistryTestSuite) pushBlob(t *testing.T, token, namespace, repository string, content []byte) string {
+	t.Helper()
+
+	uploadsPath := fmt.Sprintf(testdata.EndpointRegistryBlobUploads, namespace, repository)
+	resp := r.do(t, http.MethodPost, uploadsPath, token, nil, nil)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	sessionID := resp.Header.Get("Docker-Upload-UUID")
+	require.NotEmpty(t, sessionID)
+
+	digest := blobDigest(content)
+	resp = r.do(t, http.MethodPut, uploadsPath+sessionID+"?digest="+digest, token, bytes.NewReader(content),
+		map[string]string{"Content-Type": "application/octet-stream"})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	return digest
+}
+
+func (r *RegistryTestSuite) testMountAndDeleteBlob(t *testing.T) {
+	password := "MountBlob12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-mount-m1", "registry-mount-m1@t.com",
+		constants.RoleMaintainer, password)
+	d1 := r.seeder.ProvisionUserWithPassword(t, "registry-mount-d1", "registry-mount-d1@t.com",
+		constants.RoleDeveloper, password)
+
+	nsId := r.seeder.CreateNamespace(t, "registry-mount-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "base", "", m1, nsId, false)
+	r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+	r.seeder.GrantAccess(t, nsId, constants.ResourceTypeNamespace, d1, constants.AccessLevelDeveloper)
+
+	// d1 can push into this namespace, but can't read from the other one
+	otherNsId := r.seeder.CreateNamespace(t, "registry-mount-other", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "secret", "", m1, otherNsId, false)
+
+	maintainer := r.seeder.RegistryToken(t, "registry-mount-m1", password)
+	developer := r.seeder.RegistryToken(t, "registry-mount-d1", password)
+
+	content := []byte("shared base layer")
+	digest := r.pushBlob(t, developer, "registry-mount-ns", "base", content)
+	secretDigest := r.pushBlob(t, maintainer, "registry-mount-other", "secret", []byte("secret layer"))
+
+	blobPath := func(namespace, repository, digest string) string {
+		return fmt.Sprintf(testdata.EndpointRegistryBlob, namespace, repository, digest)
+	}
+	mountPath := func(namespace, repository, digest, from string) string {
+		return fmt.Sprintf(testdata.EndpointRegistryBlobUploads, namespace, repository) + "?mount=" + digest +
+			"&from=" + from
+	}
+
+	t.Run("Mount blob from readable repository", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, mountPath("registry-mount-ns", "app", digest, "registry-mount-ns/base"),
+			developer, nil, nil)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, blobPath("registry-mount-ns", "app", digest), resp.Header.Get("Location"))
+		assert.Equal(t, digest, resp.Header.Get("Docker-Content-Digest"))
+
+		resp = r.do(t, http.MethodGet, blobPath("registry-mount-ns", "app", digest), developer, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content, body)
+	})
+
+	t.Run("Mount blob from unreadable repository", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, mountPath("registry-mount-ns", "app", secretDigest,
+			"registry-mount-other/secret"), developer, nil, nil)
+		resp.Body.Close()
+
+		// falls back to regular blob upload
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("Docker-Upload-UUID"))
+	})
+
+	t.Run("Mount unknown blob", func(t *testing.T) {
+		unknown := blobDigest([]byte("unknown layer"))
+		resp := r.do(t, http.MethodPost, mountPath("registry-mount-ns", "app", unknown, "registry-mount-ns/base"),
+			developer, nil, nil)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	})
+
+	t.Run("Delete blob", func(t *testing.T) {
+		resp := r.do(t, http.MethodDelete, blobPath("registry-mount-ns", "base", digest), developer, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = r.do(t, http.MethodDelete, blobPath("registry-mount-ns", "base", digest), maintainer, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		resp = r.do(t, http.MethodHead, blobPath("registry-mount-ns", "base", digest), maintainer, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		// mounted blob is not affected
+		resp = r.do(t, http.MethodGet, blobPath("registry-mount-ns", "app", digest), maintainer, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content, body)
+
+		resp = r.do(t, http.MethodDelete, blobPath("registry-mount-ns", "base", digest), maintainer, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Delete blob with invalid digest", func(t *testing.T) {
+		resp := r.do(t, http.MethodDelete, blobPath("registry-mount-ns", "base", "sha256:..%2F..%2Fapp"), maintainer,
+			nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func (r *RegistryTestSuite) testChunkedUploadAndRange(t *testing.T) {
+	password := "ChunkedBlob123!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-chunked-m1", "registry-chunked-m1@t.com",
+		constants.RoleMaintainer, password)
+	d1 := r.seeder.ProvisionUserWithPassword(t, "registry-chunked-d1", "registry-chunked-d1@t.com",
+		constants.RoleDeveloper, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-chunked-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "layers", "", m1, nsId, false)
+	r.seeder.GrantAccess(t, nsId, constants.ResourceTypeNamespace, d1, constants.AccessLevelDeveloper)
+
+	token := r.seeder.RegistryToken(t, "registry-chunked-d1", password)
+
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	digest := blobDigest(content)
+	uploadsPath := fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-chunked-ns", "layers")
+	blobPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "registry-chunked-ns", "layers", digest)
+
+	t.Run("Chunked upload", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, uploadsPath, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		sessionID := resp.Header.Get("Docker-Upload-UUID")
+
+		resp = r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader(content[:10]),
+			map[string]string{"Content-Type": "application/octet-stream", "Content-Range": "0-9"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "0-9", resp.Header.Get("Range"))
+
+		// chunk without Content-Range continues from the last received byte
+		resp = r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader(content[10:20]),
+			map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "0-19", resp.Header.Get("Range"))
+
+		// last chunk is sent with the closing PUT
+		resp = r.do(t, http.MethodPut, uploadsPath+sessionID+"?digest="+digest, token,
+			bytes.NewReader(content[20:]), map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, blobPath, resp.Header.Get("Location"))
+		assert.Equal(t, digest, resp.Header.Get("Docker-Content-Digest"))
+		assert.Equal(t, fmt.Sprintf("0-%d", len(content)-1), resp.Header.Get("Range"))
+
+		resp = r.do(t, http.MethodGet, blobPath, token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, digest, resp.Header.Get("Docker-Content-Digest"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content, body)
+	})
+
+	t.Run("Out of order chunk", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, uploadsPath, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		sessionID := resp.Header.Get("Docker-Upload-UUID")
+
+		resp = r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader(content[10:20]),
+			map[string]string{"Content-Type": "application/octet-stream", "Content-Range": "10-19"})
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Digest mismatch", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, uploadsPath, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		sessionID := resp.Header.Get("Docker-Upload-UUID")
+
+		resp = r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader(content[:10]),
+			map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		wrongDigest := blobDigest([]byte("other content"))
+		resp = r.do(t, http.MethodPut, uploadsPath+sessionID+"?digest="+wrongDigest, token,
+			bytes.NewReader(content[10:]), map[string]string{"Content-Type": "application/octet-stream"})
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var errResp struct {
+			Errors []struct {
+				Code string `json:"code"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		require.Len(t, errResp.Errors, 1)
+		assert.Equal(t, "DIGEST_INVALID", errResp.Errors[0].Code)
+
+		// session is cleaned up
+		resp = r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader(content[10:]),
+			map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = r.do(t, http.MethodHead, fmt.Sprintf(testdata.EndpointRegistryBlob, "registry-chunked-ns", "layers",
+			wrongDigest), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("sha512 digest", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, uploadsPath, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		sessionID := resp.Header.Get("Docker-Upload-UUID")
+
+		sha512Digest := fmt.Sprintf("sha512:%x", sha512.Sum512(content))
+		resp = r.do(t, http.MethodPut, uploadsPath+sessionID+"?digest="+sha512Digest, token,
+			bytes.NewReader(content), map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("Range request", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, blobPath, token, nil, map[string]string{"Range": "bytes=5-14"})
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, fmt.Sprintf("bytes 5-14/%d", len(content)), resp.Header.Get("Content-Range"))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content[5:15], body)
+	})
+
+	t.Run("Unsatisfiable range", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, blobPath, token, nil, map[string]string{"Range": "bytes=1000-2000"})
+		resp.Body.Close()
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+	})
+
+	t.Run("HEAD reports size", func(t *testing.T) {
+		resp := r.do(t, http.MethodHead, blobPath, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, digest, resp.Header.Get("Docker-Content-Digest"))
+	})
+}
+
+func (r *RegistryTestSuite) testUploadSession(t *testing.T) {
+	password := "UploadSession123!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-session-m1", "registry-session-m1@t.com",
+		constants.RoleMaintainer, password)
+	d1 := r.seeder.ProvisionUserWithPassword(t, "registry-session-d1", "registry-session-d1@t.com",
+		constants.RoleDeveloper, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-session-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "layers", "", m1, nsId, false)
+	r.seeder.CreateRepository(t, "other", "", m1, nsId, false)
+	r.seeder.GrantAccess(t, nsId, constants.ResourceTypeNamespace, d1, constants.AccessLevelDeveloper)
+
+	token := r.seeder.RegistryToken(t, "registry-session-d1", password)
+	uploadsPath := fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-session-ns", "layers")
+
+	startUpload := func(t *testing.T) string {
+		resp := r.do(t, http.MethodPost, uploadsPath, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		return resp.Header.Get("Docker-Upload-UUID")
+	}
+
+	t.Run("Upload status", func(t *testing.T) {
+		sessionID := startUpload(t)
+
+		resp := r.do(t, http.MethodGet, uploadsPath+sessionID, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "0-0", resp.Header.Get("Range"))
+		assert.Equal(t, sessionID, resp.Header.Get("Docker-Upload-UUID"))
+
+		resp = r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader([]byte("resumable")),
+			map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		resp = r.do(t, http.MethodGet, uploadsPath+sessionID, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "0-8", resp.Header.Get("Range"))
+	})
+
+	t.Run("Upload status of other repository", func(t *testing.T) {
+		sessionID := startUpload(t)
+
+		otherPath := fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-session-ns", "other")
+		resp := r.do(t, http.MethodGet, otherPath+sessionID, token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Unknown upload", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, uploadsPath+"unknown-session", token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = r.do(t, http.MethodDelete, uploadsPath+"unknown-session", token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Cancel upload", func(t *testing.T) {
+		sessionID := startUpload(t)
+
+		resp := r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader([]byte("cancelled")),
+			map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		resp = r.do(t, http.MethodDelete, uploadsPath+sessionID, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp = r.do(t, http.MethodGet, uploadsPath+sessionID, token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Idle upload is reaped", func(t *testing.T) {
+		sessionID := startUpload(t)
+
+		resp := r.do(t, http.MethodPatch, uploadsPath+sessionID, token, bytes.NewReader([]byte("abandoned")),
+			map[string]string{"Content-Type": "application/octet-stream"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		// test config expires sessions idle for 2 seconds
+		require.Eventually(t, func() bool {
+			resp := r.do(t, http.MethodGet, uploadsPath+sessionID, token, nil, nil)
+			resp.Body.Close()
+			return resp.StatusCode == http.StatusNotFound
+		}, 10*time.Second, 500*time.Millisecond)
+	})
+}
+
+// artifactManifest builds an OCI artifact manifest which refers the subject
+func artifactManifest(artifactType, subjectDigest string, subjectSize int, layerDigest, annotation string) []byte {
+	return []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",`+
+		`"artifactType":"%s",`+
+		`"config":{"mediaType":"application/vnd.oci.empty.v1+json","size":2,`+
+		`"digest":"sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"},`+
+		`"layers":[{"mediaType":"application/octet-stream","size":4,"digest":"%s"}],`+
+		`"subject":{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":%d,"digest":"%s"},`+
+		`"annotations":{"org.example.note":"%s"}}`,
+		artifactType, layerDigest, subjectSize, subjectDigest, annotation))
+}
+
+func (r *RegistryTestSuite) testReferrers(t *testing.T) {
+	password := "Referrers12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-referrers-m1", "registry-referrers-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-referrers-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+
+	token := r.seeder.RegistryToken(t, "registry-referrers-m1", password)
+
+	subject := imageManifest(r.pushBlob(t, token, "registry-referrers-ns", "app", []byte("referrers config")))
+	subjectDigest := r.pushManifest(t, token, "registry-referrers-ns", "app", "v1", subject)
+
+	// empty config of artifacts
+	r.pushBlob(t, token, "registry-referrers-ns", "app", []byte("{}"))
+
+	signature := artifactManifest("application/vnd.example.signature", subjectDigest, len(subject),
+		r.pushBlob(t, token, "registry-referrers-ns", "app", []byte("sig1")), "signature")
+	sbom := artifactManifest("application/vnd.example.sbom", subjectDigest, len(subject),
+		r.pushBlob(t, token, "registry-referrers-ns", "app", []byte("sbom")), "sbom")
+
+	referrersPath := func(digest string) string {
+		return fmt.Sprintf("/v2/%s/%s/referrers/%s", "registry-referrers-ns", "app", digest)
+	}
+
+	t.Run("Push manifest with subject", func(t *testing.T) {
+		path := fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-referrers-ns", "app", "signature")
+		resp := r.do(t, http.MethodPut, path, token, bytes.NewReader(signature),
+			map[string]string{"Content-Type": "application/vnd.oci.image.manifest.v1+json"})
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, subjectDigest, resp.Header.Get("OCI-Subject"))
+
+		r.pushManifest(t, token, "registry-referrers-ns", "app", "sbom", sbom)
+	})
+
+	decodeIndex := func(t *testing.T, resp *http.Response) oci.OCIImageIndex {
+		var index oci.OCIImageIndex
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&index))
+		return index
+	}
+
+	t.Run("List referrers", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, referrersPath(subjectDigest), token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, oci.MediaTypeImageIndex, resp.Header.Get("Content-Type"))
+		assert.Empty(t, resp.Header.Get("OCI-Filters-Applied"))
+
+		index := decodeIndex(t, resp)
+		assert.Equal(t, 2, index.SchemaVersion)
+		require.Len(t, index.Manifests, 2)
+
+		artifactTypes := []string{index.Manifests[0].ArtifactType, index.Manifests[1].ArtifactType}
+		assert.ElementsMatch(t, []string{"application/vnd.example.signature", "application/vnd.example.sbom"},
+			artifactTypes)
+		for _, descriptor := range index.Manifests {
+			assert.Equal(t, oci.MediaTypeImageManifest, descriptor.MediaType)
+			assert.NotEmpty(t, descriptor.Annotations["org.example.note"])
+		}
+	})
+
+	t.Run("Filter by artifact type", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, referrersPath(subjectDigest)+"?artifactType=application/vnd.example.sbom",
+			token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "artifactType", resp.Header.Get("OCI-Filters-Applied"))
+
+		index := decodeIndex(t, resp)
+		require.Len(t, index.Manifests, 1)
+		assert.Equal(t, blobDigest(sbom), index.Manifests[0].Digest)
+		assert.Equal(t, int64(len(sbom)), index.Manifests[0].Size)
+		assert.Equal(t, "sbom", index.Manifests[0].Annotations["org.example.note"])
+	})
+
+	t.Run("No referrers", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, referrersPath(blobDigest([]byte("no referrers"))), token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, decodeIndex(t, resp).Manifests)
+	})
+
+	t.Run("Referrer is removed with manifest", func(t *testing.T) {
+		path := fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-referrers-ns", "app", blobDigest(signature))
+		resp := r.do(t, http.MethodDelete, path, token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		resp = r.do(t, http.MethodGet, referrersPath(subjectDigest), token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, decodeIndex(t, resp).Manifests, 1)
+	})
+
+	t.Run("Invalid digest", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, referrersPath("invalid"), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func (r *RegistryTestSuite) testPushManifestByDigest(t *testing.T) {
+	password := "DigestPush12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-digest-m1", "registry-digest-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-digest-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+
+	token := r.seeder.RegistryToken(t, "registry-digest-m1", password)
+
+	manifestPath := func(reference string) string {
+		return fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-digest-ns", "app", reference)
+	}
+	put := func(t *testing.T, reference, mediaType string, content []byte) *http.Response {
+		return r.do(t, http.MethodPut, manifestPath(reference), token, bytes.NewReader(content),
+			map[string]string{"Content-Type": mediaType})
+	}
+	errorCodes := func(t *testing.T, resp *http.Response) []string {
+		var errResp struct {
+			Errors []struct {
+				Code   string            `json:"code"`
+				Detail map[string]string `json:"detail"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		codes := []string{}
+		for _, e := range errResp.Errors {
+			codes = append(codes, e.Code+" "+e.Detail["digest"])
+		}
+		return codes
+	}
+
+	configDigest := r.pushBlob(t, token, "registry-digest-ns", "app", []byte("digest push config"))
+	child := imageManifest(configDigest)
+	childDigest := blobDigest(child)
+
+	t.Run("Push untagged manifest", func(t *testing.T) {
+		resp := put(t, childDigest, oci.MediaTypeImageManifest, child)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, childDigest, resp.Header.Get("Docker-Content-Digest"))
+
+		resp = r.do(t, http.MethodGet, manifestPath(childDigest), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// no tag is created for the digest
+		resp = r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryTags, "registry-digest-ns", "app"), token,
+			nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var tagList dockerv2.TagListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&tagList))
+		assert.Empty(t, tagList.Tags)
+	})
+
+	t.Run("Same manifest with different bytes", func(t *testing.T) {
+		variant := append(append([]byte{}, child...), '\n')
+		variantDigest := blobDigest(variant)
+
+		resp := put(t, variantDigest, oci.MediaTypeImageManifest, variant)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "MANIFEST_INVALID")
+
+		resp = r.do(t, http.MethodGet, manifestPath(variantDigest), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		// tag is linked to the stored manifest
+		resp = put(t, "variant", oci.MediaTypeImageManifest, variant)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, childDigest, resp.Header.Get("Docker-Content-Digest"))
+
+		resp = r.do(t, http.MethodGet, manifestPath("variant"), token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, childDigest, resp.Header.Get("Docker-Content-Digest"))
+	})
+
+	t.Run("Digest mismatch", func(t *testing.T) {
+		resp := put(t, blobDigest([]byte("something else")), oci.MediaTypeImageManifest, child)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, []string{"DIGEST_INVALID " + blobDigest([]byte("something else"))}, errorCodes(t, resp))
+	})
+
+	t.Run("Push by sha512 digest", func(t *testing.T) {
+		manifest := imageManifest(r.pushBlob(t, token, "registry-digest-ns", "app", []byte("sha512 push config")))
+		sum := sha512.Sum512(manifest)
+		digest := fmt.Sprintf("sha512:%x", sum)
+
+		resp := put(t, digest, oci.MediaTypeImageManifest, manifest)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, digest, resp.Header.Get("Docker-Content-Digest"))
+
+		resp = r.do(t, http.MethodGet, manifestPath(digest), token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, manifest, body)
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		resp := put(t, "unsupported", "application/vnd.example.unknown+json", child)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "MANIFEST_INVALID")
+	})
+
+	t.Run("Malformed manifest", func(t *testing.T) {
+		resp := put(t, "malformed", oci.MediaTypeImageManifest, []byte("{not json"))
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "MANIFEST_INVALID")
+	})
+
+	t.Run("Unknown blobs", func(t *testing.T) {
+		missingConfig := blobDigest([]byte("missing config"))
+		resp := put(t, "missing", oci.MediaTypeImageManifest, imageManifest(missingConfig))
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, []string{"MANIFEST_BLOB_UNKNOWN " + missingConfig}, errorCodes(t, resp))
+
+		resp = r.do(t, http.MethodGet, manifestPath("missing"), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Index with unknown children", func(t *testing.T) {
+		missingChild := blobDigest([]byte("missing child"))
+		index := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json",`+
+			`"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":%d,"digest":"%s"},`+
+			`{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":10,"digest":"%s"}]}`,
+			len(child), childDigest, missingChild))
+
+		resp := put(t, "multi", oci.MediaTypeImageIndex, index)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, []string{"MANIFEST_BLOB_UNKNOWN " + missingChild}, errorCodes(t, resp))
+	})
+
+	t.Run("Index with pushed children", func(t *testing.T) {
+		index := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json",`+
+			`"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":%d,"digest":"%s"}]}`,
+			len(child), childDigest))
+
+		resp := put(t, "multi", oci.MediaTypeImageIndex, index)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+}
+
+func (r *RegistryTestSuite) testMultipleTags(t *testing.T) {
+	password := "MultiTags12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-multitag-m1", "registry-multitag-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-multitag-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+
+	token := r.seeder.RegistryToken(t, "registry-multitag-m1", password)
+
+	resolve := func(t *testing.T, tag string) (int, string) {
+		resp := r.do(t, http.MethodHead, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-multitag-ns", "app",
+			tag), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get("Docker-Content-Digest")
+	}
+	listTags := func(t *testing.T) []string {
+		resp := r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryTags, "registry-multitag-ns", "app"),
+			token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var tagList dockerv2.TagListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&tagList))
+		return tagList.Tags
+	}
+
+	v1 := imageManifest(r.pushBlob(t, token, "registry-multitag-ns", "app", []byte("multitag config 1")))
+	v2 := imageManifest(r.pushBlob(t, token, "registry-multitag-ns", "app", []byte("multitag config 2")))
+
+	t.Run("Same manifest under version and latest", func(t *testing.T) {
+		r.pushManifest(t, token, "registry-multitag-ns", "app", "1.4.2", v1)
+		r.pushManifest(t, token, "registry-multitag-ns", "app", "latest", v1)
+		// pushing the same manifest to the same tag again is a no-op
+		r.pushManifest(t, token, "registry-multitag-ns", "app", "latest", v1)
+
+		for _, tag := range []string{"1.4.2", "latest"} {
+			status, digest := resolve(t, tag)
+			require.Equal(t, http.StatusOK, status, tag)
+			assert.Equal(t, blobDigest(v1), digest, tag)
+		}
+		assert.Equal(t, []string{"1.4.2", "latest"}, listTags(t))
+	})
+
+	t.Run("Move latest to a new version", func(t *testing.T) {
+		r.pushManifest(t, token, "registry-multitag-ns", "app", "1.5.0", v2)
+		r.pushManifest(t, token, "registry-multitag-ns", "app", "latest", v2)
+
+		_, digest := resolve(t, "latest")
+		assert.Equal(t, blobDigest(v2), digest)
+		_, digest = resolve(t, "1.5.0")
+		assert.Equal(t, blobDigest(v2), digest)
+		_, digest = resolve(t, "1.4.2")
+		assert.Equal(t, blobDigest(v1), digest)
+	})
+
+	t.Run("Deleting a tag keeps the other tags", func(t *testing.T) {
+		resp := r.do(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-multitag-ns", "app",
+			"latest"), token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		status, _ := resolve(t, "latest")
+		assert.Equal(t, http.StatusNotFound, status)
+		status, digest := resolve(t, "1.5.0")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, blobDigest(v2), digest)
+		assert.Equal(t, []string{"1.4.2", "1.5.0"}, listTags(t))
+	})
+}
+
+func (r *RegistryTestSuite) testNestedRepository(t *testing.T) {
+	password := "NestedRepo12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-nested-m1", "registry-nested-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-nested-ns", "", constants.NamespacePurposeTeam, false, m1)
+
+	token := r.seeder.RegistryToken(t, "registry-nested-m1", password)
+
+	repository := "team-a/services/billing-api"
+
+	var configDigest, manifestDigest string
+
+	t.Run("Push to repository created on push", func(t *testing.T) {
+		configDigest = r.pushBlob(t, token, "registry-nested-ns", repository, []byte("nested config"))
+		manifest := imageManifest(configDigest)
+		manifestDigest = blobDigest(manifest)
+		r.pushManifest(t, token, "registry-nested-ns", repository, "1.0.0", manifest)
+	})
+
+	t.Run("Pull", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-nested-ns",
+			repository, "1.0.0"), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, manifestDigest, resp.Header.Get("Docker-Content-Digest"))
+
+		resp = r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryBlob, "registry-nested-ns", repository,
+			configDigest), token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		content, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "nested config", string(content))
+	})
+
+	t.Run("List tags", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryTags, "registry-nested-ns", repository),
+			token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var tagList dockerv2.TagListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&tagList))
+		assert.Equal(t, "registry-nested-ns/"+repository, tagList.Name)
+		assert.Equal(t, []string{"1.0.0"}, tagList.Tags)
+	})
+
+	t.Run("Parent path is a different repository", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-nested-ns",
+			"team-a/services", "1.0.0"), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Catalog", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, testdata.EndpointRegistryCatalog, token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var body dockerv2.CatalogResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Contains(t, body.Repositories, "registry-nested-ns/"+repository)
+	})
+
+	t.Run("Mount from nested repository", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-nested-ns",
+			"team-b/billing-api")+"?mount="+configDigest+"&from=registry-nested-ns/"+repository, token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("Invalid repository name", func(t *testing.T) {
+		for _, name := range []string{"Team-A/billing-api", "team-a//billing-api", "team-a/../billing-api"} {
+			for _, req := range []struct {
+				method string
+				path   string
+			}{
+				{http.MethodPost, fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-nested-ns", name)},
+				{http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryBlob, "registry-nested-ns", name, configDigest)},
+				{http.MethodHead, fmt.Sprintf(testdata.EndpointRegistryBlob, "registry-nested-ns", name, configDigest)},
+				{http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-nested-ns", name, "1.0.0")},
+				{http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryTags, "registry-nested-ns", name)},
+			} {
+				resp := r.do(t, req.method, req.path, token, nil, nil)
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode, req.method+" "+req.path)
+				if req.method != http.MethodHead {
+					assert.Contains(t, string(body), "NAME_INVALID", req.method+" "+req.path)
+				}
+			}
+		}
+	})
+
+	t.Run("Mount from invalid repository name", func(t *testing.T) {
+		resp := r.do(t, http.MethodPost, fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-nested-ns",
+			"team-b/billing-api")+"?mount="+configDigest+"&from=registry-nested-ns/Team-A/billing-api", token, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "NAME_INVALID")
+	})
+
+	t.Run("Repository with uppercase name of earlier versions", func(t *testing.T) {
+		r.seeder.CreateLegacyRepository(t, "Billing-API", m1, nsId)
+
+		manifest := imageManifest(r.pushBlob(t, token, "registry-nested-ns", "Billing-API", []byte("legacy config")))
+		r.pushManifest(t, token, "registry-nested-ns", "Billing-API", "1.0.0", manifest)
+
+		resp := r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-nested-ns",
+			"Billing-API", "1.0.0"), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, blobDigest(manifest), resp.Header.Get("Docker-Content-Digest"))
+
+		// new repositories aren't created with such names
+		resp = r.do(t, http.MethodPost, fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-nested-ns",
+			"Payments-API"), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func (r *RegistryTestSuite) testImmutableTags(t *testing.T) {
+	password := "Immutable12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-immutable-m1", "registry-immutable-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-immutable-ns", "", constants.NamespacePurposeTeam, false, m1)
+	repoId := r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+	r.seeder.SetImmutableTagPatterns(t, repoId, "semver")
+
+	token := r.seeder.RegistryToken(t, "registry-immutable-m1", password)
+
+	put := func(t *testing.T, tag string, content []byte) *http.Response {
+		return r.do(t, http.MethodPut, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-immutable-ns", "app",
+			tag), token, bytes.NewReader(content), map[string]string{"Content-Type": oci.MediaTypeImageManifest})
+	}
+	assertDenied := func(t *testing.T, resp *http.Response, tag string) {
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		var errResp struct {
+			Errors []struct {
+				Code    string            `json:"code"`
+				Message string            `json:"message"`
+				Detail  map[string]string `json:"detail"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		require.Len(t, errResp.Errors, 1)
+		assert.Equal(t, "DENIED", errResp.Errors[0].Code)
+		assert.Equal(t, tag, errResp.Errors[0].Detail["tag"])
+		assert.Contains(t, errResp.Errors[0].Message, "immutable")
+	}
+	resolve := func(t *testing.T, tag string) string {
+		resp := r.do(t, http.MethodHead, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-immutable-ns", "app",
+			tag), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.Header.Get("Docker-Content-Digest")
+	}
+
+	v1 := imageManifest(r.pushBlob(t, token, "registry-immutable-ns", "app", []byte("immutable config 1")))
+	v2 := imageManifest(r.pushBlob(t, token, "registry-immutable-ns", "app", []byte("immutable config 2")))
+
+	t.Run("Tag matching pattern can't be moved", func(t *testing.T) {
+		r.pushManifest(t, token, "registry-immutable-ns", "app", "1.0.0", v1)
+
+		assertDenied(t, put(t, "1.0.0", v2), "1.0.0")
+		assert.Equal(t, blobDigest(v1), resolve(t, "1.0.0"))
+
+		// pushing the same manifest again doesn't move the tag
+		r.pushManifest(t, token, "registry-immutable-ns", "app", "1.0.0", v1)
+	})
+
+	t.Run("Tag not matching pattern can be moved", func(t *testing.T) {
+		r.pushManifest(t, token, "registry-immutable-ns", "app", "latest", v1)
+		r.pushManifest(t, token, "registry-immutable-ns", "app", "latest", v2)
+		assert.Equal(t, blobDigest(v2), resolve(t, "latest"))
+	})
+
+	t.Run("Stable tag can't be moved", func(t *testing.T) {
+		r.pushManifest(t, token, "registry-immutable-ns", "app", "prod", v1)
+		r.seeder.SetTagStable(t, repoId, "prod", true)
+
+		assertDenied(t, put(t, "prod", v2), "prod")
+		assert.Equal(t, blobDigest(v1), resolve(t, "prod"))
+	})
+
+	del := func(t *testing.T, reference string) *http.Response {
+		return r.do(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-immutable-ns",
+			"app", reference), token, nil, nil)
+	}
+
+	t.Run("Tag matching pattern can't be deleted", func(t *testing.T) {
+		assertDenied(t, del(t, "1.0.0"), "1.0.0")
+		assert.Equal(t, blobDigest(v1), resolve(t, "1.0.0"))
+	})
+
+	t.Run("Stable tag can't be deleted", func(t *testing.T) {
+		assertDenied(t, del(t, "prod"), "prod")
+		assert.Equal(t, blobDigest(v1), resolve(t, "prod"))
+	})
+
+	t.Run("Manifest of immutable tag can't be deleted by digest", func(t *testing.T) {
+		assertDenied(t, del(t, blobDigest(v1)), "1.0.0")
+		assert.Equal(t, blobDigest(v1), resolve(t, "1.0.0"))
+		assert.Equal(t, blobDigest(v1), resolve(t, "prod"))
+	})
+
+	t.Run("Manifest without immutable tags can be deleted by digest", func(t *testing.T) {
+		resp := del(t, blobDigest(v2))
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		resp = del(t, "latest")
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func (r *RegistryTestSuite) testGarbageCollection(t *testing.T) {
+	password := "Collect12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-gc-m1", "registry-gc-m1@t.com", constants.RoleMaintainer,
+		password)
+	nsId := r.seeder.CreateNamespace(t, "registry-gc-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+
+	token := r.seeder.RegistryToken(t, "registry-gc-m1", password)
+
+	exists := func(t *testing.T, endpoint, reference string) bool {
+		resp := r.do(t, http.MethodHead, fmt.Sprintf(endpoint, "registry-gc-ns", "app", reference), token, nil,
+			map[string]string{"Accept": oci.MediaTypeImageManifest + "," + oci.MediaTypeImageIndex})
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}
+	// other tests may leave garbage behind as well
+	collected := func(items []mgmt.GCItem) []string {
+		digests := []string{}
+		for _, item := range items {
+			if item.Repository == "registry-gc-ns/app" {
+				digests = append(digests, item.Digest)
+			}
+		}
+		return digests
+	}
+
+	oldConfig := r.pushBlob(t, token, "registry-gc-ns", "app", []byte("gc config 1"))
+	newConfig := r.pushBlob(t, token, "registry-gc-ns", "app", []byte("gc config 2"))
+	childConfig := r.pushBlob(t, token, "registry-gc-ns", "app", []byte("gc child config"))
+	emptyConfig := r.pushBlob(t, token, "registry-gc-ns", "app", []byte("{}"))
+	signature := r.pushBlob(t, token, "registry-gc-ns", "app", []byte("sign"))
+	orphan := r.pushBlob(t, token, "registry-gc-ns", "app", []byte("gc orphan"))
+	reuploaded := r.pushBlob(t, token, "registry-gc-ns", "app", []byte("gc reuploaded"))
+
+	// previous manifest of the tag becomes unreferenced
+	oldManifest := r.pushManifest(t, token, "registry-gc-ns", "app", "latest", imageManifest(oldConfig))
+	newImage := imageManifest(newConfig)
+	newManifest := r.pushManifest(t, token, "registry-gc-ns", "app", "latest", newImage)
+
+	artifact := artifactManifest("application/vnd.example.signature", newManifest, len(newImage), signature, "gc")
+	referrer := r.pushManifest(t, token, "registry-gc-ns", "app", blobDigest(artifact), artifact)
+
+	child := imageManifest(childConfig)
+	childManifest := r.pushManifest(t, token, "registry-gc-ns", "app", blobDigest(child), child)
+	index := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json",`+
+		`"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":%d,"digest":"%s"}]}`,
+		len(child), childManifest))
+	resp := r.do(t, http.MethodPut, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-gc-ns", "app", "multi"),
+		token, bytes.NewReader(index), map[string]string{"Content-Type": oci.MediaTypeImageIndex})
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	t.Run("Recent content is kept", func(t *testing.T) {
+		report := r.seeder.CollectGarbage(t, false)
+		assert.Empty(t, collected(report.Manifests))
+		assert.Empty(t, collected(report.Blobs))
+		assert.True(t, exists(t, testdata.EndpointRegistryManifest, oldManifest))
+	})
+
+	// grace period of test config is 2s
+	time.Sleep(3 * time.Second)
+
+	// uploading the blob again renews its grace period
+	r.pushBlob(t, token, "registry-gc-ns", "app", []byte("gc reuploaded"))
+
+	t.Run("Dry run", func(t *testing.T) {
+		report := r.seeder.CollectGarbage(t, true)
+		assert.True(t, report.DryRun)
+		assert.ElementsMatch(t, []string{oldManifest}, collected(report.Manifests))
+		assert.ElementsMatch(t, []string{oldConfig, orphan}, collected(report.Blobs))
+
+		assert.True(t, exists(t, testdata.EndpointRegistryManifest, oldManifest))
+		assert.True(t, exists(t, testdata.EndpointRegistryBlob, oldConfig))
+		assert.True(t, exists(t, testdata.EndpointRegistryBlob, orphan))
+	})
+
+	t.Run("Unreferenced content is removed", func(t *testing.T) {
+		report := r.seeder.CollectGarbage(t, false)
+		assert.False(t, report.DryRun)
+		assert.ElementsMatch(t, []string{oldManifest}, collected(report.Manifests))
+		assert.ElementsMatch(t, []string{oldConfig, orphan}, collected(report.Blobs))
+		assert.GreaterOrEqual(t, report.FreedBytes, int64(len("gc config 1")+len("gc orphan")))
+
+		assert.False(t, exists(t, testdata.EndpointRegistryManifest, oldManifest))
+		assert.False(t, exists(t, testdata.EndpointRegistryBlob, oldConfig))
+		assert.False(t, exists(t, testdata.EndpointRegistryBlob, orphan))
+
+		for _, digest := range []string{newManifest, referrer, childManifest, "multi"} {
+			assert.True(t, exists(t, testdata.EndpointRegistryManifest, digest), digest)
+		}
+		for _, digest := range []string{newConfig, childConfig, emptyConfig, signature, reuploaded} {
+			assert.True(t, exists(t, testdata.EndpointRegistryBlob, digest), digest)
+		}
+	})
+
+	t.Run("Nothing left to collect", func(t *testing.T) {
+		report := r.seeder.CollectGarbage(t, false)
+		assert.Empty(t, collected(report.Manifests))
+		assert.Empty(t, collected(report.Blobs))
+	})
+
+	// content left without links is reported without repository
+	collectedContent := func(items []mgmt.GCItem, digest string) bool {
+		for _, item := range items {
+			if item.Repository == "" && item.Digest == digest {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("Content of deleted repository is removed", func(t *testing.T) {
+		repoId := r.seeder.CreateRepository(t, "removed", "", m1, nsId, false)
+		digest := r.pushBlob(t, token, "registry-gc-ns", "removed", []byte("gc removed repository"))
+		r.seeder.DeleteRepository(t, repoId)
+
+		report := r.seeder.CollectGarbage(t, true)
+		assert.True(t, collectedContent(report.Blobs, digest))
+		require.NotNil(t, r.seeder.BlobContent(t, digest))
+
+		report = r.seeder.CollectGarbage(t, false)
+		assert.True(t, collectedContent(report.Blobs, digest))
+		assert.Nil(t, r.seeder.BlobContent(t, digest))
+		_, err := storage.Size(utils.BlobLocation(digest))
+		assert.Error(t, err)
+	})
+
+	t.Run("Orphaned file is removed", func(t *testing.T) {
+		content := []byte("gc orphaned file")
+		digest := blobDigest(content)
+		_, err := storage.Create(utils.BlobLocation(digest), bytes.NewReader(content))
+		require.NoError(t, err)
+
+		report := r.seeder.CollectGarbage(t, false)
+		assert.False(t, collectedContent(report.Blobs, digest))
+
+		time.Sleep(3 * time.Second)
+		report = r.seeder.CollectGarbage(t, false)
+		assert.True(t, collectedContent(report.Blobs, digest))
+		_, err = storage.Size(utils.BlobLocation(digest))
+		assert.Error(t, err)
+	})
+}
+
+func (r *RegistryTestSuite) testSharedBlobContent(t *testing.T) {
+	password := "SharedBlob12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-shared-m1", "registry-shared-m1@t.com",
+		constants.RoleMaintainer, password)
+
+	nsId := r.seeder.CreateNamespace(t, "registry-shared-ns", "", constants.NamespacePurposeTeam, false, m1)
+	repoId := r.seeder.CreateRepository(t, "cuda-app", "", m1, nsId, false)
+	otherNsId := r.seeder.CreateNamespace(t, "registry-shared-other", "", constants.NamespacePurposeTeam, false,
+		m1)
+	r.seeder.CreateRepository(t, "cuda-job", "", m1, otherNsId, false)
+
+	maintainer := r.seeder.RegistryToken(t, "registry-shared-m1", password)
+
+	content := []byte("large shared base layer")
+	digest := r.pushBlob(t, maintainer, "registry-shared-ns", "cuda-app", content)
+
+	blobPath := func(namespace, repository string) string {
+		return fmt.Sprintf(testdata.EndpointRegistryBlob, namespace, repository, digest)
+	}
+
+	t.Run("Content is stored at content addressed location", func(t *testing.T) {
+		blob := r.seeder.BlobContent(t, digest)
+		require.NotNil(t, blob)
+		assert.Equal(t, utils.BlobLocation(digest), blob.Location)
+		assert.Equal(t, 1, blob.RefCount)
+
+		size, err := storage.Size(blob.Location)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), size)
+	})
+
+	t.Run("Same blob pushed to another repository is stored once", func(t *testing.T) {
+		r.pushBlob(t, maintainer, "registry-shared-other", "cuda-job", content)
+
+		blob := r.seeder.BlobContent(t, digest)
+		require.NotNil(t, blob)
+		assert.Equal(t, utils.BlobLocation(digest), blob.Location)
+		assert.Equal(t, 2, blob.RefCount)
+
+		// pushing again to the same repository doesn't add a reference
+		r.pushBlob(t, maintainer, "registry-shared-ns", "cuda-app", content)
+		assert.Equal(t, 2, r.seeder.BlobContent(t, digest).RefCount)
+	})
+
+	t.Run("Deleting from one repository keeps the content", func(t *testing.T) {
+		resp := r.do(t, http.MethodDelete, blobPath("registry-shared-ns", "cuda-app"), maintainer, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		blob := r.seeder.BlobContent(t, digest)
+		require.NotNil(t, blob)
+		assert.Equal(t, 1, blob.RefCount)
+
+		resp = r.do(t, http.MethodGet, blobPath("registry-shared-other", "cuda-job"), maintainer, nil, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content, body)
+	})
+
+	t.Run("Deleting the last reference removes the content", func(t *testing.T) {
+		resp := r.do(t, http.MethodDelete, blobPath("registry-shared-other", "cuda-job"), maintainer, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		assert.Nil(t, r.seeder.BlobContent(t, digest))
+
+		_, err := storage.Size(utils.BlobLocation(digest))
+		assert.Error(t, err)
+	})
+
+	t.Run("Deleting a repository unlinks its blobs", func(t *testing.T) {
+		r.pushBlob(t, maintainer, "registry-shared-ns", "cuda-app", content)
+		r.pushBlob(t, maintainer, "registry-shared-other", "cuda-job", content)
+		require.Equal(t, 2, r.seeder.BlobContent(t, digest).RefCount)
+
+		r.seeder.DeleteRepository(t, repoId)
+
+		blob := r.seeder.BlobContent(t, digest)
+		require.NotNil(t, blob)
+		assert.Equal(t, 1, blob.RefCount)
+	})
+}
+
+func (r *RegistryTestSuite) testQuota(t *testing.T) {
+	password := "RegistryQuota12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-quota-m1", "registry-quota-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-quota-ns", "", constants.NamespacePurposeTeam, false, m1)
+	repoId := r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+	r.seeder.CreateRepository(t, "tools", "", m1, nsId, false)
+
+	token := r.seeder.RegistryToken(t, "registry-quota-m1", password)
+
+	limit := func(v int64) *int64 {
+		return &v
+	}
+	assertDenied := func(t *testing.T, resp *http.Response, resourceType, unit string) {
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		var errResp struct {
+			Errors []struct {
+				Code    string         `json:"code"`
+				Message string         `json:"message"`
+				Detail  map[string]any `json:"detail"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		require.Len(t, errResp.Errors, 1)
+		assert.Equal(t, "DENIED", errResp.Errors[0].Code)
+		assert.Equal(t, resourceType, errResp.Errors[0].Detail["resource_type"])
+		assert.Equal(t, unit, errResp.Errors[0].Detail["unit"])
+		assert.Contains(t, errResp.Errors[0].Message, "quota exceeded")
+	}
+	initiate := func(t *testing.T, repository string) *http.Response {
+		return r.do(t, http.MethodPost, fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-quota-ns",
+			repository), token, nil, nil)
+	}
+
+	config1 := []byte("quota config 1")
+	config2 := []byte("quota config 2")
+	m1Content := imageManifest(r.pushBlob(t, token, "registry-quota-ns", "app", config1))
+	m2Content := imageManifest(r.pushBlob(t, token, "registry-quota-ns", "app", config2))
+
+	t.Run("Image quota", func(t *testing.T) {
+		r.seeder.SetRepositoryQuota(t, repoId, nil, limit(1))
+
+		r.pushManifest(t, token, "registry-quota-ns", "app", "v1", m1Content)
+
+		resp := r.do(t, http.MethodPut, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-quota-ns", "app",
+			"v2"), token, bytes.NewReader(m2Content), map[string]string{"Content-Type": oci.MediaTypeImageManifest})
+		assertDenied(t, resp, constants.ResourceTypeRepository, "images")
+
+		// tagging a stored manifest doesn't add an image
+		r.pushManifest(t, token, "registry-quota-ns", "app", "latest", m1Content)
+	})
+
+	t.Run("Usage is reported", func(t *testing.T) {
+		quota := r.seeder.RepositoryQuota(t, repoId)
+		assert.Equal(t, int64(len(config1)+len(config2)+len(m1Content)), quota.UsedBytes)
+		assert.Equal(t, int64(1), quota.UsedImages)
+		require.NotNil(t, quota.MaxImages)
+		assert.Equal(t, int64(1), *quota.MaxImages)
+		assert.Nil(t, quota.MaxBytes)
+
+		nsQuota := r.seeder.NamespaceQuota(t, nsId)
+		assert.Equal(t, quota.UsedBytes, nsQuota.UsedBytes)
+		assert.Equal(t, quota.UsedImages, nsQuota.UsedImages)
+	})
+
+	t.Run("Byte quota", func(t *testing.T) {
+		used := r.seeder.RepositoryQuota(t, repoId).UsedBytes
+		r.seeder.SetRepositoryQuota(t, repoId, limit(used), nil)
+
+		assertDenied(t, initiate(t, "app"), constants.ResourceTypeRepository, "bytes")
+
+		resp := r.do(t, http.MethodPut, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-quota-ns", "app",
+			"v2"), token, bytes.NewReader(m2Content), map[string]string{"Content-Type": oci.MediaTypeImageManifest})
+		assertDenied(t, resp, constants.ResourceTypeRepository, "bytes")
+	})
+
+	t.Run("Completed upload exceeding byte quota", func(t *testing.T) {
+		used := r.seeder.RepositoryQuota(t, repoId).UsedBytes
+		r.seeder.SetRepositoryQuota(t, repoId, limit(used+4), nil)
+
+		resp := initiate(t, "app")
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		content := []byte("quota layer larger than the remaining quota")
+		uploadsPath := fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-quota-ns", "app")
+		resp = r.do(t, http.MethodPut, uploadsPath+resp.Header.Get("Docker-Upload-UUID")+"?digest="+
+			blobDigest(content), token, bytes.NewReader(content),
+			map[string]string{"Content-Type": "application/octet-stream"})
+		assertDenied(t, resp, constants.ResourceTypeRepository, "bytes")
+
+		assert.Equal(t, used, r.seeder.RepositoryQuota(t, repoId).UsedBytes)
+		resp = r.do(t, http.MethodHead, fmt.Sprintf(testdata.EndpointRegistryBlob, "registry-quota-ns", "app",
+			blobDigest(content)), token, nil, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Namespace quota", func(t *testing.T) {
+		r.seeder.SetRepositoryQuota(t, repoId, nil, nil)
+		used := r.seeder.NamespaceQuota(t, nsId).UsedBytes
+		r.seeder.SetNamespaceQuota(t, nsId, limit(used), nil)
+
+		assertDenied(t, initiate(t, "tools"), constants.ResourceTypeNamespace, "bytes")
+
+		// mounted blobs count towards the quota of target repository
+		resp := r.do(t, http.MethodPost, fmt.Sprintf(testdata.EndpointRegistryBlobUploads, "registry-quota-ns",
+			"tools")+"?mount="+blobDigest(config1)+"&from=registry-quota-ns/app", token, nil, nil)
+		assertDenied(t, resp, constants.ResourceTypeNamespace, "bytes")
+	})
+
+	t.Run("Push is accepted once quota is raised", func(t *testing.T) {
+		r.seeder.SetNamespaceQuota(t, nsId, nil, nil)
+
+		resp := initiate(t, "tools")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		r.pushManifest(t, token, "registry-quota-ns", "app", "v2", m2Content)
+	})
+
+	t.Run("Images are counted as tagged manifests", func(t *testing.T) {
+		r.seeder.SetRepositoryQuota(t, repoId, nil, limit(2))
+		defer r.seeder.SetRepositoryQuota(t, repoId, nil, nil)
+		require.Equal(t, int64(2), r.seeder.RepositoryQuota(t, repoId).UsedImages)
+
+		// children of an index are pushed untagged
+		m3Content := imageManifest(r.pushBlob(t, token, "registry-quota-ns", "app", []byte("quota config 3")))
+		m3Digest := r.pushManifest(t, token, "registry-quota-ns", "app", blobDigest(m3Content), m3Content)
+		assert.Equal(t, int64(2), r.seeder.RepositoryQuota(t, repoId).UsedImages)
+
+		// moving the only tag of a manifest doesn't add an image
+		r.pushManifest(t, token, "registry-quota-ns", "app", "v2", m3Content)
+		r.pushManifest(t, token, "registry-quota-ns", "app", "v3", m3Content)
+		assert.Equal(t, int64(2), r.seeder.RepositoryQuota(t, repoId).UsedImages)
+
+		index := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json",`+
+			`"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":%d,"digest":"%s"}]}`,
+			len(m3Content), m3Digest))
+		resp := r.do(t, http.MethodPut, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-quota-ns", "app",
+			"multi"), token, bytes.NewReader(index), map[string]string{"Content-Type": oci.MediaTypeImageIndex})
+		assertDenied(t, resp, constants.ResourceTypeRepository, "images")
+	})
+}
+
+func (r *RegistryTestSuite) testRetention(t *testing.T) {
+	password := "Retention12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-retention-m1", "registry-retention-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-retention-ns", "", constants.NamespacePurposeTeam, false, m1)
+	repoId := r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+
+	token := r.seeder.RegistryToken(t, "registry-retention-m1", password)
+
+	limit := func(v int64) *int64 {
+		return &v
+	}
+	push := func(t *testing.T, tag string) string {
+		config := r.pushBlob(t, token, "registry-retention-ns", "app", []byte("retention config "+tag))
+		return r.pushManifest(t, token, "registry-retention-ns", "app", tag, imageManifest(config))
+	}
+	exists := func(t *testing.T, reference string) bool {
+		resp := r.do(t, http.MethodHead, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-retention-ns", "app",
+			reference), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}
+	tags := func(report *mgmt.RetentionReportResponse) []string {
+		res := []string{}
+		for _, item := range report.Tags {
+			res = append(res, item.Tag)
+		}
+		return res
+	}
+
+	r.seeder.SetRepositoryRetentionRules(t, repoId, &models.RetentionRuleModel{TagPattern: "pr-*", KeepLast: limit(2)})
+
+	pr0 := push(t, "pr-0")
+	r.seeder.SetTagStable(t, repoId, "pr-0", true)
+	pr1 := push(t, "pr-1")
+	pr2 := push(t, "pr-2")
+	push(t, "pr-3")
+	push(t, "pr-4")
+	push(t, "latest")
+
+	t.Run("Dry run", func(t *testing.T) {
+		report := r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, repoId, true)
+		assert.True(t, report.DryRun)
+		require.Len(t, report.Tags, 2)
+		assert.Equal(t, []string{"pr-2", "pr-1"}, tags(report))
+		assert.Equal(t, "registry-retention-ns/app", report.Tags[0].Repository)
+		assert.Equal(t, pr2, report.Tags[0].Digest)
+		assert.Equal(t, pr1, report.Tags[1].Digest)
+
+		assert.True(t, exists(t, "pr-1"))
+		assert.True(t, exists(t, "pr-2"))
+	})
+
+	t.Run("Expired tags are deleted", func(t *testing.T) {
+		report := r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, repoId, false)
+		assert.False(t, report.DryRun)
+		assert.Equal(t, []string{"pr-2", "pr-1"}, tags(report))
+
+		assert.False(t, exists(t, "pr-1"))
+		assert.False(t, exists(t, "pr-2"))
+		for _, tag := range []string{"pr-0", "pr-3", "pr-4", "latest"} {
+			assert.True(t, exists(t, tag), tag)
+		}
+
+		// untagged manifests are left to garbage collection
+		assert.True(t, exists(t, pr1))
+		assert.True(t, exists(t, pr2))
+		assert.True(t, exists(t, pr0))
+	})
+
+	t.Run("Pushing a tag again renews it", func(t *testing.T) {
+		// push time is recorded in seconds
+		time.Sleep(1100 * time.Millisecond)
+
+		resp := r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-retention-ns", "app",
+			"pr-3"), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		content, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		r.pushManifest(t, token, "registry-retention-ns", "app", "pr-3", content)
+		push(t, "pr-5")
+
+		report := r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, repoId, true)
+		assert.Equal(t, []string{"pr-4"}, tags(report))
+	})
+
+	t.Run("Changing stability doesn't renew a tag", func(t *testing.T) {
+		time.Sleep(1100 * time.Millisecond)
+
+		r.seeder.SetTagStable(t, repoId, "pr-4", true)
+		r.seeder.SetTagStable(t, repoId, "pr-4", false)
+
+		report := r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, repoId, true)
+		assert.Equal(t, []string{"pr-4"}, tags(report))
+	})
+
+	t.Run("Immutable tags are kept", func(t *testing.T) {
+		r.seeder.SetImmutableTagPatterns(t, repoId, "pr-4")
+		defer r.seeder.SetImmutableTagPatterns(t, repoId)
+
+		report := r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, repoId, false)
+		assert.Empty(t, report.Tags)
+		assert.True(t, exists(t, "pr-4"))
+	})
+
+	t.Run("Namespace rules", func(t *testing.T) {
+		r.seeder.SetNamespaceRetentionRules(t, nsId,
+			&models.RetentionRuleModel{TagPattern: "pr-*", MaxAgeDays: limit(30)},
+			&models.RetentionRuleModel{TagPattern: "nightly-*", MaxAgeDays: limit(0)})
+		push(t, "nightly-1")
+
+		// tags are deleted only if every rule matching them expires them
+		report := r.seeder.ApplyRetention(t, testdata.EndpointNamespaceRetentionRun, nsId, false)
+		assert.Equal(t, []string{"nightly-1"}, tags(report))
+		assert.False(t, exists(t, "nightly-1"))
+		assert.True(t, exists(t, "pr-4"))
+	})
+
+	t.Run("Namespace with deletion disabled is skipped", func(t *testing.T) {
+		lockedNsId := r.seeder.CreateNamespace(t, "registry-retention-locked", "", constants.NamespacePurposeTeam,
+			false, m1)
+		r.seeder.CreateRepository(t, "app", "", m1, lockedNsId, false)
+		r.seeder.SetNamespaceRetentionRules(t, lockedNsId, &models.RetentionRuleModel{TagPattern: "*", KeepLast: limit(0)})
+
+		config := r.pushBlob(t, token, "registry-retention-locked", "app", []byte("retention locked config"))
+		r.pushManifest(t, token, "registry-retention-locked", "app", "v1", imageManifest(config))
+
+		report := r.seeder.ApplyRetention(t, testdata.EndpointNamespaceRetentionRun, lockedNsId, false)
+		assert.Empty(t, report.Tags)
+
+		resp := r.do(t, http.MethodHead, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-retention-locked",
+			"app", "v1"), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+// webhookReceiver records events posted to webhooks. Requests to /fail-once/ fail the first time and requests to
+// /fail/ always fail.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	requests []*receivedEvent
+	failed   map[string]bool
+}
+
+type receivedEvent struct {
+	path    string
+	header  http.Header
+	payload []byte
+	event   dockerv2.Event
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, _ := io.ReadAll(req.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/fail/"):
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case strings.HasPrefix(req.URL.Path, "/fail-once/") && !rc.failed[req.URL.Path]:
+		rc.failed[req.URL.Path] = true
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var envelope dockerv2.EventEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil || len(envelope.Events) != 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.requests = append(rc.requests, &receivedEvent{
+		path:    req.URL.Path,
+		header:  req.Header,
+		payload: payload,
+		event:   envelope.Events[0],
+	})
+}
+
+// await waits until an event of the action is received at the path
+func (rc *webhookReceiver) await(t *testing.T, path, action string) *receivedEvent {
+	t.Helper()
+
+	var received *receivedEvent
+	require.Eventually(t, func() bool {
+		received = rc.find(path, action)
+		return received != nil
+	}, 5*time.Second, 10*time.Millisecond, "%s event wasn't received at %s", action, path)
+	return received
+}
+
+func (rc *webhookReceiver) find(path, action string) *receivedEvent {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for _, req := range rc.requests {
+		if req.path == path && req.event.Action == action {
+			return req
+		}
+	}
+	return nil
+}
+
+func (rc *webhookReceiver) actions(path string) []string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	res := []string{}
+	for _, req := range rc.requests {
+		if req.path == path {
+			res = append(res, req.event.Action)
+		}
+	}
+	return res
+}
+
+func (r *RegistryTestSuite) testWebhooks(t *testing.T) {
+	password := "Webhooks12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "registry-webhook-m1", "registry-webhook-m1@t.com",
+		constants.RoleMaintainer, password)
+	nsId := r.seeder.CreateNamespace(t, "registry-webhook-ns", "", constants.NamespacePurposeTeam, false, m1)
+	otherNsId := r.seeder.CreateNamespace(t, "registry-webhook-other", "", constants.NamespacePurposeTeam, false, m1)
+	repoId := r.seeder.CreateRepository(t, "app", "", m1, nsId, false)
+	r.seeder.CreateRepository(t, "app", "", m1, otherNsId, false)
+
+	limit := func(v int64) *int64 {
+		return &v
+	}
+	receiver := &webhookReceiver{failed: map[string]bool{}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	secret := "webhook-secret"
+	webhooks := []string{
+		r.seeder.CreateWebhook(t, "registry-webhook-all", server.URL+"/all", secret, nsId),
+		r.seeder.CreateWebhook(t, "registry-webhook-push", server.URL+"/push", "", nsId, constants.WebhookActionPush),
+		r.seeder.CreateWebhook(t, "registry-webhook-other", server.URL+"/other", "", otherNsId),
+	}
+	defer func() {
+		for _, id := range webhooks {
+			r.seeder.DeleteWebhook(t, id)
+		}
+	}()
+
+	token := r.seeder.RegistryToken(t, "registry-webhook-m1", password)
+	manifest := imageManifest(r.pushBlob(t, token, "registry-webhook-ns", "app", []byte("webhook config")))
+
+	t.Run("Push", func(t *testing.T) {
+		digest := r.pushManifest(t, token, "registry-webhook-ns", "app", "v1", manifest)
+
+		received := receiver.await(t, "/all", constants.WebhookActionPush)
+		assert.Equal(t, dockerv2.EventsMediaType, received.header.Get("Content-Type"))
+		assert.Equal(t, constants.WebhookActionPush, received.header.Get(registry.WebhookEventHeader))
+		assert.NotEmpty(t, received.header.Get(registry.WebhookDeliveryHeader))
+		assert.Equal(t, registry.SignWebhookPayload(secret, received.payload),
+			received.header.Get(registry.WebhookSignatureHeader))
+
+		event := received.event
+		assert.NotEmpty(t, event.ID)
+		assert.Equal(t, "registry-webhook-ns/app", event.Target.Repository)
+		assert.Equal(t, "v1", event.Target.Tag)
+		assert.Equal(t, digest, event.Target.Digest)
+		assert.Equal(t, oci.MediaTypeImageManifest, event.Target.MediaType)
+		assert.Equal(t, int64(len(manifest)), event.Target.Size)
+		assert.True(t, strings.HasSuffix(event.Target.URL, "/v2/registry-webhook-ns/app/manifests/"+digest))
+		assert.Equal(t, "registry-webhook-m1", event.Actor.Name)
+		assert.Equal(t, http.MethodPut, event.Request.Method)
+		assert.NotEmpty(t, event.Source.InstanceID)
+
+		unsigned := receiver.await(t, "/push", constants.WebhookActionPush)
+		assert.Empty(t, unsigned.header.Get(registry.WebhookSignatureHeader))
+	})
+
+	t.Run("Pull", func(t *testing.T) {
+		resp := r.do(t, http.MethodGet, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-webhook-ns", "app",
+			"v1"), token, nil, map[string]string{"Accept": oci.MediaTypeImageManifest})
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		event := receiver.await(t, "/all", constants.WebhookActionPull).event
+		assert.Equal(t, "registry-webhook-ns/app", event.Target.Repository)
+		assert.Equal(t, "v1", event.Target.Tag)
+		assert.Equal(t, http.MethodGet, event.Request.Method)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		resp := r.do(t, http.MethodDelete, fmt.Sprintf(testdata.EndpointRegistryManifest, "registry-webhook-ns", "app",
+			"v1"), token, nil, nil)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		event := receiver.await(t, "/all", constants.WebhookActionDelete).event
+		assert.Equal(t, "registry-webhook-ns/app", event.Target.Repository)
+		assert.Equal(t, "v1", event.Target.Tag)
+	})
+
+	t.Run("Create repository", func(t *testing.T) {
+		r.seeder.CreateRepository(t, "created", "", m1, nsId, false)
+
+		event := receiver.await(t, "/all", constants.WebhookActionCreate).event
+		assert.Equal(t, "registry-webhook-ns/created", event.Target.Repository)
+	})
+
+	t.Run("Events are filtered", func(t *testing.T) {
+		// deliveries are asynchronous; the event of the other namespace is awaited so that earlier events are
+		// delivered as well
+		r.pushManifest(t, token, "registry-webhook-other", "app", "v1",
+			imageManifest(r.pushBlob(t, token, "registry-webhook-other", "app", []byte("webhook other config"))))
+		receiver.await(t, "/other", constants.WebhookActionPush)
+
+		assert.Equal(t, []string{constants.WebhookActionPush}, receiver.actions("/push"))
+		assert.Equal(t, []string{constants.WebhookActionPush}, receiver.actions("/other"))
+		assert.ElementsMatch(t, []string{constants.WebhookActionPush, constants.WebhookActionPull,
+			constants.WebhookActionDelete, constants.WebhookActionCreate}, receiver.actions("/all"))
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		webhooks = append(webhooks, r.seeder.CreateWebhook(t, "registry-webhook-retention", server.URL+"/retention", "",
+			nsId, constants.WebhookActionDelete))
+		r.pushManifest(t, token, "registry-webhook-ns", "app", "pr-1", manifest)
+		r.seeder.SetRepositoryRetentionRules(t, repoId, &models.RetentionRuleModel{TagPattern: "pr-*",
+			KeepLast: limit(0)})
+
+		report := r.seeder.ApplyRetention(t, testdata.EndpointRepositoryRetentionRun, repoId, false)
+		require.Len(t, report.Tags, 1)
+
+		event := receiver.await(t, "/retention", constants.WebhookActionDelete).event
+		assert.Equal(t, "registry-webhook-ns/app", event.Target.Repository)
+		assert.Equal(t, "pr-1", event.Target.Tag)
+	})
+
+	t.Run("Failed delivery is retried", func(t *testing.T) {
+		id := r.seeder.CreateWebhook(t, "registry-webhook-retry", server.URL+"/fail-once/retry", "", nsId,
+			constants.WebhookActionPush)
+		webhooks = append(webhooks, id)
+
+		r.pushManifest(t, token, "registry-webhook-ns", "app", "v2", manifest)
+
+		receiver.await(t, "/fail-once/retry", constants.WebhookActionPush)
+		var deliveries []*models.WebhookDeliveryModel
+		require.Eventually(t, func() bool {
+			deliveries = r.seeder.WebhookDeliveries(t, id)
+			return len(deliveries) == 1 && deliveries[0].Status == constants.WebhookDeliveryDelivered
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, 2, deliveries[0].Attempts)
+		assert.Equal(t, http.StatusOK, deliveries[0].ResponseCode)
+		assert.Equal(t, constants.WebhookActionPush, deliveries[0].Action)
+		assert.Empty(t, deliveries[0].Error)
+	})
+
+	t.Run("Delivery fails after max attempts", func(t *testing.T) {
+		id := r.seeder.CreateWebhook(t, "registry-webhook-fail", server.URL+"/fail/", "", nsId,
+			constants.WebhookActionPush)
+		webhooks = append(webhooks, id)
+
+		r.pushManifest(t, token, "registry-webhook-ns", "app", "v3", manifest)
+
+		var deliveries []*models.WebhookDeliveryModel
+		require.Eventually(t, func() bool {
+			deliveries = r.seeder.WebhookDeliveries(t, id)
+			return len(deliveries) == 1 && deliveries[0].Status == constants.WebhookDeliveryFailed
+		}, 5*time.Second, 10*time.Millisecond)
+		// max_attempts of test configuration
+		assert.Equal(t, 3, deliveries[0].Attempts)
+		assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseCode)
+		assert.NotEmpty(t, deliveries[0].Error)
+	})
+}
\ No newline at end of file
@@ -0,0 +1,752 @@
+package v2
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksankeerth/open-image-registry/constants"
+	"github.com/ksankeerth/open-image-registry/storage"
+	"github.com/ksankeerth/open-image-registry/tests/integration/helpers"
+	"github.com/ksankeerth/open-image-registry/tests/testdata"
+	"github.com/ksankeerth/open-image-registry/types/api/v1alpha/mgmt"
+	"github.com/ksankeerth/open-image-registry/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstreamProxy is an upstream registry of a fake distribution registry served by the registry under test
+type upstreamProxy struct {
+	id      string
+	baseURL string
+	token   string
+}
+
+// startUpstreamProxy creates an upstream registry of upstreamURL and waits until it is listening
+func (r *RegistryTestSuite) startUpstreamProxy(t *testing.T, name, upstreamURL string,
+	cacheEnabled bool) *upstreamProxy {
+	t.Helper()
+
+	password := "UpstreamProxy123!"
+	r.seeder.ProvisionUserWithPassword(t, "registry-upstream-admin", "registry-upstream-admin@t.com",
+		constants.RoleAdmin, password)
+
+	port := helpers.FindFreePort()
+	proxy := &upstreamProxy{
+		id:      r.seeder.CreateUpstream(t, name, port, upstreamURL, cacheEnabled),
+		baseURL: fmt.Sprintf("http://localhost:%d", port),
+		token:   r.seeder.RegistryToken(t, "registry-upstream-admin", password),
+	}
+	proxy.waitUntilListening(t)
+
+	return proxy
+}
+
+// waitUntilListening waits until the upstream registry is listening. Listeners are restarted when configs of the
+// upstream registry are updated.
+func (p *upstreamProxy) waitUntilListening(t *testing.T) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(p.baseURL + testdata.EndpointRegistryBase)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return true
+	}, 5*time.Second, 50*time.Millisecond, "upstream registry %s isn't listening", p.id)
+}
+
+func (p *upstreamProxy) get(t *testing.T, path string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, p.baseURL+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func (r *RegistryTestSuite) testUpstreamBlobs(t *testing.T) {
+	content := bytes.Repeat([]byte("upstream layer "), 64*1024)
+	digest := blobDigest(content)
+	half := len(content) / 2
+
+	// upstream registry serves content which doesn't match the digest
+	corruptedDigest := blobDigest([]byte("original layer"))
+
+	blobPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "layers", digest)
+	corruptedPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "layers", corruptedDigest)
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	requestCount := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+
+	// second half of the blob is served once released
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	releaseBlob := func() { releaseOnce.Do(func() { close(release) }) }
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests[req.URL.Path]++
+		mu.Unlock()
+
+		switch req.URL.Path {
+		case testdata.EndpointRegistryBase:
+		case blobPath:
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:half])
+			w.(http.Flusher).Flush()
+			<-release
+			w.Write(content[half:])
+		case corruptedPath:
+			w.Write([]byte("corrupted layer"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+	defer releaseBlob()
+
+	proxy := r.startUpstreamProxy(t, "upstream-blobs", upstream.URL, true)
+	defer r.seeder.DeleteUpstream(t, proxy.id)
+
+	_, repoId := r.seeder.CreateUpstreamRepository(t, proxy.id, "library", "layers")
+
+	t.Run("Blob is streamed while it is cached", func(t *testing.T) {
+		resp := proxy.get(t, blobPath, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, strconv.Itoa(len(content)), resp.Header.Get("Content-Length"))
+		assert.Equal(t, digest, resp.Header.Get("Docker-Content-Digest"))
+
+		// first half reaches the client before the upstream registry completes the response
+		received := make([]byte, half)
+		_, err := io.ReadFull(resp.Body, received)
+		require.NoError(t, err)
+		assert.Equal(t, content[:half], received)
+		assert.Nil(t, r.seeder.CachedBlob(t, repoId, digest), "blob must not be cached before it is verified")
+
+		releaseBlob()
+		rest, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content[half:], rest)
+
+		require.Eventually(t, func() bool {
+			return r.seeder.CachedBlob(t, repoId, digest) != nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		blob := r.seeder.BlobContent(t, digest)
+		require.NotNil(t, blob)
+		assert.Equal(t, int64(len(content)), blob.Size)
+	})
+
+	t.Run("Cached blob is served from storage", func(t *testing.T) {
+		requested := requestCount(blobPath)
+
+		resp := proxy.get(t, blobPath, map[string]string{"Range": "bytes=0-9"})
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+
+		received, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content[:10], received)
+		assert.Equal(t, requested, requestCount(blobPath))
+	})
+
+	t.Run("Blob not matching the digest is not cached", func(t *testing.T) {
+		for i := 1; i <= 2; i++ {
+			resp := proxy.get(t, corruptedPath, nil)
+			// response is aborted once the content is known to be invalid
+			received, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err == nil {
+				assert.NotEqual(t, corruptedDigest, blobDigest(received))
+			}
+
+			assert.Nil(t, r.seeder.CachedBlob(t, repoId, corruptedDigest))
+			assert.Nil(t, r.seeder.BlobContent(t, corruptedDigest))
+			// every pull reaches the upstream registry since nothing was cached
+			assert.Equal(t, i, requestCount(corruptedPath))
+		}
+	})
+
+	t.Run("Anonymous pull", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, proxy.baseURL+blobPath, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+r.seeder.RegistryToken(t, "", ""))
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Blob not found in upstream", func(t *testing.T) {
+		resp := proxy.get(t, fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "layers",
+			blobDigest([]byte("unknown"))), nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+// pullResult is the response received by one of the concurrent pulls
+type pullResult struct {
+	status int
+	body   []byte
+	err    error
+}
+
+// pullConcurrently sends GET requests of path from concurrent clients and returns once all of them are answered
+func (p *upstreamProxy) pullConcurrently(path string, clients int) <-chan []pullResult {
+	results := make(chan []pullResult, 1)
+
+	go func() {
+		var wg sync.WaitGroup
+		responses := make([]pullResult, clients)
+		for i := range responses {
+			wg.Add(1)
+			go func(result *pullResult) {
+				defer wg.Done()
+
+				req, err := http.NewRequest(http.MethodGet, p.baseURL+path, nil)
+				if err != nil {
+					result.err = err
+					return
+				}
+				req.Header.Set("Authorization", "Bearer "+p.token)
+
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					result.err = err
+					return
+				}
+				defer resp.Body.Close()
+
+				result.status = resp.StatusCode
+				result.body, result.err = io.ReadAll(resp.Body)
+			}(&responses[i])
+		}
+		wg.Wait()
+		results <- responses
+	}()
+
+	return results
+}
+
+func (r *RegistryTestSuite) testUpstreamCoalescing(t *testing.T) {
+	const clients = 8
+
+	layer := bytes.Repeat([]byte("coalesced layer "), 32*1024)
+	layerDigest := blobDigest(layer)
+	manifest := imageManifest(layerDigest)
+
+	manifestPath := fmt.Sprintf(testdata.EndpointRegistryManifest, "library", "coalesced", "latest")
+	blobPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "coalesced", layerDigest)
+	failingPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "coalesced",
+		blobDigest([]byte("failing layer")))
+	// same layer is pulled through two repositories
+	sharedLayer := bytes.Repeat([]byte("shared layer "), 32*1024)
+	sharedPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "coalesced", blobDigest(sharedLayer))
+	mirroredPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "mirror", "coalesced", blobDigest(sharedLayer))
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	requestCount := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+
+	// upstream registry answers once the path is released so that concurrent pulls wait for the first one
+	gates := map[string]chan struct{}{
+		manifestPath: make(chan struct{}),
+		blobPath:     make(chan struct{}),
+		failingPath:  make(chan struct{}),
+		sharedPath:   make(chan struct{}),
+	}
+	var releaseOnce sync.Map
+	release := func(path string) {
+		if _, released := releaseOnce.LoadOrStore(path, true); !released {
+			close(gates[path])
+		}
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests[req.URL.Path]++
+		requests[req.Method+" "+req.URL.Path]++
+		mu.Unlock()
+
+		if gate, ok := gates[req.URL.Path]; ok {
+			<-gate
+		}
+
+		switch req.URL.Path {
+		case testdata.EndpointRegistryBase:
+		case manifestPath:
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Write(manifest)
+		case blobPath:
+			w.Header().Set("Content-Length", strconv.Itoa(len(layer)))
+			w.Write(layer)
+		case sharedPath, mirroredPath:
+			w.Header().Set("Content-Length", strconv.Itoa(len(sharedLayer)))
+			w.Write(sharedLayer)
+		case failingPath:
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+	for path := range gates {
+		defer release(path)
+	}
+
+	proxy := r.startUpstreamProxy(t, "upstream-coalescing", upstream.URL, true)
+	defer r.seeder.DeleteUpstream(t, proxy.id)
+
+	r.seeder.CreateUpstreamRepository(t, proxy.id, "library", "coalesced")
+	r.seeder.CreateUpstreamRepository(t, proxy.id, "mirror", "coalesced")
+
+	// pullOnce releases path once the first pull reaches the upstream registry and the others are waiting for it
+	pullOnce := func(t *testing.T, path string) []pullResult {
+		results := proxy.pullConcurrently(path, clients)
+
+		require.Eventually(t, func() bool {
+			return requestCount(path) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(200 * time.Millisecond)
+		release(path)
+
+		select {
+		case responses := <-results:
+			return responses
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "concurrent pulls were not answered", path)
+			return nil
+		}
+	}
+
+	t.Run("Concurrent manifest pulls fetch the tag once", func(t *testing.T) {
+		for _, result := range pullOnce(t, manifestPath) {
+			require.NoError(t, result.err)
+			assert.Equal(t, http.StatusOK, result.status)
+			assert.Equal(t, manifest, result.body)
+		}
+		assert.Equal(t, 1, requestCount(manifestPath))
+	})
+
+	t.Run("Concurrent blob pulls fetch the blob once", func(t *testing.T) {
+		for _, result := range pullOnce(t, blobPath) {
+			require.NoError(t, result.err)
+			assert.Equal(t, http.StatusOK, result.status)
+			assert.Equal(t, layerDigest, blobDigest(result.body))
+		}
+		assert.Equal(t, 1, requestCount(blobPath))
+	})
+
+	t.Run("Concurrent pulls through other repositories fetch the blob once", func(t *testing.T) {
+		first := proxy.pullConcurrently(sharedPath, 1)
+		require.Eventually(t, func() bool {
+			return requestCount(sharedPath) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		mirrored := proxy.pullConcurrently(mirroredPath, clients)
+		time.Sleep(200 * time.Millisecond)
+		release(sharedPath)
+
+		for _, results := range []<-chan []pullResult{first, mirrored} {
+			select {
+			case responses := <-results:
+				for _, result := range responses {
+					require.NoError(t, result.err)
+					assert.Equal(t, http.StatusOK, result.status)
+					assert.Equal(t, sharedLayer, result.body)
+				}
+			case <-time.After(10 * time.Second):
+				require.FailNow(t, "concurrent pulls were not answered")
+			}
+		}
+
+		// content cached through the first repository is linked to the other one
+		assert.Equal(t, 1, requestCount(http.MethodGet+" "+sharedPath))
+		assert.Equal(t, 0, requestCount(http.MethodGet+" "+mirroredPath))
+	})
+
+	t.Run("Failed fetch releases waiting pulls", func(t *testing.T) {
+		for _, result := range pullOnce(t, failingPath) {
+			require.NoError(t, result.err)
+			assert.NotEqual(t, http.StatusOK, result.status)
+		}
+		requested := requestCount(failingPath)
+		assert.Less(t, requested, clients, "failure of the first fetch must be shared with waiting pulls")
+
+		// failure isn't cached; the next pull fetches the blob again
+		resp := proxy.get(t, failingPath, nil)
+		resp.Body.Close()
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, requested+1, requestCount(failingPath))
+	})
+}
+
+func (r *RegistryTestSuite) testUpstreamCacheEviction(t *testing.T) {
+	// four blobs of 200KB exceed the threshold of 1MB storage limit at 50 percent
+	layers := map[string][]byte{}
+	digests := map[string]string{}
+	for _, name := range []string{"pinned", "oldest", "older", "recent"} {
+		layers[name] = bytes.Repeat([]byte(name+" layer "), 200*1024/len(name+" layer "))
+		digests[name] = blobDigest(layers[name])
+	}
+	manifest := imageManifest(digests["pinned"])
+
+	blobPath := func(name string) string {
+		return fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "evicted", digests[name])
+	}
+	manifestPath := fmt.Sprintf(testdata.EndpointRegistryManifest, "library", "evicted", "stable")
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	requestCount := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests[req.URL.Path]++
+		mu.Unlock()
+
+		if req.URL.Path == manifestPath {
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Write(manifest)
+			return
+		}
+		for name, content := range layers {
+			if req.URL.Path == blobPath(name) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.Write(content)
+				return
+			}
+		}
+		if req.URL.Path != testdata.EndpointRegistryBase {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	proxy := r.startUpstreamProxy(t, "upstream-eviction", upstream.URL, true)
+
+	_, repoId := r.seeder.CreateUpstreamRepository(t, proxy.id, "library", "evicted")
+	r.seeder.SetUpstreamStorageLimit(t, proxy.id, 1, 50)
+	r.seeder.PinCachedImage(t, proxy.id, "library", "evicted", "stable")
+
+	pull := func(t *testing.T, path string) {
+		t.Helper()
+
+		resp := proxy.get(t, path, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		_, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+	}
+
+	pull(t, manifestPath)
+	for _, name := range []string{"pinned", "oldest", "older", "recent"} {
+		pull(t, blobPath(name))
+		require.Eventually(t, func() bool {
+			return r.seeder.CachedBlob(t, repoId, digests[name]) != nil
+		}, 5*time.Second, 50*time.Millisecond)
+		// last access is recorded in milliseconds
+		time.Sleep(20 * time.Millisecond)
+	}
+	// pulls of cached blobs count as access; "recent" is older than the pinned blob otherwise
+	pull(t, blobPath("pinned"))
+	time.Sleep(20 * time.Millisecond)
+	pull(t, blobPath("recent"))
+
+	// content of "older" is shared with a repository of the hosted registry; evicting it doesn't free storage
+	password := "Eviction12345!"
+	m1 := r.seeder.ProvisionUserWithPassword(t, "upstream-eviction-m1", "upstream-eviction-m1@t.com",
+		constants.RoleMaintainer, password)
+	hostedNsId := r.seeder.CreateNamespace(t, "upstream-eviction-ns", "", constants.NamespacePurposeTeam, false, m1)
+	r.seeder.CreateRepository(t, "app", "", m1, hostedNsId, false)
+	r.pushBlob(t, r.seeder.RegistryToken(t, "upstream-eviction-m1", password), "upstream-eviction-ns", "app",
+		layers["older"])
+
+	evicted := func(items []mgmt.EvictionItem) []string {
+		names := []string{}
+		for _, item := range items {
+			for name, digest := range digests {
+				if item.Digest == digest {
+					names = append(names, name)
+				}
+			}
+		}
+		return names
+	}
+
+	t.Run("Dry run doesn't evict", func(t *testing.T) {
+		report := r.seeder.EvictUpstreamCache(t, proxy.id, true)
+		assert.True(t, report.DryRun)
+		assert.Greater(t, report.UsedBytes, report.ThresholdBytes)
+		assert.Equal(t, int64(512*1024), report.ThresholdBytes)
+		assert.Equal(t, []string{"oldest", "older"}, evicted(report.Blobs))
+		assert.Empty(t, report.Manifests)
+
+		for name := range layers {
+			assert.NotNil(t, r.seeder.CachedBlob(t, repoId, digests[name]), name)
+		}
+	})
+
+	t.Run("Least recently pulled blobs are evicted", func(t *testing.T) {
+		report := r.seeder.EvictUpstreamCache(t, proxy.id, false)
+		assert.False(t, report.DryRun)
+		assert.Equal(t, []string{"oldest", "older"}, evicted(report.Blobs))
+		assert.Equal(t, int64(len(layers["oldest"])), report.FreedBytes)
+		assert.LessOrEqual(t, report.UsedBytes-int64(len(layers["oldest"])+len(layers["older"])),
+			report.ThresholdBytes)
+
+		for _, name := range []string{"oldest", "older"} {
+			assert.Nil(t, r.seeder.CachedBlob(t, repoId, digests[name]), name)
+		}
+		assert.Nil(t, r.seeder.BlobContent(t, digests["oldest"]))
+		assert.NotNil(t, r.seeder.BlobContent(t, digests["older"]))
+		for _, name := range []string{"pinned", "recent"} {
+			assert.NotNil(t, r.seeder.CachedBlob(t, repoId, digests[name]), name)
+		}
+
+		// usage is below the threshold; nothing else is evicted
+		report = r.seeder.EvictUpstreamCache(t, proxy.id, false)
+		assert.Empty(t, report.Blobs)
+		assert.Empty(t, report.Manifests)
+	})
+
+	t.Run("Evicted blob is fetched again", func(t *testing.T) {
+		requested := requestCount(blobPath("oldest"))
+
+		pull(t, blobPath("oldest"))
+		assert.Equal(t, requested+1, requestCount(blobPath("oldest")))
+		require.Eventually(t, func() bool {
+			return r.seeder.CachedBlob(t, repoId, digests["oldest"]) != nil
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("Pinned manifest is served from the cache", func(t *testing.T) {
+		requested := requestCount(manifestPath)
+
+		resp := proxy.get(t, manifestPath, nil)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, requested, requestCount(manifestPath))
+	})
+
+	t.Run("Deleting the upstream registry removes cached content", func(t *testing.T) {
+		r.seeder.DeleteUpstream(t, proxy.id)
+
+		for _, name := range []string{"pinned", "oldest", "recent"} {
+			assert.Nil(t, r.seeder.BlobContent(t, digests[name]), name)
+			_, err := storage.Size(utils.BlobLocation(digests[name]))
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func (r *RegistryTestSuite) testUpstreamStaleCache(t *testing.T) {
+	layer := []byte("stale layer")
+	layerDigest := blobDigest(layer)
+	manifest := imageManifest(layerDigest)
+
+	manifestPath := fmt.Sprintf(testdata.EndpointRegistryManifest, "library", "stale", "latest")
+	uncachedPath := fmt.Sprintf(testdata.EndpointRegistryManifest, "library", "stale", "uncached")
+	digestPath := fmt.Sprintf(testdata.EndpointRegistryManifest, "library", "stale", blobDigest(manifest))
+	blobPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "stale", layerDigest)
+	uncachedBlobPath := fmt.Sprintf(testdata.EndpointRegistryBlob, "library", "stale",
+		blobDigest([]byte("uncached layer")))
+	tagsPath := fmt.Sprintf(testdata.EndpointRegistryTags, "library", "stale")
+
+	var mu sync.Mutex
+	requests := 0
+	// failure is the status the upstream registry answers artifact requests with; zero serves them
+	failure := 0
+	setFailure := func(status int) {
+		mu.Lock()
+		defer mu.Unlock()
+		failure = status
+	}
+	requestCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == testdata.EndpointRegistryBase {
+			return
+		}
+
+		mu.Lock()
+		requests++
+		status := failure
+		mu.Unlock()
+
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+
+		switch req.URL.Path {
+		case manifestPath, uncachedPath, digestPath:
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Write(manifest)
+		case blobPath:
+			w.Header().Set("Content-Length", strconv.Itoa(len(layer)))
+			w.Write(layer)
+		case tagsPath:
+			w.Write([]byte(`{"name":"library/stale","tags":["latest","uncached"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	proxy := r.startUpstreamProxy(t, "upstream-stale", upstream.URL, true)
+	defer r.seeder.DeleteUpstream(t, proxy.id)
+
+	_, repoId := r.seeder.CreateUpstreamRepository(t, proxy.id, "library", "stale")
+
+	pull := func(t *testing.T, path string) (int, []byte) {
+		t.Helper()
+
+		resp := proxy.get(t, path, nil)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, body
+	}
+
+	status, _ := pull(t, manifestPath)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = pull(t, blobPath)
+	require.Equal(t, http.StatusOK, status)
+	require.Eventually(t, func() bool {
+		return r.seeder.CachedBlob(t, repoId, layerDigest) != nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// rate limiting isn't retried, unlike server errors
+	setFailure(http.StatusTooManyRequests)
+	r.seeder.ExpireCachedManifest(t, repoId, "latest")
+
+	t.Run("Expired manifest isn't served by default", func(t *testing.T) {
+		status, _ := pull(t, manifestPath)
+		assert.NotEqual(t, http.StatusOK, status)
+	})
+
+	r.seeder.UpdateUpstreamCacheConfig(t, proxy.id, mgmt.UpstreamCacheConfigDTO{StaleIfError: true})
+	proxy.waitUntilListening(t)
+
+	t.Run("Expired manifest is served when upstream is unavailable", func(t *testing.T) {
+		requested := requestCount()
+
+		status, body := pull(t, manifestPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, manifest, body)
+		assert.Equal(t, requested+1, requestCount(), "expired manifest must be revalidated")
+	})
+
+	t.Run("Cached tags are listed when upstream is unavailable", func(t *testing.T) {
+		status, body := pull(t, tagsPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"name":"library/stale","tags":["latest"]}`, string(body))
+	})
+
+	t.Run("Manifest missing from the cache isn't served", func(t *testing.T) {
+		status, _ := pull(t, uncachedPath)
+		assert.NotEqual(t, http.StatusOK, status)
+	})
+
+	t.Run("Expired manifest isn't served when upstream rejects the request", func(t *testing.T) {
+		setFailure(http.StatusForbidden)
+		defer setFailure(http.StatusTooManyRequests)
+
+		status, _ := pull(t, manifestPath)
+		assert.NotEqual(t, http.StatusOK, status)
+	})
+
+	setFailure(0)
+	r.seeder.UpdateUpstreamCacheConfig(t, proxy.id, mgmt.UpstreamCacheConfigDTO{OfflineMode: true})
+	proxy.waitUntilListening(t)
+
+	t.Run("Offline mode serves cached content without contacting upstream", func(t *testing.T) {
+		requested := requestCount()
+
+		status, body := pull(t, manifestPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, manifest, body)
+
+		status, body = pull(t, blobPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, layer, body)
+
+		status, body = pull(t, tagsPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"name":"library/stale","tags":["latest"]}`, string(body))
+
+		assert.Equal(t, requested, requestCount())
+	})
+
+	t.Run("Offline mode doesn't fetch uncached content", func(t *testing.T) {
+		requested := requestCount()
+
+		status, _ := pull(t, uncachedPath)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _ = pull(t, uncachedBlobPath)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		assert.Equal(t, requested, requestCount())
+	})
+
+	r.seeder.UpdateUpstreamCacheConfig(t, proxy.id, mgmt.UpstreamCacheConfigDTO{TtlInSeconds: 3600})
+	proxy.waitUntilListening(t)
+
+	t.Run("Expired manifest unchanged in upstream is refreshed", func(t *testing.T) {
+		r.seeder.ExpireCachedManifest(t, repoId, "latest")
+		requested := requestCount()
+
+		status, body := pull(t, manifestPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, manifest, body)
+		assert.Equal(t, requested+1, requestCount(), "expired manifest must be revalidated")
+
+		// the cache entry is valid again
+		status, body = pull(t, manifestPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, manifest, body)
+		assert.Equal(t, requested+1, requestCount())
+	})
+
+	t.Run("Manifest cached by tag is cached by digest", func(t *testing.T) {
+		status, body := pull(t, digestPath)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, manifest, body)
+	})
+}
\ No newline at end of file
@@ -102,6 +102,7 @@ const (
 	// Authentication
 	EndpointLogin  = "/api/v1/auth/login"
 	EndpointLogout = "/api/v1/auth/logout"
+	EndpointToken  = "/api/v1/auth/token"
 
 	// User Management (Base)
 	EndpointUsers       = "/api/v1/users"
@@ -128,18 +129,52 @@ const (
 	EndpointUpstreams    = "/api/v1/resource/upstreams"
 
 	// Namespace ID Specific
-	EndpointNamespaceByID       = "/api/v1/resource/namespaces/%s"
-	EndpointNamespaceState      = "/api/v1/resource/namespaces/%s/state"
-	EndpointNamespaceVisibility = "/api/v1/resource/namespaces/%s/visibility"
-	EndpointNamespaceUsers      = "/api/v1/resource/namespaces/%s/users"
-	EndpointNamespaceUserRevoke = "/api/v1/resource/namespaces/%s/users/%s" // Identifier, UserID
+	EndpointNamespaceByID         = "/api/v1/resource/namespaces/%s"
+	EndpointNamespaceState        = "/api/v1/resource/namespaces/%s/state"
+	EndpointNamespaceVisibility   = "/api/v1/resource/namespaces/%s/visibility"
+	EndpointNamespaceUsers        = "/api/v1/resource/namespaces/%s/users"
+	EndpointNamespaceUserRevoke   = "/api/v1/resource/namespaces/%s/users/%s" // Identifier, UserID
+	EndpointNamespaceQuota        = "/api/v1/resource/namespaces/%s/quota"
+	EndpointNamespaceRetention    = "/api/v1/resource/namespaces/%s/retention-rules"
+	EndpointNamespaceRetentionRun = "/api/v1/resource/namespaces/%s/retention-rules/run"
 
 	// Repository ID Specific
-	EndpointRepositoryByID       = "/api/v1/resource/repositories/%s"
-	EndpointRepositoryState      = "/api/v1/resource/repositories/%s/state"
-	EndpointRepositoryVisibility = "/api/v1/resource/repositories/%s/visibility"
-	EndpointRepositoryUsers      = "/api/v1/resource/repositories/%s/users"
-	EndpointRepositoryUserRevoke = "/api/v1/resource/repositories/%s/users/%s"
+	EndpointRepositoryByID         = "/api/v1/resource/repositories/%s"
+	EndpointRepositoryState        = "/api/v1/resource/repositories/%s/state"
+	EndpointRepositoryVisibility   = "/api/v1/resource/repositories/%s/visibility"
+	EndpointRepositoryUsers        = "/api/v1/resource/repositories/%s/users"
+	EndpointRepositoryUserRevoke   = "/api/v1/resource/repositories/%s/users/%s"
+	EndpointRepositoryImmutable    = "/api/v1/resource/repositories/%s/immutable-tags"
+	EndpointRepositoryTagStable    = "/api/v1/resource/repositories/%s/tags/%s/stability" // Identifier, Tag
+	EndpointRepositoryQuota        = "/api/v1/resource/repositories/%s/quota"
+	EndpointRepositoryRetention    = "/api/v1/resource/repositories/%s/retention-rules"
+	EndpointRepositoryRetentionRun = "/api/v1/resource/repositories/%s/retention-rules/run"
+
+	// Webhooks
+	EndpointWebhooks          = "/api/v1/resource/webhooks"
+	EndpointWebhookByID       = "/api/v1/resource/webhooks/%s"
+	EndpointWebhookDeliveries = "/api/v1/resource/webhooks/%s/deliveries"
+
+	// Upstreams
+	EndpointUpstreamByID          = "/api/v1/resource/upstreams/%s"
+	EndpointUpstreamState         = "/api/v1/resource/upstreams/%s/state"
+	EndpointUpstreamAuthConfig    = "/api/v1/resource/upstreams/%s/auth-config"
+	EndpointUpstreamCacheConfig   = "/api/v1/resource/upstreams/%s/cache-config"
+	EndpointUpstreamNetworkConfig = "/api/v1/resource/upstreams/%s/network-config"
+	EndpointUpstreamCachePins     = "/api/v1/resource/upstreams/%s/cache/pins"
+	EndpointUpstreamCacheEvict    = "/api/v1/resource/upstreams/%s/cache/evict"
+
+	EndpointGC = "/api/v1/gc"
 
 	EndpointHealthCheck = "/api/v1/health"
+)
+
+// Registry (Docker V2) Endpoints
+const (
+	EndpointRegistryBase        = "/v2/"
+	EndpointRegistryCatalog     = "/v2/_catalog"
+	EndpointRegistryManifest    = "/v2/%s/%s/manifests/%s"   // namespace, repository, tag or digest
+	EndpointRegistryBlob        = "/v2/%s/%s/blobs/%s"       // namespace, repository, digest
+	EndpointRegistryBlobUploads = "/v2/%s/%s/blobs/uploads/" // namespace, repository
+	EndpointRegistryTags        = "/v2/%s/%s/tags/list"      // namespace, repository
 )
\ No newline at end of file
@@ -54,4 +54,73 @@ type DockerRegistryLoginResponse struct {
 	AccessToken string    `json:"access_token"`
 	ExpiresIn   int64     `json:"expires_in"`
 	IssuedAt    time.Time `json:"issued_at"`
+}
+
+// RegistryTokenAccess represents a scope requested for registry bearer tokens.
+// eg: {"type": "repository", "name": "library/nginx", "actions": ["pull", "push"]}
+type RegistryTokenAccess struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Actions []string `json:"actions"`
+}
+
+// TagListResponse is the response body of `GET /v2/<name>/tags/list`
+type TagListResponse struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// CatalogResponse is the response body of `GET /v2/_catalog`
+type CatalogResponse struct {
+	Repositories []string `json:"repositories"`
+}
+
+// EventsMediaType is the media type of the envelope of registry events sent to webhooks
+const EventsMediaType = "application/vnd.docker.distribution.events.v1+json"
+
+// EventEnvelope is the payload of webhook requests. It is compatible with Docker distribution notifications.
+type EventEnvelope struct {
+	Events []Event `json:"events"`
+}
+
+// Event describes an action performed on a repository
+type Event struct {
+	ID        string       `json:"id"`
+	Timestamp time.Time    `json:"timestamp"`
+	Action    string       `json:"action"`
+	Target    EventTarget  `json:"target"`
+	Request   EventRequest `json:"request"`
+	Actor     EventActor   `json:"actor"`
+	Source    EventSource  `json:"source"`
+}
+
+// EventTarget is the manifest or repository the action was performed on
+type EventTarget struct {
+	MediaType  string `json:"mediaType,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	Length     int64  `json:"length,omitempty"`
+	Repository string `json:"repository"`
+	URL        string `json:"url,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+}
+
+// EventRequest is the request which triggered the event
+type EventRequest struct {
+	ID        string `json:"id"`
+	Addr      string `json:"addr"`
+	Host      string `json:"host"`
+	Method    string `json:"method"`
+	UserAgent string `json:"useragent"`
+}
+
+// EventActor is the user who performed the action
+type EventActor struct {
+	Name string `json:"name,omitempty"`
+}
+
+// EventSource is the registry instance which emitted the event
+type EventSource struct {
+	Addr       string `json:"addr"`
+	InstanceID string `json:"instanceID"`
 }
\ No newline at end of file
@@ -0,0 +1,16 @@
+package mgmt
+
+type GCItem struct {
+	Repository string `json:"repository"`
+	Digest     string `json:"digest"`
+	Size       int64  `json:"size,omitempty"`
+}
+
+// GCResponse lists the manifests and the blobs removed by garbage collection. If dry_run is true, nothing was
+// removed.
+type GCResponse struct {
+	DryRun     bool     `json:"dry_run"`
+	Manifests  []GCItem `json:"manifests"`
+	Blobs      []GCItem `json:"blobs"`
+	FreedBytes int64    `json:"freed_bytes"`
+}
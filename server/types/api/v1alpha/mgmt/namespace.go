@@ -15,16 +15,17 @@ type CreateNamespaceResponse struct {
 }
 
 type NamespaceResponse struct {
-	ID          string     `json:"id"`
-	RegistryID  string     `json:"registry_id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Purpose     string     `json:"purpose"`
-	IsPublic    bool       `json:"is_public"`
-	State       string     `json:"state"`
-	CreatedBy   string     `json:"created_by"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at"`
+	ID          string         `json:"id"`
+	RegistryID  string         `json:"registry_id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Purpose     string         `json:"purpose"`
+	IsPublic    bool           `json:"is_public"`
+	State       string         `json:"state"`
+	CreatedBy   string         `json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   *time.Time     `json:"updated_at"`
+	Quota       *QuotaResponse `json:"quota"`
 }
 
 type UpdateNamespaceRequest struct {
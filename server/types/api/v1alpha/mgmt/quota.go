@@ -0,0 +1,16 @@
+package mgmt
+
+// QuotaRequest replaces the quota of a namespace or a repository. Omitted limits are unlimited.
+type QuotaRequest struct {
+	MaxBytes  *int64 `json:"max_bytes"`
+	MaxImages *int64 `json:"max_images"`
+}
+
+// QuotaResponse is the quota of a namespace or a repository along with its usage. null limits are unlimited.
+// Images are counted as the tagged manifests.
+type QuotaResponse struct {
+	MaxBytes   *int64 `json:"max_bytes"`
+	UsedBytes  int64  `json:"used_bytes"`
+	MaxImages  *int64 `json:"max_images"`
+	UsedImages int64  `json:"used_images"`
+}
\ No newline at end of file
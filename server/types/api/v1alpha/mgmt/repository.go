@@ -29,15 +29,16 @@ type CreateRepositoryResponse struct {
 }
 
 type RepositoryResponse struct {
-	ID          string     `json:"id"`
-	RegistryID  string     `json:"registry_id"`
-	NamespaceID string     `json:"namespace_id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	IsPublic    bool       `json:"is_public"`
-	State       string     `json:"state"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at"`
+	ID          string         `json:"id"`
+	RegistryID  string         `json:"registry_id"`
+	NamespaceID string         `json:"namespace_id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	IsPublic    bool           `json:"is_public"`
+	State       string         `json:"state"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   *time.Time     `json:"updated_at"`
+	Quota       *QuotaResponse `json:"quota"`
 }
 
 type UpdateRepositoryRequest struct {
@@ -46,4 +47,13 @@ type UpdateRepositoryRequest struct {
 
 type RepositoryNameCheckResponse struct {
 	Available bool `json:"available"`
+}
+
+// ImmutableTagsRequest replaces immutable tag patterns of a repository. Patterns are either globs (eg: v*) or `semver`.
+type ImmutableTagsRequest struct {
+	Patterns []string `json:"patterns"`
+}
+
+type ImmutableTagsResponse struct {
+	Patterns []string `json:"patterns"`
 }
\ No newline at end of file
@@ -0,0 +1,31 @@
+package mgmt
+
+// RetentionRule selects tags to delete. Tags matching tag_pattern are deleted unless they are among the keep_last
+// most recently pushed matching tags or pushed within max_age_days. Omitted conditions are ignored, but at least
+// one of them is required.
+type RetentionRule struct {
+	TagPattern string `json:"tag_pattern"`
+	KeepLast   *int64 `json:"keep_last"`
+	MaxAgeDays *int64 `json:"max_age_days"`
+}
+
+// RetentionRulesRequest replaces the retention rules of a namespace or a repository
+type RetentionRulesRequest struct {
+	Rules []RetentionRule `json:"rules"`
+}
+
+type RetentionRulesResponse struct {
+	Rules []RetentionRule `json:"rules"`
+}
+
+type RetentionItem struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest"`
+}
+
+// RetentionReportResponse lists the tags deleted by retention rules. If dry_run is true, nothing was deleted.
+type RetentionReportResponse struct {
+	DryRun bool            `json:"dry_run"`
+	Tags   []RetentionItem `json:"tags"`
+}
\ No newline at end of file
@@ -4,6 +4,8 @@ import "time"
 
 type UpstreamAuthConfigDTO struct {
 	// AuthType defines authentication methods. Possible values: `anonymous`, `basic`, `bearer`
+	// `basic` and `bearer` require `username` and `password` in `CredentialJson`. Password is never returned in
+	// responses.
 	AuthType string `json:"auth_type"`
 	// CredentialJson contains required data for the defined `AuthType`. eg:
 	// 1. { grant_type:'client-credentials', basic_auth_header: ''}
@@ -14,31 +16,44 @@ type UpstreamAuthConfigDTO struct {
 	TokenEndpoint string `json:"token_endpoint"`
 }
 
+// UpstreamAccessConfigDTO defines the network config of the upstream registry. Zero values are replaced by defaults.
+// Proxies are not supported yet.
 type UpstreamAccessConfigDTO struct {
 	ProxyEnabled               bool   `json:"proxy_enabled"`
 	ProxyUrl                   string `json:"proxy_url,omitempty"`
 	ConnectionTimeoutInSeconds int    `json:"connection_timeout"`
 	ReadTimeoutInSeconds       int    `json:"read_timeout"`
 	MaxConnections             int    `json:"max_connections"`
-	MaxRetries                 int    `json:"max_retries"`
+	MaxRetries                 *int   `json:"max_retries"`
 	RetryDelayInSeconds        int    `json:"retry_delay"`
 }
 
+// UpstreamStorageConfigDTO defines the storage limit of cached artifacts. Zero values are replaced by defaults.
 type UpstreamStorageConfigDTO struct {
 	StorageLimitInMbs float32 `json:"storage_limit"`
-	CleanupPolicy     string  `json:"cleanup_policy"`
-	CleanupThreshold  float32 `json:"cleanup_threshold"`
+	// CleanupPolicy is not used yet
+	CleanupPolicy    string  `json:"cleanup_policy"`
+	CleanupThreshold float32 `json:"cleanup_threshold"`
 }
 
+// UpstreamCacheConfigDTO defines caching of artifacts pulled from the upstream registry. Caching is enabled unless
+// stated otherwise. StaleIfError serves expired artifacts when the upstream registry is unreachable, rate limiting
+// or failing; OfflineMode serves cached artifacts only, without contacting the upstream registry.
 type UpstreamCacheConfigDTO struct {
-	Enabled      bool `json:"enabled"`
-	TtlInSeconds int  `json:"ttl_seconds"`
-	OfflineMode  bool `json:"offline_mode"`
+	Enabled      *bool `json:"enabled"`
+	TtlInSeconds int   `json:"ttl_seconds"`
+	StaleIfError bool  `json:"stale_if_error"`
+	OfflineMode  bool  `json:"offline_mode"`
 }
 
 type CreateUpstreamRegistryRequest struct {
 	Name        string `json:"name"`
-	Port        int    `json:"port"`
+	Description string `json:"description"`
+	// Vendor is `custom` unless stated otherwise. Docker Hub is served by its own client, other vendors are expected
+	// to implement the OCI distribution spec.
+	Vendor string `json:"vendor,omitempty"`
+	Port   int    `json:"port"`
+	// Status is the initial state of the upstream registry. It is `Active` unless stated otherwise.
 	Status      string `json:"status,omitempty"`
 	UpstreamUrl string `json:"upstream_url"`
 
@@ -54,17 +69,27 @@ type CreateUpstreamRegistryResponse struct {
 }
 
 type UpdateUpstreamRegistryRequest struct {
-	RegId string `json:"reg_id"`
-	CreateUpstreamRegistryRequest
+	Description string `json:"description"`
+	Port        int    `json:"port"`
+	UpstreamUrl string `json:"upstream_url"`
+}
+
+type UpdateUpstreamCacheConfigRequest struct {
+	StorageConfig UpstreamStorageConfigDTO `json:"storage_config"`
+	CacheConfig   UpstreamCacheConfigDTO   `json:"cache_config"`
 }
 
 type UpstreamRegistrySummaryDTO struct {
-	Id                string `json:"id"`
-	Name              string `json:"name"`
-	Port              int    `json:"port"`
-	Status            string `json:"status,omitempty"`
-	UpstreamUrl       string `json:"upstream_url"`
-	CachedImagesCount int    `json:"cached_images_count"`
+	Id                string     `json:"id"`
+	Name              string     `json:"name"`
+	Description       string     `json:"description"`
+	Vendor            string     `json:"vendor"`
+	Port              int        `json:"port"`
+	Status            string     `json:"status,omitempty"`
+	UpstreamUrl       string     `json:"upstream_url"`
+	CachedImagesCount int        `json:"cached_images_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
 }
 
 type ListUpstreamsResponse struct {
@@ -88,19 +113,23 @@ type UpstreamAccessConfigResponse struct {
 
 type UpstreamStorageConfigResponse struct {
 	UpstreamStorageConfigDTO
+	// UsedBytes is the size of the manifests and the blobs cached from the upstream registry
+	UsedBytes int64      `json:"used_bytes"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
 }
 
 type UpstreamCacheConfigResponse struct {
 	UpstreamCacheConfigDTO
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
 }
 
 type UpstreamRegistryResponse struct {
 	Id            string                        `json:"id"`
 	Name          string                        `json:"name"`
+	Description   string                        `json:"description"`
+	Vendor        string                        `json:"vendor"`
 	Port          int                           `json:"port"`
 	Status        string                        `json:"status,omitempty"`
 	UpstreamUrl   string                        `json:"upstream_url"`
@@ -110,4 +139,40 @@ type UpstreamRegistryResponse struct {
 	AccessConfig  UpstreamAccessConfigResponse  `json:"access_config"`
 	StorageConfig UpstreamStorageConfigResponse `json:"storage_config"`
 	CacheConfig   UpstreamCacheConfigResponse   `json:"cache_config"`
-}
\ No newline at end of file
+}
+
+// PinCachedImageRequest protects a cached image from eviction. Reference is a tag or a digest. Child manifests,
+// referrers and blobs of the image are protected as well.
+type PinCachedImageRequest struct {
+	Namespace  string `json:"namespace"`
+	Repository string `json:"repository"`
+	Reference  string `json:"reference"`
+}
+
+type CachePinDTO struct {
+	Namespace  string    `json:"namespace"`
+	Repository string    `json:"repository"`
+	Reference  string    `json:"reference"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ListCachePinsResponse struct {
+	Pins []*CachePinDTO `json:"pins"`
+}
+
+type EvictionItem struct {
+	Repository string `json:"repository"`
+	Digest     string `json:"digest"`
+	Size       int64  `json:"size"`
+}
+
+// CacheEvictionResponse lists the manifests and the blobs evicted from the cache of the upstream registry. Nothing is
+// evicted unless used_bytes exceeds threshold_bytes. If dry_run is true, nothing was evicted.
+type CacheEvictionResponse struct {
+	DryRun         bool           `json:"dry_run"`
+	UsedBytes      int64          `json:"used_bytes"`
+	ThresholdBytes int64          `json:"threshold_bytes"`
+	Manifests      []EvictionItem `json:"manifests"`
+	Blobs          []EvictionItem `json:"blobs"`
+	FreedBytes     int64          `json:"freed_bytes"`
+}
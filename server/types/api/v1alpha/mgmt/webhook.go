@@ -0,0 +1,64 @@
+package mgmt
+
+import "time"
+
+// CreateWebhookRequest registers an endpoint receiving registry events. namespace is the id or the name of the
+// namespace whose events are delivered; webhooks without namespace receive events of all namespaces. events lists
+// the actions delivered (push, pull, delete, create); empty means all actions.
+type CreateWebhookRequest struct {
+	Name      string   `json:"name"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret"`
+	Namespace string   `json:"namespace"`
+	Events    []string `json:"events"`
+	Enabled   *bool    `json:"enabled"`
+}
+
+// UpdateWebhookRequest replaces the url, events and state of a webhook. The secret is kept if it is omitted and
+// removed if it is empty.
+type UpdateWebhookRequest struct {
+	URL     string   `json:"url"`
+	Secret  *string  `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled bool     `json:"enabled"`
+}
+
+type CreateWebhookResponse struct {
+	ID string `json:"id"`
+}
+
+// WebhookResponse describes a webhook. The secret is never returned.
+type WebhookResponse struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	URL         string     `json:"url"`
+	NamespaceID string     `json:"namespace_id"`
+	Namespace   string     `json:"namespace"`
+	Events      []string   `json:"events"`
+	Enabled     bool       `json:"enabled"`
+	HasSecret   bool       `json:"has_secret"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// WebhookDeliveryResponse is a delivery of an event to a webhook. response_code and error describe the last attempt.
+type WebhookDeliveryResponse struct {
+	ID           string     `json:"id"`
+	EventID      string     `json:"event_id"`
+	Action       string     `json:"action"`
+	Payload      string     `json:"payload"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	ResponseCode int        `json:"response_code"`
+	Error        string     `json:"error"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
\ No newline at end of file
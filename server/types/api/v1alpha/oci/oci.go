@@ -1,5 +1,10 @@
 package oci
 
+const (
+	MediaTypeImageIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+)
+
 // -------- application/vnd.oci.image.index.v1+json ---------------
 // OCIImageIndex represents the top-level OCI image index structure
 type OCIImageIndex struct {
@@ -7,6 +12,8 @@ type OCIImageIndex struct {
 	MediaType     string            `json:"mediaType"`
 	Manifests     []OCIDescriptor   `json:"manifests"`
 	Annotations   map[string]string `json:"annotations,omitempty"`
+	Subject       *OCIDescriptor    `json:"subject,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
 }
 
 // OCIDescriptor represents a content descriptor in the OCI spec
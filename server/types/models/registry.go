@@ -28,6 +28,40 @@ type RepositoryModel struct {
 	UpdatedAt   *time.Time
 }
 
+// QuotaModel is the storage limit of a namespace or a repository along with its current usage. nil limits are
+// unlimited.
+type QuotaModel struct {
+	MaxBytes   *int64
+	MaxImages  *int64
+	UsedBytes  int64
+	UsedImages int64
+}
+
+// RetentionRuleModel selects tags of a namespace or a repository to delete. Tags matching TagPattern are deleted
+// unless they are among the KeepLast most recently pushed matching tags or pushed within MaxAgeDays. nil disables
+// the condition.
+type RetentionRuleModel struct {
+	TagPattern string
+	KeepLast   *int64
+	MaxAgeDays *int64
+}
+
+// RetentionRepositoryModel identifies a repository of the hosted registry which is subject to retention rules
+type RetentionRepositoryModel struct {
+	ID          string
+	NamespaceID string
+	Namespace   string
+	Name        string
+}
+
+// RetentionTagModel is a tag considered by retention rules. PushedAt is the last time the tag was pushed.
+type RetentionTagModel struct {
+	Tag      string
+	Digest   string
+	IsStable bool
+	PushedAt time.Time
+}
+
 type ImageBlobMetaModel struct {
 	NamespaceID  string
 	RegistryID   string
@@ -39,15 +73,59 @@ type ImageBlobMetaModel struct {
 	UpdatedAt    *time.Time
 }
 
+// ImageBlobModel is the content of a blob shared by the repositories linking it
+type ImageBlobModel struct {
+	Digest   string
+	Size     int64
+	Location string
+	RefCount int
+}
+
 type ImageBlobUploadSessionModel struct {
 	SessionID     string
 	NamespaceID   string
 	RepositoryID  string
 	BytesReceived int
+	SHA256State   []byte // marshalled state of the running sha256 hash
+	SHA512State   []byte // marshalled state of the running sha512 hash
 	CreatedAt     time.Time
 	UpdatedAt     *time.Time
 }
 
+// IdleUploadSessionModel identifies an upload session which has not received content for a while. Namespace and
+// Repository are empty if they no longer exist.
+type IdleUploadSessionModel struct {
+	SessionID  string
+	Namespace  string
+	Repository string
+}
+
+// GCManifestModel describes a manifest for garbage collection. Repository is empty if the repository no longer
+// exists. Recent is true if the manifest was pushed within the grace period.
+type GCManifestModel struct {
+	ID               string
+	RepositoryID     string
+	Repository       string
+	Digest           string
+	MediaType        string
+	SubjectDigest    string
+	Tagged           bool
+	RepositoryExists bool
+	Recent           bool
+}
+
+// GCBlobModel describes a blob for garbage collection. Repository is empty if the repository no longer exists.
+// Recent is true if the blob was uploaded within the grace period.
+type GCBlobModel struct {
+	RepositoryID string
+	Repository   string
+	Digest       string
+	Size         int64
+	Location     string
+	RefCount     int
+	Recent       bool
+}
+
 type ImageManifestModel struct {
 	ID           string
 	Digest       string
@@ -62,6 +140,15 @@ type ImageManifestModel struct {
 	UpdatedAt    *time.Time
 }
 
+// ImageReferrerModel is a manifest which refers a subject manifest
+type ImageReferrerModel struct {
+	Digest       string
+	Size         int64
+	MediaType    string
+	ArtifactType string
+	Content      string
+}
+
 type ImageTagModel struct {
 	Id           string
 	NamespaceId  string
@@ -83,3 +170,35 @@ type RegistryCacheModel struct {
 	CreatedAt    time.Time
 	UpdatedAt    *time.Time
 }
+
+// CachePinModel is a cached image of an upstream registry which is never evicted. Reference is a tag or a digest.
+type CachePinModel struct {
+	RepositoryID string
+	Namespace    string
+	Repository   string
+	Reference    string
+	CreatedAt    time.Time
+}
+
+// CachedManifestModel describes a manifest cached from an upstream registry for eviction. Pinned is true if the
+// manifest is pinned by its digest or by one of its tags.
+type CachedManifestModel struct {
+	RepositoryID  string
+	Repository    string
+	Digest        string
+	MediaType     string
+	Size          int64
+	SubjectDigest string
+	Pinned        bool
+	LastAccessed  time.Time
+}
+
+// CachedBlobModel describes a blob cached for a repository of an upstream registry for eviction
+type CachedBlobModel struct {
+	RepositoryID string
+	Repository   string
+	Digest       string
+	Size         int64
+	Location     string
+	LastAccessed time.Time
+}
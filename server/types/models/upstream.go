@@ -28,8 +28,12 @@ type UpstreamRegistryCacheStoreConfig struct {
 	TTLSeconds       int
 	StorageLimit     float32
 	CleanupThreshold float32
-	CreatedAt        time.Time
-	UpdatedAt        *time.Time
+	// StaleIfError serves expired cache entries when the upstream registry is unavailable
+	StaleIfError bool
+	// OfflineMode serves only cached content without contacting the upstream registry
+	OfflineMode bool
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
 }
 
 type UpstreamRegistryNetworkConfig struct {
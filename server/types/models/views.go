@@ -40,6 +40,11 @@ type UpstreamAddressView struct {
 	UpstreamUrl string
 }
 
+type UpstreamRegistryView struct {
+	UpstreamRegistry
+	CachedImagesCount int
+}
+
 type NamespaceView struct {
 	RegistryID  string
 	ID          string
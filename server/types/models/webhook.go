@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// WebhookModel is an HTTP endpoint receiving registry events. Webhooks without NamespaceID receive events of all
+// namespaces. Events lists the actions delivered; empty means all actions.
+type WebhookModel struct {
+	ID          string
+	Name        string
+	URL         string
+	Secret      string
+	NamespaceID string
+	Namespace   string
+	Events      []string
+	Enabled     bool
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
+}
+
+// WebhookDeliveryModel is a delivery of an event to a webhook. ResponseCode and Error describe the last attempt.
+type WebhookDeliveryModel struct {
+	ID           string
+	WebhookID    string
+	EventID      string
+	Action       string
+	Payload      string
+	Status       string
+	Attempts     int
+	ResponseCode int
+	Error        string
+	CreatedAt    time.Time
+	UpdatedAt    *time.Time
+}
\ No newline at end of file
@@ -2,8 +2,10 @@ package utils
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -15,8 +17,19 @@ import (
 
 const NameRegex = "^[a-zA-Z0-9_-]+$"
 
+// IsImageDigest reports whether the reference is a digest rather than a tag. Tags can't contain `:`, so any
+// well-formed digest is treated as a digest regardless of its algorithm. eg: sha256:<hex>, sha512:<hex>
 func IsImageDigest(value string) bool {
-	return strings.HasPrefix(value, "sha256:")
+	return IsValidDigest(value)
+}
+
+// digestRegex follows the digest grammar of OCI image spec. eg: sha256:<hex>
+var digestRegex = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+
+// IsValidDigest verifies the value is a well-formed digest. Digests validated by this function are safe to be
+// used in storage locations.
+func IsValidDigest(value string) bool {
+	return digestRegex.MatchString(value)
 }
 
 func RemoveDuplicateKeys(keys []string) []string {
@@ -40,6 +53,22 @@ func CalcuateDigest(content []byte) string {
 	return fmt.Sprintf("sha256:%s", hex.EncodeToString(hash.Sum(nil)))
 }
 
+// CalculateDigestWith computes the digest of the content using the algorithm of the given digest. Only sha256 and
+// sha512 algorithms are supported; false is returned for others.
+func CalculateDigestWith(digest string, content []byte) (string, bool) {
+	algorithm, _, _ := strings.Cut(digest, ":")
+	switch algorithm {
+	case "sha256":
+		sum := sha256.Sum256(content)
+		return fmt.Sprintf("sha256:%s", hex.EncodeToString(sum[:])), true
+	case "sha512":
+		sum := sha512.Sum512(content)
+		return fmt.Sprintf("sha512:%s", hex.EncodeToString(sum[:])), true
+	default:
+		return "", false
+	}
+}
+
 func isValidName(name string) bool {
 	matched, _ := regexp.MatchString(NameRegex, name)
 	return matched
@@ -53,8 +82,50 @@ func IsValidRegistry(registry string) bool {
 	return isValidName(registry)
 }
 
+// repositoryRegex follows the name grammar of OCI distribution spec. Repository names may have any number of path
+// components separated by `/`. eg: team-a/services/billing-api
+var repositoryRegex = regexp.MustCompile(`^[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*)*$`)
+
+// IsValidRepository verifies the repository name within a namespace. Path components of valid names can't be `.` or
+// `..`, hence they are safe to be used in storage locations.
 func IsValidRepository(repository string) bool {
-	return isValidName(repository)
+	return repositoryRegex.MatchString(repository)
+}
+
+// IsValidExistingRepository verifies the name of a repository being accessed. Besides the names accepted by
+// IsValidRepository, single level names with uppercase letters are accepted so that repositories created by earlier
+// versions can still be pulled and deleted. New repositories must be verified with IsValidRepository.
+func IsValidExistingRepository(repository string) bool {
+	return IsValidRepository(repository) || isValidName(repository)
+}
+
+// TagPatternSemver is a tag pattern which matches semantic versions with optional `v` prefix. eg: 1.4.2, v2.0.0-rc.1
+const TagPatternSemver = "semver"
+
+var semverRegex = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*)?` +
+	`(?:\+[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*)?$`)
+
+// IsValidTagPattern verifies the tag pattern. A tag pattern is either `semver` or a glob such as `v*` or `release-?.*`.
+// See path.Match for the glob syntax.
+func IsValidTagPattern(pattern string) bool {
+	if pattern == TagPatternSemver {
+		return true
+	}
+	if pattern == "" || strings.Contains(pattern, "/") {
+		return false
+	}
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// MatchTagPattern reports whether the tag matches the pattern. Invalid patterns don't match any tag.
+func MatchTagPattern(pattern, tag string) bool {
+	if pattern == TagPatternSemver {
+		return semverRegex.MatchString(tag)
+	}
+	matched, err := path.Match(pattern, tag)
+	return err == nil && matched
 }
 
 func ParseImageBlobContentRangeFromRequest(headerValue string) (start, end int64, err error) {
@@ -80,10 +151,22 @@ func ParseImageBlobContentRangeFromRequest(headerValue string) (start, end int64
 	return start, end, nil
 }
 
+// StorageLocation joins the args into a storage location. Repository names with multiple path components are stored
+// as nested directories.
 func StorageLocation(args ...string) string {
 	return filepath.Clean(filepath.Join(args...))
 }
 
+// BlobLocation returns the content addressed storage location of the blob. Blobs are spread across directories by
+// the first two characters of the hex portion of the digest. eg: blobs/sha256/ab/abcd...
+func BlobLocation(digest string) string {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	if len(hex) < 2 {
+		return StorageLocation("blobs", algorithm, hex)
+	}
+	return StorageLocation("blobs", algorithm, hex[:2], hex)
+}
+
 func CombineAndCalculateSHA256Digest(inputs ...string) string {
 	hash := sha256.New()
 	hash.Write([]byte(strings.Join(inputs, ":")))
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +17,9 @@ func TestIsImageDigest(t *testing.T) {
 	}{
 		{"sha256adecf...", false},
 		{"sha256:1d34ffeaf190be23d3de5a8de0a436676b758f48f835c3a2d4768b798c15a7f1", true},
+		{"sha512:" + strings.Repeat("ab", 64), true},
+		{"latest", false},
+		{"v1.0.0", false},
 	}
 
 	for _, tt := range tests {
@@ -23,6 +27,24 @@ func TestIsImageDigest(t *testing.T) {
 	}
 }
 
+func TestIsValidDigest(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"sha256:1d34ffeaf190be23d3de5a8de0a436676b758f48f835c3a2d4768b798c15a7f1", true},
+		{"sha512:abcdef0123", true},
+		{"sha256:../../etc/passwd", false},
+		{"sha256:abc/def", false},
+		{"sha256", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, IsValidDigest(tt.value), tt.value)
+	}
+}
+
 func TestRemoveDuplicateKeys(t *testing.T) {
 	tests := []struct {
 		keys       []string
@@ -57,6 +79,24 @@ func TestCalcuateDigest(t *testing.T) {
 	}
 }
 
+func TestCalculateDigestWith(t *testing.T) {
+	tests := []struct {
+		digest   string
+		expected string
+		ok       bool
+	}{
+		{"sha256:", "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", true},
+		{"sha512:", "sha512:9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043", true},
+		{"md5:", "", false},
+	}
+
+	for _, tt := range tests {
+		digest, ok := CalculateDigestWith(tt.digest, []byte("hello"))
+		assert.Equal(t, tt.ok, ok, tt.digest)
+		assert.Equal(t, tt.expected, digest, tt.digest)
+	}
+}
+
 func TestIsValidRegistry(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -85,9 +125,21 @@ func TestIsValidRepository(t *testing.T) {
 		{"repo", true},
 		{"repo-1", true},
 		{"repo_2", true},
-		{"repo.name", false},
-		{"org/repo", false},
+		{"repo__3", true},
+		{"repo--4", true},
+		{"repo.name", true},
+		{"org/repo", true},
+		{"team-a/services/billing-api", true},
+		{"Repo", false},
 		{"repo name", false},
+		{"-repo", false},
+		{"repo_", false},
+		{"repo___name", false},
+		{"repo..name", false},
+		{"org//repo", false},
+		{"org/repo/", false},
+		{"/repo", false},
+		{"org/../repo", false},
 		{"", false},
 	}
 
@@ -96,6 +148,27 @@ func TestIsValidRepository(t *testing.T) {
 	}
 }
 
+func TestIsValidExistingRepository(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"repo", true},
+		{"team-a/services/billing-api", true},
+		{"Repo", true},
+		{"Billing_API-2", true},
+		{"Team-A/billing-api", false},
+		{"repo.Name", false},
+		{"org/../repo", false},
+		{"..", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, IsValidExistingRepository(tt.input))
+	}
+}
+
 func TestIsValidNamespace(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -116,6 +189,52 @@ func TestIsValidNamespace(t *testing.T) {
 	}
 }
 
+func TestIsValidTagPattern(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"semver", true},
+		{"v*", true},
+		{"release-?.*", true},
+		{"[0-9]*", true},
+		{"latest", true},
+		{"[0-9", false},
+		{"v*/x", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, IsValidTagPattern(tt.input), tt.input)
+	}
+}
+
+func TestMatchTagPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		tag      string
+		expected bool
+	}{
+		{"semver", "1.4.2", true},
+		{"semver", "v1.4.2", true},
+		{"semver", "2.0.0-rc.1", true},
+		{"semver", "1.4", false},
+		{"semver", "01.4.2", false},
+		{"semver", "latest", false},
+		{"v*", "v1", true},
+		{"v*", "v1.4.2", true},
+		{"v*", "1.4.2", false},
+		{"release-?.*", "release-1.0", true},
+		{"release-?.*", "release-10.0", false},
+		{"latest", "latest", true},
+		{"[0-9", "[0-9", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, MatchTagPattern(tt.pattern, tt.tag), "%s %s", tt.pattern, tt.tag)
+	}
+}
+
 func TestParseImageBlobContentRangeFromRequest(t *testing.T) {
 	tests := []struct {
 		header    string
@@ -152,6 +271,8 @@ func TestStorageLocation(t *testing.T) {
 		{[]string{"var", "lib", "..", "etc"}, filepath.Clean("var/etc")},
 		{[]string{}, "."},
 		{[]string{"/", "usr", "local"}, filepath.Clean("/usr/local")},
+		{[]string{"blobs", "hosted", "platform", "team-a/services/billing-api", "sha256:abc"},
+			filepath.Clean("blobs/hosted/platform/team-a/services/billing-api/sha256:abc")},
 	}
 
 	for _, tt := range tests {
@@ -159,6 +280,21 @@ func TestStorageLocation(t *testing.T) {
 	}
 }
 
+func TestBlobLocation(t *testing.T) {
+	tests := []struct {
+		digest string
+		want   string
+	}{
+		{"sha256:abcdef0123", filepath.Clean("blobs/sha256/ab/abcdef0123")},
+		{"sha512:0f1e2d", filepath.Clean("blobs/sha512/0f/0f1e2d")},
+		{"sha256:a", filepath.Clean("blobs/sha256/a")},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, BlobLocation(tt.digest))
+	}
+}
+
 func TestCombineAndCalculateSHA256Digest(t *testing.T) {
 	tests := []struct {
 		inputs []string